@@ -30,11 +30,6 @@ type FileBackend struct {
 	tracer trace.Tracer
 }
 
-type token struct {
-	Offset uint64  // Offset of the next object to return
-	Limit  *uint64 // Maximum number of objects to return for each iteration
-}
-
 var _ backend.Backend = (*FileBackend)(nil)
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -85,35 +80,48 @@ func (self *FileBackend) URL() *url.URL {
 	return url
 }
 
-// Create object in the backend
+// Create object in the backend. The file system has no conditional writes,
+// so the preconditions are checked before the write and a concurrent write
+// to the same path can be overwritten. Objects have no etag, so IfMatch only
+// succeeds with "*".
 func (self *FileBackend) CreateObject(ctx context.Context, req schema.CreateObjectRequest) (*schema.Object, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	// IfNotExists is true, we should fail if the object already exists
+	// IfNotExists is true, we should fail if the object already exists, and
+	// IfMatch is set, we should fail if the object does not exist
 	_, info, err := self.statObject(req.ObjectKey)
+	ifmatch := strings.TrimSpace(req.IfMatch)
 	if req.IfNotExists && err == nil {
 		return nil, gofiler.ErrConflict.Withf("object already exists: %q", req.Path)
+	} else if ifmatch != "" && errors.Is(err, gofiler.ErrNotFound) {
+		return nil, gofiler.ErrPreconditionFailed.Withf("object does not exist: %q", req.Path)
 	} else if err != nil && !errors.Is(err, gofiler.ErrNotFound) {
 		return nil, err
 	} else if info != nil && info.IsDir() {
 		return nil, gofiler.ErrBadParameter.Withf("cannot create object at directory path: %q", req.Path)
+	} else if ifmatch != "" && ifmatch != "*" {
+		return nil, gofiler.ErrPreconditionFailed.Withf("object has been modified: %q", req.Path)
 	}
 
-	// Create the object in the file system
-	r, err := self.fs.Create(req.Path)
+	// Write the body to a temporary file, which replaces the object once the
+	// whole body has been written, so a failed write leaves any existing
+	// object unchanged
+	r, err := self.fs.CreateTemp(req.Path)
 	if err != nil {
 		return nil, err
 	}
+	defer os.Remove(r.Name())
 	defer r.Close()
-
-	// Copy the body to the file
 	if req.Body != nil {
 		if _, err := io.Copy(r, req.Body); err != nil {
 			return nil, err
 		}
 	}
+	if err := self.fs.Replace(r, req.Path); err != nil {
+		return nil, err
+	}
 
 	// Return the object metadata
 	return self.GetObject(ctx, schema.GetObjectRequest{ObjectKey: req.ObjectKey})
@@ -195,10 +203,9 @@ func (self FileBackend) ListObjects(ctx context.Context, iterator *schema.Object
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	tok, ok := iterator.Token.(*token)
-	if tok == nil || !ok {
-		tok = types.Ptr(token{Offset: 0, Limit: types.Ptr(uint64(schema.ObjectListLimit))})
-		iterator.Token = tok
+	var after string
+	if iterator.Token != nil {
+		after = iterator.Token.After
 	}
 	iterator.Body = make([]*schema.Object, 0, schema.ObjectListLimit)
 
@@ -225,13 +232,8 @@ func (self FileBackend) ListObjects(ctx context.Context, iterator *schema.Object
 
 	errPageFull := errors.New("page full")
 
-	n := uint64(0)
 	appendEntry := func(entryPath string, isDir bool) error {
-		defer func() { n++ }()
-		if n < tok.Offset {
-			return nil
-		}
-		if tok.Limit != nil && uint64(len(iterator.Body)) >= *tok.Limit {
+		if len(iterator.Body) >= schema.ObjectListLimit {
 			return errPageFull
 		}
 		if isDir {
@@ -271,6 +273,17 @@ func (self FileBackend) ListObjects(ctx context.Context, iterator *schema.Object
 			return nil
 		}
 
+		// When resuming, skip the entries up to the last path returned, and
+		// only descend into the directories which contain it
+		if after != "" && (entryPath == after || walkBefore(entryPath, after)) {
+			if d.IsDir() && (entryPath == after || strings.HasPrefix(after, entryPath+"/")) && iterator.Recursive {
+				return nil
+			} else if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
 		// Emit directories when the caller is listing directories, or else emit files
 		if d.IsDir() && types.Value(iterator.Type) == schema.ContentTypeDirectory {
 			if err := appendEntry(entryPath, true); err != nil {
@@ -294,7 +307,7 @@ func (self FileBackend) ListObjects(ctx context.Context, iterator *schema.Object
 	})
 
 	if errors.Is(err, errPageFull) {
-		tok.Offset += uint64(len(iterator.Body))
+		iterator.Token = &schema.ListPosition{After: iterator.Body[len(iterator.Body)-1].Path}
 		return nil
 	}
 	if err != nil {
@@ -349,3 +362,16 @@ func (self *FileBackend) statObject(req schema.ObjectKey) (string, fs.FileInfo,
 	// Return the file info
 	return path.Dir(name), info, nil
 }
+
+// walkBefore returns true when path a is visited before path b in a walk of
+// the file system, which visits a directory before its entries and the
+// entries of a directory in lexical order
+func walkBefore(a, b string) bool {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return as[i] < bs[i]
+		}
+	}
+	return len(as) < len(bs)
+}
@@ -14,6 +14,8 @@ type WritableFS interface {
 	fs.FS
 	Root() string
 	Create(name string) (*os.File, error)
+	CreateTemp(name string) (*os.File, error)
+	Replace(f *os.File, name string) error
 	MkdirAll(name string, perm fs.FileMode) error
 	Remove(name string) error
 	RemoveAll(name string) error
@@ -64,6 +66,44 @@ func (w *dirFS) Create(name string) (*os.File, error) {
 	return os.Create(osPath)
 }
 
+// CreateTemp creates a hidden temporary file in the directory of the named
+// file, which replaces the named file with Replace. Parent directories are
+// created as needed.
+func (w *dirFS) CreateTemp(name string) (*os.File, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrInvalid}
+	}
+	osPath := filepath.Join(w.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(osPath), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(osPath), "."+filepath.Base(osPath)+".*")
+	if err != nil {
+		return nil, err
+	}
+
+	// Temporary files are only readable by the owner, so use the mode of
+	// files made by Create
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// Replace closes a file created with CreateTemp, and renames it to the named
+// file, replacing any existing file.
+func (w *dirFS) Replace(f *os.File, name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "rename", Path: name, Err: fs.ErrInvalid}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(w.root, filepath.FromSlash(name)))
+}
+
 // MkdirAll creates the named directory and any necessary parents.
 func (w *dirFS) MkdirAll(name string, perm fs.FileMode) error {
 	if name != "." && !fs.ValidPath(name) {
@@ -5,6 +5,8 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -178,3 +180,39 @@ func TestWritableFS_Remove_InvalidPath(t *testing.T) {
 		t.Error("expected error for absolute path, got nil")
 	}
 }
+
+func TestWritableFS_CreateTemp_Replace(t *testing.T) {
+	wfs, err := newWritableFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := wfs.Create("a/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("old")
+	f.Close()
+
+	// The temporary file is hidden, and the file is unchanged until replaced
+	tmp, err := wfs.CreateTemp("a/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp.WriteString("new")
+	if data, _ := fs.ReadFile(wfs, "a/b.txt"); string(data) != "old" {
+		t.Errorf("before replace: got %q, want %q", string(data), "old")
+	}
+	if !strings.HasPrefix(filepath.Base(tmp.Name()), ".") {
+		t.Errorf("temporary file %q is not hidden", tmp.Name())
+	}
+	if err := wfs.Replace(tmp, "a/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := fs.ReadFile(wfs, "a/b.txt"); string(data) != "new" {
+		t.Errorf("after replace: got %q, want %q", string(data), "new")
+	}
+	if entries, _ := fs.ReadDir(wfs, "a"); len(entries) != 1 {
+		t.Errorf("expected one file, got %d", len(entries))
+	}
+}
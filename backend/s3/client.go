@@ -17,7 +17,6 @@ import (
 	smithyhttp "github.com/aws/smithy-go/transport/http"
 	gofiler "github.com/mutablelogic/go-filer"
 	backend "github.com/mutablelogic/go-filer/backend"
-	schema "github.com/mutablelogic/go-filer/filer/schema"
 	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
 	types "github.com/mutablelogic/go-server/pkg/types"
 	otelaws "go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
@@ -207,16 +206,3 @@ func s3Client(ctx context.Context, tracer trace.Tracer, decryptfn backend.Decryp
 	}
 	return s3.NewFromConfig(config, s3Opts...), u, nil
 }
-
-////////////////////////////////////////////////////////////////////////////////
-// STUBS
-
-// Create object in the backend
-func (self *S3Backend) CreateObject(context.Context, schema.CreateObjectRequest) (*schema.Object, error) {
-	return nil, gofiler.ErrNotImplemented.With("CreateObject")
-}
-
-// Delete objects in the backend (single object or prefix)
-func (self *S3Backend) DeleteObjects(context.Context, schema.DeleteObjectsRequest) error {
-	return gofiler.ErrNotImplemented.With("DeleteObjects")
-}
@@ -0,0 +1,135 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	// Packages
+	aws "github.com/aws/aws-sdk-go-v2/aws"
+	s3svc "github.com/aws/aws-sdk-go-v2/service/s3"
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Create object in the backend. The preconditions are checked by S3 as part
+// of the write, so a concurrent write to the same key cannot be overwritten:
+// IfNotExists is sent as If-None-Match: *, and IfMatch is sent as If-Match
+// with the etag of the object which matched.
+func (self *S3Backend) CreateObject(ctx context.Context, req schema.CreateObjectRequest) (_ *schema.Object, err error) {
+	// Otel span
+	ctx, endSpan := otel.StartSpan(self.tracer, ctx, "s3.CreateObject",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	basePrefix := strings.TrimPrefix(strings.TrimSuffix(self.url.Path, "/"), "/")
+	input := &s3svc.PutObjectInput{
+		Bucket: aws.String(self.url.Host),
+		Key:    aws.String(s3KeyFromPath(req.Path, basePrefix)),
+	}
+	if req.ContentType != "" {
+		input.ContentType = aws.String(req.ContentType)
+	}
+	for _, meta := range req.Meta {
+		var value string
+		if json.Unmarshal(meta.Value, &value) == nil && meta.Key != "" {
+			if input.Metadata == nil {
+				input.Metadata = make(map[string]string, len(req.Meta))
+			}
+			input.Metadata[meta.Key] = value
+		}
+	}
+
+	// S3 accepts a single etag, so the existing object is matched first and
+	// its etag is used for the conditional write
+	if ifmatch := strings.TrimSpace(req.IfMatch); ifmatch != "" {
+		existing, err := self.GetObject(ctx, schema.GetObjectRequest{ObjectKey: req.ObjectKey})
+		if errors.Is(err, gofiler.ErrNotFound) {
+			return nil, gofiler.ErrPreconditionFailed.Withf("object does not exist: %q", req.Path)
+		} else if err != nil {
+			return nil, err
+		} else if ifmatch != "*" && !schema.MatchETags(ifmatch, types.Value(existing.ETag), true) {
+			return nil, gofiler.ErrPreconditionFailed.Withf("object has been modified: %q", req.Path)
+		}
+		input.IfMatch = aws.String(`"` + types.Value(existing.ETag) + `"`)
+	} else if req.IfNotExists {
+		input.IfNoneMatch = aws.String("*")
+	}
+
+	// The body needs to be seekable to sign the request and set the content
+	// length, so other readers are spooled to a temporary file
+	body, release, err := seekableBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	input.Body = body
+
+	// Write the object
+	if _, err := self.client.PutObject(ctx, input); err != nil {
+		switch s3StatusCode(err) {
+		case 412:
+			if req.IfNotExists {
+				return nil, gofiler.ErrConflict.Withf("object already exists: %q", req.Path)
+			}
+			return nil, gofiler.ErrPreconditionFailed.Withf("object has been modified: %q", req.Path)
+		case 409:
+			return nil, gofiler.ErrConflict.Withf("concurrent write to object: %q", req.Path)
+		}
+		return nil, err
+	}
+
+	// Return the object metadata
+	return self.GetObject(ctx, schema.GetObjectRequest{ObjectKey: req.ObjectKey})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// seekableBody returns a seekable reader for the body of an object, which is
+// empty when there is no body. Other readers are copied into a temporary
+// file, which is removed by the returned function.
+func seekableBody(r io.Reader) (io.ReadSeeker, func(), error) {
+	if r == nil {
+		return strings.NewReader(""), func() {}, nil
+	} else if rs, ok := r.(io.ReadSeeker); ok {
+		return rs, func() {}, nil
+	}
+
+	f, err := os.CreateTemp("", "filer-s3-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		release()
+		return nil, nil, err
+	} else if _, err := f.Seek(0, io.SeekStart); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return f, release, nil
+}
+
+// s3StatusCode returns the HTTP status code of an S3 error, or zero
+func s3StatusCode(err error) int {
+	type httpCoder interface{ HTTPStatusCode() int }
+	var he httpCoder
+	if errors.As(err, &he) {
+		return he.HTTPStatusCode()
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+package s3
+
+import (
+	"context"
+	"strings"
+
+	// Packages
+	aws "github.com/aws/aws-sdk-go-v2/aws"
+	s3svc "github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// s3DeleteBatchSize is the maximum number of keys in a DeleteObjects request
+	s3DeleteBatchSize = 1000
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Delete objects in the backend (single object or prefix). If an object exists
+// at the path, only that object is deleted, otherwise all objects under the
+// path prefix are deleted.
+func (self *S3Backend) DeleteObjects(ctx context.Context, req schema.DeleteObjectsRequest) (err error) {
+	// Otel span
+	ctx, endSpan := otel.StartSpan(self.tracer, ctx, "s3.DeleteObjects",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Delete a single object when it exists
+	basePrefix := strings.TrimPrefix(strings.TrimSuffix(self.url.Path, "/"), "/")
+	if key := s3KeyFromPath(req.Path, basePrefix); key != "" && key != basePrefix+"/" {
+		if _, err := self.client.HeadObject(ctx, &s3svc.HeadObjectInput{
+			Bucket: aws.String(self.url.Host),
+			Key:    aws.String(key),
+		}); err == nil {
+			_, err := self.client.DeleteObject(ctx, &s3svc.DeleteObjectInput{
+				Bucket: aws.String(self.url.Host),
+				Key:    aws.String(key),
+			})
+			return err
+		} else if !s3IsNotFound(err) {
+			return err
+		}
+	}
+
+	// Otherwise list the prefix, and delete the objects in batches
+	var prefix string
+	if key := strings.TrimSuffix(s3KeyFromPath(req.Path, basePrefix), "/"); key != "" {
+		prefix = key + "/"
+	}
+	input := &s3svc.ListObjectsV2Input{
+		Bucket: aws.String(self.url.Host),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	var deleted int
+	for {
+		out, err := self.client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		// Batch delete the page of objects
+		ids := make([]s3types.ObjectIdentifier, 0, len(out.Contents))
+		for _, item := range out.Contents {
+			if item.Key != nil {
+				ids = append(ids, s3types.ObjectIdentifier{Key: item.Key})
+			}
+		}
+		for len(ids) > 0 {
+			batch := ids[:min(len(ids), s3DeleteBatchSize)]
+			ids = ids[len(batch):]
+			result, err := self.client.DeleteObjects(ctx, &s3svc.DeleteObjectsInput{
+				Bucket: aws.String(self.url.Host),
+				Delete: &s3types.Delete{
+					Objects: batch,
+					Quiet:   aws.Bool(true),
+				},
+			})
+			if err != nil {
+				return err
+			} else if len(result.Errors) > 0 {
+				return gofiler.ErrInternalServerError.Withf("delete %q: %s", aws.ToString(result.Errors[0].Key), aws.ToString(result.Errors[0].Message))
+			}
+			deleted += len(batch)
+		}
+
+		// Next page
+		if !aws.ToBool(out.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+
+	// Return not found if nothing was deleted
+	if deleted == 0 {
+		return gofiler.ErrNotFound.Withf("object not found: %q", req.Path)
+	}
+
+	// Return success
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"path"
 	"strings"
+	"unicode/utf8"
 
 	// Packages
 	aws "github.com/aws/aws-sdk-go-v2/aws"
@@ -17,14 +18,6 @@ import (
 	attribute "go.opentelemetry.io/otel/attribute"
 )
 
-////////////////////////////////////////////////////////////////////////////////
-// TYPES
-
-type s3ListToken struct {
-	ContinuationToken *string
-	SeenDirs          map[string]bool // populated when synthesizing dirs from a recursive flat listing
-}
-
 ////////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
@@ -47,10 +40,9 @@ func (self *S3Backend) ListObjects(ctx context.Context, iterator *schema.ObjectL
 		return err
 	}
 
-	tok, ok := iterator.Token.(*s3ListToken)
-	if tok == nil || !ok {
-		tok = &s3ListToken{}
-		iterator.Token = tok
+	var tok schema.ListPosition
+	if iterator.Token != nil {
+		tok = *iterator.Token
 	}
 	iterator.Body = make([]*schema.Object, 0, schema.ObjectListLimit)
 
@@ -84,8 +76,15 @@ func (self *S3Backend) ListObjects(ctx context.Context, iterator *schema.ObjectL
 	if delimiter != "" {
 		input.Delimiter = aws.String(delimiter)
 	}
-	if tok.ContinuationToken != nil {
-		input.ContinuationToken = tok.ContinuationToken
+	if tok.Continuation != "" {
+		input.ContinuationToken = aws.String(tok.Continuation)
+	} else if tok.After != "" {
+		// Resume after a path without a continuation string. A directory is
+		// returned as a common prefix, so the keys under it are skipped too.
+		input.StartAfter = aws.String(s3KeyFromPath(tok.After, basePrefix))
+		if types.Value(iterator.Type) == schema.ContentTypeDirectory && !iterator.Recursive {
+			input.StartAfter = aws.String(aws.ToString(input.StartAfter) + "/" + string(utf8.MaxRune))
+		}
 	}
 
 	out, err := self.client.ListObjectsV2(ctx, input)
@@ -112,18 +111,16 @@ func (self *S3Backend) ListObjects(ctx context.Context, iterator *schema.ObjectL
 		}
 	} else if listingDirs && iterator.Recursive {
 		// Recursive: no delimiter means no CommonPrefixes; synthesize dir paths from object keys.
-		if tok.SeenDirs == nil {
-			tok.SeenDirs = make(map[string]bool)
-		}
+		// Keys are sorted, so the keys under a directory are contiguous, and a directory has
+		// already been returned when the previous key is also under it.
 		for _, item := range out.Contents {
 			relPath := s3PathFromKey(aws.ToString(item.Key), basePrefix)
 			parts := strings.Split(relPath, "/")
 			for i := 1; i < len(parts); i++ {
 				dir := strings.Join(parts[:i], "/")
-				if dir == reqPath || tok.SeenDirs[dir] {
+				if dir == reqPath || strings.HasPrefix(reqPath, dir+"/") || strings.HasPrefix(tok.After, dir+"/") {
 					continue
 				}
-				tok.SeenDirs[dir] = true
 				iterator.Body = append(iterator.Body, &schema.Object{
 					ObjectKey: schema.ObjectKey{
 						Volume: self.Name(),
@@ -134,6 +131,7 @@ func (self *S3Backend) ListObjects(ctx context.Context, iterator *schema.ObjectL
 					},
 				})
 			}
+			tok.After = relPath
 		}
 	} else {
 		for _, item := range out.Contents {
@@ -164,7 +162,8 @@ func (self *S3Backend) ListObjects(ctx context.Context, iterator *schema.ObjectL
 	}
 
 	if aws.ToBool(out.IsTruncated) {
-		tok.ContinuationToken = out.NextContinuationToken
+		tok.Continuation = aws.ToString(out.NextContinuationToken)
+		iterator.Token = &tok
 		return nil
 	}
 	iterator.Token = nil
@@ -24,6 +24,8 @@ const (
 	ErrForbidden
 	ErrNotIndexed
 	ErrNotModified
+	ErrPreconditionFailed
+	ErrUnauthorized
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -57,6 +59,10 @@ func (e Err) Error() string {
 		return "not indexed"
 	case ErrNotModified:
 		return "not modified"
+	case ErrPreconditionFailed:
+		return "precondition failed"
+	case ErrUnauthorized:
+		return "unauthorized"
 	}
 	return fmt.Sprintf("error code %d", int(e))
 }
@@ -89,6 +95,10 @@ func (e Err) HTTP() httpresponse.Err {
 		return httpresponse.Err(http.StatusPreconditionFailed)
 	case ErrNotModified:
 		return httpresponse.Err(http.StatusNotModified)
+	case ErrPreconditionFailed:
+		return httpresponse.Err(http.StatusPreconditionFailed)
+	case ErrUnauthorized:
+		return httpresponse.ErrNotAuthorized
 	default:
 		return httpresponse.ErrInternalError
 	}
@@ -14,3 +14,19 @@ func TestHTTPErrMapsPgNotFound(t *testing.T) {
 		t.Fatalf("expected ErrNotFound, got %v", err)
 	}
 }
+
+func TestHTTPErrMapsPreconditionFailed(t *testing.T) {
+	err := HTTPErr(ErrPreconditionFailed.With("etag mismatch"))
+	var httpErr httpresponse.Err
+	if !errors.As(err, &httpErr) || int(httpErr) != 412 {
+		t.Fatalf("expected 412, got %v", err)
+	}
+}
+
+func TestHTTPErrMapsUnauthorized(t *testing.T) {
+	err := HTTPErr(ErrUnauthorized.With("password required"))
+	var httpErr httpresponse.Err
+	if !errors.As(err, &httpErr) || int(httpErr) != 401 {
+		t.Fatalf("expected 401, got %v", err)
+	}
+}
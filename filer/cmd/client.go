@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	// Packages
 	otel "github.com/mutablelogic/go-client/pkg/otel"
@@ -23,34 +27,54 @@ import (
 // TYPES
 
 type ClientCommands struct {
+	Token string `name:"token" env:"${ENV_NAME}_TOKEN" help:"API token sent with each request, when the server requires authentication."`
+
 	ObjectClientCommands
 	SearchClientCommands
+	EventClientCommands
 	VolumeClientCommands
 	MetadataClientCommands
 	ArtworkClientCommands
 	CredentialClientCommands
 	LLMProviderClientCommands
+	BatchClientCommands
+	TokenClientCommands
+	AuditClientCommands
+	ShareClientCommands
 }
 
 type ObjectClientCommands struct {
-	ObjectList ObjectListCmd `cmd:"" name:"objects" help:"List server objects." group:"OBJECT"`
-	ObjectGet  ObjectGetCmd  `cmd:"" name:"object" help:"Get object metadata by volume and path." group:"OBJECT"`
+	ObjectList    ObjectListCmd    `cmd:"" name:"objects" help:"List server objects." group:"OBJECT"`
+	ObjectGet     ObjectGetCmd     `cmd:"" name:"object" help:"Get object metadata by volume and path." group:"OBJECT"`
+	ObjectArchive ObjectArchiveCmd `cmd:"" name:"object-archive" help:"Download objects under a path prefix as a zip or tar.gz archive." group:"OBJECT"`
+	ObjectPatch   ObjectPatchCmd   `cmd:"" name:"object-patch" help:"Set or remove user metadata on an object." group:"OBJECT"`
 }
 
 type SearchClientCommands struct {
 	Search SearchCmd `cmd:"" name:"search" help:"Search server objects." group:"SEARCH"`
 }
 
+type EventClientCommands struct {
+	Events EventsCmd `cmd:"" name:"events" help:"Stream volume and object events." group:"EVENT"`
+}
+
 type VolumeClientCommands struct {
-	VolumeGet        VolumeGetCmd        `cmd:"" name:"volume" help:"Get a volume by name." group:"VOLUME"`
-	VolumeList       VolumeListCmd       `cmd:"" name:"volumes" help:"List server volumes." group:"VOLUME"`
-	VolumeCreateFile VolumeCreateFileCmd `cmd:"" name:"volume-create-file" help:"Create a new file-backed volume." group:"VOLUME"`
-	VolumeCreateS3   VolumeCreateS3Cmd   `cmd:"" name:"volume-create-s3" help:"Create a new S3-backed volume." group:"VOLUME"`
-	VolumeMount      VolumeMountCmd      `cmd:"" name:"volume-mount" help:"Mount a volume by name." group:"VOLUME"`
-	VolumeUnmount    VolumeUnmountCmd    `cmd:"" name:"volume-unmount" help:"Unmount a volume by name." group:"VOLUME"`
-	VolumeUpdate     VolumeUpdateCmd     `cmd:"" name:"volume-update" help:"Update a volume by name." group:"VOLUME"`
-	VolumeDelete     VolumeDeleteCmd     `cmd:"" name:"volume-delete" help:"Delete a volume by name." group:"VOLUME"`
-	VolumeReindex    VolumeReindexCmd    `cmd:"" name:"volume-reindex" help:"Reindex a volume by name." group:"VOLUME"`
+	VolumeGet           VolumeGetCmd           `cmd:"" name:"volume" help:"Get a volume by name." group:"VOLUME"`
+	VolumeList          VolumeListCmd          `cmd:"" name:"volumes" help:"List server volumes." group:"VOLUME"`
+	VolumeCreateFile    VolumeCreateFileCmd    `cmd:"" name:"volume-create-file" help:"Create a new file-backed volume." group:"VOLUME"`
+	VolumeCreateS3      VolumeCreateS3Cmd      `cmd:"" name:"volume-create-s3" help:"Create a new S3-backed volume." group:"VOLUME"`
+	VolumeMount         VolumeMountCmd         `cmd:"" name:"volume-mount" help:"Mount a volume by name." group:"VOLUME"`
+	VolumeUnmount       VolumeUnmountCmd       `cmd:"" name:"volume-unmount" help:"Unmount a volume by name." group:"VOLUME"`
+	VolumeUpdate        VolumeUpdateCmd        `cmd:"" name:"volume-update" help:"Update a volume by name." group:"VOLUME"`
+	VolumeDelete        VolumeDeleteCmd        `cmd:"" name:"volume-delete" help:"Delete a volume by name." group:"VOLUME"`
+	VolumeReindex       VolumeReindexCmd       `cmd:"" name:"volume-reindex" help:"Reindex a volume by name." group:"VOLUME"`
+	VolumeIndex         VolumeIndexCmd         `cmd:"" name:"volume-index" help:"Show the indexing progress of a volume." group:"VOLUME"`
+	VolumeFailures      VolumeFailureListCmd   `cmd:"" name:"volume-failures" help:"List objects in a volume which could not be indexed." group:"VOLUME"`
+	VolumeFailureRetry  VolumeFailureRetryCmd  `cmd:"" name:"volume-failure-retry" help:"Retry indexing objects which failed, or all failures when no paths are given." group:"VOLUME"`
+	VolumeFailureIgnore VolumeFailureIgnoreCmd `cmd:"" name:"volume-failure-ignore" help:"Ignore objects when indexing a volume." group:"VOLUME"`
+	VolumeExport        VolumeExportCmd        `cmd:"" name:"volume-export" help:"Export the index of a volume, for backup or migration." group:"VOLUME"`
+	VolumeImport        VolumeImportCmd        `cmd:"" name:"volume-import" help:"Import the index of a volume from an export." group:"VOLUME"`
+	Indexers            IndexerListCmd         `cmd:"" name:"indexers" help:"List indexers, their capabilities and the volumes they have claimed." group:"VOLUME"`
 }
 
 type MetadataClientCommands struct {
@@ -58,7 +82,11 @@ type MetadataClientCommands struct {
 }
 
 type ArtworkClientCommands struct {
+	ArtworkList   ArtworkListCmd   `cmd:"" name:"artworks" help:"List artwork." group:"ARTWORK"`
 	ArtworkCreate ArtworkCreateCmd `cmd:"" name:"artwork-upload" help:"Upload a new artwork." group:"ARTWORK"`
+	ArtworkDelete ArtworkDeleteCmd `cmd:"" name:"artwork-delete" help:"Delete artwork by key." group:"ARTWORK"`
+	ArtworkLink   ArtworkLinkCmd   `cmd:"" name:"artwork-link" help:"Link artwork to an object." group:"ARTWORK"`
+	ArtworkUnlink ArtworkUnlinkCmd `cmd:"" name:"artwork-unlink" help:"Unlink artwork from an object." group:"ARTWORK"`
 }
 
 type CredentialClientCommands struct {
@@ -72,8 +100,36 @@ type LLMProviderClientCommands struct {
 	LLMProviderCreate LLMProviderCreateCmd `cmd:"" name:"llm-create" help:"Create or update an LLM provider." group:"LLM PROVIDER"`
 }
 
+type BatchClientCommands struct {
+	Batch       BatchCmd       `cmd:"" name:"batch" help:"Run batch operations from a JSON file." group:"BATCH"`
+	BatchStatus BatchStatusCmd `cmd:"" name:"batch-status" help:"Get the progress and results of a batch." group:"BATCH"`
+}
+
+type TokenClientCommands struct {
+	TokenList   TokenListCmd   `cmd:"" name:"tokens" help:"List API tokens." group:"TOKEN"`
+	TokenGet    TokenGetCmd    `cmd:"" name:"token" help:"Get an API token by id." group:"TOKEN"`
+	TokenCreate TokenCreateCmd `cmd:"" name:"token-create" help:"Create an API token, and print the token value." group:"TOKEN"`
+	TokenDelete TokenDeleteCmd `cmd:"" name:"token-delete" help:"Revoke an API token by id." group:"TOKEN"`
+}
+
+type AuditClientCommands struct {
+	AuditList AuditListCmd `cmd:"" name:"audit" help:"List audit log entries, most recent first." group:"AUDIT"`
+}
+
+type ShareClientCommands struct {
+	ShareList   ShareListCmd   `cmd:"" name:"shares" help:"List share links." group:"SHARE"`
+	ShareGet    ShareGetCmd    `cmd:"" name:"share" help:"Get a share link by id." group:"SHARE"`
+	ShareCreate ShareCreateCmd `cmd:"" name:"share-create" help:"Create a share link for an object or folder, and print the link." group:"SHARE"`
+	ShareDelete ShareDeleteCmd `cmd:"" name:"share-delete" help:"Revoke a share link by id." group:"SHARE"`
+}
+
 type SearchCmd struct {
 	schema.SearchListRequest
+	All bool `name:"all" help:"Stream all results as newline-delimited JSON, without a page limit."`
+}
+
+type EventsCmd struct {
+	schema.EventRequest
 }
 
 type MetadataCmd struct {
@@ -102,6 +158,23 @@ type LLMProviderCreateCmd struct {
 	schema.LLMProviderCreate
 }
 
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// clientToken is the API token sent by client commands, which is set from
+// the token flag after the command line is parsed
+var clientToken string
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// AfterApply is called once the command line is parsed, and sets the token
+// used by client commands
+func (cmd *ClientCommands) AfterApply() error {
+	clientToken = strings.TrimSpace(cmd.Token)
+	return nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
@@ -109,7 +182,10 @@ func withClient(ctx server.Cmd, span string, fn func(context.Context, *httpclien
 	endpoint, opts, err := ctx.ClientEndpoint()
 	if err != nil {
 		return err
-	} else if client, err := httpclient.New(endpoint, opts...); err != nil {
+	} else if clientToken != "" {
+		opts = append(opts, httpclient.OptToken(clientToken))
+	}
+	if client, err := httpclient.New(endpoint, opts...); err != nil {
 		return err
 	} else {
 		var err error
@@ -125,12 +201,23 @@ func withClient(ctx server.Cmd, span string, fn func(context.Context, *httpclien
 
 type ObjectListCmd struct {
 	schema.ObjectListRequest
+	All bool `name:"all" help:"Stream all objects as newline-delimited JSON, without a page limit."`
 }
 
 type ObjectGetCmd struct {
 	schema.ObjectKey
 }
 
+type ObjectArchiveCmd struct {
+	schema.ArchiveRequest
+	Output string `name:"output" short:"o" help:"Output file, or - for stdout. Defaults to the archive name in the current directory."`
+}
+
+type ObjectPatchCmd struct {
+	schema.ObjectKey
+	Meta []string `arg:"" help:"Metadata as key=value, where the value is JSON or a string. Use key= to remove a key."`
+}
+
 func (cmd *ObjectListCmd) Run(ctx server.Cmd) error {
 	// Set the width of the terminal
 	width := ctx.IsTerm()
@@ -138,6 +225,11 @@ func (cmd *ObjectListCmd) Run(ctx server.Cmd) error {
 
 	// Perform the request
 	return withClient(ctx, "objects", func(ctx context.Context, client *httpclient.Client) error {
+		// Stream all objects, one per line
+		if cmd.All {
+			return writeJSONStream(client.StreamObjects(ctx, cmd.ObjectListRequest))
+		}
+
 		objects, err := client.ListObjects(ctx, cmd.ObjectListRequest)
 		if err != nil {
 			return err
@@ -161,6 +253,11 @@ func (cmd *ObjectListCmd) Run(ctx server.Cmd) error {
 			return err
 		}
 
+		// Continuation token for the next page
+		if objects.NextToken != "" {
+			fmt.Fprintln(os.Stderr, "Next page: --continue", objects.NextToken)
+		}
+
 		return nil
 	})
 }
@@ -178,6 +275,62 @@ func (cmd *ObjectGetCmd) Run(ctx server.Cmd) error {
 	})
 }
 
+func (cmd *ObjectPatchCmd) Run(ctx server.Cmd) error {
+	var req schema.ObjectPatchRequest
+	for _, kv := range cmd.Meta {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid metadata %q, expected key=value", kv)
+		}
+		meta := schema.Meta{Key: key}
+		if value != "" && json.Valid([]byte(value)) {
+			meta.Value = json.RawMessage(value)
+		} else if value != "" {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			meta.Value = data
+		}
+		req.Meta = append(req.Meta, meta)
+	}
+
+	// Perform the request
+	return withClient(ctx, "object-patch", func(ctx context.Context, client *httpclient.Client) error {
+		object, err := client.PatchObject(ctx, cmd.Volume, cmd.Path, req)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(object)
+		return nil
+	})
+}
+
+func (cmd *ObjectArchiveCmd) Run(ctx server.Cmd) error {
+	output := cmd.Output
+	if output == "" {
+		output = cmd.Filename()
+	}
+
+	// Perform the request
+	return withClient(ctx, "object-archive", func(ctx context.Context, client *httpclient.Client) error {
+		if output == "-" {
+			return client.ReadArchive(ctx, cmd.ArchiveRequest, os.Stdout)
+		}
+
+		// Write to the output file, removing it on error
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		if err := client.ReadArchive(ctx, cmd.ArchiveRequest, f); err != nil {
+			return errors.Join(err, f.Close(), os.Remove(output))
+		}
+		return f.Close()
+	})
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // SEARCH COMMANDS
 
@@ -188,6 +341,11 @@ func (cmd *SearchCmd) Run(ctx server.Cmd) error {
 
 	// Perform the request
 	return withClient(ctx, "search", func(ctx context.Context, client *httpclient.Client) error {
+		// Stream all results, one per line
+		if cmd.All {
+			return writeJSONStream(client.StreamSearch(ctx, cmd.SearchListRequest))
+		}
+
 		results, err := client.Search(ctx, cmd.SearchListRequest)
 		if err != nil {
 			return err
@@ -215,6 +373,35 @@ func (cmd *SearchCmd) Run(ctx server.Cmd) error {
 	})
 }
 
+///////////////////////////////////////////////////////////////////////////////
+// EVENT COMMANDS
+
+func (cmd *EventsCmd) Run(ctx server.Cmd) error {
+	return withClient(ctx, "events", func(ctx context.Context, client *httpclient.Client) error {
+		for event, err := range client.Events(ctx, cmd.EventRequest) {
+			if err != nil {
+				return err
+			}
+			fmt.Println(event)
+		}
+		return nil
+	})
+}
+
+// writeJSONStream writes each value from an iterator to stdout as a line of
+// JSON, stopping at the first error
+func writeJSONStream[T any](seq iter.Seq2[*T, error]) error {
+	enc := json.NewEncoder(os.Stdout)
+	for value, err := range seq {
+		if err != nil {
+			return err
+		} else if err := enc.Encode(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // VOLUME COMMANDS
 
@@ -229,6 +416,7 @@ type VolumeListCmd struct {
 type VolumeCreateFileCmd struct {
 	Name string `arg:"" name:"name" help:"Volume name."`
 	Path string `arg:"" name:"path" type:"file" help:"Path to filesystem."`
+	schema.VolumePolicy
 }
 
 type VolumeCreateS3Cmd struct {
@@ -237,6 +425,7 @@ type VolumeCreateS3Cmd struct {
 	Anonymous bool     `name:"anonymous" help:"Use anonymous credentials for S3." negatable:""`
 	AccessKey string   `name:"access-key" help:"AWS access credential."`
 	SecretKey string   `name:"secret-key" help:"AWS secret credential."`
+	schema.VolumePolicy
 }
 
 type VolumeMountCmd struct {
@@ -261,6 +450,39 @@ type VolumeReindexCmd struct {
 	schema.ObjectListFilters
 }
 
+type VolumeIndexCmd struct {
+	VolumeGetCmd
+	Watch bool `name:"watch" short:"w" help:"Display the progress until indexing is done."`
+}
+
+type VolumeExportCmd struct {
+	VolumeGetCmd
+	Output string `name:"output" short:"o" help:"Output file, or - for stdout. Defaults to the export name in the current directory."`
+}
+
+type VolumeImportCmd struct {
+	VolumeGetCmd
+	Input string `arg:"" name:"input" help:"Index export file, or - for stdin."`
+}
+
+type IndexerListCmd struct {
+	schema.IndexerListRequest
+}
+
+type VolumeFailureListCmd struct {
+	schema.IndexFailureListRequest
+}
+
+type VolumeFailureRetryCmd struct {
+	VolumeGetCmd
+	schema.IndexFailureRequest
+}
+
+type VolumeFailureIgnoreCmd struct {
+	VolumeGetCmd
+	Paths []string `arg:"" name:"path" help:"Object paths."`
+}
+
 func (cmd *VolumeListCmd) Run(ctx server.Cmd) error {
 	// Set the width of the terminal
 	width := ctx.IsTerm()
@@ -301,7 +523,8 @@ func (cmd *VolumeCreateFileCmd) Run(ctx server.Cmd) error {
 		volume, err := client.CreateVolume(ctx, schema.VolumeCreate{
 			URL: "file://" + cmd.Name + types.NormalisePath(cmd.Path),
 			VolumeMeta: schema.VolumeMeta{
-				Enabled: types.Ptr(true),
+				Enabled:      types.Ptr(true),
+				VolumePolicy: cmd.VolumePolicy,
 			},
 		})
 		if err != nil {
@@ -338,7 +561,8 @@ func (cmd *VolumeCreateS3Cmd) Run(ctx server.Cmd) error {
 		volume, err := client.CreateVolume(ctx, schema.VolumeCreate{
 			URL: cmd.URL.String(),
 			VolumeMeta: schema.VolumeMeta{
-				Enabled: types.Ptr(true),
+				Enabled:      types.Ptr(true),
+				VolumePolicy: cmd.VolumePolicy,
 			},
 		})
 		if err != nil {
@@ -423,6 +647,222 @@ func (cmd *VolumeReindexCmd) Run(ctx server.Cmd) error {
 	})
 }
 
+func (cmd *VolumeIndexCmd) Run(ctx server.Cmd) error {
+	isterm := ctx.IsTerm() > 0
+
+	// Perform the request
+	return withClient(ctx, "volume-index", func(ctx context.Context, client *httpclient.Client) error {
+		index, err := client.GetVolumeIndex(ctx, cmd.Name)
+		if err != nil {
+			return err
+		} else if !cmd.Watch {
+			fmt.Println(formatVolumeIndex(index))
+			if index.LastError != "" {
+				fmt.Println("last error:", index.LastError)
+			}
+			return nil
+		}
+
+		// Redraw the progress each second until the index run is done
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		var lastError string
+		for {
+			if index.LastError != lastError {
+				if isterm {
+					fmt.Print("\r\033[K")
+				}
+				fmt.Println("last error:", index.LastError)
+				lastError = index.LastError
+			}
+			if isterm {
+				fmt.Print("\r\033[K", formatVolumeIndex(index))
+			} else {
+				fmt.Println(formatVolumeIndex(index))
+			}
+			if index.Status == schema.IndexStatusDone {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				if isterm {
+					fmt.Println()
+				}
+				return nil
+			case <-ticker.C:
+				if index, err = client.GetVolumeIndex(ctx, cmd.Name); err != nil {
+					if isterm {
+						fmt.Println()
+					}
+					return err
+				}
+			}
+		}
+		if isterm {
+			fmt.Println()
+		}
+		return nil
+	})
+}
+
+func (cmd *VolumeFailureListCmd) Run(ctx server.Cmd) error {
+	// Set the width of the terminal
+	width := ctx.IsTerm()
+	debug := ctx.IsDebug()
+
+	// Perform the request
+	return withClient(ctx, "volume-failures", func(ctx context.Context, client *httpclient.Client) error {
+		failures, err := client.ListIndexFailures(ctx, cmd.IndexFailureListRequest)
+		if err != nil {
+			return err
+		}
+
+		// With debugging
+		if debug {
+			fmt.Println(failures)
+			return nil
+		}
+
+		// Failures list table
+		table := tui.TableFor[*schema.IndexFailure](tui.SetWidth(width))
+		if _, err := table.Write(os.Stdout, failures.Body...); err != nil {
+			return err
+		}
+
+		// Failures list summary
+		summary := tui.TableSummary("failures", uint(failures.Count), uint(len(failures.Body)), failures.Offset, failures.Limit)
+		if _, err := summary.Write(os.Stdout); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (cmd *VolumeExportCmd) Run(ctx server.Cmd) error {
+	output := cmd.Output
+	if output == "" {
+		output = schema.IndexExportFilename(cmd.Name)
+	}
+
+	// Perform the request
+	return withClient(ctx, "volume-export", func(ctx context.Context, client *httpclient.Client) error {
+		if output == "-" {
+			return client.ExportIndex(ctx, cmd.Name, os.Stdout)
+		}
+
+		// Write to the output file, removing it on error
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		if err := client.ExportIndex(ctx, cmd.Name, f); err != nil {
+			return errors.Join(err, f.Close(), os.Remove(output))
+		}
+		return f.Close()
+	})
+}
+
+func (cmd *VolumeImportCmd) Run(ctx server.Cmd) error {
+	// Read from the input file, or stdin
+	r := io.Reader(os.Stdin)
+	if cmd.Input != "-" {
+		f, err := os.Open(cmd.Input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	// Perform the request
+	return withClient(ctx, "volume-import", func(ctx context.Context, client *httpclient.Client) error {
+		result, err := client.ImportIndex(ctx, cmd.Name, r)
+		if err != nil {
+			return err
+		}
+		fmt.Println(result)
+		return nil
+	})
+}
+
+func (cmd *IndexerListCmd) Run(ctx server.Cmd) error {
+	// Set the width of the terminal
+	width := ctx.IsTerm()
+	debug := ctx.IsDebug()
+
+	// Perform the request
+	return withClient(ctx, "indexers", func(ctx context.Context, client *httpclient.Client) error {
+		indexers, err := client.ListIndexers(ctx, cmd.IndexerListRequest)
+		if err != nil {
+			return err
+		}
+
+		// With debugging
+		if debug {
+			fmt.Println(indexers)
+			return nil
+		}
+
+		// Indexers list table
+		table := tui.TableFor[*schema.Indexer](tui.SetWidth(width))
+		if _, err := table.Write(os.Stdout, indexers.Body...); err != nil {
+			return err
+		}
+
+		// Indexers list summary
+		summary := tui.TableSummary("indexers", uint(indexers.Count), uint(len(indexers.Body)), indexers.Offset, indexers.Limit)
+		if _, err := summary.Write(os.Stdout); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (cmd *VolumeFailureRetryCmd) Run(ctx server.Cmd) error {
+	// Perform the request
+	return withClient(ctx, "volume-failure-retry", func(ctx context.Context, client *httpclient.Client) error {
+		failures, err := client.RetryIndexFailures(ctx, cmd.Name, cmd.Paths...)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d objects queued for indexing\n", failures.Count)
+		return nil
+	})
+}
+
+func (cmd *VolumeFailureIgnoreCmd) Run(ctx server.Cmd) error {
+	// Perform the request
+	return withClient(ctx, "volume-failure-ignore", func(ctx context.Context, client *httpclient.Client) error {
+		failures, err := client.IgnoreIndexFailures(ctx, cmd.Name, cmd.Paths...)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d objects ignored\n", failures.Count)
+		return nil
+	})
+}
+
+// formatVolumeIndex returns the progress of an index run on a single line
+func formatVolumeIndex(index *schema.VolumeIndex) string {
+	var percent float64
+	if index.Total > 0 {
+		percent = 100 * float64(index.Completed()) / float64(index.Total)
+	}
+	str := fmt.Sprintf("%s %d/%d (%.0f%%) queued=%d running=%d succeeded=%d failed=%d skipped=%d",
+		index.Status, index.Completed(), index.Total, percent,
+		index.Queued, index.Running, index.Succeeded, index.Failed, index.Skipped,
+	)
+	if index.ETA != nil {
+		str += fmt.Sprintf(" eta=%s", time.Until(*index.ETA).Round(time.Second))
+	}
+	return str
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // METADATA COMMANDS
 
@@ -449,10 +889,80 @@ func (cmd *MetadataCmd) Run(ctx server.Cmd) error {
 ///////////////////////////////////////////////////////////////////////////////
 // ARTWORK COMMANDS
 
+type ArtworkListCmd struct {
+	schema.ArtworkListRequest
+}
+
 type ArtworkCreateCmd struct {
 	Path string `cmd:"" name:"path" help:"Path to the artwork file." arg:"" required:""`
 }
 
+type ArtworkDeleteCmd struct {
+	Key string `arg:"" name:"key" help:"Artwork key."`
+}
+
+type ArtworkLinkCmd struct {
+	Key string `arg:"" name:"key" help:"Artwork key."`
+	schema.ObjectKey
+}
+
+type ArtworkUnlinkCmd struct {
+	Key string `arg:"" name:"key" help:"Artwork key."`
+	schema.ObjectKey
+}
+
+func (cmd *ArtworkListCmd) Run(ctx server.Cmd) error {
+	// Perform the request
+	return withClient(ctx, "artworks", func(ctx context.Context, client *httpclient.Client) error {
+		artwork, err := client.ListArtwork(ctx, cmd.ArtworkListRequest)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(artwork)
+		return nil
+	})
+}
+
+func (cmd *ArtworkDeleteCmd) Run(ctx server.Cmd) error {
+	// Perform the request
+	return withClient(ctx, "artwork-delete", func(ctx context.Context, client *httpclient.Client) error {
+		artwork, err := client.DeleteArtwork(ctx, schema.ArtworkKey(cmd.Key))
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(artwork)
+		return nil
+	})
+}
+
+func (cmd *ArtworkLinkCmd) Run(ctx server.Cmd) error {
+	// Perform the request
+	return withClient(ctx, "artwork-link", func(ctx context.Context, client *httpclient.Client) error {
+		link, err := client.LinkArtwork(ctx, schema.ArtworkKey(cmd.Key), cmd.ObjectKey)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(link)
+		return nil
+	})
+}
+
+func (cmd *ArtworkUnlinkCmd) Run(ctx server.Cmd) error {
+	// Perform the request
+	return withClient(ctx, "artwork-unlink", func(ctx context.Context, client *httpclient.Client) error {
+		link, err := client.UnlinkArtwork(ctx, schema.ArtworkKey(cmd.Key), cmd.ObjectKey)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(link)
+		return nil
+	})
+}
+
 func (cmd *ArtworkCreateCmd) Run(ctx server.Cmd) error {
 	// Perform the request
 	return withClient(ctx, "artwork-upload", func(ctx context.Context, client *httpclient.Client) error {
@@ -614,3 +1124,324 @@ func (cmd *LLMProviderCreateCmd) Run(ctx server.Cmd) error {
 		return nil
 	})
 }
+
+///////////////////////////////////////////////////////////////////////////////
+// BATCH COMMANDS
+
+type BatchCmd struct {
+	Path     string `arg:"" name:"path" help:"JSON file with an array of operations, or - for stdin."`
+	Continue bool   `name:"continue" help:"Continue after an operation fails, rather than aborting the batch."`
+	Async    bool   `name:"async" help:"Run the batch as a background job."`
+}
+
+type BatchStatusCmd struct {
+	schema.BatchKey
+}
+
+func (cmd *BatchCmd) Run(ctx server.Cmd) error {
+	width := ctx.IsTerm()
+
+	// Read the operations
+	var r io.Reader = os.Stdin
+	if cmd.Path != "-" {
+		f, err := os.Open(cmd.Path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	req := schema.BatchRequest{
+		Continue: cmd.Continue,
+		Async:    cmd.Async,
+	}
+	if err := json.NewDecoder(r).Decode(&req.Operations); err != nil {
+		return fmt.Errorf("%s: %w", cmd.Path, err)
+	}
+
+	// Perform the request
+	return withClient(ctx, "batch", func(ctx context.Context, client *httpclient.Client) error {
+		batch, err := client.Batch(ctx, req)
+		if err != nil {
+			return err
+		}
+		return writeBatch(batch, width)
+	})
+}
+
+func (cmd *BatchStatusCmd) Run(ctx server.Cmd) error {
+	width := ctx.IsTerm()
+
+	// Perform the request
+	return withClient(ctx, "batch-status", func(ctx context.Context, client *httpclient.Client) error {
+		batch, err := client.GetBatch(ctx, cmd.BatchKey)
+		if err != nil {
+			return err
+		}
+		return writeBatch(batch, width)
+	})
+}
+
+// writeBatch writes the results of a batch as a table, followed by its progress
+func writeBatch(batch *schema.Batch, width int) error {
+	if len(batch.Results) > 0 {
+		table := tui.TableFor[schema.BatchResult](tui.SetWidth(width))
+		if _, err := table.Write(os.Stdout, batch.Results...); err != nil {
+			return err
+		}
+	}
+	if batch.ID != 0 {
+		fmt.Printf("batch %d: ", batch.ID)
+	}
+	fmt.Printf("%s, %d of %d operations completed, %d failed\n", batch.Status, batch.Completed, batch.Total, batch.Failed)
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// TOKEN COMMANDS
+
+type TokenListCmd struct {
+	schema.TokenListRequest
+}
+
+type TokenGetCmd struct {
+	ID schema.TokenID `arg:"" name:"id" help:"Token identifier."`
+}
+
+type TokenCreateCmd struct {
+	schema.TokenMeta
+	TTL time.Duration `name:"ttl" help:"Duration after which the token expires, or never when zero."`
+}
+
+type TokenDeleteCmd struct {
+	ID schema.TokenID `arg:"" name:"id" help:"Token identifier."`
+}
+
+func (cmd *TokenListCmd) Run(ctx server.Cmd) error {
+	// Set the width of the terminal
+	width := ctx.IsTerm()
+	debug := ctx.IsDebug()
+
+	// Perform the request
+	return withClient(ctx, "tokens", func(ctx context.Context, client *httpclient.Client) error {
+		tokens, err := client.ListTokens(ctx, cmd.TokenListRequest)
+		if err != nil {
+			return err
+		}
+
+		// With debugging
+		if debug {
+			fmt.Println(tokens)
+			return nil
+		}
+
+		// Tokens list table
+		table := tui.TableFor[*schema.Token](tui.SetWidth(width))
+		if _, err := table.Write(os.Stdout, tokens.Body...); err != nil {
+			return err
+		}
+
+		// Tokens list summary
+		summary := tui.TableSummary("tokens", uint(tokens.Count), uint(len(tokens.Body)), tokens.Offset, tokens.Limit)
+		if _, err := summary.Write(os.Stdout); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (cmd *TokenGetCmd) Run(ctx server.Cmd) error {
+	// Perform the request
+	return withClient(ctx, "token", func(ctx context.Context, client *httpclient.Client) error {
+		token, err := client.GetToken(ctx, cmd.ID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(token)
+		return nil
+	})
+}
+
+func (cmd *TokenCreateCmd) Run(ctx server.Cmd) error {
+	if cmd.TTL < 0 {
+		return fmt.Errorf("invalid ttl: %v", cmd.TTL)
+	} else if cmd.TTL > 0 {
+		cmd.ExpiresAt = types.Ptr(time.Now().Add(cmd.TTL))
+	}
+
+	// Perform the request
+	return withClient(ctx, "token-create", func(ctx context.Context, client *httpclient.Client) error {
+		token, err := client.CreateToken(ctx, cmd.TokenMeta)
+		if err != nil {
+			return err
+		}
+
+		// The token value is only returned once, so print it on its own
+		fmt.Fprintln(os.Stderr, token)
+		fmt.Println(token.Value)
+		return nil
+	})
+}
+
+func (cmd *TokenDeleteCmd) Run(ctx server.Cmd) error {
+	// Perform the request
+	return withClient(ctx, "token-delete", func(ctx context.Context, client *httpclient.Client) error {
+		token, err := client.DeleteToken(ctx, cmd.ID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(token)
+		return nil
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// AUDIT COMMANDS
+
+type AuditListCmd struct {
+	schema.AuditListRequest
+}
+
+func (cmd *AuditListCmd) Run(ctx server.Cmd) error {
+	// Set the width of the terminal
+	width := ctx.IsTerm()
+	debug := ctx.IsDebug()
+
+	// Perform the request
+	return withClient(ctx, "audit", func(ctx context.Context, client *httpclient.Client) error {
+		entries, err := client.ListAudit(ctx, cmd.AuditListRequest)
+		if err != nil {
+			return err
+		}
+
+		// With debugging
+		if debug {
+			fmt.Println(entries)
+			return nil
+		}
+
+		// Audit list table
+		table := tui.TableFor[*schema.Audit](tui.SetWidth(width))
+		if _, err := table.Write(os.Stdout, entries.Body...); err != nil {
+			return err
+		}
+
+		// Audit list summary
+		summary := tui.TableSummary("entries", uint(entries.Count), uint(len(entries.Body)), entries.Offset, entries.Limit)
+		if _, err := summary.Write(os.Stdout); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// SHARE COMMANDS
+
+type ShareListCmd struct {
+	schema.ShareListRequest
+}
+
+type ShareGetCmd struct {
+	ID schema.ShareID `arg:"" name:"id" help:"Share link identifier."`
+}
+
+type ShareCreateCmd struct {
+	schema.ShareCreate
+	TTL time.Duration `name:"ttl" help:"Duration after which the share link expires, or never when zero."`
+}
+
+type ShareDeleteCmd struct {
+	ID schema.ShareID `arg:"" name:"id" help:"Share link identifier."`
+}
+
+func (cmd *ShareListCmd) Run(ctx server.Cmd) error {
+	// Set the width of the terminal
+	width := ctx.IsTerm()
+	debug := ctx.IsDebug()
+
+	// Perform the request
+	return withClient(ctx, "shares", func(ctx context.Context, client *httpclient.Client) error {
+		shares, err := client.ListShares(ctx, cmd.ShareListRequest)
+		if err != nil {
+			return err
+		}
+
+		// With debugging
+		if debug {
+			fmt.Println(shares)
+			return nil
+		}
+
+		// Shares list table
+		table := tui.TableFor[*schema.Share](tui.SetWidth(width))
+		if _, err := table.Write(os.Stdout, shares.Body...); err != nil {
+			return err
+		}
+
+		// Shares list summary
+		summary := tui.TableSummary("shares", uint(shares.Count), uint(len(shares.Body)), shares.Offset, shares.Limit)
+		if _, err := summary.Write(os.Stdout); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (cmd *ShareGetCmd) Run(ctx server.Cmd) error {
+	// Perform the request
+	return withClient(ctx, "share", func(ctx context.Context, client *httpclient.Client) error {
+		share, err := client.GetShare(ctx, cmd.ID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(share)
+		return nil
+	})
+}
+
+func (cmd *ShareCreateCmd) Run(ctx server.Cmd) error {
+	if cmd.TTL < 0 {
+		return fmt.Errorf("invalid ttl: %v", cmd.TTL)
+	} else if cmd.TTL > 0 {
+		cmd.ExpiresAt = types.Ptr(time.Now().Add(cmd.TTL))
+	}
+
+	// Get the endpoint, which the share link is relative to
+	endpoint, _, err := ctx.ClientEndpoint()
+	if err != nil {
+		return err
+	}
+
+	// Perform the request
+	return withClient(ctx, "share-create", func(ctx context.Context, client *httpclient.Client) error {
+		share, err := client.CreateShare(ctx, cmd.ShareCreate)
+		if err != nil {
+			return err
+		}
+
+		// Print the share link on its own
+		fmt.Fprintln(os.Stderr, share)
+		fmt.Println(strings.TrimSuffix(endpoint, "/") + "/" + schema.SharePath + "/" + share.Token)
+		return nil
+	})
+}
+
+func (cmd *ShareDeleteCmd) Run(ctx server.Cmd) error {
+	// Perform the request
+	return withClient(ctx, "share-delete", func(ctx context.Context, client *httpclient.Client) error {
+		share, err := client.DeleteShare(ctx, cmd.ID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(share)
+		return nil
+	})
+}
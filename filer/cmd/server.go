@@ -3,10 +3,13 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	// Packages
 	httphandler "github.com/mutablelogic/go-filer/filer/httphandler"
 	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
 	pg "github.com/mutablelogic/go-pg"
 	pgcmd "github.com/mutablelogic/go-pg/pkg/cmd"
 	server "github.com/mutablelogic/go-server"
@@ -30,7 +33,27 @@ type RunServer struct {
 
 	// Other flags
 	Indexer     bool     `long:"indexer" help:"Run this instance as an indexer of content" default:"false" negatable:""`
+	Sweep       float64  `name:"sweep-threshold" help:"Fraction of the indexed objects in a volume which can be removed when they are no longer found on reindex, or zero to keep them" default:"0.5"`
 	Passphrases []string `name:"passphrase" env:"${ENV_NAME}_PASSPHRASES" help:"One or more passphrases used to encrypt credentials."`
+	S3          string   `name:"s3" help:"Serve an S3-compatible API at this path, for example /s3. Access keys are credentials of type s3 which are bound to an API token."`
+	WebDAV      string   `name:"webdav" help:"Serve volumes over WebDAV at this path, for example /dav"`
+	Auth        bool     `name:"auth" help:"Require an API token for every request. An admin token is created when no tokens exist, and written to stderr or the admin token file." default:"false" negatable:""`
+	TokenFile   string   `name:"admin-token-file" type:"path" help:"File to write the admin token to when it is created, readable only by the owner"`
+
+	// Volume flags
+	SyncInterval time.Duration `name:"sync-interval" help:"Interval between synchronizing volumes and their reindexing schedules" default:"5m"`
+
+	// Indexer flags
+	IndexerCapabilities []string `name:"indexer-capability" help:"Capabilities of this indexer, such as the names of metadata extractors (video, pdf, image) or llm. Objects which require other capabilities are indexed by other indexers, and an indexer without capabilities indexes any object."`
+
+	// Audit log flags
+	AuditRetention time.Duration `name:"audit-retention" help:"Period for which audit log entries are kept, or zero to keep them indefinitely" default:"2160h"`
+
+	// Batch flags
+	BatchRetention time.Duration `name:"batch-retention" help:"Period for which finished batches are kept, or zero to keep them indefinitely" default:"168h"`
+
+	// Rendition flags
+	RenditionRetention time.Duration `name:"rendition-retention" help:"Period for which cached image renditions are kept, or zero to keep them indefinitely" default:"720h"`
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -46,27 +69,70 @@ func (runner *RunServer) Run(ctx server.Cmd) error {
 	}
 
 	// Log the server configuration
-	ctx.Logger().InfoContext(ctx.Context(), "starting filer server", "name", ctx.Name(), "version", ctx.Version(), "indexer", runner.Indexer)
+	ctx.Logger().InfoContext(ctx.Context(), "starting filer server", "name", ctx.Name(), "version", ctx.Version(), "indexer", runner.Indexer, "auth", runner.Auth)
 
 	// Create the manager, run the server, and return any error
 	return runner.WithManager(ctx, conn, func(manager *manager.Manager) error {
 		// Create an error context - which will cancel any other goroutine on exit
 		errgroup, errctx := errgroup.WithContext(ctx.Context())
 
+		// Create the first admin token when authentication is enabled
+		if runner.Auth {
+			if token, err := manager.BootstrapToken(ctx.Context()); err != nil {
+				return err
+			} else if token != nil {
+				if err := runner.writeToken(token.Value); err != nil {
+					return err
+				}
+				ctx.Logger().InfoContext(ctx.Context(), "created admin token, which will not be shown again")
+			}
+		}
+
 		// Register http handlers for the manager
 		runner.Register(func(router *httprouter.Router) error {
 			ctx.Logger().DebugContext(ctx.Context(), "registering http handlers")
+			router.AddMiddleware(httphandler.RequestID)
+			if runner.Auth {
+				if err := router.RegisterSecurityScheme(schema.SecurityTokenAuth, httphandler.NewTokenAuth(manager)); err != nil {
+					return err
+				}
+			}
 			return errors.Join(
-				httphandler.RegisterVolumeHandlers(manager, router),
-				httphandler.RegisterObjectHandlers(manager, router),
-				httphandler.RegisterSearchHandlers(manager, router),
-				httphandler.RegisterMetadataHandlers(manager, router),
-				httphandler.RegisterArtworkHandlers(manager, router),
-				httphandler.RegisterCredentialHandlers(manager, router),
-				httphandler.RegisterLLMProviderHandlers(manager, router),
+				httphandler.RegisterVolumeHandlers(manager, router, runner.Auth),
+				httphandler.RegisterIndexFailureHandlers(manager, router, runner.Auth),
+				httphandler.RegisterIndexerHandlers(manager, router, runner.Auth),
+				httphandler.RegisterIndexExportHandlers(manager, router, runner.Auth),
+				httphandler.RegisterObjectHandlers(manager, router, runner.Auth),
+				httphandler.RegisterSearchHandlers(manager, router, runner.Auth),
+				httphandler.RegisterEventHandlers(manager, router, runner.Auth),
+				httphandler.RegisterMetadataHandlers(manager, router, runner.Auth),
+				httphandler.RegisterArtworkHandlers(manager, router, runner.Auth),
+				httphandler.RegisterRenditionHandlers(manager, router, runner.Auth),
+				httphandler.RegisterCredentialHandlers(manager, router, runner.Auth),
+				httphandler.RegisterLLMProviderHandlers(manager, router, runner.Auth),
+				httphandler.RegisterBatchHandlers(manager, router, runner.Auth),
+				httphandler.RegisterTokenHandlers(manager, router, runner.Auth),
+				httphandler.RegisterAuditHandlers(manager, router, runner.Auth),
+				httphandler.RegisterShareHandlers(manager, router, runner.Auth),
 			)
 		})
 
+		// Register the S3-compatible API
+		if runner.S3 != "" {
+			runner.Register(func(router *httprouter.Router) error {
+				ctx.Logger().DebugContext(ctx.Context(), "registering s3 handlers", "path", runner.S3)
+				return httphandler.RegisterS3Handlers(manager, router, runner.S3)
+			})
+		}
+
+		// Register the WebDAV server
+		if runner.WebDAV != "" {
+			runner.Register(func(router *httprouter.Router) error {
+				ctx.Logger().DebugContext(ctx.Context(), "registering webdav handlers", "path", runner.WebDAV)
+				return httphandler.RegisterWebDAVHandlers(manager, router, runner.WebDAV, runner.Auth)
+			})
+		}
+
 		// Register the UI handlers
 		runner.Register(func(router *httprouter.Router) error {
 			return runner.MaybeRegisterUI(ctx.Context(), ctx.Logger(), router)
@@ -101,7 +167,13 @@ func (runner *RunServer) WithManager(ctx server.Cmd, conn pg.PoolConn, fn func(*
 		manager.WithMeter(ctx.Meter()),
 		manager.WithTracer(ctx.Tracer()),
 		manager.WithIndexer(runner.Indexer),
+		manager.WithSweepThreshold(runner.Sweep),
+		manager.WithSyncInterval(runner.SyncInterval),
+		manager.WithIndexerCapabilities(runner.IndexerCapabilities...),
 		manager.WithLLMClientOpts(clientopts...),
+		manager.WithAuditRetention(runner.AuditRetention),
+		manager.WithBatchRetention(runner.BatchRetention),
+		manager.WithRenditionRetention(runner.RenditionRetention),
 	}
 
 	// Set passphrases for credential encryption
@@ -116,3 +188,19 @@ func (runner *RunServer) WithManager(ctx server.Cmd, conn pg.PoolConn, fn func(*
 		return fn(manager)
 	}
 }
+
+// writeToken writes the admin token to the admin token file, which is only
+// readable by the owner, or else to stderr. The token is not logged, so
+// that it is not collected with the server logs.
+func (runner *RunServer) writeToken(value string) error {
+	if runner.TokenFile == "" {
+		_, err := fmt.Fprintln(os.Stderr, "admin token:", value)
+		return err
+	}
+	f, err := os.OpenFile(runner.TokenFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(f, value)
+	return errors.Join(err, f.Close())
+}
@@ -44,3 +44,43 @@ func (c *Client) CreateArtwork(ctx context.Context, r io.ReadCloser) (*schema.Ar
 	// Return the responses
 	return types.Ptr(response), nil
 }
+
+func (c *Client) ListArtwork(ctx context.Context, req schema.ArtworkListRequest) (*schema.ArtworkList, error) {
+	var response schema.ArtworkList
+	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("artwork"), client.OptQuery(req.Query())); err != nil {
+		return nil, err
+	}
+
+	// Return the responses
+	return types.Ptr(response), nil
+}
+
+func (c *Client) DeleteArtwork(ctx context.Context, key schema.ArtworkKey) (*schema.ArtworkInfo, error) {
+	var response schema.ArtworkInfo
+	if err := c.DoWithContext(ctx, client.MethodDelete, &response, client.OptPath("artwork", string(key))); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+func (c *Client) LinkArtwork(ctx context.Context, key schema.ArtworkKey, object schema.ObjectKey) (*schema.ObjectArtwork, error) {
+	var response schema.ObjectArtwork
+	if err := c.DoWithContext(ctx, client.MethodPut, &response, client.OptPath("artwork", string(key), "object", object.Volume, object.Path)); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+func (c *Client) UnlinkArtwork(ctx context.Context, key schema.ArtworkKey, object schema.ObjectKey) (*schema.ObjectArtwork, error) {
+	var response schema.ObjectArtwork
+	if err := c.DoWithContext(ctx, client.MethodDelete, &response, client.OptPath("artwork", string(key), "object", object.Volume, object.Path)); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
@@ -0,0 +1,24 @@
+package httpclient
+
+import (
+	"context"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ListAudit returns audit log entries, most recent first
+func (c *Client) ListAudit(ctx context.Context, req schema.AuditListRequest) (*schema.AuditList, error) {
+	var response schema.AuditList
+	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("audit"), client.OptQuery(req.Query())); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
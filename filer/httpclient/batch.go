@@ -0,0 +1,43 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Batch runs batch operations. When the batch runs as a background job, the
+// returned batch has no results, and GetBatch is used to poll for progress.
+func (c *Client) Batch(ctx context.Context, batch schema.BatchRequest) (*schema.Batch, error) {
+	req, err := client.NewJSONRequestEx(http.MethodPost, batch, types.ContentTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.Batch
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("batch")); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+func (c *Client) GetBatch(ctx context.Context, key schema.BatchKey) (*schema.Batch, error) {
+	var response schema.Batch
+	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("batch", strconv.FormatUint(key.ID, 10))); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
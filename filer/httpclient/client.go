@@ -5,6 +5,14 @@ import (
 	client "github.com/mutablelogic/go-client"
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// tokenHeader is the header which carries the API token
+	tokenHeader = "X-API-Key"
+)
+
 ///////////////////////////////////////////////////////////////////////////////
 // TYPES
 
@@ -29,3 +37,9 @@ func New(url string, opts ...client.ClientOpt) (*Client, error) {
 	c.Client = cl
 	return c, nil
 }
+
+// OptToken returns a client option which sends an API token with each
+// request, when the server requires authentication
+func OptToken(token string) client.ClientOpt {
+	return client.OptHeader(tokenHeader, token)
+}
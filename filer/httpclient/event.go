@@ -0,0 +1,50 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"iter"
+	"net/http"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Events returns an iterator of volume and object events which match the
+// request. The iteration ends when the loop is exited, the context is
+// cancelled or the server closes the stream. Errors are yielded with a nil
+// event.
+func (c *Client) Events(ctx context.Context, req schema.EventRequest) iter.Seq2[*schema.Event, error] {
+	return func(yield func(*schema.Event, error) bool) {
+		err := c.DoWithContext(ctx, client.NewRequestEx(http.MethodGet, client.ContentTypeTextStream), nil,
+			client.OptPath("events"),
+			client.OptQuery(req.Query()),
+			client.OptNoTimeout(),
+			client.OptTextStreamCallback(func(e client.TextStreamEvent) error {
+				// Ignore keep-alive events
+				if e.Data == "" {
+					return nil
+				}
+
+				// Decode the event, and stop when the loop exits
+				var event schema.Event
+				if err := json.Unmarshal([]byte(e.Data), &event); err != nil {
+					if !yield(nil, err) {
+						return io.EOF
+					}
+				} else if !yield(&event, nil) {
+					return io.EOF
+				}
+				return nil
+			}),
+		)
+		if err != nil && ctx.Err() == nil {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// importPayload streams an index export in the request body
+type importPayload struct {
+	io.Reader
+}
+
+var _ client.Payload = (*importPayload)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ExportIndex streams the index of a volume as a gzipped tar archive to the
+// writer
+func (c *Client) ExportIndex(ctx context.Context, name string, w io.Writer) error {
+	return c.DoWithContext(ctx,
+		client.NewRequestEx(http.MethodGet, types.ContentTypeAny),
+		&writerResponse{w: w},
+		client.OptPath("volume", name, "export"),
+		client.OptNoTimeout(),
+	)
+}
+
+// ImportIndex streams an index export into a volume, and returns the number
+// of objects which were imported, changed or missing from the backend
+func (c *Client) ImportIndex(ctx context.Context, name string, r io.Reader) (*schema.IndexImport, error) {
+	var response schema.IndexImport
+	if err := c.DoWithContext(ctx, &importPayload{Reader: r}, &response, client.OptPath("volume", name, "import"), client.OptNoTimeout()); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PAYLOAD
+
+func (importPayload) Method() string { return http.MethodPost }
+func (importPayload) Accept() string { return types.ContentTypeJSON }
+func (importPayload) Type() string   { return schema.ContentTypeGzip }
@@ -0,0 +1,50 @@
+package httpclient
+
+import (
+	"context"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (c *Client) ListIndexFailures(ctx context.Context, req schema.IndexFailureListRequest) (*schema.IndexFailureList, error) {
+	var response schema.IndexFailureList
+	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("volume", req.Volume, "failure"), client.OptQuery(req.Query())); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+func (c *Client) RetryIndexFailures(ctx context.Context, name string, paths ...string) (*schema.IndexFailureList, error) {
+	return c.indexFailures(ctx, name, "retry", paths)
+}
+
+func (c *Client) IgnoreIndexFailures(ctx context.Context, name string, paths ...string) (*schema.IndexFailureList, error) {
+	return c.indexFailures(ctx, name, "ignore", paths)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (c *Client) indexFailures(ctx context.Context, name, op string, paths []string) (*schema.IndexFailureList, error) {
+	req, err := client.NewJSONRequest(schema.IndexFailureRequest{Paths: paths})
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.IndexFailureList
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("volume", name, "failure", op)); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
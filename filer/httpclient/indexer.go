@@ -0,0 +1,23 @@
+package httpclient
+
+import (
+	"context"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (c *Client) ListIndexers(ctx context.Context, req schema.IndexerListRequest) (*schema.IndexerList, error) {
+	var response schema.IndexerList
+	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("indexer"), client.OptQuery(req.Query())); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
+	"net/url"
 
 	// Packages
 	client "github.com/mutablelogic/go-client"
@@ -20,7 +22,21 @@ type getObjectResponse struct {
 	Object *schema.Object
 }
 
+// readObjectResponse captures the object metadata and streams the
+// response body to a writer
+type readObjectResponse struct {
+	getObjectResponse
+	w io.Writer
+}
+
+// writerResponse streams the response body to a writer
+type writerResponse struct {
+	w io.Writer
+}
+
 var _ client.Unmarshaler = (*getObjectResponse)(nil)
+var _ client.Unmarshaler = (*readObjectResponse)(nil)
+var _ client.Unmarshaler = (*writerResponse)(nil)
 
 func (r *getObjectResponse) Unmarshal(header http.Header, _ io.Reader) error {
 	data := header.Get(schema.ContentObjectHeader)
@@ -35,6 +51,19 @@ func (r *getObjectResponse) Unmarshal(header http.Header, _ io.Reader) error {
 	return nil
 }
 
+func (r *readObjectResponse) Unmarshal(header http.Header, reader io.Reader) error {
+	if err := r.getObjectResponse.Unmarshal(header, nil); err != nil {
+		return err
+	}
+	_, err := io.Copy(r.w, reader)
+	return err
+}
+
+func (r *writerResponse) Unmarshal(_ http.Header, reader io.Reader) error {
+	_, err := io.Copy(r.w, reader)
+	return err
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
@@ -50,6 +79,74 @@ func (c *Client) GetObject(ctx context.Context, volume, path string) (*schema.Ob
 	return response.Object, nil
 }
 
+// ReadObject streams the object content to the writer, and returns the
+// object metadata
+func (c *Client) ReadObject(ctx context.Context, volume, path string, w io.Writer) (*schema.Object, error) {
+	response := readObjectResponse{w: w}
+	if err := c.DoWithContext(ctx,
+		client.NewRequestEx(http.MethodGet, types.ContentTypeAny),
+		&response,
+		client.OptPath("object", volume, path),
+		client.OptNoTimeout(),
+	); err != nil {
+		return nil, err
+	}
+	return response.Object, nil
+}
+
+// DeleteObjects deletes an object, or all objects under a prefix when
+// Recursive is set, and returns the deleted objects
+func (c *Client) DeleteObjects(ctx context.Context, req schema.DeleteObjectsRequest) (*schema.DeleteObjectsResponse, error) {
+	opts := []client.RequestOpt{
+		client.OptPath("object", req.Volume, req.Path),
+	}
+	if req.Recursive {
+		opts = append(opts, client.OptQuery(url.Values{"recursive": {"true"}}))
+	}
+	if req.IfMatch != "" {
+		opts = append(opts, client.OptReqHeader(schema.ContentIfMatchHeader, req.IfMatch))
+	}
+
+	// Perform the request
+	var response schema.DeleteObjectsResponse
+	if err := c.DoWithContext(ctx, client.MethodDelete, &response, opts...); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+// PatchObject sets user metadata keys on an object. A key with a null value
+// is removed.
+func (c *Client) PatchObject(ctx context.Context, volume, path string, req schema.ObjectPatchRequest) (*schema.Object, error) {
+	payload, err := client.NewJSONRequestEx(http.MethodPatch, req, types.ContentTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform the request
+	var response schema.Object
+	if err := c.DoWithContext(ctx, payload, &response, client.OptPath("object", volume, path)); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+// ReadArchive streams a zip or tar.gz archive of all objects under a path
+// prefix to the writer
+func (c *Client) ReadArchive(ctx context.Context, req schema.ArchiveRequest, w io.Writer) error {
+	return c.DoWithContext(ctx,
+		client.NewRequestEx(http.MethodGet, types.ContentTypeAny),
+		&writerResponse{w: w},
+		client.OptPath("archive", req.Volume, req.Path),
+		client.OptQuery(req.Query()),
+		client.OptNoTimeout(),
+	)
+}
+
 func (c *Client) ListObjects(ctx context.Context, req schema.ObjectListRequest) (*schema.ObjectList, error) {
 	var response schema.ObjectList
 	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("object"), client.OptQuery(req.Query())); err != nil {
@@ -59,3 +156,11 @@ func (c *Client) ListObjects(ctx context.Context, req schema.ObjectListRequest)
 	// Return the responses
 	return types.Ptr(response), nil
 }
+
+// StreamObjects returns an iterator of all objects which match the request,
+// without a page limit. The server streams the objects as they are listed,
+// so the listing proceeds as fast as the loop consumes them. Errors are
+// yielded with a nil object.
+func (c *Client) StreamObjects(ctx context.Context, req schema.ObjectListRequest) iter.Seq2[*schema.Object, error] {
+	return jsonStream[schema.Object](ctx, c, "object", req.Query())
+}
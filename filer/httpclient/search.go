@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"context"
+	"iter"
 
 	// Packages
 	client "github.com/mutablelogic/go-client"
@@ -21,3 +22,9 @@ func (c *Client) Search(ctx context.Context, req schema.SearchListRequest) (*sch
 	// Return the responses
 	return types.Ptr(response), nil
 }
+
+// StreamSearch returns an iterator of all search results, in rank order and
+// without a page limit. Errors are yielded with a nil result.
+func (c *Client) StreamSearch(ctx context.Context, req schema.SearchListRequest) iter.Seq2[*schema.SearchResult, error] {
+	return jsonStream[schema.SearchResult](ctx, c, "search", req.Query())
+}
@@ -0,0 +1,61 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (c *Client) ListShares(ctx context.Context, req schema.ShareListRequest) (*schema.ShareList, error) {
+	var response schema.ShareList
+	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("share"), client.OptQuery(req.Query())); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+// CreateShare creates a share link for an object, or the objects under a prefix
+func (c *Client) CreateShare(ctx context.Context, meta schema.ShareCreate) (*schema.Share, error) {
+	req, err := client.NewJSONRequestEx(http.MethodPost, meta, types.ContentTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.Share
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("share")); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+func (c *Client) GetShare(ctx context.Context, id schema.ShareID) (*schema.Share, error) {
+	var response schema.Share
+	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("share", types.Stringify(id))); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+func (c *Client) DeleteShare(ctx context.Context, id schema.ShareID) (*schema.Share, error) {
+	var response schema.Share
+	if err := c.DoWithContext(ctx, client.MethodDelete, &response, client.OptPath("share", types.Stringify(id))); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
@@ -0,0 +1,45 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// jsonStream returns an iterator of values read from a newline-delimited JSON
+// response. The iteration ends when the loop is exited, the context is
+// cancelled or the stream ends. Errors are yielded with a nil value.
+func jsonStream[T any](ctx context.Context, c *Client, path string, query url.Values) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		err := c.DoWithContext(ctx, client.NewRequestEx(http.MethodGet, types.ContentTypeJSONStreamLegacy), nil,
+			client.OptPath(path),
+			client.OptQuery(query),
+			client.OptNoTimeout(),
+			client.OptJsonStreamCallback(func(data json.RawMessage) error {
+				// Decode the value, and stop when the loop exits
+				var value T
+				if err := json.Unmarshal(data, &value); err != nil {
+					if !yield(nil, err) {
+						return io.EOF
+					}
+				} else if !yield(&value, nil) {
+					return io.EOF
+				}
+				return nil
+			}),
+		)
+		if err != nil && ctx.Err() == nil {
+			yield(nil, err)
+		}
+	}
+}
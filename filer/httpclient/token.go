@@ -0,0 +1,62 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (c *Client) ListTokens(ctx context.Context, req schema.TokenListRequest) (*schema.TokenList, error) {
+	var response schema.TokenList
+	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("token"), client.OptQuery(req.Query())); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+// CreateToken creates an API token. The token value is only returned when
+// the token is created.
+func (c *Client) CreateToken(ctx context.Context, meta schema.TokenMeta) (*schema.Token, error) {
+	req, err := client.NewJSONRequestEx(http.MethodPost, meta, types.ContentTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.Token
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("token")); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+func (c *Client) GetToken(ctx context.Context, id schema.TokenID) (*schema.Token, error) {
+	var response schema.Token
+	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("token", types.Stringify(id))); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+func (c *Client) DeleteToken(ctx context.Context, id schema.TokenID) (*schema.Token, error) {
+	var response schema.Token
+	if err := c.DoWithContext(ctx, client.MethodDelete, &response, client.OptPath("token", types.Stringify(id))); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
@@ -0,0 +1,181 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ProgressFunc is called as an object is uploaded, with the object path,
+// the number of bytes written so far, and the total number of bytes, which
+// is -1 when the size is not known
+type ProgressFunc func(path string, written, total int64)
+
+// objectPayload streams an object body in a PUT request
+type objectPayload struct {
+	io.Reader
+	contentType string
+}
+
+// progressReader calls a ProgressFunc as a body is read
+type progressReader struct {
+	io.Reader
+	path    string
+	written int64
+	total   int64
+	emitted int64
+	fn      ProgressFunc
+}
+
+var _ client.Payload = (*objectPayload)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// progressInterval is the minimum number of bytes between progress callbacks
+	progressInterval = 64 * 1024
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// CreateObject streams the request body into an object. When IfNotExists is
+// set, the upload fails if the object already exists, and when IfMatch is
+// set, the upload fails unless the existing object matches the etag. The
+// progress function can be nil.
+func (c *Client) CreateObject(ctx context.Context, req schema.CreateObjectRequest, progress ProgressFunc) (*schema.Object, error) {
+	opts := []client.RequestOpt{
+		client.OptPath("object", req.Volume, req.Path),
+		client.OptNoTimeout(),
+	}
+
+	// Set the object metadata and conditional headers
+	if data, err := json.Marshal(req.ObjectMeta); err != nil {
+		return nil, err
+	} else {
+		opts = append(opts, client.OptReqHeader(schema.ContentObjectHeader, string(data)))
+	}
+	if req.IfNotExists {
+		opts = append(opts, client.OptReqHeader(schema.ContentIfNoneMatchHeader, "*"))
+	}
+	if req.IfMatch != "" {
+		opts = append(opts, client.OptReqHeader(schema.ContentIfMatchHeader, req.IfMatch))
+	}
+
+	// Set the body, with progress
+	body := req.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	if progress != nil {
+		body = newProgressReader(body, req.Path, progress)
+	}
+
+	// Perform the request
+	var response schema.Object
+	if err := c.DoWithContext(ctx, &objectPayload{Reader: body, contentType: req.ContentType}, &response, opts...); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
+// CreateObjects uploads files under a prefix in a volume, using one multipart
+// request for every schema.MaxUploadFiles files. The file path is relative to
+// the prefix. The progress function can be nil.
+func (c *Client) CreateObjects(ctx context.Context, volume, prefix string, files []types.File, progress ProgressFunc) (*schema.CreateObjectsResponse, error) {
+	var result schema.CreateObjectsResponse
+	for len(files) > 0 {
+		batch := files[:min(len(files), schema.MaxUploadFiles)]
+		files = files[len(batch):]
+
+		// Wrap each body with progress
+		request := schema.CreateObjectsRequest{
+			Files: make([]types.File, 0, len(batch)),
+		}
+		for _, file := range batch {
+			if progress != nil {
+				file.Body = readCloser{newProgressReader(file.Body, file.Path, progress), file.Body}
+			}
+			request.Files = append(request.Files, file)
+		}
+
+		// Upload the batch
+		var response schema.CreateObjectsResponse
+		if payload, err := client.NewStreamingMultipartRequest(request, types.ContentTypeJSON); err != nil {
+			return nil, err
+		} else if err := c.DoWithContext(ctx, payload, &response, client.OptPath("object", volume, prefix), client.OptNoTimeout()); err != nil {
+			return types.Ptr(result), err
+		} else {
+			result.Body = append(result.Body, response.Body...)
+		}
+	}
+
+	// Return the response
+	return types.Ptr(result), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PAYLOAD
+
+func (objectPayload) Method() string { return http.MethodPut }
+func (objectPayload) Accept() string { return types.ContentTypeJSON }
+func (p objectPayload) Type() string {
+	if p.contentType != "" {
+		return p.contentType
+	}
+	return types.ContentTypeBinary
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func newProgressReader(r io.Reader, path string, fn ProgressFunc) *progressReader {
+	return &progressReader{
+		Reader: r,
+		path:   path,
+		total:  readerSize(r),
+		fn:     fn,
+	}
+}
+
+func (r *progressReader) Read(data []byte) (int, error) {
+	n, err := r.Reader.Read(data)
+	r.written += int64(n)
+	if r.written-r.emitted >= progressInterval || (n > 0 && r.written == r.total) || (errors.Is(err, io.EOF) && r.written != r.emitted) {
+		r.emitted = r.written
+		r.fn(r.path, r.written, r.total)
+	}
+	return n, err
+}
+
+// readerSize returns the size of a reader, or -1 if the size is not known
+func readerSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if info, err := r.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	case interface{ Len() int }:
+		return int64(r.Len())
+	}
+	return -1
+}
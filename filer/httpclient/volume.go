@@ -49,6 +49,16 @@ func (c *Client) GetVolume(ctx context.Context, name string) (*schema.Volume, er
 	return types.Ptr(response), nil
 }
 
+func (c *Client) GetVolumeIndex(ctx context.Context, name string) (*schema.VolumeIndex, error) {
+	var response schema.VolumeIndex
+	if err := c.DoWithContext(ctx, client.MethodGet, &response, client.OptPath("volume", name, "index")); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return types.Ptr(response), nil
+}
+
 func (c *Client) UpdateVolume(ctx context.Context, name string, meta schema.VolumeMeta) (*schema.Volume, error) {
 	req, err := client.NewJSONRequestEx(http.MethodPatch, meta, types.ContentTypeAny)
 	if err != nil {
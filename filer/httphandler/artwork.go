@@ -24,17 +24,29 @@ import (
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
-func RegisterArtworkHandlers(manager *manager.Manager, router *httprouter.Router) error {
+func RegisterArtworkHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
 	router.Spec().AddTag("Artwork", "Artwork Operations")
 
 	return errors.Join(
-		router.RegisterPath("artwork", nil, httprequest.NewPathItem("Artwork", "Create artwork").
+		router.RegisterPath("artwork", nil, httprequest.NewPathItem("Artwork", "List or create artwork").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = ListArtwork(w, r, manager)
+				},
+				"List artwork",
+				openapi.WithTags("Artwork"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithDescription(`Returns artwork metadata without the image data. Set orphaned=true to list artwork which is not linked to any object, which is removed after a grace period.`),
+				openapi.WithQuery(jsonschema.MustFor[schema.ArtworkListRequest]()),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.ArtworkList]()),
+			).
 			Post(
 				func(w http.ResponseWriter, r *http.Request) {
 					_ = CreateArtwork(w, r, manager)
 				},
 				"Create artwork",
 				openapi.WithTags("Artwork"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
 				openapi.WithMultipartRequest(jsonschema.MustFor[schema.ArtworkUploadRequest]()),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Artwork]()),
 			),
@@ -46,7 +58,40 @@ func RegisterArtworkHandlers(manager *manager.Manager, router *httprouter.Router
 				},
 				"Get artwork",
 				openapi.WithTags("Artwork"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Artwork]()),
+			).
+			Delete(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = DeleteArtwork(w, r, manager, r.PathValue("etag"))
+				},
+				"Delete artwork",
+				openapi.WithTags("Artwork"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription(`Deletes artwork, its links to objects and its renditions.`),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.ArtworkInfo]()),
+			),
+		),
+		router.RegisterPath("artwork/{etag}/object/{volume}/{path...}", nil, httprequest.NewPathItem("Artwork", "Link or unlink artwork on an object").
+			Put(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = LinkArtwork(w, r, manager, r.PathValue("etag"), r.PathValue("volume"), r.PathValue("path"))
+				},
+				"Link artwork to an object",
+				openapi.WithTags("Artwork"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription(`Links artwork to an indexed object. Linking artwork which is already linked has no effect.`),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.ObjectArtwork]()),
+			).
+			Delete(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = UnlinkArtwork(w, r, manager, r.PathValue("etag"), r.PathValue("volume"), r.PathValue("path"))
+				},
+				"Unlink artwork from an object",
+				openapi.WithTags("Artwork"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription(`Removes the link between artwork and an object. Artwork which is not linked to any object is removed after a grace period.`),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.ObjectArtwork]()),
 			),
 		),
 	)
@@ -55,6 +100,43 @@ func RegisterArtworkHandlers(manager *manager.Manager, router *httprouter.Router
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+func ListArtwork(w http.ResponseWriter, r *http.Request, manager *manager.Manager) error {
+	var req schema.ArtworkListRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if resp, err := manager.ListArtwork(r.Context(), req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), req.String())
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+func DeleteArtwork(w http.ResponseWriter, r *http.Request, manager *manager.Manager, etag string) error {
+	if resp, err := manager.DeleteArtwork(r.Context(), schema.ArtworkKey(etag)); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), etag)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+func LinkArtwork(w http.ResponseWriter, r *http.Request, manager *manager.Manager, etag, volume, path string) error {
+	object := schema.ObjectKey{Volume: volume, Path: path}
+	if resp, err := manager.LinkArtwork(r.Context(), object, schema.ArtworkKey(etag)); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), etag)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+func UnlinkArtwork(w http.ResponseWriter, r *http.Request, manager *manager.Manager, etag, volume, path string) error {
+	object := schema.ObjectKey{Volume: volume, Path: path}
+	if resp, err := manager.UnlinkArtwork(r.Context(), object, schema.ArtworkKey(etag)); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), etag)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
 func CreateArtwork(w http.ResponseWriter, r *http.Request, manager *manager.Manager) error {
 	var req schema.ArtworkUploadRequest
 	if err := httprequest.Read(r, &req); err != nil {
@@ -0,0 +1,100 @@
+package httphandler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	jsonschema "github.com/mutablelogic/go-server/pkg/jsonschema"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	requestIDHeader    = "X-Request-Id"
+	requestIDMaxLength = 128
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func RegisterAuditHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
+	router.Spec().AddTag("Audit", "Audit Log Operations")
+
+	return router.RegisterPath("audit", nil, httprequest.NewPathItem("Audit", "Audit log of changes to volumes, objects, credentials and tokens").
+		Get(
+			func(w http.ResponseWriter, r *http.Request) {
+				_ = ListAudit(w, r, manager)
+			},
+			"List audit entries",
+			openapi.WithTags("Audit"),
+			openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeAdmin),
+			openapi.WithDescription(`Entries are returned most recent first. The target filter matches targets which start with the value, so "object:photos:" returns changes to all objects in the photos volume.`),
+			openapi.WithJSONRequest(jsonschema.MustFor[schema.AuditListRequest]()),
+			openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.AuditList]()),
+		),
+	)
+}
+
+// RequestID is middleware which sets the request identifier recorded in the
+// audit log. The X-Request-Id header is used when set by the client or a
+// proxy, otherwise an identifier is generated. The identifier is returned in
+// the X-Request-Id response header.
+func RequestID(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(r.Header.Get(requestIDHeader))
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next(w, r.WithContext(manager.WithRequestID(r.Context(), id)))
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func ListAudit(w http.ResponseWriter, r *http.Request, manager *manager.Manager) error {
+	var req schema.AuditListRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if resp, err := manager.ListAudit(r.Context(), req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), req.String())
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// validRequestID returns true when a client-supplied request identifier is
+// short and contains only printable ASCII characters
+func validRequestID(id string) bool {
+	if id == "" || len(id) > requestIDMaxLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request identifier
+func newRequestID() string {
+	var data [16]byte
+	_, _ = rand.Read(data[:])
+	return hex.EncodeToString(data[:])
+}
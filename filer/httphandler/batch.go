@@ -0,0 +1,87 @@
+package httphandler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	jsonschema "github.com/mutablelogic/go-server/pkg/jsonschema"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func RegisterBatchHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
+	router.Spec().AddTag("Batch", "Batch Operations")
+
+	return errors.Join(
+		router.RegisterPath("batch", nil, httprequest.NewPathItem("Batch", "Run batch operations").
+			Post(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = CreateBatch(w, r, manager)
+				},
+				"Run batch operations",
+				openapi.WithTags("Batch"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription(`Runs delete, copy, move, patch and reindex operations in order, with a result for each operation. Small batches run immediately. Large batches, or batches with async set, run as a background job and return 202 Accepted with a batch identifier to poll for progress.`),
+				openapi.WithJSONRequest(jsonschema.MustFor[schema.BatchRequest]()),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Batch]()),
+				openapi.WithJSONResponse(http.StatusAccepted, jsonschema.MustFor[schema.Batch]()),
+			),
+		),
+		router.RegisterPath("batch/{id}", jsonschema.MustFor[schema.BatchKey](), httprequest.NewPathItem("Batch", "Get batch progress").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = GetBatch(w, r, manager, r.PathValue("id"))
+				},
+				"Get the progress and results of a batch",
+				openapi.WithTags("Batch"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Batch]()),
+			),
+		),
+	)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func CreateBatch(w http.ResponseWriter, r *http.Request, manager *manager.Manager) error {
+	var req schema.BatchRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
+	// Run the batch, or create the background job
+	batch, err := manager.Batch(r.Context(), req)
+	if err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err))
+	}
+
+	// Return accepted when the batch runs in the background
+	status := http.StatusOK
+	if batch.FinishedAt == nil {
+		status = http.StatusAccepted
+	}
+	return httpresponse.JSON(w, status, httprequest.Indent(r), batch)
+}
+
+func GetBatch(w http.ResponseWriter, r *http.Request, manager *manager.Manager, id string) error {
+	key, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.Withf("invalid batch id: %q", id))
+	}
+	if batch, err := manager.GetBatch(r.Context(), schema.BatchKey{ID: key}); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), id)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), batch)
+	}
+}
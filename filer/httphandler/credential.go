@@ -19,7 +19,7 @@ import (
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
-func RegisterCredentialHandlers(manager *manager.Manager, router *httprouter.Router) error {
+func RegisterCredentialHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
 	router.Spec().AddTag("Credentials", "Credential Operations")
 
 	return errors.Join(
@@ -30,6 +30,7 @@ func RegisterCredentialHandlers(manager *manager.Manager, router *httprouter.Rou
 				},
 				"List encrypted credential keys",
 				openapi.WithTags("Credentials"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeCredential),
 				openapi.WithJSONRequest(jsonschema.MustFor[schema.CredentialListRequest]()),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.CredentialList]()),
 			).
@@ -39,6 +40,7 @@ func RegisterCredentialHandlers(manager *manager.Manager, router *httprouter.Rou
 				},
 				"Create or update an encrypted credential",
 				openapi.WithTags("Credentials"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeCredential),
 				openapi.WithJSONRequest(jsonschema.MustFor[schema.CredentialCreate]()),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Credential]()),
 			),
@@ -50,6 +52,7 @@ func RegisterCredentialHandlers(manager *manager.Manager, router *httprouter.Rou
 				},
 				"Get an encrypted credential",
 				openapi.WithTags("Credentials"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeCredential),
 				openapi.WithDescription(`The passphrase must be provided in the request body as text/plain content type. The response contains the credential in JSON format.`),
 				openapi.WithRequest(types.ContentTypeTextPlain, jsonschema.MustFor[string]()),
 				openapi.WithResponse(http.StatusOK, types.ContentTypeJSON, jsonschema.MustFor[[]byte](), "Credential in JSON format"),
@@ -60,6 +63,7 @@ func RegisterCredentialHandlers(manager *manager.Manager, router *httprouter.Rou
 				},
 				"Delete an encrypted credential",
 				openapi.WithTags("Credentials"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeCredential),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Credential]()),
 			),
 		),
@@ -0,0 +1,70 @@
+package httphandler
+
+import (
+	"errors"
+	"net/http"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	jsonschema "github.com/mutablelogic/go-server/pkg/jsonschema"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func RegisterEventHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
+	router.Spec().AddTag("Events", "Event Operations")
+
+	return errors.Join(
+		router.RegisterPath("events", nil, httprequest.NewPathItem("Events", "Stream volume and object events").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = ListEvents(w, r, manager)
+				},
+				"Stream volume and object events",
+				openapi.WithTags("Events"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithDescription(`Streams server-sent events as volumes are mounted, unmounted or updated, and as objects are created, deleted, indexed or removed from the index. The event name is the event type. Filter by volume, path prefix and event type or category (volume or object).`),
+				openapi.WithQuery(jsonschema.MustFor[schema.EventRequest]()),
+				openapi.WithResponse(http.StatusOK, types.ContentTypeTextStream, jsonschema.MustFor[schema.Event](), "Event stream"),
+			),
+		),
+	)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func ListEvents(w http.ResponseWriter, r *http.Request, manager *manager.Manager) error {
+	var req schema.EventRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
+	// Subscribe to events, which ends when the request is cancelled
+	events, err := manager.Events(r.Context(), req)
+	if err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), types.Stringify(req))
+	}
+
+	// Stream events until the channel is closed. The stream sends a ping
+	// event without data when there have been no events for ten seconds,
+	// which keeps the connection open and is ignored by the client.
+	stream := httpresponse.NewTextStream(w)
+	if stream == nil {
+		return httpresponse.Error(w, httpresponse.ErrInternalError.With("failed to create event stream"))
+	}
+	for event := range events {
+		stream.Write(event.Type, event)
+	}
+
+	// Return any stream errors
+	return stream.Close()
+}
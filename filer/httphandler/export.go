@@ -0,0 +1,78 @@
+package httphandler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	jsonschema "github.com/mutablelogic/go-server/pkg/jsonschema"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func RegisterIndexExportHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
+	return errors.Join(
+		router.RegisterPath("volume/{name}/export", nil, httprequest.NewPathItem("Volumes", "Export the index of a volume").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = ExportIndex(w, r, manager, r.PathValue("name"))
+				},
+				"Export the index of a volume",
+				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithDescription("Streams the indexed objects, the metadata extracted from them, the linked artwork and the search vectors as a gzipped tar archive of newline-delimited JSON and artwork data, for backup or migration."),
+				openapi.WithResponse(http.StatusOK, schema.ContentTypeGzip, jsonschema.MustFor[[]byte](), "Gzipped tar archive"),
+			),
+		),
+		router.RegisterPath("volume/{name}/import", nil, httprequest.NewPathItem("Volumes", "Import the index of a volume").
+			Post(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = ImportIndex(w, r, manager, r.PathValue("name"))
+				},
+				"Import the index of a volume",
+				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription("Reads an index export into the volume, which can have a different name from the exported volume. Objects are imported when their etag, or size and modification time, match the object in the backend. Objects which have changed are queued for indexing, and objects which no longer exist are skipped."),
+				openapi.WithRequest(schema.ContentTypeGzip, jsonschema.MustFor[[]byte]()),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.IndexImport]()),
+			),
+		),
+	)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func ExportIndex(w http.ResponseWriter, r *http.Request, manager *manager.Manager, name string) error {
+	// Set the headers, which are written with the manifest
+	w.Header().Set(types.ContentTypeHeader, schema.ContentTypeGzip)
+	w.Header().Set(types.ContentDispositonHeader, mime.FormatMediaType("attachment", map[string]string{"filename": schema.IndexExportFilename(name)}))
+	writer := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+	// Write the export, returning an error if nothing has been written yet
+	if err := manager.ExportIndex(r.Context(), name, writer); err != nil && !writer.written {
+		w.Header().Del(types.ContentDispositonHeader)
+		return httpresponse.Error(w, gofiler.HTTPErr(err), name)
+	} else {
+		return err
+	}
+}
+
+func ImportIndex(w http.ResponseWriter, r *http.Request, manager *manager.Manager, name string) error {
+	defer r.Body.Close()
+	if resp, err := manager.ImportIndex(r.Context(), name, r.Body); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), name)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
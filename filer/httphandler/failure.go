@@ -0,0 +1,102 @@
+package httphandler
+
+import (
+	"errors"
+	"net/http"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	jsonschema "github.com/mutablelogic/go-server/pkg/jsonschema"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func RegisterIndexFailureHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
+	return errors.Join(
+		router.RegisterPath("volume/{name}/failure", nil, httprequest.NewPathItem("Volumes", "Objects which could not be indexed").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = ListIndexFailures(w, r, manager, r.PathValue("name"))
+				},
+				"List index failures",
+				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithDescription("Objects are listed when the last attempt to index them failed, or when they are ignored by the indexer. Failures are removed when the object is indexed."),
+				openapi.WithJSONRequest(jsonschema.MustFor[schema.IndexFailureListRequest]()),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.IndexFailureList]()),
+			),
+		),
+		router.RegisterPath("volume/{name}/failure/retry", nil, httprequest.NewPathItem("Volumes", "Retry objects which could not be indexed").
+			Post(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = RetryIndexFailures(w, r, manager, r.PathValue("name"))
+				},
+				"Retry index failures",
+				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription("Queues the objects for indexing, and the objects are no longer ignored. All failures which are not ignored are retried when no paths are given."),
+				openapi.WithJSONRequest(jsonschema.MustFor[schema.IndexFailureRequest]()),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.IndexFailureList]()),
+			),
+		),
+		router.RegisterPath("volume/{name}/failure/ignore", nil, httprequest.NewPathItem("Volumes", "Ignore objects when indexing").
+			Post(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = IgnoreIndexFailures(w, r, manager, r.PathValue("name"))
+				},
+				"Ignore objects",
+				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription("Ignored objects are skipped by the indexer until they are retried."),
+				openapi.WithJSONRequest(jsonschema.MustFor[schema.IndexFailureRequest]()),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.IndexFailureList]()),
+			),
+		),
+	)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func ListIndexFailures(w http.ResponseWriter, r *http.Request, manager *manager.Manager, name string) error {
+	var req schema.IndexFailureListRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else {
+		req.Volume = name
+	}
+	if resp, err := manager.ListIndexFailures(r.Context(), req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), name)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+func RetryIndexFailures(w http.ResponseWriter, r *http.Request, manager *manager.Manager, name string) error {
+	var req schema.IndexFailureRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if resp, err := manager.RetryIndexFailures(r.Context(), name, req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), name)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+func IgnoreIndexFailures(w http.ResponseWriter, r *http.Request, manager *manager.Manager, name string) error {
+	var req schema.IndexFailureRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if resp, err := manager.IgnoreIndexFailures(r.Context(), name, req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), name)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
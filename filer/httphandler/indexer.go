@@ -0,0 +1,48 @@
+package httphandler
+
+import (
+	"net/http"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	jsonschema "github.com/mutablelogic/go-server/pkg/jsonschema"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func RegisterIndexerHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
+	return router.RegisterPath("indexer", nil, httprequest.NewPathItem("Volumes", "Instances which index volumes").
+		Get(
+			func(w http.ResponseWriter, r *http.Request) {
+				_ = ListIndexers(w, r, manager)
+			},
+			"List indexers",
+			openapi.WithTags("Volumes"),
+			openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+			openapi.WithDescription("Indexers send a heartbeat, and each enabled volume is claimed by one indexer. Volumes are claimed by other indexers when an indexer stops sending a heartbeat. Objects which require capabilities the indexer does not have are indexed by another indexer."),
+			openapi.WithJSONRequest(jsonschema.MustFor[schema.IndexerListRequest]()),
+			openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.IndexerList]()),
+		),
+	)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func ListIndexers(w http.ResponseWriter, r *http.Request, manager *manager.Manager) error {
+	var req schema.IndexerListRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if resp, err := manager.ListIndexers(r.Context(), req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err))
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
@@ -18,7 +18,7 @@ import (
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
-func RegisterLLMProviderHandlers(manager *manager.Manager, router *httprouter.Router) error {
+func RegisterLLMProviderHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
 	router.Spec().AddTag("LLM Providers", "LLM Provider Operations")
 
 	return errors.Join(
@@ -29,6 +29,7 @@ func RegisterLLMProviderHandlers(manager *manager.Manager, router *httprouter.Ro
 				},
 				"Create a LLM provider",
 				openapi.WithTags("LLM Providers"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeAdmin),
 				openapi.WithJSONRequest(jsonschema.MustFor[schema.LLMProviderCreate]()),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.LLMProvider]()),
 			),
@@ -20,7 +20,7 @@ import (
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
-func RegisterMetadataHandlers(manager *manager.Manager, router *httprouter.Router) error {
+func RegisterMetadataHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
 	router.Spec().AddTag("Metadata", "Metadata Operations")
 
 	return errors.Join(
@@ -31,6 +31,7 @@ func RegisterMetadataHandlers(manager *manager.Manager, router *httprouter.Route
 				},
 				"Extract metadata for a file",
 				openapi.WithTags("Metadata"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
 				openapi.WithMultipartRequest(jsonschema.MustFor[metadataRequest]()),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.ObjectMeta]()),
 			),
@@ -3,8 +3,11 @@ package httphandler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"mime"
 	"net/http"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -21,10 +24,20 @@ import (
 	types "github.com/mutablelogic/go-server/pkg/types"
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// statusWriter writes the response status on the first write
+type statusWriter struct {
+	http.ResponseWriter
+	status  int
+	written bool
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
-func RegisterObjectHandlers(manager *manager.Manager, router *httprouter.Router) error {
+func RegisterObjectHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
 	router.Spec().AddTag("Objects", "Object Operations")
 
 	return errors.Join(
@@ -35,17 +48,91 @@ func RegisterObjectHandlers(manager *manager.Manager, router *httprouter.Router)
 				},
 				"List objects",
 				openapi.WithTags("Objects"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
 				openapi.WithJSONRequest(jsonschema.MustFor[schema.ObjectListRequest]()),
+				openapi.WithDescription(`Returns a page of objects. When the Accept header is application/x-ndjson or application/ndjson, all objects are streamed as one object per line without a page limit.`),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.ObjectList]()),
+				openapi.WithResponse(http.StatusOK, types.ContentTypeJSONStreamLegacy, jsonschema.MustFor[schema.Object](), "Objects, one per line"),
+			),
+		),
+		router.RegisterPath("archive/{volume}/{path...}", nil, httprequest.NewPathItem("Objects", "Download objects as an archive").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = GetArchive(w, r, manager, r.PathValue("volume"), r.PathValue("path"))
+				},
+				"Download objects as an archive",
+				openapi.WithTags("Objects"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithDescription(`Streams all objects under the path prefix as a zip or tar.gz archive, optionally filtered by content type. Modification times are preserved in the archive.`),
+				openapi.WithQuery(jsonschema.MustFor[schema.ArchiveRequest]()),
+				openapi.WithResponse(http.StatusOK, schema.ContentTypeZip, jsonschema.MustFor[[]byte](), "Zip archive"),
+				openapi.WithResponse(http.StatusOK, schema.ContentTypeGzip, jsonschema.MustFor[[]byte](), "Gzipped tar archive"),
 			),
 		),
 		router.RegisterPath("object/{volume}/{path...}", nil, httprequest.NewPathItem("Objects", "Get, update or delete an object").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = GetObject(w, r, manager, r.PathValue("volume"), r.PathValue("path"))
+				},
+				"Get object content",
+				openapi.WithTags("Objects"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithQuery(jsonschema.MustFor[schema.ReadObjectRequest]()),
+				openapi.WithDescription(`Returns the object content. Conditional requests are supported with the If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since headers. Single and multiple byte ranges are supported with the Range and If-Range headers. Set download=true to return the content as an attachment.`),
+				openapi.WithResponse(http.StatusOK, types.ContentTypeBinary, jsonschema.MustFor[[]byte](), "Object content"),
+				openapi.WithResponse(http.StatusPartialContent, types.ContentTypeBinary, jsonschema.MustFor[[]byte](), "Partial object content"),
+			).
+			Put(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = PutObject(w, r, manager, r.PathValue("volume"), r.PathValue("path"))
+				},
+				"Create or replace an object",
+				openapi.WithTags("Objects"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription(`Streams the request body into the object. Set If-None-Match to * to fail when the object already exists, or If-Match to replace the object only when it matches the etag. The content type and metadata can be set with the X-Object header, otherwise the Content-Type header is used.`),
+				openapi.WithRequest(types.ContentTypeBinary, jsonschema.MustFor[[]byte]()),
+				openapi.WithJSONResponse(http.StatusCreated, jsonschema.MustFor[schema.Object]()),
+			).
+			Post(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = CreateObjects(w, r, manager, r.PathValue("volume"), r.PathValue("path"))
+				},
+				"Upload objects under a prefix",
+				openapi.WithTags("Objects"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription(fmt.Sprintf(`Uploads up to %d files in a multipart request under the path prefix. The file path is taken from the X-Path header or the filename of each part.`, schema.MaxUploadFiles)),
+				openapi.WithMultipartRequest(jsonschema.MustFor[schema.CreateObjectsRequest]()),
+				openapi.WithJSONResponse(http.StatusCreated, jsonschema.MustFor[schema.CreateObjectsResponse]()),
+			).
+			Patch(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = PatchObject(w, r, manager, r.PathValue("volume"), r.PathValue("path"))
+				},
+				"Set user metadata on an object",
+				openapi.WithTags("Objects"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription(`Sets user metadata keys on an object, such as tags, ratings, titles and descriptions. A key with a null value is removed. User metadata is kept when the object is reindexed, is included in search, and takes precedence over extracted metadata with the same key.`),
+				openapi.WithJSONRequest(jsonschema.MustFor[schema.ObjectPatchRequest]()),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Object]()),
+			).
+			Delete(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = DeleteObjects(w, r, manager, r.PathValue("volume"), r.PathValue("path"))
+				},
+				"Delete an object or prefix",
+				openapi.WithTags("Objects"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription(`Deletes an object, or all objects under a path prefix when recursive is true, and removes them from the index. Set If-Match to delete the object only when it matches the etag.`),
+				openapi.WithQuery(jsonschema.MustFor[schema.DeleteObjectsRequest]()),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.DeleteObjectsResponse]()),
+			).
 			Head(
 				func(w http.ResponseWriter, r *http.Request) {
 					_ = HeadObject(w, r, manager, r.PathValue("volume"), r.PathValue("path"))
 				},
 				"Get object metadata",
 				openapi.WithTags("Objects"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Object]()),
 			),
 		),
@@ -59,7 +146,17 @@ func ListObjects(w http.ResponseWriter, r *http.Request, manager *manager.Manage
 	var req schema.ObjectListRequest
 	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
 		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
-	} else if resp, err := manager.ListObjects(r.Context(), req); err != nil {
+	}
+
+	// Stream the objects without a page limit when requested
+	if stream := newJSONStream(w, r); stream != nil {
+		return stream.Close(manager.StreamObjects(r.Context(), req, func(obj *schema.Object) error {
+			return stream.Write(obj)
+		}), types.Stringify(req))
+	}
+
+	// Return a page of objects
+	if resp, err := manager.ListObjects(r.Context(), req); err != nil {
 		return httpresponse.Error(w, gofiler.HTTPErr(err), types.Stringify(req))
 	} else {
 		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
@@ -80,10 +177,252 @@ func HeadObject(w http.ResponseWriter, r *http.Request, manager *manager.Manager
 
 	// TODO: An error occurred, but we have an object, so we continue...
 
+	// Set the object headers
+	setObjectHeaders(w, obj, "inline")
+
+	// Determine the response code based on the preconditions
+	w.WriteHeader(checkPreconditions(r, obj))
+
+	// Return success
+	return nil
+}
+
+func GetObject(w http.ResponseWriter, r *http.Request, manager *manager.Manager, volume, path string) error {
+	var req schema.ReadObjectRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else {
+		req.Volume = volume
+		req.Path = path
+	}
+
+	// Open the object
+	reader, obj, err := manager.ReadObject(r.Context(), req.ObjectKey)
+	if err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), types.Stringify(req))
+	}
+	defer reader.Close()
+
+	// Write the object
+	if req.Download {
+		return writeObject(w, r, reader, obj, "attachment")
+	} else {
+		return writeObject(w, r, reader, obj, "inline")
+	}
+}
+
+func PutObject(w http.ResponseWriter, r *http.Request, manager *manager.Manager, volume, path string) error {
+	req := schema.CreateObjectRequest{
+		ObjectKey: schema.ObjectKey{
+			Volume: volume,
+			Path:   path,
+		},
+		Body:    r.Body,
+		IfMatch: r.Header.Get(schema.ContentIfMatchHeader),
+	}
+
+	// Read the object metadata from the headers
+	if err := readObjectMeta(r.Header, &req.ObjectMeta); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
+	// Only If-None-Match: * is supported for writes
+	if inm := strings.TrimSpace(r.Header.Get(schema.ContentIfNoneMatchHeader)); inm == "*" {
+		req.IfNotExists = true
+	} else if inm != "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.Withf("unsupported %s header: %q", schema.ContentIfNoneMatchHeader, inm))
+	}
+
+	// Create the object. When the object is returned with an error, the
+	// object was written but could not be recorded or queued for indexing,
+	// which the next reindex of the volume picks up.
+	obj, err := manager.CreateObject(r.Context(), req)
+	if err != nil && obj == nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), types.Stringify(req))
+	}
+
+	// Return the object
+	return httpresponse.JSON(w, http.StatusCreated, httprequest.Indent(r), obj)
+}
+
+func CreateObjects(w http.ResponseWriter, r *http.Request, manager *manager.Manager, volume, prefix string) error {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
+	// Stream each file part into the volume
+	var resp schema.CreateObjectsResponse
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+		} else if part.FormName() != "file" {
+			continue
+		} else if len(resp.Body) >= schema.MaxUploadFiles {
+			return httpresponse.Error(w, httpresponse.Err(http.StatusRequestEntityTooLarge).Withf("too many files in upload (limit is %d)", schema.MaxUploadFiles))
+		}
+
+		// Determine the path, which is always under the prefix
+		filename := part.Header.Get(types.ContentPathHeader)
+		if filename == "" {
+			filename = part.FileName()
+		}
+		if filename == "" {
+			return httpresponse.Error(w, httpresponse.ErrBadRequest.Withf("missing filename for part %d", len(resp.Body)))
+		}
+		req := schema.CreateObjectRequest{
+			ObjectKey: schema.ObjectKey{
+				Volume: volume,
+				Path:   strings.TrimPrefix(path.Join("/", prefix, path.Clean("/"+filename)), "/"),
+			},
+			Body: part,
+		}
+		if err := readObjectMeta(http.Header(part.Header), &req.ObjectMeta); err != nil {
+			return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+		}
+
+		// Create the object
+		obj, err := manager.CreateObject(r.Context(), req)
+		if err != nil && obj == nil {
+			return httpresponse.Error(w, gofiler.HTTPErr(err), types.Stringify(req))
+		}
+		resp.Body = append(resp.Body, types.Value(obj))
+	}
+
+	// Check for at least one file
+	if len(resp.Body) == 0 {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(`missing "file" parts`))
+	}
+
+	// Return the objects
+	return httpresponse.JSON(w, http.StatusCreated, httprequest.Indent(r), resp)
+}
+
+func DeleteObjects(w http.ResponseWriter, r *http.Request, manager *manager.Manager, volume, path string) error {
+	var req schema.DeleteObjectsRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else {
+		req.Volume = volume
+		req.Path = path
+		req.IfMatch = r.Header.Get(schema.ContentIfMatchHeader)
+	}
+
+	// Delete the objects
+	if resp, err := manager.DeleteObjects(r.Context(), req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), types.Stringify(req))
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+func PatchObject(w http.ResponseWriter, r *http.Request, manager *manager.Manager, volume, path string) error {
+	var req schema.ObjectPatchRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if object, err := manager.PatchObject(r.Context(), schema.ObjectKey{Volume: volume, Path: path}, req.Meta); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), path)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), object)
+	}
+}
+
+func GetArchive(w http.ResponseWriter, r *http.Request, manager *manager.Manager, volume, path string) error {
+	var req schema.ArchiveRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else {
+		req.Volume = volume
+		req.Path = path
+	}
+
+	// Check the format
+	contentType := req.ContentType()
+	if contentType == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.Withf("unsupported archive format: %q", req.Format))
+	}
+
+	// Set the headers, which are written with the first object in the archive
+	w.Header().Set(types.ContentTypeHeader, contentType)
+	w.Header().Set(types.ContentDispositonHeader, mime.FormatMediaType("attachment", map[string]string{"filename": req.Filename()}))
+	writer := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+	// Write the archive, returning an error if nothing has been written yet
+	if err := manager.WriteArchive(r.Context(), req, writer); err != nil && !writer.written {
+		w.Header().Del(types.ContentDispositonHeader)
+		return httpresponse.Error(w, gofiler.HTTPErr(err), types.Stringify(req))
+	} else {
+		return err
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (w *statusWriter) Write(data []byte) (int, error) {
+	if !w.written {
+		w.written = true
+		w.WriteHeader(w.status)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// readObjectMeta reads the object metadata from the X-Object header, and
+// falls back to the Content-Type header for the content type
+func readObjectMeta(header http.Header, meta *schema.ObjectMeta) error {
+	if data := header.Get(schema.ContentObjectHeader); data != "" {
+		if err := json.Unmarshal([]byte(data), meta); err != nil {
+			return fmt.Errorf("invalid %s header: %w", schema.ContentObjectHeader, err)
+		}
+	}
+	if meta.ContentType == "" {
+		meta.ContentType = header.Get(types.ContentTypeHeader)
+	}
+	return nil
+}
+
+// writeObject writes the object content, or the byte ranges requested,
+// after checking the conditional request headers
+func writeObject(w http.ResponseWriter, r *http.Request, reader io.Reader, obj *schema.Object, disposition string) error {
+	// Set the object headers
+	setObjectHeaders(w, obj, disposition)
+
+	// Return 304 or 412 without content if the preconditions are not met
+	if status := checkPreconditions(r, obj); status != http.StatusOK {
+		w.Header().Del(types.ContentLengthHeader)
+		w.WriteHeader(status)
+		return nil
+	}
+
+	// Serve byte ranges
+	if header := r.Header.Get(schema.RangeHeader); header != "" && checkIfRange(r, obj) {
+		ranges, err := parseRange(header, obj.Size)
+		switch {
+		case errors.Is(err, errRangeNotSatisfiable):
+			w.Header().Del(types.ContentLengthHeader)
+			w.Header().Set(schema.ContentRangeResponseHeader, fmt.Sprintf("bytes */%d", obj.Size))
+			return httpresponse.Error(w, httpresponse.Err(http.StatusRequestedRangeNotSatisfiable).Withf("range %q not satisfiable", header))
+		case err == nil && seekable(reader, ranges):
+			return writeRanges(w, reader, obj, obj.ContentType, ranges)
+		}
+	}
+
+	// Serve the whole object
+	w.WriteHeader(http.StatusOK)
+	_, err := io.Copy(w, reader)
+	return err
+}
+
+// setObjectHeaders sets the content headers for an object, with the
+// disposition being either "inline" or "attachment"
+func setObjectHeaders(w http.ResponseWriter, obj *schema.Object, disposition string) {
 	// Set the content type and disposition headers
 	w.Header().Set(types.ContentTypeHeader, obj.ContentType)
 	if filename := filepath.Base(obj.Path); filename != "" {
-		if cd := mime.FormatMediaType("inline", map[string]string{"filename": filename}); cd != "" {
+		if cd := mime.FormatMediaType(disposition, map[string]string{"filename": filename}); cd != "" {
 			w.Header().Set(types.ContentDispositonHeader, cd)
 		}
 	}
@@ -95,6 +434,7 @@ func HeadObject(w http.ResponseWriter, r *http.Request, manager *manager.Manager
 	}
 	if obj.Size >= 0 {
 		w.Header().Set(types.ContentLengthHeader, strconv.FormatInt(obj.Size, 10))
+		w.Header().Set(schema.ContentAcceptRangesHeader, "bytes")
 	}
 	w.Header().Set(types.ContentModifiedHeader, obj.ModTime.Format(http.TimeFormat))
 
@@ -102,62 +442,34 @@ func HeadObject(w http.ResponseWriter, r *http.Request, manager *manager.Manager
 	if data, err := json.Marshal(obj); err == nil {
 		w.Header().Set(schema.ContentObjectHeader, string(data))
 	}
-
-	// Determine the response code based on the preconditions
-	return checkPreconditions(w, r, obj)
 }
 
-///////////////////////////////////////////////////////////////////////////////
-// PRIVATE METHODS
-
-func checkPreconditions(w http.ResponseWriter, r *http.Request, obj *schema.Object) error {
+// checkPreconditions returns the response status based on the conditional
+// request headers: 412 if a precondition fails, 304 if the object has not
+// been modified, or else 200
+func checkPreconditions(r *http.Request, obj *schema.Object) int {
 	etag := types.Value(obj.ETag)
 	modtime := obj.ModTime
 
 	if im := r.Header.Get(schema.ContentIfMatchHeader); im != "" {
-		if !matchETags(im, etag, true) {
-			w.WriteHeader(http.StatusPreconditionFailed)
-			return nil
+		if !schema.MatchETags(im, etag, true) {
+			return http.StatusPreconditionFailed
 		}
 	} else if ius := r.Header.Get(schema.ContentIfUnmodifiedSinceHeader); ius != "" {
 		if t, err := http.ParseTime(ius); err == nil && modtime.After(t) {
-			w.WriteHeader(http.StatusPreconditionFailed)
-			return nil
+			return http.StatusPreconditionFailed
 		}
 	}
 
 	if inm := r.Header.Get(schema.ContentIfNoneMatchHeader); inm != "" {
-		if matchETags(inm, etag, false) {
-			w.WriteHeader(http.StatusNotModified)
-			return nil
+		if schema.MatchETags(inm, etag, false) {
+			return http.StatusNotModified
 		}
 	} else if ims := r.Header.Get(schema.ContentIfModifiedSinceHeader); ims != "" {
 		if t, err := http.ParseTime(ims); err == nil && !modtime.After(t) {
-			w.WriteHeader(http.StatusNotModified)
-			return nil
+			return http.StatusNotModified
 		}
 	}
 
-	w.WriteHeader(http.StatusOK)
-	return nil
-}
-
-func matchETags(header, etag string, strong bool) bool {
-	if strings.TrimSpace(header) == "*" {
-		return etag != ""
-	}
-	if strong && strings.HasPrefix(etag, "W/") {
-		return false
-	}
-	for part := range strings.SplitSeq(header, ",") {
-		part = strings.TrimSpace(part)
-		if strong && strings.HasPrefix(part, "W/") {
-			continue
-		}
-		if strings.Trim(strings.TrimPrefix(part, "W/"), `"`) ==
-			strings.Trim(strings.TrimPrefix(etag, "W/"), `"`) {
-			return true
-		}
-	}
-	return false
+	return http.StatusOK
 }
@@ -0,0 +1,216 @@
+package httphandler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// byteRange is a satisfiable byte range within an object
+type byteRange struct {
+	start, length int64
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// maxRanges is the maximum number of ranges accepted in a single request
+	maxRanges = 100
+)
+
+var (
+	errRangeInvalid        = errors.New("invalid range")
+	errRangeNotSatisfiable = errors.New("range not satisfiable")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// parseRange parses a Range header for an object of the given size. It returns
+// errRangeInvalid when the header should be ignored, and errRangeNotSatisfiable
+// when none of the ranges overlap the object.
+func parseRange(header string, size int64) ([]byteRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || size < 0 {
+		return nil, errRangeInvalid
+	}
+
+	var ranges []byteRange
+	var satisfiable bool
+	for part := range strings.SplitSeq(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, errRangeInvalid
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+		var r byteRange
+		if first == "" {
+			// Suffix range: the last N bytes of the object
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errRangeInvalid
+			} else if n == 0 {
+				continue
+			}
+			r.start = max(size-n, 0)
+			r.length = size - r.start
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, errRangeInvalid
+			} else if start >= size {
+				continue
+			}
+			r.start = start
+			r.length = size - start
+			if last != "" {
+				end, err := strconv.ParseInt(last, 10, 64)
+				if err != nil || end < start {
+					return nil, errRangeInvalid
+				} else if end < size-1 {
+					r.length = end - start + 1
+				}
+			}
+		}
+		if r.length > 0 {
+			satisfiable = true
+			ranges = append(ranges, r)
+		}
+	}
+
+	// Return errors for too many ranges, or no satisfiable ranges
+	if len(ranges) > maxRanges {
+		return nil, errRangeInvalid
+	} else if !satisfiable {
+		return nil, errRangeNotSatisfiable
+	}
+
+	// If the multiple ranges cover more than the object, serve the whole object instead
+	if len(ranges) > 1 {
+		var total int64
+		for _, r := range ranges {
+			total += r.length
+		}
+		if total > size {
+			return nil, errRangeInvalid
+		}
+	}
+
+	// Return success
+	return ranges, nil
+}
+
+// checkIfRange returns true if the Range header should be honoured, based on
+// the If-Range header, which can contain a strong etag or a date
+func checkIfRange(r *http.Request, obj *schema.Object) bool {
+	ir := strings.TrimSpace(r.Header.Get(schema.ContentIfRangeHeader))
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		return schema.MatchETags(ir, types.Value(obj.ETag), true)
+	}
+	if t, err := http.ParseTime(ir); err == nil && !obj.ModTime.IsZero() {
+		return obj.ModTime.Truncate(time.Second).Equal(t)
+	}
+	return false
+}
+
+// contentRange returns the value of the Content-Range header for the range
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// seekable returns true if the ranges can be served from the reader in order.
+// A reader which implements io.Seeker can serve ranges in any order, otherwise
+// the ranges need to be ascending and non-overlapping.
+func seekable(reader io.Reader, ranges []byteRange) bool {
+	if _, ok := reader.(io.Seeker); ok {
+		return true
+	}
+	var pos int64
+	for _, r := range ranges {
+		if r.start < pos {
+			return false
+		}
+		pos = r.start + r.length
+	}
+	return true
+}
+
+// writeRanges writes a 206 Partial Content response for the ranges, using
+// multipart/byteranges when there is more than one range
+func writeRanges(w http.ResponseWriter, reader io.Reader, obj *schema.Object, contentType string, ranges []byteRange) error {
+	var pos int64
+
+	// Move the reader to the start of a range
+	seek := func(start int64) error {
+		if seeker, ok := reader.(io.Seeker); ok {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return err
+			}
+		} else if _, err := io.CopyN(io.Discard, reader, start-pos); err != nil {
+			return err
+		}
+		pos = start
+		return nil
+	}
+
+	// Single range
+	if len(ranges) == 1 {
+		r := ranges[0]
+		w.Header().Set(schema.ContentRangeResponseHeader, r.contentRange(obj.Size))
+		w.Header().Set(types.ContentLengthHeader, strconv.FormatInt(r.length, 10))
+		if err := seek(r.start); err != nil {
+			return httpresponse.Error(w, httpresponse.ErrInternalError.With(err.Error()))
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		_, err := io.CopyN(w, reader, r.length)
+		return err
+	}
+
+	// Multiple ranges
+	mw := multipart.NewWriter(w)
+	w.Header().Set(types.ContentTypeHeader, schema.ContentTypeByteRanges+"; boundary="+mw.Boundary())
+	w.Header().Del(types.ContentLengthHeader)
+	w.WriteHeader(http.StatusPartialContent)
+	for _, r := range ranges {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			types.ContentTypeHeader:           {contentType},
+			schema.ContentRangeResponseHeader: {r.contentRange(obj.Size)},
+		})
+		if err != nil {
+			return err
+		}
+		if err := seek(r.start); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(part, reader, r.length); err != nil {
+			return err
+		}
+		pos += r.length
+	}
+
+	// Write the closing boundary
+	return mw.Close()
+}
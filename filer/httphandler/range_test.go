@@ -0,0 +1,180 @@
+package httphandler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// parseRange
+
+func TestParseRange_001(t *testing.T) {
+	tests := []struct {
+		header string
+		size   int64
+		want   []byteRange
+		err    error
+	}{
+		{"bytes=0-9", 100, []byteRange{{0, 10}}, nil},
+		{"bytes=10-", 100, []byteRange{{10, 90}}, nil},
+		{"bytes=-10", 100, []byteRange{{90, 10}}, nil},
+		{"bytes=-200", 100, []byteRange{{0, 100}}, nil},
+		{"bytes=90-200", 100, []byteRange{{90, 10}}, nil},
+		{"bytes= 0-1 , 5-6", 100, []byteRange{{0, 2}, {5, 2}}, nil},
+		{"bytes=0-0,-1", 100, []byteRange{{0, 1}, {99, 1}}, nil},
+		{"bytes=100-", 100, nil, errRangeNotSatisfiable},
+		{"bytes=-0", 100, nil, errRangeNotSatisfiable},
+		{"bytes=0-", 0, nil, errRangeNotSatisfiable},
+		{"bytes=200-300,100-", 100, nil, errRangeNotSatisfiable},
+		{"bytes=0-9", -1, nil, errRangeInvalid},
+		{"items=0-9", 100, nil, errRangeInvalid},
+		{"bytes=9-0", 100, nil, errRangeInvalid},
+		{"bytes=a-9", 100, nil, errRangeInvalid},
+		{"bytes=0-a", 100, nil, errRangeInvalid},
+		{"bytes=--1", 100, nil, errRangeInvalid},
+		{"bytes=5", 100, nil, errRangeInvalid},
+		{"bytes=0-99,0-99", 100, nil, errRangeInvalid},
+		{"bytes=" + strings.Repeat("0-0,", maxRanges+1), 100, nil, errRangeInvalid},
+	}
+	for _, test := range tests {
+		t.Run(test.header, func(t *testing.T) {
+			ranges, err := parseRange(test.header, test.size)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("parseRange(%q, %d): got error %v, want %v", test.header, test.size, err, test.err)
+			}
+			if len(ranges) != len(test.want) {
+				t.Fatalf("parseRange(%q, %d): got %v, want %v", test.header, test.size, ranges, test.want)
+			}
+			for i := range ranges {
+				if ranges[i] != test.want[i] {
+					t.Errorf("parseRange(%q, %d)[%d]: got %v, want %v", test.header, test.size, i, ranges[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestContentRange_001(t *testing.T) {
+	if got := (byteRange{start: 10, length: 5}).contentRange(100); got != "bytes 10-14/100" {
+		t.Errorf("contentRange: got %q", got)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// checkIfRange
+
+func TestCheckIfRange_001(t *testing.T) {
+	modtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	obj := &schema.Object{ObjectAttr: schema.ObjectAttr{ETag: types.Ptr(`"abc"`), ModTime: modtime.Add(500 * time.Millisecond)}}
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", true},
+		{`"abc"`, true},
+		{`"abd"`, false},
+		{`W/"abc"`, false},
+		{modtime.Format(http.TimeFormat), true},
+		{modtime.Add(time.Second).Format(http.TimeFormat), false},
+		{"invalid", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.header != "" {
+			r.Header.Set(schema.ContentIfRangeHeader, test.header)
+		}
+		if got := checkIfRange(r, obj); got != test.want {
+			t.Errorf("checkIfRange(%q): got %v, want %v", test.header, got, test.want)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// seekable
+
+func TestSeekable_001(t *testing.T) {
+	ascending := []byteRange{{0, 2}, {5, 2}}
+	descending := []byteRange{{5, 2}, {0, 2}}
+	overlapping := []byteRange{{0, 5}, {3, 2}}
+
+	reader := io.MultiReader(strings.NewReader("0123456789"))
+	if !seekable(reader, ascending) {
+		t.Error("ascending ranges should be seekable")
+	}
+	if seekable(reader, descending) || seekable(reader, overlapping) {
+		t.Error("descending or overlapping ranges should not be seekable without io.Seeker")
+	}
+	if !seekable(strings.NewReader("0123456789"), descending) {
+		t.Error("any ranges should be seekable with io.Seeker")
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// writeRanges
+
+func TestWriteRanges_001(t *testing.T) {
+	content := "0123456789"
+	obj := &schema.Object{ObjectAttr: schema.ObjectAttr{Size: int64(len(content))}}
+
+	t.Run("single", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		if err := writeRanges(w, strings.NewReader(content), obj, "text/plain", []byteRange{{2, 3}}); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusPartialContent {
+			t.Errorf("status: got %d", w.Code)
+		}
+		if got := w.Header().Get(schema.ContentRangeResponseHeader); got != "bytes 2-4/10" {
+			t.Errorf("Content-Range: got %q", got)
+		}
+		if got := w.Body.String(); got != "234" {
+			t.Errorf("body: got %q", got)
+		}
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		reader := io.MultiReader(strings.NewReader(content))
+		if err := writeRanges(w, reader, obj, "text/plain", []byteRange{{0, 2}, {7, 3}}); err != nil {
+			t.Fatal(err)
+		}
+		mediaType, params, err := mime.ParseMediaType(w.Header().Get(types.ContentTypeHeader))
+		if err != nil || mediaType != schema.ContentTypeByteRanges {
+			t.Fatalf("Content-Type: got %q", w.Header().Get(types.ContentTypeHeader))
+		}
+		mr := multipart.NewReader(bytes.NewReader(w.Body.Bytes()), params["boundary"])
+		want := []struct{ contentRange, body string }{
+			{"bytes 0-1/10", "01"},
+			{"bytes 7-9/10", "789"},
+		}
+		for _, want := range want {
+			part, err := mr.NextPart()
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(part)
+			if got := part.Header.Get(schema.ContentRangeResponseHeader); got != want.contentRange {
+				t.Errorf("part Content-Range: got %q, want %q", got, want.contentRange)
+			}
+			if string(body) != want.body {
+				t.Errorf("part body: got %q, want %q", body, want.body)
+			}
+		}
+		if _, err := mr.NextPart(); !errors.Is(err, io.EOF) {
+			t.Errorf("expected end of parts, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package httphandler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	jsonschema "github.com/mutablelogic/go-server/pkg/jsonschema"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func RegisterRenditionHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
+	return errors.Join(
+		router.RegisterPath("artwork/{etag}/rendition", jsonschema.MustFor[schema.ArtworkKey](), httprequest.NewPathItem("Artwork", "Get an artwork rendition").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = GetArtworkRendition(w, r, manager, r.PathValue("etag"))
+				},
+				"Get a resized rendition of artwork",
+				openapi.WithTags("Artwork"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithDescription(`Returns the artwork resized to the requested width and height, with the fit mode and format. Renditions are cached, and are returned with an etag and long-lived cache headers.`),
+				openapi.WithQuery(jsonschema.MustFor[schema.RenditionRequest]()),
+				openapi.WithResponse(http.StatusOK, types.ContentTypeBinary, jsonschema.MustFor[[]byte](), "Image rendition"),
+			),
+		),
+		router.RegisterPath("rendition/{volume}/{path...}", nil, httprequest.NewPathItem("Artwork", "Get an image object rendition").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = GetObjectRendition(w, r, manager, r.PathValue("volume"), r.PathValue("path"))
+				},
+				"Get a resized rendition of an image object",
+				openapi.WithTags("Artwork"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithDescription(`Returns the image object resized to the requested width and height, with the fit mode and format. Renditions are cached by the object etag, and are returned with an etag and long-lived cache headers.`),
+				openapi.WithQuery(jsonschema.MustFor[schema.RenditionRequest]()),
+				openapi.WithResponse(http.StatusOK, types.ContentTypeBinary, jsonschema.MustFor[[]byte](), "Image rendition"),
+			),
+		),
+	)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func GetArtworkRendition(w http.ResponseWriter, r *http.Request, manager *manager.Manager, etag string) error {
+	var req schema.RenditionRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
+	// Get the rendition and return it
+	rendition, err := manager.GetArtworkRendition(r.Context(), schema.ArtworkKey(etag), req)
+	if err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), etag)
+	}
+	return writeRendition(w, r, rendition)
+}
+
+func GetObjectRendition(w http.ResponseWriter, r *http.Request, manager *manager.Manager, volume, path string) error {
+	var req schema.RenditionRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
+	// Get the rendition and return it
+	rendition, err := manager.GetObjectRendition(r.Context(), schema.ObjectKey{Volume: volume, Path: path}, req)
+	if err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), path)
+	}
+	return writeRendition(w, r, rendition)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// writeRendition writes a rendition with cache headers. The etag is derived
+// from the source etag and the rendition parameters, so the rendition is
+// immutable and can be cached for a long time.
+func writeRendition(w http.ResponseWriter, r *http.Request, rendition *schema.Artwork) error {
+	etag := `"` + string(rendition.ETag) + `"`
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", rendition.CreatedAt.Format(http.TimeFormat))
+
+	// Return not modified when the client has the rendition
+	if header := r.Header.Get(schema.ContentIfNoneMatchHeader); header != "" && schema.MatchETags(header, etag, false) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	// Write the rendition
+	w.Header().Set(types.ContentLengthHeader, fmt.Sprintf("%d", len(rendition.Data)))
+	return httpresponse.Write(w, http.StatusOK, rendition.Type, func(w io.Writer) (int, error) {
+		return w.Write(rendition.Data)
+	})
+}
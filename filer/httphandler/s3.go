@@ -0,0 +1,802 @@
+package httphandler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	// Packages
+	authschema "github.com/mutablelogic/go-auth/auth/schema"
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// s3Gateway serves the S3-compatible API, where each bucket is a volume
+type s3Gateway struct {
+	manager *manager.Manager
+	uploads *s3Uploads
+}
+
+// s3Err is an error response of the S3-compatible API
+type s3Err struct {
+	status  int
+	code    string
+	message string
+}
+
+// s3Entry is an object or common prefix in an object listing
+type s3Entry struct {
+	key    string
+	object *schema.Object
+}
+
+// s3ListPosition is the position in an object listing. The common prefixes
+// of a slash-delimited listing are listed as directories before the objects.
+type s3ListPosition struct {
+	Dirs  bool   `json:"d,omitempty"` // true when listing the common prefixes
+	After string `json:"a,omitempty"` // last path listed from the backend
+	Start string `json:"s,omitempty"` // path to list the objects after, once the common prefixes are listed
+	Last  string `json:"l,omitempty"` // last key returned, which is not returned again
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// s3MaxKeys is the maximum number of keys returned in an object listing
+	s3MaxKeys = 1000
+
+	// s3StorageClass is the storage class reported for all objects
+	s3StorageClass = "STANDARD"
+
+	// s3CopySourceHeader is set on CopyObject and UploadPartCopy requests
+	s3CopySourceHeader = "X-Amz-Copy-Source"
+
+	// s3EmptyETag is the etag of an empty object
+	s3EmptyETag = `"d41d8cd98f00b204e9800998ecf8427e"`
+
+	// s3MaxKeyLength is the maximum length of a key, in bytes
+	s3MaxKeyLength = 1024
+)
+
+var (
+	// s3ListParams are the query parameters accepted by ListObjects and ListObjectsV2
+	s3ListParams = []string{"list-type", "prefix", "delimiter", "max-keys", "marker", "continuation-token", "start-after", "encoding-type", "fetch-owner"}
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// RegisterS3Handlers registers an S3-compatible API at an absolute path, such
+// as /s3, which clients use as the endpoint with path-style addressing.
+// Requests are authenticated with AWS Signature Version 4, where the access
+// key is the key of a credential created as an S3 access key, which is bound
+// to an API token and grants the same scopes and volumes.
+func RegisterS3Handlers(manager *manager.Manager, router *httprouter.Router, path string) error {
+	router.Spec().AddTag("S3", "S3-compatible API")
+
+	// Create the gateway
+	gateway := &s3Gateway{
+		manager: manager,
+		uploads: newS3Uploads(),
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_ = gateway.ServeHTTP(w, r)
+	}
+
+	return errors.Join(
+		router.RegisterPath(types.NormalisePath(path)+"/{path...}", nil, httprequest.NewPathItem("S3", "S3-compatible API, where buckets are volumes").
+			Get(handler, "ListBuckets, ListObjects, ListObjectsV2, GetBucketLocation or GetObject",
+				openapi.WithTags("S3"),
+				openapi.WithDescription(`Lists volumes as buckets, lists objects in a bucket, or returns the object content. A single byte range is supported with the Range header.`),
+			).
+			Head(handler, "HeadBucket or HeadObject",
+				openapi.WithTags("S3"),
+			).
+			Put(handler, "PutObject or UploadPart",
+				openapi.WithTags("S3"),
+				openapi.WithDescription(`Writes an object or a part of a multipart upload. Writes use the same code paths as the object API, so objects are indexed when the volume is indexed.`),
+			).
+			Post(handler, "DeleteObjects, CreateMultipartUpload or CompleteMultipartUpload",
+				openapi.WithTags("S3"),
+			).
+			Delete(handler, "DeleteObject or AbortMultipartUpload",
+				openapi.WithTags("S3"),
+			),
+		),
+	)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ServeHTTP authenticates an S3 request and dispatches it to an operation
+func (s3 *s3Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
+	var user *authschema.UserInfo
+	var principal *authschema.Key
+	sig, err := s3Authenticate(r, func(ctx context.Context, key string) (string, error) {
+		secret, u, p, err := s3.manager.SecretAccessKey(ctx, schema.CredentialKey{Key: key})
+		user, principal = u, p
+		return secret, err
+	})
+	if err != nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchBucket)
+	}
+
+	// Requests are made as the token the access key is bound to, so that the
+	// manager restricts the volumes and audits the mutations
+	r = r.WithContext(manager.WithPrincipal(r.Context(), user, principal))
+
+	// Determine the bucket, key and operation
+	bucket, key, _ := strings.Cut(r.PathValue("path"), "/")
+	query := r.URL.Query()
+
+	// Check the token grants the operation on the bucket. Reads are GET and
+	// HEAD requests, and any other request writes.
+	scope := schema.ScopeWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		scope = schema.ScopeRead
+	}
+	if !user.HasAllScopes(scope) {
+		return writeS3Error(w, r, newS3Err(http.StatusForbidden, schema.S3ErrAccessDenied, "access key does not grant %q", scope), "")
+	} else if volumes := schema.GrantedVolumes(user.Scopes); bucket != "" && volumes != nil && !slices.Contains(volumes, bucket) {
+		return writeS3Error(w, r, newS3Err(http.StatusForbidden, schema.S3ErrAccessDenied, "access key does not grant access to bucket %q", bucket), "")
+	}
+
+	switch {
+	case bucket == "":
+		if r.Method == http.MethodGet {
+			return s3.listBuckets(w, r, sig)
+		}
+	case key == "":
+		switch r.Method {
+		case http.MethodHead:
+			return s3.headBucket(w, r, bucket)
+		case http.MethodGet:
+			if query.Has("location") {
+				return s3.getBucketLocation(w, r, bucket)
+			} else if s3OnlyParams(query, s3ListParams...) {
+				return s3.listObjects(w, r, bucket)
+			}
+		case http.MethodPut:
+			if s3OnlyParams(query) {
+				return s3.createBucket(w, r, bucket)
+			}
+		case http.MethodPost:
+			if query.Has("delete") {
+				return s3.deleteObjects(w, r, sig, bucket)
+			}
+		}
+	default:
+		switch r.Method {
+		case http.MethodHead:
+			return s3.getObject(w, r, bucket, key, true)
+		case http.MethodGet:
+			if s3OnlyParams(query, "partNumber", "versionId") {
+				return s3.getObject(w, r, bucket, key, false)
+			}
+		case http.MethodPut:
+			if r.Header.Get(s3CopySourceHeader) != "" {
+				break
+			} else if query.Has("uploadId") {
+				return s3.uploadPart(w, r, sig, bucket, key)
+			} else if s3OnlyParams(query) {
+				return s3.putObject(w, r, sig, bucket, key)
+			}
+		case http.MethodPost:
+			if query.Has("uploads") {
+				return s3.createMultipartUpload(w, r, bucket, key)
+			} else if query.Has("uploadId") {
+				return s3.completeMultipartUpload(w, r, sig, bucket, key)
+			}
+		case http.MethodDelete:
+			if query.Has("uploadId") {
+				return s3.abortMultipartUpload(w, r, bucket, key)
+			} else if s3OnlyParams(query) {
+				return s3.deleteObject(w, r, bucket, key)
+			}
+		}
+	}
+
+	// Return not implemented for any other operation
+	return writeS3Error(w, r, newS3Err(http.StatusNotImplemented, schema.S3ErrNotImplemented, "%s %s is not implemented", r.Method, r.URL.RequestURI()), "")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// BUCKET OPERATIONS
+
+// listBuckets lists the enabled volumes, which are restricted by the manager
+// to the volumes the access key can access
+func (s3 *s3Gateway) listBuckets(w http.ResponseWriter, r *http.Request, sig *s3Signature) error {
+	result := schema.S3ListBucketsResult{
+		Owner: schema.S3Owner{
+			ID:          sig.accessKey,
+			DisplayName: sig.accessKey,
+		},
+	}
+
+	// Enumerate the enabled volumes
+	var offset uint64
+	for {
+		volumes, err := s3.manager.ListVolumes(r.Context(), schema.VolumeListRequest{
+			Enabled: types.Ptr(true),
+			OffsetLimit: pg.OffsetLimit{
+				Offset: offset,
+			},
+		})
+		if err != nil {
+			return writeS3Error(w, r, err, "")
+		} else if len(volumes.Body) == 0 {
+			break
+		}
+		for _, volume := range volumes.Body {
+			result.Buckets = append(result.Buckets, schema.S3Bucket{
+				Name:         volume.Name,
+				CreationDate: volume.CreatedAt.UTC(),
+			})
+		}
+		offset += uint64(len(volumes.Body))
+	}
+
+	// Return the buckets
+	return writeS3XML(w, http.StatusOK, result)
+}
+
+func (s3 *s3Gateway) headBucket(w http.ResponseWriter, r *http.Request, bucket string) error {
+	if _, err := s3.mountedVolume(r.Context(), bucket); err != nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchBucket)
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s3 *s3Gateway) getBucketLocation(w http.ResponseWriter, r *http.Request, bucket string) error {
+	if _, err := s3.mountedVolume(r.Context(), bucket); err != nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchBucket)
+	}
+	return writeS3XML(w, http.StatusOK, schema.S3LocationConstraint{})
+}
+
+// createBucket succeeds when the volume already exists, since volumes are
+// created through the volume API
+func (s3 *s3Gateway) createBucket(w http.ResponseWriter, r *http.Request, bucket string) error {
+	if _, err := s3.manager.GetVolume(r.Context(), bucket); errors.Is(err, pg.ErrNotFound) || errors.Is(err, gofiler.ErrNotFound) {
+		return writeS3Error(w, r, newS3Err(http.StatusNotImplemented, schema.S3ErrNotImplemented, "buckets are created as volumes: %q", bucket), "")
+	} else if err != nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchBucket)
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// listObjects implements ListObjects and ListObjectsV2, which return the
+// objects and common prefixes in key order
+func (s3 *s3Gateway) listObjects(w http.ResponseWriter, r *http.Request, bucket string) error {
+	query := r.URL.Query()
+	v2 := query.Get("list-type") == "2"
+	result := schema.S3ListObjectsResult{
+		Name:         bucket,
+		Prefix:       query.Get("prefix"),
+		Delimiter:    query.Get("delimiter"),
+		MaxKeys:      s3MaxKeys,
+		EncodingType: query.Get("encoding-type"),
+	}
+	if result.EncodingType != "" && result.EncodingType != "url" {
+		return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidArgument, "invalid encoding type: %q", result.EncodingType), "")
+	}
+	if value := query.Get("max-keys"); value != "" {
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidArgument, "invalid max-keys: %q", value), "")
+		} else {
+			result.MaxKeys = min(n, s3MaxKeys)
+		}
+	}
+
+	// Determine the position to list from
+	var pos s3ListPosition
+	if v2 {
+		result.ContinuationToken = query.Get("continuation-token")
+		result.StartAfter = query.Get("start-after")
+		if token := result.ContinuationToken; token != "" {
+			if p, err := decodeS3ListPosition(token); err != nil {
+				return writeS3Error(w, r, err, "")
+			} else {
+				pos = p
+			}
+		} else {
+			pos = s3StartAfterPosition(result.StartAfter, result.Delimiter)
+		}
+	} else {
+		result.Marker = types.Ptr(query.Get("marker"))
+		pos = s3MarkerPosition(types.Value(result.Marker), result.Delimiter)
+	}
+	if len(pos.After) > s3MaxKeyLength || len(pos.Start) > s3MaxKeyLength || len(pos.Last) > s3MaxKeyLength {
+		return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidArgument, "invalid marker"), "")
+	}
+
+	// List a page of entries from the position
+	entries, next, err := s3.list(r.Context(), bucket, result.Prefix, result.Delimiter, pos, result.MaxKeys)
+	if err != nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchBucket)
+	}
+	result.IsTruncated = next != nil
+
+	// Make the response
+	encode := func(s string) string {
+		if result.EncodingType == "url" {
+			return s3Escape(s, false)
+		}
+		return s
+	}
+	for _, entry := range entries {
+		if entry.object == nil {
+			result.CommonPrefixes = append(result.CommonPrefixes, schema.S3CommonPrefix{
+				Prefix: encode(entry.key),
+			})
+		} else {
+			result.Contents = append(result.Contents, schema.S3Object{
+				Key:          encode(entry.key),
+				LastModified: entry.object.ModTime.UTC(),
+				ETag:         s3ETag(entry.object),
+				Size:         entry.object.Size,
+				StorageClass: s3StorageClass,
+			})
+		}
+	}
+	if next != nil {
+		if v2 {
+			result.NextContinuationToken = next.encode()
+		} else if result.Delimiter != "" && len(entries) > 0 {
+			result.NextMarker = encode(entries[len(entries)-1].key)
+		}
+	}
+	if v2 {
+		result.KeyCount = types.Ptr(len(entries))
+		result.StartAfter = encode(result.StartAfter)
+	} else {
+		result.Marker = types.Ptr(encode(types.Value(result.Marker)))
+	}
+	result.Prefix = encode(result.Prefix)
+	result.Delimiter = encode(result.Delimiter)
+
+	// Return the listing
+	return writeS3XML(w, http.StatusOK, result)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// OBJECT OPERATIONS
+
+func (s3 *s3Gateway) getObject(w http.ResponseWriter, r *http.Request, bucket, key string, head bool) error {
+	req := schema.ObjectKey{
+		Volume: bucket,
+		Path:   key,
+	}
+
+	// Get the object metadata, and the content unless this is a HEAD request
+	var reader io.ReadCloser
+	var obj *schema.Object
+	var err error
+	if head {
+		obj, err = s3.manager.GetObject(r.Context(), req)
+	} else {
+		reader, obj, err = s3.manager.ReadObject(r.Context(), req)
+	}
+	if err != nil && obj == nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchKey)
+	}
+	if reader != nil {
+		defer reader.Close()
+	}
+
+	// Set the object headers
+	setS3ObjectHeaders(w, obj)
+
+	// Return 304 or 412 without content if the preconditions are not met
+	if status := checkPreconditions(r, obj); status == http.StatusPreconditionFailed {
+		w.Header().Del(types.ContentLengthHeader)
+		return writeS3Error(w, r, newS3Err(status, schema.S3ErrPreconditionFailed, "precondition failed: %q", key), "")
+	} else if status != http.StatusOK {
+		w.Header().Del(types.ContentLengthHeader)
+		w.WriteHeader(status)
+		return nil
+	}
+
+	// Serve a single byte range
+	if header := r.Header.Get(schema.RangeHeader); header != "" {
+		ranges, err := parseRange(header, obj.Size)
+		switch {
+		case errors.Is(err, errRangeNotSatisfiable):
+			w.Header().Del(types.ContentLengthHeader)
+			w.Header().Set(schema.ContentRangeResponseHeader, fmt.Sprintf("bytes */%d", obj.Size))
+			return writeS3Error(w, r, newS3Err(http.StatusRequestedRangeNotSatisfiable, schema.S3ErrInvalidRange, "range %q not satisfiable", header), "")
+		case err == nil && len(ranges) == 1 && head:
+			w.Header().Set(schema.ContentRangeResponseHeader, ranges[0].contentRange(obj.Size))
+			w.Header().Set(types.ContentLengthHeader, strconv.FormatInt(ranges[0].length, 10))
+			w.WriteHeader(http.StatusPartialContent)
+			return nil
+		case err == nil && len(ranges) == 1 && seekable(reader, ranges):
+			return writeRanges(w, reader, obj, obj.ContentType, ranges)
+		}
+	}
+
+	// Serve the whole object
+	w.WriteHeader(http.StatusOK)
+	if head {
+		return nil
+	}
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+func (s3 *s3Gateway) putObject(w http.ResponseWriter, r *http.Request, sig *s3Signature, bucket, key string) error {
+	body, err := sig.body(r)
+	if err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+
+	// Directory markers are accepted but not stored, since directories are implicit
+	if strings.HasSuffix(key, "/") {
+		if n, err := io.Copy(io.Discard, body); err != nil {
+			return writeS3Error(w, r, err, "")
+		} else if n > 0 {
+			return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidArgument, "a key ending with a slash cannot have content: %q", key), "")
+		}
+		w.Header().Set(types.ContentHashHeader, s3EmptyETag)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	// Create the object
+	req := schema.CreateObjectRequest{
+		ObjectKey: schema.ObjectKey{
+			Volume: bucket,
+			Path:   key,
+		},
+		Body:    body,
+		IfMatch: r.Header.Get(schema.ContentIfMatchHeader),
+		ObjectMeta: schema.ObjectMeta{
+			ContentType: r.Header.Get(types.ContentTypeHeader),
+		},
+	}
+	if inm := strings.TrimSpace(r.Header.Get(schema.ContentIfNoneMatchHeader)); inm == "*" {
+		req.IfNotExists = true
+	} else if inm != "" {
+		return writeS3Error(w, r, newS3Err(http.StatusNotImplemented, schema.S3ErrNotImplemented, "only If-None-Match: * is supported"), "")
+	}
+	obj, err := s3.manager.CreateObject(r.Context(), req)
+	if err != nil && obj == nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchBucket)
+	}
+
+	// Return the etag
+	w.Header().Set(types.ContentHashHeader, s3ETag(obj))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// deleteObject deletes a single object, and succeeds when the object does
+// not exist
+func (s3 *s3Gateway) deleteObject(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	if err := s3.delete(r.Context(), bucket, key, r.Header.Get(schema.ContentIfMatchHeader)); err != nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchBucket)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// deleteObjects deletes up to 1000 objects
+func (s3 *s3Gateway) deleteObjects(w http.ResponseWriter, r *http.Request, sig *s3Signature, bucket string) error {
+	body, err := sig.body(r)
+	if err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+
+	// Read the request
+	var req schema.S3Delete
+	if err := xml.NewDecoder(body).Decode(&req); err != nil {
+		return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrMalformedXML, "%v", err), "")
+	} else if len(req.Objects) == 0 || len(req.Objects) > s3MaxKeys {
+		return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrMalformedXML, "between 1 and %d objects can be deleted", s3MaxKeys), "")
+	}
+
+	// Check the bucket
+	if _, err := s3.mountedVolume(r.Context(), bucket); err != nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchBucket)
+	}
+
+	// Delete each object
+	var result schema.S3DeleteResult
+	for _, object := range req.Objects {
+		if err := s3.delete(r.Context(), bucket, object.Key, ""); err != nil {
+			e := s3Error(err, schema.S3ErrNoSuchKey)
+			result.Errors = append(result.Errors, schema.S3DeleteError{
+				Key:     object.Key,
+				Code:    e.code,
+				Message: e.message,
+			})
+		} else if !req.Quiet {
+			result.Deleted = append(result.Deleted, object)
+		}
+	}
+
+	// Return the result
+	return writeS3XML(w, http.StatusOK, result)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// mountedVolume returns the volume for a bucket, or an error if the volume
+// does not exist or is not enabled
+func (s3 *s3Gateway) mountedVolume(ctx context.Context, bucket string) (*schema.Volume, error) {
+	volume, err := s3.manager.GetVolume(ctx, bucket)
+	if err != nil {
+		return nil, err
+	} else if !types.Value(volume.Enabled) {
+		return nil, gofiler.ErrServiceUnavailable.Withf("volume %q is not mounted", bucket)
+	}
+	return volume, nil
+}
+
+// delete deletes a single object through the manager, ignoring objects which
+// do not exist and prefixes
+func (s3 *s3Gateway) delete(ctx context.Context, bucket, key, ifmatch string) error {
+	_, err := s3.manager.DeleteObjects(ctx, schema.DeleteObjectsRequest{
+		ObjectKey: schema.ObjectKey{
+			Volume: bucket,
+			Path:   key,
+		},
+		IfMatch: ifmatch,
+	})
+	if ifmatch == "" && (errors.Is(err, gofiler.ErrNotFound) || errors.Is(err, gofiler.ErrBadParameter)) {
+		return nil
+	}
+	return err
+}
+
+// list returns a page of up to maxKeys objects and common prefixes under a
+// prefix, from a position in the listing, and the position of the next page
+// or nil when there are no more entries. With a slash delimiter, only the
+// directory containing the prefix is listed, and its subdirectories are
+// returned as common prefixes before the objects. Otherwise the directory is
+// listed recursively, and keys are grouped by the delimiter within a page.
+// Entries are returned in the order of the backend, which is key order for
+// S3 backends.
+func (s3 *s3Gateway) list(ctx context.Context, bucket, prefix, delimiter string, pos s3ListPosition, maxKeys int) ([]s3Entry, *s3ListPosition, error) {
+	if _, err := s3.mountedVolume(ctx, bucket); err != nil {
+		return nil, nil, err
+	}
+
+	// List from the directory which contains the prefix
+	var dir *string
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		dir = types.Ptr(prefix[:i])
+	}
+
+	// Collect entries which match the prefix, grouping keys by the delimiter,
+	// until the page is full
+	var entries []s3Entry
+	prefixes := make(map[string]struct{})
+	for {
+		iterator := &schema.ObjectListIterator{Path: dir, Recursive: delimiter != "/"}
+		if pos.Dirs {
+			iterator.Type = types.Ptr(schema.ContentTypeDirectory)
+		}
+		if pos.After != "" {
+			iterator.Token = &schema.ListPosition{After: pos.After}
+		}
+		for {
+			err := s3.manager.IterateObjects(ctx, bucket, iterator)
+			if errors.Is(err, gofiler.ErrNotFound) || errors.Is(err, gofiler.ErrBadParameter) {
+				// The directory does not exist, or is an object
+				break
+			} else if err != nil && !errors.Is(err, io.EOF) {
+				return nil, nil, err
+			}
+			for _, object := range iterator.Body {
+				entry, ok := s3ListEntry(object, prefix, delimiter)
+				if !ok || entry.key == pos.Last {
+					pos.After = object.Path
+					continue
+				} else if _, exists := prefixes[entry.key]; exists && entry.object == nil {
+					pos.After = object.Path
+					continue
+				} else if len(entries) >= maxKeys {
+					return entries, types.Ptr(pos), nil
+				}
+				if entry.object == nil {
+					prefixes[entry.key] = struct{}{}
+				}
+				entries = append(entries, entry)
+				pos.After, pos.Last = object.Path, entry.key
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+		}
+
+		// List the objects after the common prefixes
+		if !pos.Dirs {
+			return entries, nil, nil
+		}
+		pos = s3ListPosition{After: pos.Start, Last: pos.Last}
+	}
+}
+
+// s3ListEntry returns the listing entry for an object or directory, which is
+// a common prefix when the key contains the delimiter after the prefix, and
+// false when the key does not match the prefix
+func s3ListEntry(object *schema.Object, prefix, delimiter string) (s3Entry, bool) {
+	key := object.Path
+	if object.ContentType == schema.ContentTypeDirectory {
+		key += "/"
+	}
+	if !strings.HasPrefix(key, prefix) {
+		return s3Entry{}, false
+	} else if object.ContentType == schema.ContentTypeDirectory {
+		return s3Entry{key: key}, true
+	} else if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+		return s3Entry{key: key[:len(prefix)+i+len(delimiter)]}, true
+	}
+	return s3Entry{key: key, object: object}, true
+}
+
+// s3MarkerPosition returns the position after a ListObjects marker, which
+// is the last key of the previous page. A common prefix of a slash-delimited
+// listing resumes the common prefixes, and any other key resumes the objects.
+func s3MarkerPosition(marker, delimiter string) s3ListPosition {
+	if marker == "" {
+		return s3ListPosition{Dirs: delimiter == "/"}
+	} else if delimiter == "/" && strings.HasSuffix(marker, "/") {
+		return s3ListPosition{Dirs: true, After: strings.TrimSuffix(marker, "/"), Last: marker}
+	}
+	return s3ListPosition{After: marker, Last: marker}
+}
+
+// s3StartAfterPosition returns the position after a ListObjectsV2 start key,
+// where both the common prefixes and the objects are listed after the key
+func s3StartAfterPosition(key, delimiter string) s3ListPosition {
+	after := strings.TrimSuffix(key, "/")
+	return s3ListPosition{Dirs: delimiter == "/", After: after, Start: after, Last: key}
+}
+
+// decodeS3ListPosition returns the position from a continuation token
+func decodeS3ListPosition(token string) (s3ListPosition, error) {
+	var pos s3ListPosition
+	if data, err := base64.RawURLEncoding.DecodeString(token); err != nil {
+		return pos, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidArgument, "invalid continuation token")
+	} else if err := json.Unmarshal(data, &pos); err != nil {
+		return pos, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidArgument, "invalid continuation token")
+	}
+	return pos, nil
+}
+
+// encode returns the continuation token for a position. The token only
+// contains keys, which a client could equally list after with start-after.
+func (pos s3ListPosition) encode() string {
+	data, _ := json.Marshal(pos)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// s3OnlyParams returns true if the query has no parameters other than those
+// listed and the presigned URL parameters
+func s3OnlyParams(query map[string][]string, params ...string) bool {
+	for key := range query {
+		if !strings.HasPrefix(key, "X-Amz-") && !slices.Contains(params, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// s3ETag returns the quoted etag of an object, or an etag made from the
+// modification time and size when the backend does not provide one. The
+// made etag contains a hyphen, so clients do not treat it as an MD5 digest.
+func s3ETag(obj *schema.Object) string {
+	if etag := strings.Trim(types.Value(obj.ETag), `"`); etag != "" {
+		return `"` + etag + `"`
+	}
+	return fmt.Sprintf(`"%x-%x"`, obj.ModTime.UnixNano(), obj.Size)
+}
+
+func setS3ObjectHeaders(w http.ResponseWriter, obj *schema.Object) {
+	if obj.ETag == nil {
+		obj.ETag = types.Ptr(strings.Trim(s3ETag(obj), `"`))
+	}
+	w.Header().Set(types.ContentTypeHeader, obj.ContentType)
+	w.Header().Set(types.ContentLengthHeader, strconv.FormatInt(obj.Size, 10))
+	w.Header().Set(types.ContentHashHeader, s3ETag(obj))
+	w.Header().Set(types.ContentModifiedHeader, obj.ModTime.UTC().Format(http.TimeFormat))
+	w.Header().Set(schema.ContentAcceptRangesHeader, "bytes")
+}
+
+func newS3Err(status int, code, format string, args ...any) s3Err {
+	return s3Err{status: status, code: code, message: fmt.Sprintf(format, args...)}
+}
+
+func (e s3Err) Error() string {
+	return e.code + ": " + e.message
+}
+
+// s3Error converts an error into an S3 error, using the code for errors
+// where the resource is not found
+func s3Error(err error, notFound string) s3Err {
+	var e s3Err
+	if errors.As(err, &e) {
+		return e
+	}
+
+	// Map the HTTP status to an S3 error code
+	status := http.StatusInternalServerError
+	var httpErr httpresponse.Err
+	if errors.As(gofiler.HTTPErr(err), &httpErr) {
+		status = int(httpErr)
+	}
+	switch status {
+	case http.StatusNotFound:
+		return s3Err{status, notFound, err.Error()}
+	case http.StatusBadRequest:
+		return s3Err{status, schema.S3ErrInvalidArgument, err.Error()}
+	case http.StatusForbidden:
+		return s3Err{status, schema.S3ErrAccessDenied, err.Error()}
+	case http.StatusPreconditionFailed:
+		return s3Err{status, schema.S3ErrPreconditionFailed, err.Error()}
+	case http.StatusNotImplemented:
+		return s3Err{status, schema.S3ErrNotImplemented, err.Error()}
+	case http.StatusServiceUnavailable:
+		return s3Err{status, schema.S3ErrServiceUnavailable, err.Error()}
+	default:
+		return s3Err{http.StatusInternalServerError, schema.S3ErrInternalError, err.Error()}
+	}
+}
+
+// writeS3Error writes an error response, without a body for HEAD requests
+func writeS3Error(w http.ResponseWriter, r *http.Request, err error, notFound string) error {
+	e := s3Error(err, notFound)
+	if e.code == "" {
+		e.code = schema.S3ErrNoSuchKey
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(e.status)
+		return nil
+	}
+	return writeS3XML(w, e.status, schema.S3Error{
+		Code:     e.code,
+		Message:  e.message,
+		Resource: r.URL.Path,
+	})
+}
+
+// writeS3XML writes an XML response
+func writeS3XML(w http.ResponseWriter, status int, v any) error {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return httpresponse.Error(w, httpresponse.ErrInternalError.With(err.Error()))
+	}
+	w.Header().Set(types.ContentTypeHeader, schema.ContentTypeXML)
+	w.Header().Set(types.ContentLengthHeader, strconv.Itoa(len(xml.Header)+len(data)))
+	w.WriteHeader(status)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
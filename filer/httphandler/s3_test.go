@@ -0,0 +1,63 @@
+package httphandler
+
+import (
+	"testing"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// s3ListPosition
+
+func TestS3ListPosition_001(t *testing.T) {
+	tests := []struct {
+		name string
+		pos  s3ListPosition
+		want s3ListPosition
+	}{
+		{"marker", s3MarkerPosition("", "/"), s3ListPosition{Dirs: true}},
+		{"marker prefix", s3MarkerPosition("a/b/", "/"), s3ListPosition{Dirs: true, After: "a/b", Last: "a/b/"}},
+		{"marker object", s3MarkerPosition("a/c.txt", "/"), s3ListPosition{After: "a/c.txt", Last: "a/c.txt"}},
+		{"marker recursive", s3MarkerPosition("a/b/", ""), s3ListPosition{After: "a/b/", Last: "a/b/"}},
+		{"start after", s3StartAfterPosition("a/c.txt", "/"), s3ListPosition{Dirs: true, After: "a/c.txt", Start: "a/c.txt", Last: "a/c.txt"}},
+		{"start after recursive", s3StartAfterPosition("a/c.txt", ""), s3ListPosition{After: "a/c.txt", Start: "a/c.txt", Last: "a/c.txt"}},
+	}
+	for _, test := range tests {
+		if test.pos != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.name, test.pos, test.want)
+		}
+		if pos, err := decodeS3ListPosition(test.pos.encode()); err != nil || pos != test.pos {
+			t.Errorf("%s: token: got %+v, %v", test.name, pos, err)
+		}
+	}
+	if _, err := decodeS3ListPosition("!"); s3ErrCode(err) != schema.S3ErrInvalidArgument {
+		t.Errorf("expected %s, got %v", schema.S3ErrInvalidArgument, err)
+	}
+}
+
+func TestS3ListEntry_001(t *testing.T) {
+	dir := &schema.Object{ObjectKey: schema.ObjectKey{Path: "a/b"}, ObjectMeta: schema.ObjectMeta{ContentType: schema.ContentTypeDirectory}}
+	file := &schema.Object{ObjectKey: schema.ObjectKey{Path: "a/b-c/d.txt"}}
+
+	tests := []struct {
+		object            *schema.Object
+		prefix, delimiter string
+		key               string
+		common            bool
+		ok                bool
+	}{
+		{dir, "a/", "/", "a/b/", true, true},
+		{dir, "a/c", "/", "", true, false},
+		{file, "a/", "", "a/b-c/d.txt", false, true},
+		{file, "a/", "/", "a/b-c/", true, true},
+		{file, "a/b", "-", "a/b-", true, true},
+		{file, "b/", "", "", true, false},
+	}
+	for _, test := range tests {
+		entry, ok := s3ListEntry(test.object, test.prefix, test.delimiter)
+		if ok != test.ok || entry.key != test.key || (entry.object == nil) != test.common {
+			t.Errorf("s3ListEntry(%q, %q, %q): got %q, %v, %v", test.object.Path, test.prefix, test.delimiter, entry.key, entry.object == nil, ok)
+		}
+	}
+}
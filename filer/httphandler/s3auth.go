@@ -0,0 +1,440 @@
+package httphandler
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// s3Signature is a request which has been authenticated with AWS Signature
+// Version 4, and which is used to verify the request payload
+type s3Signature struct {
+	accessKey string
+	amzDate   string // request time, as yyyymmddThhmmssZ
+	scope     string // credential scope, as yyyymmdd/region/s3/aws4_request
+	key       []byte // signing key derived from the secret access key
+	signature string // request signature, which seeds chunk signatures
+	payload   string // value of the x-amz-content-sha256 header
+}
+
+// s3SecretFunc returns the secret for an access key
+type s3SecretFunc func(context.Context, string) (string, error)
+
+// s3HashReader verifies the SHA256 hash of a payload at EOF
+type s3HashReader struct {
+	io.Reader
+	hash hash.Hash
+	sum  string
+}
+
+// s3ChunkReader decodes an aws-chunked payload, verifying the chunk and
+// trailer signatures when the payload is signed
+type s3ChunkReader struct {
+	r         *bufio.Reader
+	sig       *s3Signature
+	trailer   bool
+	prev      string
+	chunkSig  string
+	hash      hash.Hash
+	remaining int64
+	started   bool
+	done      bool
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	s3Algorithm                = "AWS4-HMAC-SHA256"
+	s3DateFormat               = "20060102T150405Z"
+	s3MaxSkew                  = 15 * time.Minute
+	s3MaxExpires               = 7 * 24 * time.Hour
+	s3UnsignedPayload          = "UNSIGNED-PAYLOAD"
+	s3StreamingPayload         = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	s3StreamingPayloadTrailer  = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	s3StreamingUnsignedTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+	s3ContentSHA256Header      = "X-Amz-Content-Sha256"
+	s3DateHeader               = "X-Amz-Date"
+	s3AuthorizationHeader      = "Authorization"
+)
+
+var (
+	s3EmptySHA256 = hex.EncodeToString(sha256.New().Sum(nil))
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// s3Authenticate verifies the signature of a request, which is either in
+// the Authorization header or in the query parameters of a presigned URL
+func s3Authenticate(r *http.Request, secretfn s3SecretFunc) (*s3Signature, error) {
+	var sig s3Signature
+	var credential, signedHeaders string
+
+	// Read the signature from the header or the query
+	query := r.URL.Query()
+	presigned := false
+	if auth := r.Header.Get(s3AuthorizationHeader); strings.HasPrefix(auth, s3Algorithm+" ") {
+		for field := range strings.SplitSeq(strings.TrimPrefix(auth, s3Algorithm+" "), ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+			switch name {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				sig.signature = value
+			}
+		}
+		sig.amzDate = r.Header.Get(s3DateHeader)
+		if sig.payload = r.Header.Get(s3ContentSHA256Header); sig.payload == "" {
+			return nil, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidRequest, "missing %s header", s3ContentSHA256Header)
+		}
+	} else if query.Get("X-Amz-Algorithm") == s3Algorithm {
+		presigned = true
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		sig.signature = query.Get("X-Amz-Signature")
+		sig.amzDate = query.Get("X-Amz-Date")
+		sig.payload = s3UnsignedPayload
+	} else if auth != "" {
+		return nil, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidRequest, "unsupported authorization type, use %s", s3Algorithm)
+	} else {
+		return nil, newS3Err(http.StatusForbidden, schema.S3ErrAccessDenied, "anonymous access is not allowed")
+	}
+
+	// Parse the credential, which is access-key/date/region/s3/aws4_request
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[0] == "" || parts[3] != "s3" || parts[4] != "aws4_request" {
+		return nil, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidRequest, "invalid credential: %q", credential)
+	} else {
+		sig.accessKey = parts[0]
+		sig.scope = strings.Join(parts[1:], "/")
+	}
+	headers := strings.Split(signedHeaders, ";")
+	if sig.signature == "" || !slices.Contains(headers, "host") {
+		return nil, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidRequest, "the host header must be signed")
+	}
+
+	// Check the request time
+	now := time.Now()
+	date, err := time.Parse(s3DateFormat, sig.amzDate)
+	if err != nil || !strings.HasPrefix(sig.amzDate, parts[1]) {
+		return nil, newS3Err(http.StatusForbidden, schema.S3ErrAccessDenied, "invalid request date: %q", sig.amzDate)
+	}
+	if presigned {
+		expires, err := strconv.ParseInt(query.Get("X-Amz-Expires"), 10, 64)
+		if err != nil || expires < 0 || time.Duration(expires)*time.Second > s3MaxExpires {
+			return nil, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidRequest, "invalid expiry: %q", query.Get("X-Amz-Expires"))
+		} else if now.After(date.Add(time.Duration(expires) * time.Second)) {
+			return nil, newS3Err(http.StatusForbidden, schema.S3ErrAccessDenied, "request has expired")
+		} else if date.After(now.Add(s3MaxSkew)) {
+			return nil, newS3Err(http.StatusForbidden, schema.S3ErrRequestTimeTooSkewed, "request date is in the future")
+		}
+	} else if now.Sub(date).Abs() > s3MaxSkew {
+		return nil, newS3Err(http.StatusForbidden, schema.S3ErrRequestTimeTooSkewed, "request time differs from server time by more than %v", s3MaxSkew)
+	}
+
+	// Get the secret for the access key, and derive the signing key
+	secret, err := secretfn(r.Context(), sig.accessKey)
+	if errors.Is(err, gofiler.ErrServiceUnavailable) {
+		return nil, err
+	} else if err != nil {
+		return nil, newS3Err(http.StatusForbidden, schema.S3ErrInvalidAccessKeyId, "invalid access key: %q", sig.accessKey)
+	}
+	sig.key = []byte("AWS4" + secret)
+	for _, part := range parts[1:] {
+		sig.key = s3HMAC(sig.key, part)
+	}
+
+	// Verify the signature
+	canonical := s3CanonicalRequest(r, headers, sig.payload, presigned)
+	if !sig.verify(sig.signature, s3Algorithm, sha256Hex([]byte(canonical))) {
+		return nil, newS3Err(http.StatusForbidden, schema.S3ErrSignatureDoesNotMatch, "the request signature does not match")
+	}
+
+	// Return success
+	return &sig, nil
+}
+
+// body returns the request body, which verifies the payload against the
+// signature as it is read
+func (sig *s3Signature) body(r *http.Request) (io.Reader, error) {
+	switch sig.payload {
+	case s3UnsignedPayload:
+		return r.Body, nil
+	case s3StreamingPayload, s3StreamingPayloadTrailer:
+		return &s3ChunkReader{
+			r:       bufio.NewReader(r.Body),
+			sig:     sig,
+			trailer: sig.payload == s3StreamingPayloadTrailer,
+			prev:    sig.signature,
+		}, nil
+	case s3StreamingUnsignedTrailer:
+		return &s3ChunkReader{
+			r:       bufio.NewReader(r.Body),
+			trailer: true,
+		}, nil
+	}
+	if sum, err := hex.DecodeString(sig.payload); err != nil || len(sum) != sha256.Size {
+		return nil, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidArgument, "invalid %s header: %q", s3ContentSHA256Header, sig.payload)
+	}
+	return &s3HashReader{
+		Reader: r.Body,
+		hash:   sha256.New(),
+		sum:    strings.ToLower(sig.payload),
+	}, nil
+}
+
+// verify returns true if the signature matches the string to sign, which is
+// made from the algorithm, request date, scope and the values
+func (sig *s3Signature) verify(signature, algorithm string, values ...string) bool {
+	stringToSign := strings.Join(append([]string{algorithm, sig.amzDate, sig.scope}, values...), "\n")
+	expected := hex.EncodeToString(s3HMAC(sig.key, stringToSign))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// s3CanonicalRequest returns the canonical form of a request for signing
+func s3CanonicalRequest(r *http.Request, signedHeaders []string, payload string, presigned bool) string {
+	// Canonical query, sorted by key and then value
+	type param struct{ key, value string }
+	var params []param
+	for key, values := range r.URL.Query() {
+		if presigned && key == "X-Amz-Signature" {
+			continue
+		}
+		for _, value := range values {
+			params = append(params, param{s3Escape(key, true), s3Escape(value, true)})
+		}
+	}
+	slices.SortFunc(params, func(a, b param) int {
+		if c := strings.Compare(a.key, b.key); c != 0 {
+			return c
+		}
+		return strings.Compare(a.value, b.value)
+	})
+	query := make([]string, 0, len(params))
+	for _, p := range params {
+		query = append(query, p.key+"="+p.value)
+	}
+
+	// Canonical headers, in the order they were signed
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		var values []string
+		switch name {
+		case "host":
+			values = []string{r.Host}
+		case "content-length":
+			if values = r.Header.Values(name); len(values) == 0 && r.ContentLength >= 0 {
+				values = []string{strconv.FormatInt(r.ContentLength, 10)}
+			}
+		default:
+			values = r.Header.Values(name)
+		}
+		for i, value := range values {
+			values[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		s3Escape(r.URL.Path, false),
+		strings.Join(query, "&"),
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payload,
+	}, "\n")
+}
+
+// s3Escape encodes a string as required for signing, which escapes all
+// characters except the unreserved characters, and optionally the slash
+func s3Escape(s string, slash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !slash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PAYLOAD READERS
+
+func (r *s3HashReader) Read(data []byte) (int, error) {
+	n, err := r.Reader.Read(data)
+	r.hash.Write(data[:n])
+	if errors.Is(err, io.EOF) && hex.EncodeToString(r.hash.Sum(nil)) != r.sum {
+		return n, newS3Err(http.StatusBadRequest, schema.S3ErrBadDigest, "the payload does not match %s", s3ContentSHA256Header)
+	}
+	return n, err
+}
+
+func (c *s3ChunkReader) Read(data []byte) (int, error) {
+	for c.remaining == 0 {
+		if c.done {
+			return 0, io.EOF
+		} else if err := c.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	// Read from the current chunk
+	if int64(len(data)) > c.remaining {
+		data = data[:c.remaining]
+	}
+	n, err := c.r.Read(data)
+	c.remaining -= int64(n)
+	if c.hash != nil {
+		c.hash.Write(data[:n])
+	}
+	if c.remaining > 0 && errors.Is(err, io.EOF) {
+		return n, io.ErrUnexpectedEOF
+	} else if err != nil && !errors.Is(err, io.EOF) {
+		return n, err
+	}
+
+	// Verify the chunk signature at the end of the chunk
+	if c.remaining == 0 && c.sig != nil {
+		if err := c.verify(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// next reads the header of the next chunk
+func (c *s3ChunkReader) next() error {
+	// Each chunk after the first is preceded by the CRLF ending the previous chunk
+	if c.started {
+		if line, err := c.line(); err != nil {
+			return err
+		} else if line != "" {
+			return newS3Err(http.StatusBadRequest, schema.S3ErrInvalidRequest, "malformed chunk")
+		}
+	}
+	c.started = true
+
+	// Parse the chunk size and signature
+	line, err := c.line()
+	if err != nil {
+		return err
+	}
+	size, params, _ := strings.Cut(line, ";")
+	n, err := strconv.ParseInt(strings.TrimSpace(size), 16, 64)
+	if err != nil || n < 0 {
+		return newS3Err(http.StatusBadRequest, schema.S3ErrInvalidRequest, "malformed chunk size: %q", size)
+	}
+	if c.sig != nil {
+		signature, ok := strings.CutPrefix(strings.TrimSpace(params), "chunk-signature=")
+		if !ok {
+			return newS3Err(http.StatusForbidden, schema.S3ErrSignatureDoesNotMatch, "missing chunk signature")
+		}
+		c.chunkSig = signature
+		c.hash = sha256.New()
+	}
+	c.remaining = n
+
+	// The last chunk is empty, and is followed by the trailer
+	if n == 0 {
+		c.done = true
+		if c.sig != nil {
+			if err := c.verify(); err != nil {
+				return err
+			}
+		}
+		return c.readTrailer()
+	}
+
+	// Return success
+	return nil
+}
+
+// verify checks the signature of the current chunk
+func (c *s3ChunkReader) verify() error {
+	if !c.sig.verify(c.chunkSig, s3Algorithm+"-PAYLOAD", c.prev, s3EmptySHA256, hex.EncodeToString(c.hash.Sum(nil))) {
+		return newS3Err(http.StatusForbidden, schema.S3ErrSignatureDoesNotMatch, "the chunk signature does not match")
+	}
+	c.prev = c.chunkSig
+	return nil
+}
+
+// readTrailer reads the trailing headers after the last chunk, and verifies
+// the trailer signature when the payload is signed
+func (c *s3ChunkReader) readTrailer() error {
+	var trailer strings.Builder
+	var signature string
+	for {
+		line, err := c.line()
+		if errors.Is(err, io.EOF) || (err == nil && line == "") {
+			break
+		} else if err != nil {
+			return err
+		}
+		if value, ok := strings.CutPrefix(line, "x-amz-trailer-signature:"); ok {
+			signature = strings.TrimSpace(value)
+		} else {
+			trailer.WriteString(line + "\n")
+		}
+	}
+
+	// Verify the trailer signature
+	if c.sig != nil && c.trailer {
+		if !c.sig.verify(signature, s3Algorithm+"-TRAILER", c.prev, sha256Hex([]byte(trailer.String()))) {
+			return newS3Err(http.StatusForbidden, schema.S3ErrSignatureDoesNotMatch, "the trailer signature does not match")
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+// line reads a line terminated by CRLF, without the terminator
+func (c *s3ChunkReader) line() (string, error) {
+	line, err := c.r.ReadSlice('\n')
+	if errors.Is(err, io.EOF) && len(line) > 0 {
+		err = io.ErrUnexpectedEOF
+	} else if errors.Is(err, bufio.ErrBufferFull) {
+		err = newS3Err(http.StatusBadRequest, schema.S3ErrInvalidRequest, "malformed chunk")
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
@@ -0,0 +1,328 @@
+package httphandler
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// s3Uploads holds the multipart uploads in progress, where parts are written
+// to a local temporary directory until the upload is completed. Uploads are
+// not shared between server instances.
+type s3Uploads struct {
+	sync.Mutex
+	dir     string
+	uploads map[string]*s3Upload
+}
+
+// s3Upload is a multipart upload in progress
+type s3Upload struct {
+	sync.Mutex
+	id      string
+	bucket  string
+	key     string
+	meta    schema.ObjectMeta
+	created time.Time
+	parts   map[int]s3UploadPart
+}
+
+// s3UploadPart is an uploaded part of a multipart upload
+type s3UploadPart struct {
+	etag string
+	size int64
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// s3MaxParts is the maximum part number of a multipart upload
+	s3MaxParts = 10000
+
+	// s3MinPartSize is the minimum size of all parts except the last
+	s3MinPartSize = 5 << 20
+
+	// s3UploadExpiry is the time after which incomplete uploads are removed
+	s3UploadExpiry = 24 * time.Hour
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func newS3Uploads() *s3Uploads {
+	return &s3Uploads{
+		uploads: make(map[string]*s3Upload),
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// MULTIPART OPERATIONS
+
+func (s3 *s3Gateway) createMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	if _, err := s3.mountedVolume(r.Context(), bucket); err != nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchBucket)
+	} else if strings.HasSuffix(key, "/") {
+		return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidArgument, "a key ending with a slash cannot have content: %q", key), "")
+	}
+
+	// Create the upload
+	upload, err := s3.uploads.create(bucket, key, schema.ObjectMeta{
+		ContentType: r.Header.Get(types.ContentTypeHeader),
+	})
+	if err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+
+	// Return the upload id
+	return writeS3XML(w, http.StatusOK, schema.S3InitiateMultipartUploadResult{
+		Bucket:   bucket,
+		Key:      key,
+		UploadId: upload.id,
+	})
+}
+
+func (s3 *s3Gateway) uploadPart(w http.ResponseWriter, r *http.Request, sig *s3Signature, bucket, key string) error {
+	query := r.URL.Query()
+	number, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil || number < 1 || number > s3MaxParts {
+		return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidArgument, "part number must be between 1 and %d", s3MaxParts), "")
+	}
+	upload, err := s3.uploads.get(query.Get("uploadId"), bucket, key)
+	if err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+	body, err := sig.body(r)
+	if err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+
+	// Write the part
+	part, err := s3.uploads.writePart(upload, number, body)
+	if err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+
+	// Return the etag
+	w.Header().Set(types.ContentHashHeader, part.etag)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s3 *s3Gateway) completeMultipartUpload(w http.ResponseWriter, r *http.Request, sig *s3Signature, bucket, key string) error {
+	upload, err := s3.uploads.get(r.URL.Query().Get("uploadId"), bucket, key)
+	if err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+	body, err := sig.body(r)
+	if err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+
+	// Read the request
+	var req schema.S3CompleteMultipartUpload
+	if err := xml.NewDecoder(body).Decode(&req); err != nil {
+		return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrMalformedXML, "%v", err), "")
+	} else if len(req.Parts) == 0 {
+		return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrMalformedXML, "at least one part is required"), "")
+	}
+
+	// Open the parts, in order
+	upload.Lock()
+	defer upload.Unlock()
+	readers := make([]io.Reader, 0, len(req.Parts))
+	defer func() {
+		for _, reader := range readers {
+			reader.(io.Closer).Close()
+		}
+	}()
+	for i, p := range req.Parts {
+		part, exists := upload.parts[p.PartNumber]
+		if i > 0 && p.PartNumber <= req.Parts[i-1].PartNumber {
+			return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidPartOrder, "parts must be in ascending order"), "")
+		} else if !exists || strings.Trim(p.ETag, `"`) != strings.Trim(part.etag, `"`) {
+			return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrInvalidPart, "part %d was not found or the etag does not match", p.PartNumber), "")
+		} else if i < len(req.Parts)-1 && part.size < s3MinPartSize {
+			return writeS3Error(w, r, newS3Err(http.StatusBadRequest, schema.S3ErrEntityTooSmall, "part %d is smaller than the minimum part size", p.PartNumber), "")
+		}
+		f, err := os.Open(s3.uploads.partPath(upload, p.PartNumber))
+		if err != nil {
+			return writeS3Error(w, r, err, "")
+		}
+		readers = append(readers, f)
+	}
+
+	// Create the object from the parts
+	obj, err := s3.manager.CreateObject(r.Context(), schema.CreateObjectRequest{
+		ObjectKey: schema.ObjectKey{
+			Volume: bucket,
+			Path:   key,
+		},
+		Body:       io.MultiReader(readers...),
+		ObjectMeta: upload.meta,
+	})
+	if err != nil && obj == nil {
+		return writeS3Error(w, r, err, schema.S3ErrNoSuchBucket)
+	}
+
+	// Remove the upload
+	if err := s3.uploads.remove(upload.id); err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+
+	// Return the result
+	return writeS3XML(w, http.StatusOK, schema.S3CompleteMultipartUploadResult{
+		Location: r.URL.Path,
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     s3ETag(obj),
+	})
+}
+
+func (s3 *s3Gateway) abortMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) error {
+	upload, err := s3.uploads.get(r.URL.Query().Get("uploadId"), bucket, key)
+	if err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+	upload.Lock()
+	defer upload.Unlock()
+	if err := s3.uploads.remove(upload.id); err != nil {
+		return writeS3Error(w, r, err, "")
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// create starts a new upload, removing any expired uploads
+func (u *s3Uploads) create(bucket, key string, meta schema.ObjectMeta) (*s3Upload, error) {
+	u.Lock()
+	defer u.Unlock()
+
+	// Create the temporary directory
+	if u.dir == "" {
+		dir, err := os.MkdirTemp("", "filer-s3-")
+		if err != nil {
+			return nil, err
+		}
+		u.dir = dir
+	}
+
+	// Remove expired uploads
+	for id, upload := range u.uploads {
+		if time.Since(upload.created) > s3UploadExpiry {
+			delete(u.uploads, id)
+			if err := os.RemoveAll(filepath.Join(u.dir, id)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Make a random upload id
+	var data [16]byte
+	if _, err := rand.Read(data[:]); err != nil {
+		return nil, err
+	}
+	upload := &s3Upload{
+		id:      hex.EncodeToString(data[:]),
+		bucket:  bucket,
+		key:     key,
+		meta:    meta,
+		created: time.Now(),
+		parts:   make(map[int]s3UploadPart),
+	}
+	if err := os.Mkdir(filepath.Join(u.dir, upload.id), 0o700); err != nil {
+		return nil, err
+	}
+	u.uploads[upload.id] = upload
+
+	// Return success
+	return upload, nil
+}
+
+// get returns an upload for a bucket and key
+func (u *s3Uploads) get(id, bucket, key string) (*s3Upload, error) {
+	u.Lock()
+	defer u.Unlock()
+	if upload, exists := u.uploads[id]; !exists || upload.bucket != bucket || upload.key != key {
+		return nil, newS3Err(http.StatusNotFound, schema.S3ErrNoSuchUpload, "upload not found: %q", id)
+	} else {
+		return upload, nil
+	}
+}
+
+// remove removes an upload and its parts
+func (u *s3Uploads) remove(id string) error {
+	u.Lock()
+	defer u.Unlock()
+	if _, exists := u.uploads[id]; !exists {
+		return newS3Err(http.StatusNotFound, schema.S3ErrNoSuchUpload, "upload not found: %q", id)
+	}
+	delete(u.uploads, id)
+	return os.RemoveAll(filepath.Join(u.dir, id))
+}
+
+// writePart writes a part to a temporary file, replacing any existing part
+// with the same number
+func (u *s3Uploads) writePart(upload *s3Upload, number int, body io.Reader) (_ s3UploadPart, err error) {
+	f, err := os.CreateTemp(filepath.Join(u.dir, upload.id), "part-")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s3UploadPart{}, newS3Err(http.StatusNotFound, schema.S3ErrNoSuchUpload, "upload not found: %q", upload.id)
+		}
+		return s3UploadPart{}, err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, os.Remove(f.Name()))
+		}
+	}()
+
+	// Write the part and compute the digest
+	hash := md5.New()
+	size, err := io.Copy(io.MultiWriter(f, hash), body)
+	if err := errors.Join(err, f.Close()); err != nil {
+		return s3UploadPart{}, err
+	}
+	part := s3UploadPart{
+		etag: fmt.Sprintf(`"%x"`, hash.Sum(nil)),
+		size: size,
+	}
+
+	// Replace the part, unless the upload was completed or aborted
+	upload.Lock()
+	defer upload.Unlock()
+	if _, err := u.get(upload.id, upload.bucket, upload.key); err != nil {
+		return s3UploadPart{}, err
+	} else if err := os.Rename(f.Name(), u.partPath(upload, number)); err != nil {
+		return s3UploadPart{}, err
+	}
+	upload.parts[number] = part
+
+	// Return success
+	return part, nil
+}
+
+// partPath returns the path of a part file
+func (u *s3Uploads) partPath(upload *s3Upload, number int) string {
+	return filepath.Join(u.dir, upload.id, strconv.Itoa(number))
+}
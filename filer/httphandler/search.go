@@ -19,7 +19,7 @@ import (
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
-func RegisterSearchHandlers(manager *manager.Manager, router *httprouter.Router) error {
+func RegisterSearchHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
 	router.Spec().AddTag("Search", "Search Operations")
 
 	return errors.Join(
@@ -30,8 +30,11 @@ func RegisterSearchHandlers(manager *manager.Manager, router *httprouter.Router)
 				},
 				"List search results",
 				openapi.WithTags("Search"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
 				openapi.WithJSONRequest(jsonschema.MustFor[schema.SearchListRequest]()),
+				openapi.WithDescription(`Returns a page of search results. When the Accept header is application/x-ndjson or application/ndjson, all results are streamed as one result per line without a page limit.`),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.SearchList]()),
+				openapi.WithResponse(http.StatusOK, types.ContentTypeJSONStreamLegacy, jsonschema.MustFor[schema.SearchResult](), "Search results, one per line"),
 			),
 		),
 	)
@@ -44,7 +47,17 @@ func ListSearchResults(w http.ResponseWriter, r *http.Request, manager *manager.
 	var req schema.SearchListRequest
 	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
 		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
-	} else if resp, err := manager.Search(r.Context(), req); err != nil {
+	}
+
+	// Stream the search results without a page limit when requested
+	if stream := newJSONStream(w, r); stream != nil {
+		return stream.Close(manager.StreamSearch(r.Context(), req, func(result *schema.SearchResult) error {
+			return stream.Write(result)
+		}), types.Stringify(req))
+	}
+
+	// Return a page of search results
+	if resp, err := manager.Search(r.Context(), req); err != nil {
 		return httpresponse.Error(w, gofiler.HTTPErr(err), types.Stringify(req))
 	} else {
 		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
@@ -0,0 +1,220 @@
+package httphandler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	jsonschema "github.com/mutablelogic/go-server/pkg/jsonschema"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// shareRealm is the realm sent to clients when a share link requires a password
+	shareRealm = `Basic realm="share"`
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func RegisterShareHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
+	router.Spec().AddTag("Shares", "Share Link Operations")
+
+	return errors.Join(
+		router.RegisterPath("share", nil, httprequest.NewPathItem("Shares", "Manage share links").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = ListShares(w, r, manager)
+				},
+				"List share links",
+				openapi.WithTags("Shares"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithJSONRequest(jsonschema.MustFor[schema.ShareListRequest]()),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.ShareList]()),
+			).
+			Post(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = CreateShare(w, r, manager)
+				},
+				"Create a share link",
+				openapi.WithTags("Shares"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithDescription(`The path is shared as a folder when it ends with a slash or is not an object. The content is served without authentication at `+schema.SharePath+`/{token}, using the password as the Basic authentication password when one is set.`),
+				openapi.WithJSONRequest(jsonschema.MustFor[schema.ShareCreate]()),
+				openapi.WithJSONResponse(http.StatusCreated, jsonschema.MustFor[schema.Share]()),
+			),
+		),
+		router.RegisterPath("share/{id}", nil, httprequest.NewPathItem("Shares", "Manage a share link").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = GetShare(w, r, manager, r.PathValue("id"))
+				},
+				"Get a share link",
+				openapi.WithTags("Shares"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Share]()),
+			).
+			Delete(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = DeleteShare(w, r, manager, r.PathValue("id"))
+				},
+				"Revoke a share link",
+				openapi.WithTags("Shares"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Share]()),
+			),
+		),
+		router.RegisterPath(schema.SharePath+"/{token}", nil, httprequest.NewPathItem("Shares", "Content shared through a share link").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = GetShared(w, r, manager, r.PathValue("token"), "")
+				},
+				"Download a shared object, or list a shared folder",
+				openapi.WithTags("Shares"),
+				openapi.WithDescription(`No authentication is required. When the share link has a password, it is sent as the Basic authentication password. A download is counted when the whole object, or a byte range from the start of the object, is returned.`),
+			),
+		),
+		router.RegisterPath(schema.SharePath+"/{token}/{path...}", nil, httprequest.NewPathItem("Shares", "Content shared through a share link").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = GetShared(w, r, manager, r.PathValue("token"), r.PathValue("path"))
+				},
+				"Download an object, or list a folder, within a shared folder",
+				openapi.WithTags("Shares"),
+				openapi.WithDescription(`No authentication is required. Paths ending with a slash are listed as folders. A download is counted when the whole object, or a byte range from the start of the object, is returned.`),
+			),
+		),
+	)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func ListShares(w http.ResponseWriter, r *http.Request, manager *manager.Manager) error {
+	var req schema.ShareListRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if resp, err := manager.ListShares(r.Context(), req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), req.String())
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+func CreateShare(w http.ResponseWriter, r *http.Request, manager *manager.Manager) error {
+	var req schema.ShareCreate
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if resp, err := manager.CreateShare(r.Context(), req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), req.ShareMeta.String())
+	} else {
+		return httpresponse.JSON(w, http.StatusCreated, httprequest.Indent(r), resp)
+	}
+}
+
+func GetShare(w http.ResponseWriter, r *http.Request, manager *manager.Manager, id string) error {
+	key, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.Withf("invalid share link id: %q", id))
+	}
+	if resp, err := manager.GetShare(r.Context(), schema.ShareID(key)); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), id)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+func DeleteShare(w http.ResponseWriter, r *http.Request, manager *manager.Manager, id string) error {
+	key, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.Withf("invalid share link id: %q", id))
+	}
+	if resp, err := manager.DeleteShare(r.Context(), schema.ShareID(key)); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), id)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+// GetShared serves content through a share link. A shared object is
+// downloaded, and a shared folder is listed when the path is empty or ends
+// with a slash. The password is read from Basic authentication.
+func GetShared(w http.ResponseWriter, r *http.Request, manager *manager.Manager, token, path string) error {
+	_, password, _ := r.BasicAuth()
+
+	// List a folder
+	if path == "" || strings.HasSuffix(path, "/") {
+		var req pg.OffsetLimit
+		if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+			return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+		}
+		resp, err := manager.ListShare(r.Context(), schema.ShareToken(token), password, path, req)
+		if err == nil {
+			return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+		} else if path != "" || !errors.Is(err, gofiler.ErrBadParameter) {
+			return sharedError(w, err, path)
+		}
+	}
+
+	// Download an object
+	reader, obj, err := manager.ReadShare(r.Context(), schema.ShareToken(token), password, path)
+	if err != nil {
+		return sharedError(w, err, path)
+	}
+	defer reader.Close()
+
+	// Count the download, unless only part of the object is returned
+	if sharedDownload(r, reader, obj) {
+		if _, err := manager.CountShare(r.Context(), schema.ShareToken(token)); err != nil {
+			return sharedError(w, err, path)
+		}
+	}
+
+	// Write the object
+	return writeObject(w, r, reader, obj, "attachment")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// sharedDownload returns true if the request downloads the object, which is
+// when the whole object or the first byte range is returned. Conditional
+// requests which return no content, and byte ranges which continue a
+// download, are not counted.
+func sharedDownload(r *http.Request, reader io.Reader, obj *schema.Object) bool {
+	if checkPreconditions(r, obj) != http.StatusOK {
+		return false
+	}
+	if header := r.Header.Get(schema.RangeHeader); header != "" && checkIfRange(r, obj) {
+		ranges, err := parseRange(header, obj.Size)
+		switch {
+		case errors.Is(err, errRangeNotSatisfiable):
+			return false
+		case err == nil && seekable(reader, ranges):
+			return ranges[0].start == 0
+		}
+	}
+	return true
+}
+
+// sharedError writes an error for a share link, and asks the client for
+// the password when one is required
+func sharedError(w http.ResponseWriter, err error, path string) error {
+	if errors.Is(err, gofiler.ErrUnauthorized) {
+		w.Header().Set("WWW-Authenticate", shareRealm)
+	}
+	return httpresponse.Error(w, gofiler.HTTPErr(err), path)
+}
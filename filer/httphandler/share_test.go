@@ -0,0 +1,52 @@
+package httphandler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// sharedDownload
+
+func TestSharedDownload_001(t *testing.T) {
+	obj := &schema.Object{ObjectAttr: schema.ObjectAttr{Size: 100, ETag: types.Ptr(`"abc"`), ModTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		seekable bool
+		want     bool
+	}{
+		{"whole object", nil, true, true},
+		{"not modified", map[string]string{schema.ContentIfNoneMatchHeader: `"abc"`}, true, false},
+		{"precondition failed", map[string]string{schema.ContentIfMatchHeader: `"def"`}, true, false},
+		{"first range", map[string]string{schema.RangeHeader: "bytes=0-9"}, true, true},
+		{"next range", map[string]string{schema.RangeHeader: "bytes=10-"}, true, false},
+		{"suffix range", map[string]string{schema.RangeHeader: "bytes=-10"}, true, false},
+		{"multiple ranges", map[string]string{schema.RangeHeader: "bytes=0-9,20-29"}, true, true},
+		{"not satisfiable", map[string]string{schema.RangeHeader: "bytes=200-"}, true, false},
+		{"invalid range", map[string]string{schema.RangeHeader: "bytes=a-b"}, true, true},
+		{"not seekable", map[string]string{schema.RangeHeader: "bytes=20-29,10-19"}, false, true},
+		{"if-range mismatch", map[string]string{schema.RangeHeader: "bytes=10-", schema.ContentIfRangeHeader: `"def"`}, true, true},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for key, value := range test.headers {
+			r.Header.Set(key, value)
+		}
+		var reader io.Reader = strings.NewReader(strings.Repeat("a", 100))
+		if !test.seekable {
+			reader = io.MultiReader(reader)
+		}
+		if got := sharedDownload(r, reader, obj); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
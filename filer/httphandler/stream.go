@@ -0,0 +1,98 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// jsonStream writes values as newline-delimited JSON. The response is only
+// started when the first value is written, so that an error before then is
+// returned as an error response.
+type jsonStream struct {
+	w    http.ResponseWriter
+	r    *http.Request
+	conn httpresponse.JSONStreamConn
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// newJSONStream returns a stream when the client accepts newline-delimited
+// JSON, or nil otherwise
+func newJSONStream(w http.ResponseWriter, r *http.Request) *jsonStream {
+	if acceptJSONStream(r) == "" {
+		return nil
+	}
+	return &jsonStream{w: w, r: r}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write writes a value on a single line and flushes it to the client, so
+// that writes block while the client is not reading
+func (s *jsonStream) Write(v any) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.conn.Send(data)
+}
+
+// Close ends the stream. When an error occurred before any value was written,
+// the error is returned as an error response. After the response has started,
+// the connection is aborted so that the client does not mistake a truncated
+// stream for a complete one.
+func (s *jsonStream) Close(err error, context string) error {
+	if err != nil && s.conn == nil {
+		return httpresponse.Error(s.w, gofiler.HTTPErr(err), context)
+	} else if err != nil {
+		s.conn.Close()
+		panic(http.ErrAbortHandler)
+	} else if err := s.start(); err != nil {
+		return httpresponse.Error(s.w, err, context)
+	}
+	return s.conn.Close()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// start writes the response headers, using the content type accepted by the
+// client
+func (s *jsonStream) start() error {
+	if s.conn != nil {
+		return nil
+	}
+	conn, err := httpresponse.NewJSONStream(s.w, s.r, types.ContentTypeHeader, acceptJSONStream(s.r))
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// acceptJSONStream returns the newline-delimited JSON content type accepted
+// by the client, or an empty string
+func acceptJSONStream(r *http.Request) string {
+	for _, accept := range strings.Split(r.Header.Get(types.ContentAcceptHeader), ",") {
+		mimetype, _, _ := strings.Cut(accept, ";")
+		switch mimetype = strings.ToLower(strings.TrimSpace(mimetype)); mimetype {
+		case types.ContentTypeJSONStream, types.ContentTypeJSONStreamLegacy:
+			return mimetype
+		}
+	}
+	return ""
+}
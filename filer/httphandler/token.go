@@ -0,0 +1,127 @@
+package httphandler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	// Packages
+	authmiddleware "github.com/mutablelogic/go-auth/auth/middleware"
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	jsonschema "github.com/mutablelogic/go-server/pkg/jsonschema"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewTokenAuth returns the security scheme which authenticates API tokens
+// in the X-API-Key header, and checks the token has the scopes required by
+// an operation. Volume restrictions are enforced by the manager.
+func NewTokenAuth(manager *manager.Manager) httprouter.SecurityScheme {
+	return authmiddleware.NewAPIKeyAuth(manager)
+}
+
+func RegisterTokenHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
+	router.Spec().AddTag("Tokens", "API Token Operations")
+
+	return errors.Join(
+		router.RegisterPath("token", nil, httprequest.NewPathItem("Tokens", "Manage API tokens").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = ListTokens(w, r, manager)
+				},
+				"List API tokens",
+				openapi.WithTags("Tokens"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeAdmin),
+				openapi.WithJSONRequest(jsonschema.MustFor[schema.TokenListRequest]()),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.TokenList]()),
+			).
+			Post(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = CreateToken(w, r, manager)
+				},
+				"Create an API token",
+				openapi.WithTags("Tokens"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeAdmin),
+				openapi.WithDescription(`The token value is only returned in the response when the token is created, and cannot be retrieved later.`),
+				openapi.WithJSONRequest(jsonschema.MustFor[schema.TokenMeta]()),
+				openapi.WithJSONResponse(http.StatusCreated, jsonschema.MustFor[schema.Token]()),
+			),
+		),
+		router.RegisterPath("token/{id}", nil, httprequest.NewPathItem("Tokens", "Manage an API token").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = GetToken(w, r, manager, r.PathValue("id"))
+				},
+				"Get an API token",
+				openapi.WithTags("Tokens"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeAdmin),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Token]()),
+			).
+			Delete(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = DeleteToken(w, r, manager, r.PathValue("id"))
+				},
+				"Revoke an API token",
+				openapi.WithTags("Tokens"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeAdmin),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Token]()),
+			),
+		),
+	)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func ListTokens(w http.ResponseWriter, r *http.Request, manager *manager.Manager) error {
+	var req schema.TokenListRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if resp, err := manager.ListTokens(r.Context(), req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), req.String())
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+func CreateToken(w http.ResponseWriter, r *http.Request, manager *manager.Manager) error {
+	var req schema.TokenMeta
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if resp, err := manager.CreateToken(r.Context(), req); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), req.String())
+	} else {
+		return httpresponse.JSON(w, http.StatusCreated, httprequest.Indent(r), resp)
+	}
+}
+
+func GetToken(w http.ResponseWriter, r *http.Request, manager *manager.Manager, id string) error {
+	key, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.Withf("invalid token id: %q", id))
+	}
+	if resp, err := manager.GetToken(r.Context(), schema.TokenID(key)); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), id)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
+
+func DeleteToken(w http.ResponseWriter, r *http.Request, manager *manager.Manager, id string) error {
+	key, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.Withf("invalid token id: %q", id))
+	}
+	if resp, err := manager.DeleteToken(r.Context(), schema.TokenID(key)); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), id)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), resp)
+	}
+}
@@ -27,7 +27,7 @@ type VolumePathParams struct {
 ///////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
-func RegisterVolumeHandlers(manager *manager.Manager, router *httprouter.Router) error {
+func RegisterVolumeHandlers(manager *manager.Manager, router *httprouter.Router, auth bool) error {
 	router.Spec().AddTag("Volumes", "Volume Operations")
 
 	return errors.Join(
@@ -38,6 +38,7 @@ func RegisterVolumeHandlers(manager *manager.Manager, router *httprouter.Router)
 				},
 				"List volumes",
 				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
 				openapi.WithJSONRequest(jsonschema.MustFor[schema.VolumeListRequest]()),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.VolumeList]()),
 			).
@@ -47,6 +48,7 @@ func RegisterVolumeHandlers(manager *manager.Manager, router *httprouter.Router)
 				},
 				"Create volume",
 				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeAdmin),
 				openapi.WithJSONRequest(jsonschema.MustFor[schema.VolumeCreate]()),
 				openapi.WithJSONResponse(http.StatusCreated, jsonschema.MustFor[schema.Volume]()),
 			),
@@ -58,6 +60,7 @@ func RegisterVolumeHandlers(manager *manager.Manager, router *httprouter.Router)
 				},
 				"Get volume",
 				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Volume]()),
 			).
 			Delete(
@@ -66,6 +69,7 @@ func RegisterVolumeHandlers(manager *manager.Manager, router *httprouter.Router)
 				},
 				"Delete volume",
 				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeAdmin),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Volume]()),
 			).
 			Patch(
@@ -74,6 +78,7 @@ func RegisterVolumeHandlers(manager *manager.Manager, router *httprouter.Router)
 				},
 				"Mount or unmount a volume, change parameters",
 				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeAdmin),
 				openapi.WithJSONRequest(jsonschema.MustFor[schema.VolumeMeta]()),
 				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.Volume]()),
 			),
@@ -85,10 +90,22 @@ func RegisterVolumeHandlers(manager *manager.Manager, router *httprouter.Router)
 				},
 				"Reindex a volume",
 				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeWrite),
 				openapi.WithJSONRequest(jsonschema.MustFor[schema.ObjectListFilters]()),
 				openapi.WithNoContentResponse(http.StatusNoContent, "Reindexing started"),
 			),
 		),
+		router.RegisterPath("volume/{name}/index", nil, httprequest.NewPathItem("Volumes", "Indexing progress of a volume").
+			Get(
+				func(w http.ResponseWriter, r *http.Request) {
+					_ = GetVolumeIndex(w, r, manager, r.PathValue("name"))
+				},
+				"Get indexing progress",
+				openapi.WithTags("Volumes"),
+				openapi.WithSecurity(schema.SecurityTokenAuth, auth, schema.ScopeRead),
+				openapi.WithJSONResponse(http.StatusOK, jsonschema.MustFor[schema.VolumeIndex]()),
+			),
+		),
 	)
 }
 
@@ -103,6 +120,14 @@ func GetVolume(w http.ResponseWriter, r *http.Request, manager *manager.Manager,
 	}
 }
 
+func GetVolumeIndex(w http.ResponseWriter, r *http.Request, manager *manager.Manager, name string) error {
+	if index, err := manager.GetVolumeIndex(r.Context(), name); err != nil {
+		return httpresponse.Error(w, gofiler.HTTPErr(err), name)
+	} else {
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), index)
+	}
+}
+
 func UpdateVolume(w http.ResponseWriter, r *http.Request, manager *manager.Manager, name string) error {
 	var meta schema.VolumeMeta
 	if err := httprequest.Read(r, &meta); err != nil {
@@ -0,0 +1,159 @@
+package httphandler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	// Packages
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httprouter "github.com/mutablelogic/go-server/pkg/httprouter"
+	jsonschema "github.com/mutablelogic/go-server/pkg/jsonschema"
+	openapi "github.com/mutablelogic/go-server/pkg/openapi/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	webdav "golang.org/x/net/webdav"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// webdavPathItem routes all methods to the WebDAV handler, since WebDAV uses
+// methods such as PROPFIND and MKCOL which are not in the OpenAPI spec
+type webdavPathItem struct {
+	handler http.HandlerFunc
+}
+
+var _ httprequest.PathItem = (*webdavPathItem)(nil)
+
+// webdavBody is the body of a PUT request, which records whether it has
+// been read to the end, so that an object is only created from the whole body
+type webdavBody struct {
+	io.ReadCloser
+	eof bool
+	err error
+}
+
+type webdavContextKey int
+
+const (
+	// webdavTokenHeader is the header read by the token security scheme
+	webdavTokenHeader = "X-API-Key"
+)
+
+const (
+	// webdavBodyKey is the context key for the body of a PUT request
+	webdavBodyKey webdavContextKey = iota
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// RegisterWebDAVHandlers registers a WebDAV server at an absolute path, such
+// as /dav, where each enabled volume is a collection. Writes go through the
+// manager, so objects are indexed when the volume is indexed. Locks are held
+// in memory. When auth is true, clients authenticate with an API token as the
+// basic auth password, since WebDAV clients cannot send other headers.
+func RegisterWebDAVHandlers(manager *manager.Manager, router *httprouter.Router, path string, auth bool) error {
+	path = types.NormalisePath(path)
+	handler := &webdav.Handler{
+		Prefix:     path,
+		FileSystem: newWebDAVFS(manager),
+		LockSystem: webdav.NewMemLS(),
+	}
+	item := &webdavPathItem{
+		handler: webdavPut(handler.ServeHTTP),
+	}
+	if auth {
+		item.handler = webdavAuth(NewTokenAuth(manager), item.handler)
+	}
+	return router.RegisterPath(path+"/{path...}", nil, item)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (p *webdavPathItem) Handler() http.HandlerFunc {
+	return p.handler
+}
+
+func (p *webdavPathItem) Spec(string, *jsonschema.Schema) *openapi.PathItem {
+	return &openapi.PathItem{
+		Summary:     "WebDAV",
+		Description: "Mounts volumes as network drives, supporting PROPFIND, GET, PUT, DELETE, MOVE, COPY, MKCOL, LOCK and UNLOCK",
+	}
+}
+
+func (p *webdavPathItem) WrapHandler(method string, fn func(http.HandlerFunc) http.HandlerFunc) {
+	p.handler = fn(p.handler)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// webdavAuth wraps the WebDAV handler with the token security scheme, using
+// the basic auth password as the token when there is no X-API-Key header.
+// Methods which do not modify the volume require the read scope, and other
+// methods require the write scope.
+func webdavAuth(scheme httprouter.SecurityScheme, handler http.HandlerFunc) http.HandlerFunc {
+	read := scheme.Wrap(handler, []string{schema.ScopeRead})
+	write := scheme.Wrap(handler, []string{schema.ScopeWrite})
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(webdavTokenHeader) == "" {
+			if _, password, ok := r.BasicAuth(); ok && password != "" {
+				r = r.Clone(r.Context())
+				r.Header.Set(webdavTokenHeader, password)
+			} else {
+				w.Header().Set("WWW-Authenticate", `Basic realm="filer"`)
+			}
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND":
+			read(w, r)
+		default:
+			write(w, r)
+		}
+	}
+}
+
+// webdavPut wraps the WebDAV handler so that the body of a PUT request is
+// recorded in the context, where it is found by the file opened for writing
+func webdavPut(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.Body != nil {
+			body := &webdavBody{ReadCloser: r.Body}
+			r = r.WithContext(context.WithValue(r.Context(), webdavBodyKey, body))
+			r.Body = body
+		}
+		handler(w, r)
+	}
+}
+
+// webdavBodyFromContext returns the body of a PUT request, or nil
+func webdavBodyFromContext(ctx context.Context) *webdavBody {
+	body, _ := ctx.Value(webdavBodyKey).(*webdavBody)
+	return body
+}
+
+func (b *webdavBody) Read(data []byte) (int, error) {
+	n, err := b.ReadCloser.Read(data)
+	if errors.Is(err, io.EOF) {
+		b.eof = true
+	} else if err != nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// complete returns nil when the body has been read to the end, or else the
+// error which stopped it being read
+func (b *webdavBody) complete() error {
+	if b.err != nil {
+		return b.err
+	} else if !b.eof {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
@@ -0,0 +1,725 @@
+package httphandler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"mime"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	manager "github.com/mutablelogic/go-filer/filer/manager"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	webdav "golang.org/x/net/webdav"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// webdavFS is a WebDAV file system over the volumes of a manager, where the
+// first path element is the volume name. Directories are implicit in
+// backends, so collections created with MKCOL are held in memory until an
+// object is written under them.
+type webdavFS struct {
+	sync.Mutex
+	manager *manager.Manager
+	dirs    map[string]time.Time
+}
+
+// webdavFileInfo describes a volume, directory or object
+type webdavFileInfo struct {
+	name    string
+	modtime time.Time
+	object  *schema.Object // nil for volumes and directories
+}
+
+// webdavDir is an open volume or directory
+type webdavDir struct {
+	ctx     context.Context
+	fs      *webdavFS
+	name    string
+	info    *webdavFileInfo
+	entries []os.FileInfo
+	read    bool
+}
+
+// webdavReader is an object opened for reading, which is reopened when
+// seeking backwards
+type webdavReader struct {
+	ctx    context.Context
+	fs     *webdavFS
+	key    schema.ObjectKey
+	info   *webdavFileInfo
+	reader io.ReadCloser
+	pos    int64
+	offset int64
+}
+
+// webdavWriter is an object opened for writing, where the content is piped
+// to the manager as it is written. The object is only created when the whole
+// request body has been written, and is abandoned otherwise.
+type webdavWriter struct {
+	name   string
+	pipe   *io.PipeWriter
+	body   *webdavBody // body of the PUT request, or nil
+	done   chan struct{}
+	once   sync.Once
+	object *schema.Object
+	werr   error // error writing to the pipe
+	err    error // error creating the object
+}
+
+var _ webdav.FileSystem = (*webdavFS)(nil)
+var _ webdav.ETager = (*webdavFileInfo)(nil)
+var _ webdav.ContentTyper = (*webdavFileInfo)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func newWebDAVFS(manager *manager.Manager) *webdavFS {
+	return &webdavFS{
+		manager: manager,
+		dirs:    make(map[string]time.Time),
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// FILE SYSTEM
+
+func (fs *webdavFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	volume, key := webdavSplit(name)
+	info, err := fs.stat(ctx, volume, key)
+	if err != nil {
+		return nil, webdavError("stat", name, err)
+	}
+	return info, nil
+}
+
+func (fs *webdavFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	volume, key := webdavSplit(name)
+
+	// Open for writing
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		file, err := fs.create(ctx, volume, key, flag)
+		if err != nil {
+			return nil, webdavError("open", name, err)
+		}
+		return file, nil
+	}
+
+	// Open for reading
+	info, err := fs.stat(ctx, volume, key)
+	if err != nil {
+		return nil, webdavError("open", name, err)
+	} else if info.IsDir() {
+		return &webdavDir{ctx: ctx, fs: fs, name: name, info: info}, nil
+	} else {
+		return &webdavReader{ctx: ctx, fs: fs, key: info.object.ObjectKey, info: info}, nil
+	}
+}
+
+// Mkdir creates a collection, which is held in memory until an object is
+// written under it
+func (fs *webdavFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	volume, key := webdavSplit(name)
+	if key == "" {
+		if _, err := fs.stat(ctx, volume, key); err == nil {
+			return webdavError("mkdir", name, os.ErrExist)
+		}
+		return webdavError("mkdir", name, os.ErrPermission)
+	}
+
+	// Check the collection does not exist, and the parent does
+	if _, err := fs.stat(ctx, volume, key); err == nil {
+		return webdavError("mkdir", name, os.ErrExist)
+	} else if !webdavNotExist(err) {
+		return webdavError("mkdir", name, err)
+	}
+	if parent, err := fs.stat(ctx, volume, webdavParent(key)); err != nil {
+		return webdavError("mkdir", name, err)
+	} else if !parent.IsDir() {
+		return webdavError("mkdir", name, os.ErrNotExist)
+	}
+
+	// Hold the collection
+	fs.Lock()
+	defer fs.Unlock()
+	fs.dirs[path.Join(volume, key)] = time.Now()
+
+	// Return success
+	return nil
+}
+
+// RemoveAll deletes an object, or a directory and all objects under it
+func (fs *webdavFS) RemoveAll(ctx context.Context, name string) error {
+	volume, key := webdavSplit(name)
+	if key == "" {
+		return webdavError("remove", name, os.ErrPermission)
+	}
+
+	// Remove collections held in memory
+	held := fs.release(path.Join(volume, key), true)
+
+	// Delete the objects
+	if _, err := fs.manager.DeleteObjects(ctx, schema.DeleteObjectsRequest{
+		ObjectKey: schema.ObjectKey{
+			Volume: volume,
+			Path:   key,
+		},
+		Recursive: true,
+	}); err != nil && !(held && webdavNotExist(err)) {
+		return webdavError("remove", name, err)
+	}
+
+	// Return success
+	return nil
+}
+
+// Rename copies an object, or all objects under a directory, and then
+// deletes the source, since backends cannot rename objects
+func (fs *webdavFS) Rename(ctx context.Context, oldName, newName string) error {
+	volume, key := webdavSplit(oldName)
+	dstVolume, dstKey := webdavSplit(newName)
+	if key == "" || dstKey == "" {
+		return webdavError("rename", oldName, os.ErrPermission)
+	}
+	info, err := fs.stat(ctx, volume, key)
+	if err != nil {
+		return webdavError("rename", oldName, err)
+	}
+
+	// Check the source can be deleted before anything is copied
+	if src, err := fs.manager.GetVolume(ctx, volume); err != nil {
+		return webdavError("rename", oldName, err)
+	} else if err := src.CheckDelete(); err != nil {
+		return webdavError("rename", oldName, err)
+	}
+
+	// Copy an object
+	if !info.IsDir() {
+		if _, err := fs.manager.CopyObject(ctx, info.object.ObjectKey, schema.ObjectKey{Volume: dstVolume, Path: dstKey}); err != nil {
+			return webdavError("rename", oldName, err)
+		}
+		return fs.RemoveAll(ctx, oldName)
+	}
+
+	// Copy all objects under a directory
+	iterator := &schema.ObjectListIterator{
+		Path:      types.Ptr(key),
+		Recursive: true,
+	}
+	for {
+		err := fs.manager.IterateObjects(ctx, volume, iterator)
+		if err != nil && !errors.Is(err, io.EOF) && !webdavNotExist(err) {
+			return webdavError("rename", oldName, err)
+		}
+		for _, object := range iterator.Body {
+			rel := strings.TrimPrefix(strings.TrimPrefix(object.Path, key), "/")
+			if _, err := fs.manager.CopyObject(ctx, object.ObjectKey, schema.ObjectKey{Volume: dstVolume, Path: path.Join(dstKey, rel)}); err != nil {
+				return webdavError("rename", oldName, err)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// Move collections held in memory, including the directory itself
+	fs.Lock()
+	src, dst := path.Join(volume, key), path.Join(dstVolume, dstKey)
+	dirs := map[string]time.Time{dst: info.modtime}
+	for dir, created := range fs.dirs {
+		if strings.HasPrefix(dir+"/", src+"/") {
+			dirs[dst+strings.TrimPrefix(dir, src)] = created
+		}
+	}
+	maps.Copy(fs.dirs, dirs)
+	fs.Unlock()
+
+	// Delete the source
+	return fs.RemoveAll(ctx, oldName)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// FILE INFO
+
+func (info *webdavFileInfo) Name() string {
+	return info.name
+}
+
+func (info *webdavFileInfo) Size() int64 {
+	if info.object == nil {
+		return 0
+	}
+	return info.object.Size
+}
+
+func (info *webdavFileInfo) Mode() os.FileMode {
+	if info.IsDir() {
+		return os.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+func (info *webdavFileInfo) ModTime() time.Time {
+	return info.modtime
+}
+
+func (info *webdavFileInfo) IsDir() bool {
+	return info.object == nil
+}
+
+func (info *webdavFileInfo) Sys() any {
+	return info.object
+}
+
+// ETag returns the backend etag, or ErrNotImplemented to make one from the
+// modification time and size
+func (info *webdavFileInfo) ETag(context.Context) (string, error) {
+	if info.object == nil || types.Value(info.object.ETag) == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return `"` + strings.Trim(types.Value(info.object.ETag), `"`) + `"`, nil
+}
+
+func (info *webdavFileInfo) ContentType(context.Context) (string, error) {
+	if info.object == nil || info.object.ContentType == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return info.object.ContentType, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// DIRECTORY
+
+func (d *webdavDir) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.read {
+		volume, key := webdavSplit(d.name)
+		entries, err := d.fs.readdir(d.ctx, volume, key)
+		if err != nil {
+			return nil, webdavError("readdir", d.name, err)
+		}
+		d.entries, d.read = entries, true
+	}
+
+	// Return all remaining entries, or the next count entries
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	} else if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n := min(count, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+func (d *webdavDir) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *webdavDir) Read([]byte) (int, error) {
+	return 0, webdavError("read", d.name, gofiler.ErrBadParameter.With("is a directory"))
+}
+
+func (d *webdavDir) Write([]byte) (int, error) {
+	return 0, webdavError("write", d.name, os.ErrPermission)
+}
+
+func (d *webdavDir) Seek(int64, int) (int64, error) {
+	return 0, nil
+}
+
+func (d *webdavDir) Close() error {
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (r *webdavReader) Read(data []byte) (int, error) {
+	if r.offset >= r.info.Size() {
+		return 0, io.EOF
+	}
+
+	// Reopen the object when seeking backwards, and skip forward to the offset
+	if r.reader != nil && r.offset < r.pos {
+		if seeker, ok := r.reader.(io.Seeker); ok {
+			if _, err := seeker.Seek(r.offset, io.SeekStart); err != nil {
+				return 0, err
+			}
+			r.pos = r.offset
+		} else {
+			err := r.reader.Close()
+			r.reader = nil
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	if r.reader == nil {
+		reader, _, err := r.fs.manager.ReadObject(r.ctx, r.key)
+		if err != nil {
+			return 0, webdavError("read", r.key.Path, err)
+		}
+		r.reader, r.pos = reader, 0
+	}
+	if r.offset > r.pos {
+		n, err := io.CopyN(io.Discard, r.reader, r.offset-r.pos)
+		r.pos += n
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Read from the offset
+	n, err := r.reader.Read(data)
+	r.pos += int64(n)
+	r.offset = r.pos
+	return n, err
+}
+
+func (r *webdavReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.info.Size()
+	}
+	if offset < 0 {
+		return 0, webdavError("seek", r.key.Path, gofiler.ErrBadParameter.With("negative offset"))
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *webdavReader) Readdir(int) ([]os.FileInfo, error) {
+	return nil, webdavError("readdir", r.key.Path, gofiler.ErrBadParameter.With("not a directory"))
+}
+
+func (r *webdavReader) Stat() (os.FileInfo, error) {
+	return r.info, nil
+}
+
+func (r *webdavReader) Write([]byte) (int, error) {
+	return 0, webdavError("write", r.key.Path, os.ErrPermission)
+}
+
+func (r *webdavReader) Close() error {
+	if r.reader == nil {
+		return nil
+	}
+	return r.reader.Close()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+func (w *webdavWriter) Write(data []byte) (int, error) {
+	n, err := w.pipe.Write(data)
+	if err != nil && w.werr == nil {
+		w.werr = err
+	}
+	return n, err
+}
+
+// Stat completes the write, since the object metadata is only known once
+// the content has been written
+func (w *webdavWriter) Stat() (os.FileInfo, error) {
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return &webdavFileInfo{
+		name:    path.Base(w.object.Path),
+		modtime: w.object.ModTime,
+		object:  w.object,
+	}, nil
+}
+
+// Close completes the write and returns any error from creating the object.
+// When a write failed, or the request body was not read to the end, the
+// pipe is closed with the error so that the object is not created.
+func (w *webdavWriter) Close() error {
+	w.once.Do(func() {
+		var err error
+		if w.werr != nil {
+			err = w.werr
+		} else if w.body != nil {
+			err = w.body.complete()
+		}
+		if err != nil {
+			w.pipe.CloseWithError(err)
+		} else {
+			w.pipe.Close()
+		}
+		<-w.done
+		if err != nil && w.err == nil {
+			w.err = err
+		}
+	})
+	if w.err != nil {
+		return webdavError("write", w.name, w.err)
+	}
+	return nil
+}
+
+func (w *webdavWriter) Read([]byte) (int, error) {
+	return 0, webdavError("read", w.name, os.ErrPermission)
+}
+
+func (w *webdavWriter) Seek(int64, int) (int64, error) {
+	return 0, webdavError("seek", w.name, os.ErrPermission)
+}
+
+func (w *webdavWriter) Readdir(int) ([]os.FileInfo, error) {
+	return nil, webdavError("readdir", w.name, gofiler.ErrBadParameter.With("not a directory"))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// stat returns a volume, directory or object. Backends without directories
+// return not found for a directory, which exists when it has objects under it.
+func (fs *webdavFS) stat(ctx context.Context, volume, key string) (*webdavFileInfo, error) {
+	if volume == "" {
+		return &webdavFileInfo{name: "/"}, nil
+	} else if key == "" {
+		v, err := fs.manager.GetVolume(ctx, volume)
+		if err != nil {
+			return nil, err
+		} else if !types.Value(v.Enabled) {
+			return nil, gofiler.ErrNotFound.Withf("volume %q is not mounted", volume)
+		}
+		return &webdavFileInfo{name: volume, modtime: v.CreatedAt}, nil
+	}
+
+	// Return an object
+	object, err := fs.manager.GetObject(ctx, schema.ObjectKey{Volume: volume, Path: key})
+	if err == nil {
+		return &webdavFileInfo{name: path.Base(key), modtime: object.ModTime, object: object}, nil
+	} else if !errors.Is(err, gofiler.ErrNotFound) && !errors.Is(err, gofiler.ErrBadParameter) {
+		return nil, err
+	}
+
+	// Return a directory in the backend
+	iterator := &schema.ObjectListIterator{Path: types.Ptr(key)}
+	if errors.Is(err, gofiler.ErrNotFound) {
+		iterator.Recursive = true
+	} else {
+		iterator.Type = types.Ptr(schema.ContentTypeDirectory)
+	}
+	if err := fs.manager.IterateObjects(ctx, volume, iterator); err == nil || errors.Is(err, io.EOF) {
+		if iterator.Type != nil || len(iterator.Body) > 0 {
+			fs.release(path.Join(volume, key), false)
+			return &webdavFileInfo{name: path.Base(key)}, nil
+		}
+	} else if !webdavNotExist(err) {
+		return nil, err
+	}
+
+	// Return a directory held in memory
+	fs.Lock()
+	defer fs.Unlock()
+	if created, exists := fs.dirs[path.Join(volume, key)]; exists {
+		return &webdavFileInfo{name: path.Base(key), modtime: created}, nil
+	}
+
+	// Not found
+	return nil, gofiler.ErrNotFound.Withf("object not found: %q", key)
+}
+
+// readdir returns the volumes, or the directories and objects in a directory
+func (fs *webdavFS) readdir(ctx context.Context, volume, key string) ([]os.FileInfo, error) {
+	entries := make(map[string]os.FileInfo)
+	if volume == "" {
+		var offset uint64
+		for {
+			volumes, err := fs.manager.ListVolumes(ctx, schema.VolumeListRequest{
+				Enabled: types.Ptr(true),
+				OffsetLimit: pg.OffsetLimit{
+					Offset: offset,
+				},
+			})
+			if err != nil {
+				return nil, err
+			} else if len(volumes.Body) == 0 {
+				break
+			}
+			for _, v := range volumes.Body {
+				entries[v.Name] = &webdavFileInfo{name: v.Name, modtime: v.CreatedAt}
+			}
+			offset += uint64(len(volumes.Body))
+		}
+	} else {
+		var dir *string
+		if key != "" {
+			dir = types.Ptr(key)
+		}
+
+		// Backends return directories only when asked for them, so list the
+		// directories and then the objects
+		for _, typ := range []*string{types.Ptr(schema.ContentTypeDirectory), nil} {
+			iterator := &schema.ObjectListIterator{Path: dir, Type: typ}
+			for {
+				err := fs.manager.IterateObjects(ctx, volume, iterator)
+				if err != nil && !errors.Is(err, io.EOF) {
+					return nil, err
+				}
+				for _, object := range iterator.Body {
+					name := path.Base(object.Path)
+					if object.ContentType == schema.ContentTypeDirectory {
+						entries[name] = &webdavFileInfo{name: name, modtime: object.ModTime}
+					} else {
+						entries[name] = &webdavFileInfo{name: name, modtime: object.ModTime, object: object}
+					}
+				}
+				if err != nil {
+					break
+				}
+			}
+		}
+
+		// Add collections held in memory
+		fs.Lock()
+		for dir, created := range fs.dirs {
+			if parent, name := path.Split(dir); strings.TrimSuffix(parent, "/") == path.Join(volume, key) {
+				if _, exists := entries[name]; !exists {
+					entries[name] = &webdavFileInfo{name: name, modtime: created}
+				}
+			}
+		}
+		fs.Unlock()
+	}
+
+	// Return entries sorted by name
+	result := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	slices.SortFunc(result, func(a, b os.FileInfo) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return result, nil
+}
+
+// create opens an object for writing, where the content is written when the
+// file is closed
+func (fs *webdavFS) create(ctx context.Context, volume, key string, flag int) (*webdavWriter, error) {
+	if key == "" || flag&os.O_APPEND != 0 {
+		return nil, os.ErrPermission
+	}
+
+	// Check the parent is a directory, and the object is not
+	if parent, err := fs.stat(ctx, volume, webdavParent(key)); err != nil {
+		return nil, err
+	} else if !parent.IsDir() {
+		return nil, os.ErrNotExist
+	}
+	if info, err := fs.stat(ctx, volume, key); err == nil && (info.IsDir() || flag&os.O_EXCL != 0) {
+		return nil, os.ErrExist
+	} else if err != nil && !webdavNotExist(err) {
+		return nil, err
+	}
+
+	// Create the object from the pipe
+	reader, writer := io.Pipe()
+	w := &webdavWriter{
+		name: path.Join("/", volume, key),
+		pipe: writer,
+		body: webdavBodyFromContext(ctx),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		w.object, w.err = fs.manager.CreateObject(ctx, schema.CreateObjectRequest{
+			ObjectKey: schema.ObjectKey{
+				Volume: volume,
+				Path:   key,
+			},
+			Body: reader,
+			ObjectMeta: schema.ObjectMeta{
+				ContentType: mime.TypeByExtension(path.Ext(key)),
+			},
+		})
+		if w.err == nil && w.object == nil {
+			w.err = gofiler.ErrInternalServerError.Withf("object not created: %q", key)
+		}
+		if w.err != nil {
+			reader.CloseWithError(w.err)
+		} else {
+			reader.Close()
+			fs.release(path.Join(volume, webdavParent(key)), false)
+		}
+	}()
+
+	// Return the writer
+	return w, nil
+}
+
+// release removes a collection held in memory, together with the
+// collections under it when it is deleted, or the collections above it when
+// it exists in the backend. It returns true if any were removed.
+func (fs *webdavFS) release(name string, descendants bool) bool {
+	fs.Lock()
+	defer fs.Unlock()
+	var removed bool
+	for dir := range fs.dirs {
+		if descendants && strings.HasPrefix(dir+"/", name+"/") || !descendants && strings.HasPrefix(name+"/", dir+"/") {
+			delete(fs.dirs, dir)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// webdavSplit returns the volume and the object path for a WebDAV path
+func webdavSplit(name string) (string, string) {
+	volume, key, _ := strings.Cut(strings.Trim(path.Clean("/"+name), "/"), "/")
+	return volume, key
+}
+
+// webdavParent returns the parent of an object path, or an empty string for
+// the volume root
+func webdavParent(key string) string {
+	if parent := path.Dir(key); parent != "." {
+		return parent
+	}
+	return ""
+}
+
+// webdavNotExist returns true if the error is a missing volume, directory or
+// object
+func webdavNotExist(err error) bool {
+	return errors.Is(err, os.ErrNotExist) || errors.Is(err, gofiler.ErrNotFound) || errors.Is(err, pg.ErrNotFound)
+}
+
+// webdavError returns a path error, which wraps the os errors the WebDAV
+// handler uses to choose a status code
+func webdavError(op, name string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case webdavNotExist(err):
+		err = os.ErrNotExist
+	case errors.Is(err, gofiler.ErrConflict):
+		err = os.ErrExist
+	case errors.Is(err, gofiler.ErrForbidden):
+		err = os.ErrPermission
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return err
+	}
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	backend "github.com/mutablelogic/go-filer/backend"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// archiveWriter writes objects into an archive
+type archiveWriter interface {
+	io.Closer
+
+	// Create an entry in the archive for an object, and return a writer for the content
+	Create(name string, object *schema.Object) (io.Writer, error)
+}
+
+type zipArchive struct {
+	*zip.Writer
+}
+
+type tarArchive struct {
+	tar *tar.Writer
+	gz  *gzip.Writer
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// WriteArchive streams all objects under the path prefix into an archive,
+// optionally filtered by content type. Nothing is written to the writer
+// until the first object is found, so errors returned before then can be
+// reported to the caller. Returns ErrNotFound if no objects match.
+func (manager *Manager) WriteArchive(ctx context.Context, req schema.ArchiveRequest, w io.Writer) (err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "WriteArchive",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the format
+	contentType := req.ContentType()
+	if contentType == "" {
+		return gofiler.ErrBadParameter.Withf("unsupported archive format: %q", req.Format)
+	}
+
+	// Get the mounted volume and backend
+	_, backend, err := manager.mountedVolume(ctx, req.Volume)
+	if err != nil {
+		return err
+	}
+
+	// Iterate through the objects under the prefix, and write them into the archive
+	var archive archiveWriter
+	prefix := strings.Trim(req.Path, "/")
+	iterator := &schema.ObjectListIterator{
+		Recursive: true,
+	}
+	if prefix != "" {
+		iterator.Path = types.Ptr(prefix)
+	}
+	for {
+		err := backend.ListObjects(ctx, iterator)
+		if err != nil && !errors.Is(err, io.EOF) {
+			if archive != nil {
+				return errors.Join(err, archive.Close())
+			}
+			return err
+		}
+		for _, object := range iterator.Body {
+			if object.ContentType == schema.ContentTypeDirectory || !schema.MatchContentType(types.Value(req.Type), object.ContentType) {
+				continue
+			}
+			if archive == nil {
+				archive = newArchive(contentType, w)
+			}
+			if err := archiveObject(ctx, backend, archive, prefix, object.ObjectKey); err != nil {
+				return errors.Join(err, archive.Close())
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	// Return not found if there were no objects
+	if archive == nil {
+		return gofiler.ErrNotFound.Withf("no objects found under %q", req.Path)
+	}
+
+	// Write the end of the archive
+	return archive.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func newArchive(contentType string, w io.Writer) archiveWriter {
+	if contentType == schema.ContentTypeGzip {
+		gz := gzip.NewWriter(w)
+		return &tarArchive{tar: tar.NewWriter(gz), gz: gz}
+	}
+	return &zipArchive{zip.NewWriter(w)}
+}
+
+// archiveObject reads an object from the backend and writes it into the
+// archive, with the name relative to the prefix. Objects which have been
+// removed since they were listed are skipped.
+func archiveObject(ctx context.Context, backend backend.Backend, archive archiveWriter, prefix string, key schema.ObjectKey) error {
+	reader, object, err := backend.ReadObject(ctx, schema.GetObjectRequest{ObjectKey: key})
+	if errors.Is(err, gofiler.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// Create the entry and copy the content, which is copied until the end
+	// when the size is unknown
+	name := strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(object.Path, "/"), prefix), "/")
+	w, err := archive.Create(name, object)
+	if err != nil {
+		return err
+	}
+	if object.Size < 0 {
+		_, err = io.Copy(w, reader)
+	} else {
+		_, err = io.CopyN(w, reader, object.Size)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return success
+	return nil
+}
+
+func (a *zipArchive) Create(name string, object *schema.Object) (io.Writer, error) {
+	return a.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: object.ModTime,
+	})
+}
+
+func (a *tarArchive) Create(name string, object *schema.Object) (io.Writer, error) {
+	if err := a.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     object.Size,
+		Mode:     0o644,
+		ModTime:  object.ModTime,
+		Format:   tar.FormatPAX,
+	}); err != nil {
+		return nil, err
+	}
+	return a.tar, nil
+}
+
+func (a *tarArchive) Close() error {
+	return errors.Join(a.tar.Close(), a.gz.Close())
+}
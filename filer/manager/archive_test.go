@@ -0,0 +1,112 @@
+package manager
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// HELPERS
+
+// archiveNames returns the names of the entries in an archive
+func archiveNames(t *testing.T, format string, data []byte) []string {
+	t.Helper()
+	var names []string
+	if format == schema.ArchiveFormatTarGz {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive := tar.NewReader(gz)
+		for {
+			header, err := archive.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, header.Name)
+		}
+	} else {
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range archive.File {
+			names = append(names, file.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// ARCHIVE
+
+func TestWriteArchive_001(t *testing.T) {
+	ctx := context.Background()
+	manager := testManager(t)
+	testVolume(t, manager, "test", schema.VolumeMeta{})
+
+	// The content type of files is detected from the content
+	for path, content := range map[string]string{
+		"a/1.txt":   "text",
+		"a/2.jpg":   "\xff\xd8\xff\xe0",
+		"a/b/3.png": "\x89PNG\r\n\x1a\n",
+		"a2/4.txt":  "text",
+	} {
+		if _, err := manager.CreateObject(ctx, schema.CreateObjectRequest{
+			ObjectKey: schema.ObjectKey{Volume: "test", Path: path},
+			Body:      strings.NewReader(content),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Entries are named relative to the prefix, and filtered by content type
+	tests := []struct {
+		name   string
+		path   string
+		format string
+		filter string
+		want   []string
+		err    error
+	}{
+		{"prefix", "a", schema.ArchiveFormatZip, "", []string{"1.txt", "2.jpg", "b/3.png"}, nil},
+		{"prefix tar", "/a/", schema.ArchiveFormatTarGz, "", []string{"1.txt", "2.jpg", "b/3.png"}, nil},
+		{"major type", "a", schema.ArchiveFormatZip, "image", []string{"2.jpg", "b/3.png"}, nil},
+		{"minor type", "a", schema.ArchiveFormatTarGz, "png", []string{"b/3.png"}, nil},
+		{"full type", "/", schema.ArchiveFormatZip, "text/plain", []string{"a/1.txt", "a2/4.txt"}, nil},
+		{"no match", "a", schema.ArchiveFormatZip, "video", nil, gofiler.ErrNotFound},
+		{"no objects", "c", schema.ArchiveFormatZip, "", nil, gofiler.ErrNotFound},
+		{"unsupported format", "a", "rar", "", nil, gofiler.ErrBadParameter},
+	}
+	for _, test := range tests {
+		req := schema.ArchiveRequest{ObjectKey: schema.ObjectKey{Volume: "test", Path: test.path}, Format: test.format}
+		if test.filter != "" {
+			req.Type = types.Ptr(test.filter)
+		}
+		var data bytes.Buffer
+		err := manager.WriteArchive(ctx, req, &data)
+		checkErr(t, test.name, err, test.err)
+		if err != nil {
+			continue
+		}
+		if got := archiveNames(t, test.format, data.Bytes()); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package manager
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	// Packages
 	otel "github.com/mutablelogic/go-client/pkg/otel"
@@ -21,6 +23,13 @@ func (manager *Manager) CreateArtwork(ctx context.Context, req schema.ArtworkMet
 	)
 	defer func() { endSpan(err) }()
 
+	// Check access to the volume of the linked object
+	if object != nil {
+		if err := checkVolumeAccess(ctx, object.Volume); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create the artwork in a transaction
 	var result schema.Artwork
 	if err := manager.PoolConn.Tx(ctx, func(conn pg.Conn) error {
@@ -30,8 +39,7 @@ func (manager *Manager) CreateArtwork(ctx context.Context, req schema.ArtworkMet
 		if object == nil {
 			return nil
 		}
-		var link schema.ObjectArtwork
-		return conn.Insert(ctx, &link, schema.ObjectArtwork{
+		return conn.Insert(ctx, nil, schema.ObjectArtwork{
 			ObjectKey:  *object,
 			ArtworkKey: result.ETag,
 		})
@@ -50,12 +58,18 @@ func (manager *Manager) LinkArtwork(ctx context.Context, object schema.ObjectKey
 	)
 	defer func() { endSpan(err) }()
 
-	var result schema.ObjectArtwork
+	// Check access to the volume of the object
+	if err := checkVolumeAccess(ctx, object.Volume); err != nil {
+		return nil, err
+	}
+
+	// Linking is idempotent, so an existing link is not an error
+	result := schema.ObjectArtwork{
+		ObjectKey:  object,
+		ArtworkKey: artwork,
+	}
 	if err := manager.PoolConn.Tx(ctx, func(conn pg.Conn) error {
-		return conn.Insert(ctx, &result, schema.ObjectArtwork{
-			ObjectKey:  object,
-			ArtworkKey: artwork,
-		})
+		return conn.Insert(ctx, nil, result)
 	}); err != nil {
 		return nil, pg.NormalizeError(err)
 	}
@@ -69,6 +83,11 @@ func (manager *Manager) UnlinkArtwork(ctx context.Context, object schema.ObjectK
 	)
 	defer func() { endSpan(err) }()
 
+	// Check access to the volume of the object
+	if err := checkVolumeAccess(ctx, object.Volume); err != nil {
+		return nil, err
+	}
+
 	var result schema.ObjectArtwork
 	if err := manager.PoolConn.Tx(ctx, func(conn pg.Conn) error {
 		return conn.Delete(ctx, &result, schema.ObjectArtwork{
@@ -111,3 +130,68 @@ func (manager *Manager) GetArtwork(ctx context.Context, req schema.GetArtworkReq
 	// Return the artwork
 	return types.Ptr(result), nil
 }
+
+// ListArtwork returns a paginated list of artwork metadata, excluding the
+// artwork data
+func (manager *Manager) ListArtwork(ctx context.Context, req schema.ArtworkListRequest) (_ *schema.ArtworkList, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ListArtwork",
+		attribute.String("req", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	var result schema.ArtworkList
+	if err := manager.PoolConn.List(ctx, &result, &req); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else {
+		result.ArtworkListRequest = req
+		result.OffsetLimit.Clamp(uint64(result.Count))
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// DeleteArtwork removes artwork, its links to objects and its renditions,
+// and returns the artwork metadata
+func (manager *Manager) DeleteArtwork(ctx context.Context, key schema.ArtworkKey) (_ *schema.ArtworkInfo, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "DeleteArtwork",
+		attribute.String("key", string(key)),
+	)
+	defer func() { endSpan(err) }()
+
+	var result schema.Artwork
+	if err := manager.PoolConn.Delete(ctx, &result, key); err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Return the artwork metadata
+	return &schema.ArtworkInfo{
+		ETag:      result.ETag,
+		Type:      result.Type,
+		Width:     result.Width,
+		Height:    result.Height,
+		CreatedAt: result.CreatedAt,
+	}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// gcArtwork removes artwork which is not linked to any object and was
+// created before the grace period, and returns the number removed
+func (manager *Manager) gcArtwork(ctx context.Context, grace time.Duration) (_ int, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "gcArtwork",
+		attribute.String("grace", grace.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	var keys schema.ArtworkKeys
+	if err := manager.PoolConn.Delete(ctx, &keys, schema.ArtworkGC{Grace: grace}); errors.Is(err, pg.ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, pg.NormalizeError(err)
+	}
+
+	// Return the number of artwork removed
+	return len(keys), nil
+}
@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GARBAGE COLLECTION
+
+func TestArtworkGC_001(t *testing.T) {
+	ctx := context.Background()
+	manager := testManager(t)
+	testVolume(t, manager, "test", schema.VolumeMeta{})
+	linked := testObject(t, manager, "test", "linked.txt")
+	deleted := testObject(t, manager, "test", "deleted.txt")
+
+	// Create artwork which is linked to an object, which is linked to an
+	// object which is then deleted, and which is not linked
+	artwork := func(data string, object *schema.Object) schema.ArtworkKey {
+		var key *schema.ObjectKey
+		if object != nil {
+			key = types.Ptr(object.ObjectKey)
+		}
+		result, err := manager.CreateArtwork(ctx, schema.ArtworkMeta{Data: []byte(data), Type: "image/png", Width: 1, Height: 1}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.ETag
+	}
+	keys := map[string]schema.ArtworkKey{
+		"linked":   artwork("linked", linked),
+		"orphaned": artwork("orphaned", deleted),
+		"unlinked": artwork("unlinked", nil),
+	}
+	if _, err := manager.DeleteObjects(ctx, schema.DeleteObjectsRequest{ObjectKey: deleted.ObjectKey}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Artwork is removed once it is unlinked and older than the grace period
+	tests := []struct {
+		name    string
+		grace   time.Duration
+		removed int
+		remain  []string
+	}{
+		{"within grace period", time.Hour, 0, []string{"linked", "orphaned", "unlinked"}},
+		{"after grace period", 0, 2, []string{"linked"}},
+		{"again", 0, 0, []string{"linked"}},
+	}
+	for _, test := range tests {
+		removed, err := manager.gcArtwork(ctx, test.grace)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		} else if removed != test.removed {
+			t.Errorf("%s: got %d removed, want %d", test.name, removed, test.removed)
+		}
+		for name, key := range keys {
+			var artwork schema.Artwork
+			err := manager.PoolConn.Get(ctx, &artwork, key)
+			if err != nil && !errors.Is(err, pg.ErrNotFound) {
+				t.Fatal(err)
+			}
+			want := false
+			for _, remain := range test.remain {
+				want = want || remain == name
+			}
+			if exists := err == nil; exists != want {
+				t.Errorf("%s: %s artwork: got exists=%v, want %v", test.name, name, exists, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type auditContextKey int
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	auditRequestIDKey auditContextKey = iota
+	auditDisabledKey
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// WithRequestID returns a context with a request identifier, which is
+// recorded with any audit entries written for the request
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, auditRequestIDKey, id)
+}
+
+// ListAudit returns a paginated list of audit entries, most recent first.
+// Tokens restricted to volumes cannot read the audit log.
+func (manager *Manager) ListAudit(ctx context.Context, req schema.AuditListRequest) (_ *schema.AuditList, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ListAudit",
+		attribute.String("req", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check access
+	if grantedVolumes(ctx) != nil {
+		return nil, gofiler.ErrForbidden.With("token is restricted to volumes and cannot read the audit log")
+	}
+
+	var result schema.AuditList
+	if err := manager.PoolConn.List(ctx, &result, &req); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else {
+		result.AuditListRequest = req
+		result.OffsetLimit.Clamp(result.Count)
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// audit writes an entry to the audit log using conn, so that the entry is
+// written in the same transaction as the mutation. The actor and request
+// identifier are taken from the context. Nothing is written when auditing
+// has been disabled by an enclosing operation.
+func (manager *Manager) audit(ctx context.Context, conn pg.Conn, operation, target string, before, after any) error {
+	if disabled, _ := ctx.Value(auditDisabledKey).(bool); disabled {
+		return nil
+	}
+	entry, err := schema.NewAudit(operation, target, before, after)
+	if err != nil {
+		return err
+	} else {
+		entry.Actor = auditActor(ctx)
+		entry.RequestID, _ = ctx.Value(auditRequestIDKey).(string)
+	}
+	return conn.Insert(ctx, nil, entry)
+}
+
+// withoutAudit returns a context in which mutations are not audited, for
+// operations such as copy and move which record a single entry for the
+// mutations they are composed of
+func withoutAudit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditDisabledKey, true)
+}
+
+// auditActor returns the actor for the audit log, which is the token
+// authenticated in the context, or empty when the server has no authentication
+func auditActor(ctx context.Context) string {
+	if key := keyFromContext(ctx); key != nil && key.Name != "" {
+		return key.Name
+	}
+	if user := userFromContext(ctx); user != nil {
+		return user.Name
+	}
+	return ""
+}
+
+// pruneAudit removes audit entries older than the retention period, and
+// returns the number of entries removed
+func (manager *Manager) pruneAudit(ctx context.Context, retention time.Duration) (_ uint64, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "pruneAudit",
+		attribute.String("retention", retention.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	var result schema.AuditPruned
+	if err := manager.PoolConn.Delete(ctx, &result, schema.AuditPrune{Retention: retention}); err != nil {
+		return 0, pg.NormalizeError(err)
+	}
+
+	// Return success
+	return uint64(result), nil
+}
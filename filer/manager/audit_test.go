@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// APPEND ONLY
+
+func TestAuditAppendOnly_001(t *testing.T) {
+	ctx := context.Background()
+	manager := testManager(t)
+	testVolume(t, manager, "test", schema.VolumeMeta{})
+	testObject(t, manager, "test", "a.txt")
+
+	// Entries are recorded for the volume and the object
+	if list, err := manager.ListAudit(ctx, schema.AuditListRequest{}); err != nil {
+		t.Fatal(err)
+	} else if list.Count != 2 {
+		t.Fatalf("got %d entries, want 2", list.Count)
+	}
+
+	// Entries cannot be changed
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"update all", `UPDATE ${"schema"}."audit" SET "operation" = 'volume.delete'`},
+		{"update one", `UPDATE ${"schema"}."audit" SET "before" = NULL, "after" = NULL WHERE "id" = (SELECT MIN("id") FROM ${"schema"}."audit")`},
+		{"update actor", `UPDATE ${"schema"}."audit" SET "actor" = 'admin'`},
+	}
+	for _, test := range tests {
+		if err := manager.PoolConn.Exec(ctx, test.query); err == nil {
+			t.Errorf("%s: expected the update to be rejected", test.name)
+		}
+	}
+}
+
+func TestAuditPrune_001(t *testing.T) {
+	ctx := context.Background()
+	manager := testManager(t)
+	testVolume(t, manager, "test", schema.VolumeMeta{})
+	testObject(t, manager, "test", "a.txt")
+
+	// Entries are removed after the retention period
+	tests := []struct {
+		name      string
+		retention time.Duration
+		removed   uint64
+		remain    uint64
+	}{
+		{"within retention", time.Hour, 0, 2},
+		{"after retention", 0, 2, 0},
+		{"again", 0, 0, 0},
+	}
+	for _, test := range tests {
+		removed, err := manager.pruneAudit(ctx, test.retention)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		} else if removed != test.removed {
+			t.Errorf("%s: got %d removed, want %d", test.name, removed, test.removed)
+		}
+		if list, err := manager.ListAudit(ctx, schema.AuditListRequest{}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		} else if list.Count != test.remain {
+			t.Errorf("%s: got %d entries, want %d", test.name, list.Count, test.remain)
+		}
+	}
+}
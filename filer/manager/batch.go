@@ -0,0 +1,264 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	pgqueueschema "github.com/mutablelogic/go-pg/pgqueue/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type batchTask struct {
+	ID uint64 `json:"id"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Batch runs a list of operations in order, and returns a result for each
+// operation. Unless Continue is set, the batch is aborted on the first
+// error. Large batches, or batches with Async set, are run as a background
+// job, and the batch is returned with an identifier to poll for progress.
+func (manager *Manager) Batch(ctx context.Context, req schema.BatchRequest) (_ *schema.Batch, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "Batch",
+		attribute.Int("operations", len(req.Operations)),
+		attribute.Bool("continue", req.Continue),
+		attribute.Bool("async", req.Async),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the operations, and that their volumes are accessible
+	if err := req.Validate(); err != nil {
+		return nil, err
+	} else if err := checkBatchAccess(ctx, req.Operations); err != nil {
+		return nil, err
+	}
+
+	// Run small batches now
+	if !req.Async && len(req.Operations) <= schema.BatchSyncLimit {
+		batch := &schema.Batch{
+			Status:    schema.BatchStatusCompleted,
+			Continue:  req.Continue,
+			Total:     len(req.Operations),
+			CreatedAt: time.Now(),
+		}
+		for i, op := range req.Operations {
+			result := manager.batchOperation(ctx, i, op)
+			batch.Results = append(batch.Results, result)
+			batch.Completed++
+			if result.Error != "" {
+				batch.Failed++
+				if !req.Continue {
+					batch.Status = schema.BatchStatusAborted
+					break
+				}
+			}
+		}
+		batch.FinishedAt = types.Ptr(time.Now())
+		return batch, nil
+	}
+
+	// Create the batch, and then the job which runs it as the token
+	if manager.batchQueue == nil {
+		return nil, gofiler.ErrServiceUnavailable.With("batch queue not available")
+	}
+	req.Token = tokenFromContext(ctx)
+	var batch schema.Batch
+	if err := manager.PoolConn.Insert(ctx, &batch, req); err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+	payload, err := json.Marshal(batchTask{ID: batch.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch task: %w", err)
+	}
+	if _, err := manager.queue.CreateTask(ctx, manager.batchQueue.Queue, pgqueueschema.TaskMeta{
+		Payload: payload,
+	}); err != nil {
+		_, abortErr := manager.setBatchStatus(ctx, schema.BatchKey{ID: batch.ID}, schema.BatchStatusAborted)
+		return nil, errors.Join(err, abortErr)
+	}
+
+	// Return the pending batch
+	return types.Ptr(batch), nil
+}
+
+// GetBatch returns the progress and results of a batch which runs as a
+// background job. The volumes of all the operations must be accessible.
+func (manager *Manager) GetBatch(ctx context.Context, req schema.BatchKey) (_ *schema.Batch, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "GetBatch",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	var batch schema.Batch
+	var operations schema.BatchOperations
+	if err := manager.PoolConn.Get(ctx, &batch, req); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else if err := manager.PoolConn.With("operations", true).Get(ctx, &operations, req); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else if err := checkBatchAccess(ctx, operations); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return types.Ptr(batch), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// runBatch runs the operations of a batch which do not yet have a result, so
+// a retried job continues from where it stopped
+func (manager *Manager) runBatch(ctx context.Context, id uint64) (_ *schema.Batch, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "runBatch",
+		attribute.Int64("id", int64(id)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Get the batch and its operations
+	key := schema.BatchKey{ID: id}
+	var batch schema.Batch
+	var operations schema.BatchOperations
+	if err := manager.PoolConn.Get(ctx, &batch, key); err != nil {
+		return nil, err
+	} else if batch.Status == schema.BatchStatusCompleted || batch.Status == schema.BatchStatusAborted {
+		return types.Ptr(batch), nil
+	} else if err := manager.PoolConn.With("operations", true).Get(ctx, &operations, key); err != nil {
+		return nil, err
+	}
+
+	// Run as the token which created the batch, and abort the batch when
+	// the token has since been deleted or has expired
+	if batch.Token != nil {
+		var token schema.Token
+		if err := manager.PoolConn.Get(ctx, &token, *batch.Token); errors.Is(err, pg.ErrNotFound) {
+			return manager.setBatchStatus(ctx, key, schema.BatchStatusAborted)
+		} else if err != nil {
+			return nil, err
+		} else if token.ExpiresAt != nil && !token.ExpiresAt.After(time.Now()) {
+			return manager.setBatchStatus(ctx, key, schema.BatchStatusAborted)
+		}
+		user, principal := tokenPrincipal(token)
+		ctx = WithPrincipal(ctx, user, principal)
+	}
+
+	// Determine the operations which have already run
+	done := make(map[int]bool, len(batch.Results))
+	for _, result := range batch.Results {
+		if result.Error != "" && !batch.Continue {
+			return manager.setBatchStatus(ctx, key, schema.BatchStatusAborted)
+		}
+		done[result.Index] = true
+	}
+	if _, err := manager.setBatchStatus(ctx, key, schema.BatchStatusRunning); err != nil {
+		return nil, err
+	}
+
+	// Run the remaining operations, recording each result
+	for i, op := range operations {
+		if done[i] {
+			continue
+		} else if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result := manager.batchOperation(ctx, i, op)
+		if err := manager.PoolConn.With("id", id).Insert(ctx, nil, result); err != nil {
+			return nil, err
+		}
+		if result.Error != "" && !batch.Continue {
+			return manager.setBatchStatus(ctx, key, schema.BatchStatusAborted)
+		}
+	}
+
+	// Return the completed batch
+	return manager.setBatchStatus(ctx, key, schema.BatchStatusCompleted)
+}
+
+// pruneBatches removes batches which finished before the retention period,
+// and returns the number of batches removed
+func (manager *Manager) pruneBatches(ctx context.Context, retention time.Duration) (_ uint64, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "pruneBatches",
+		attribute.String("retention", retention.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	var result schema.BatchPruned
+	if err := manager.PoolConn.Delete(ctx, &result, schema.BatchPrune{Retention: retention}); err != nil {
+		return 0, pg.NormalizeError(err)
+	}
+
+	// Return success
+	return uint64(result), nil
+}
+
+// setBatchStatus sets the status of a batch, and returns the batch without
+// results
+func (manager *Manager) setBatchStatus(ctx context.Context, key schema.BatchKey, status string) (*schema.Batch, error) {
+	var batch schema.Batch
+	if err := manager.PoolConn.Update(ctx, &batch, key, schema.BatchStatus(status)); err != nil {
+		return nil, err
+	}
+	return types.Ptr(batch), nil
+}
+
+// checkBatchAccess returns an error when the volume of an operation, or the
+// volume it copies or moves to, is not accessible
+func checkBatchAccess(ctx context.Context, operations []schema.BatchOperation) error {
+	for _, op := range operations {
+		if err := checkVolumeAccess(ctx, op.Volume); err != nil {
+			return err
+		} else if op.Dest == nil {
+			continue
+		} else if err := checkVolumeAccess(ctx, op.Dest.Volume); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchOperation runs a single operation through the manager, so each
+// operation behaves as the equivalent API call
+func (manager *Manager) batchOperation(ctx context.Context, index int, op schema.BatchOperation) schema.BatchResult {
+	result := schema.BatchResult{
+		Index:     index,
+		Op:        op.Op,
+		ObjectKey: op.ObjectKey,
+	}
+
+	var err error
+	switch op.Op {
+	case schema.BatchOpDelete:
+		_, err = manager.DeleteObjects(ctx, schema.DeleteObjectsRequest{
+			ObjectKey: op.ObjectKey,
+			Recursive: op.Recursive,
+		})
+	case schema.BatchOpCopy:
+		_, err = manager.CopyObject(ctx, op.ObjectKey, types.Value(op.Dest))
+	case schema.BatchOpMove:
+		_, err = manager.MoveObject(ctx, op.ObjectKey, types.Value(op.Dest))
+	case schema.BatchOpPatch:
+		_, err = manager.PatchObject(ctx, op.ObjectKey, op.Meta)
+	case schema.BatchOpReindex:
+		err = manager.ReindexObject(ctx, op.ObjectKey)
+	default:
+		err = gofiler.ErrBadParameter.Withf("invalid operation: %q", op.Op)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	// Return the result
+	return result
+}
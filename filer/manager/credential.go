@@ -3,8 +3,11 @@ package manager
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
 
 	// Packages
+	authschema "github.com/mutablelogic/go-auth/auth/schema"
 	otel "github.com/mutablelogic/go-client/pkg/otel"
 	gofiler "github.com/mutablelogic/go-filer"
 	schema "github.com/mutablelogic/go-filer/filer/schema"
@@ -43,6 +46,12 @@ func (m *Manager) CreateCredential(ctx context.Context, req schema.CredentialCre
 	)
 	defer func() { endSpan(err) }()
 
+	// An S3 access key can only be bound to a token with no more access than
+	// the token creating it
+	if err := m.checkS3AccessKey(ctx, req.Credentials); err != nil {
+		return nil, err
+	}
+
 	// Encrypt the credential data
 	pv, credentials, err := m.encryptCredentials(req.Credentials)
 	if err != nil {
@@ -54,7 +63,10 @@ func (m *Manager) CreateCredential(ctx context.Context, req schema.CredentialCre
 	// Insert the credential record
 	var result schema.Credential
 	if err := m.PoolConn.Tx(ctx, func(conn pg.Conn) error {
-		return conn.With("pv", pv).Insert(ctx, &result, req)
+		if err := conn.With("pv", pv).Insert(ctx, &result, req); err != nil {
+			return err
+		}
+		return m.audit(ctx, conn, schema.AuditCredentialSet, schema.AuditCredentialTarget(req.CredentialKey), nil, &result)
 	}); err != nil {
 		return nil, pg.NormalizeError(err)
 	}
@@ -72,7 +84,10 @@ func (m *Manager) DeleteCredential(ctx context.Context, key schema.CredentialKey
 
 	var result schema.Credential
 	if err := m.PoolConn.Tx(ctx, func(conn pg.Conn) error {
-		return conn.Delete(ctx, &result, key)
+		if err := conn.Delete(ctx, &result, key); err != nil {
+			return err
+		}
+		return m.audit(ctx, conn, schema.AuditCredentialDelete, schema.AuditCredentialTarget(key), &result, nil)
 	}); err != nil {
 		return nil, pg.NormalizeError(err)
 	}
@@ -117,6 +132,40 @@ func (m *Manager) GetCredential(ctx context.Context, key schema.CredentialKey, p
 	return credentials, nil
 }
 
+// SecretAccessKey returns the secret of an S3 access key, and the principal
+// of the token which the key is bound to. The key is a credential created as
+// an S3 access key, keyed by the access key id, so other credentials are not
+// accepted, and the key cannot be used once the token expires or is deleted.
+func (m *Manager) SecretAccessKey(ctx context.Context, key schema.CredentialKey) (_ string, _ *authschema.UserInfo, _ *authschema.Key, err error) {
+	ctx, endSpan := otel.StartSpan(m.tracer, ctx, "SecretAccessKey",
+		attribute.String("key", key.Key),
+	)
+	defer func() { endSpan(err) }()
+
+	var result schema.S3AccessKey
+	if credentials, err := m.getCredential(ctx, key); err != nil {
+		return "", nil, nil, err
+	} else if err := json.Unmarshal(credentials, &result); err != nil {
+		return "", nil, nil, gofiler.ErrForbidden.Withf("credential %q is not an S3 access key", key.Key)
+	} else if err := result.Validate(); err != nil {
+		return "", nil, nil, gofiler.ErrForbidden.Withf("credential %q is not an S3 access key: %v", key.Key, err)
+	}
+
+	// Get the token the key is bound to
+	var token schema.Token
+	if err := m.PoolConn.Get(ctx, &token, result.Token); errors.Is(err, pg.ErrNotFound) {
+		return "", nil, nil, gofiler.ErrForbidden.Withf("token for S3 access key %q has been deleted", key.Key)
+	} else if err != nil {
+		return "", nil, nil, pg.NormalizeError(err)
+	} else if token.ExpiresAt != nil && !token.ExpiresAt.After(time.Now()) {
+		return "", nil, nil, gofiler.ErrForbidden.Withf("token for S3 access key %q has expired", key.Key)
+	}
+
+	// Return success
+	user, principal := tokenPrincipal(token)
+	return result.Secret, user, principal, nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
@@ -142,6 +191,28 @@ func (m *Manager) getCredential(ctx context.Context, key schema.CredentialKey) (
 	return credentials, nil
 }
 
+// checkS3AccessKey returns an error when a credential is an S3 access key
+// which is invalid, or which is bound to a token granting more access than
+// the token authenticated in the context
+func (m *Manager) checkS3AccessKey(ctx context.Context, v any) error {
+	var key schema.S3AccessKey
+	if data, err := json.Marshal(v); err != nil {
+		return gofiler.ErrBadParameter.With(err)
+	} else if json.Unmarshal(data, &key) != nil || key.Type != schema.CredentialTypeS3 {
+		// The credential is not an S3 access key
+		return nil
+	} else if err := key.Validate(); err != nil {
+		return err
+	}
+
+	// Check the token
+	var token schema.Token
+	if err := m.PoolConn.Get(ctx, &token, key.Token); err != nil {
+		return pg.NormalizeError(err)
+	}
+	return checkGrants(ctx, token)
+}
+
 func (m *Manager) encryptCredentials(v any) (uint64, []byte, error) {
 	// Preserve the zero-value contract for raw credential payloads.
 	switch value := v.(type) {
@@ -0,0 +1,142 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// eventHub delivers events received from the database to subscribers
+type eventHub struct {
+	sync.RWMutex
+	subscribers map[*eventSubscriber]struct{}
+}
+
+type eventSubscriber struct {
+	filter schema.EventRequest
+	ch     chan schema.Event
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// eventBufferSize is the number of events buffered for each subscriber,
+	// after which events are dropped for that subscriber
+	eventBufferSize = 100
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Events returns a channel of volume and object events which match the
+// request, until the context is cancelled, when the channel is closed.
+// Events are only delivered while the manager is running, and events are
+// dropped when the subscriber does not keep up.
+func (manager *Manager) Events(ctx context.Context, req schema.EventRequest) (_ <-chan schema.Event, err error) {
+	_, endSpan := otel.StartSpan(manager.tracer, ctx, "Events",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the volume exists. Tokens which are restricted to volumes can
+	// only subscribe to events for one of those volumes.
+	if req.Volume == "" && grantedVolumes(ctx) != nil {
+		return nil, gofiler.ErrForbidden.With("token is restricted to volumes, a volume is required")
+	} else if req.Volume != "" {
+		if _, err := manager.GetVolume(ctx, req.Volume); err != nil {
+			return nil, err
+		}
+	}
+
+	// Subscribe, and unsubscribe when the context is done
+	subscriber := manager.events.subscribe(req)
+	go func() {
+		<-ctx.Done()
+		manager.events.unsubscribe(subscriber)
+	}()
+
+	// Return success
+	return subscriber.ch, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// notifyEvents publishes events to all subscribers, through the database
+// so that subscribers of other instances also receive them
+func (manager *Manager) notifyEvents(ctx context.Context, events ...schema.Event) error {
+	return manager.notifyEventsConn(ctx, manager.PoolConn, events...)
+}
+
+// notifyEventsConn publishes events through a connection, so that events
+// raised in a transaction are only delivered when it is committed
+func (manager *Manager) notifyEventsConn(ctx context.Context, conn pg.Conn, events ...schema.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return conn.Insert(ctx, nil, schema.Events(events))
+}
+
+// publishNotification decodes a notification from the database and
+// delivers it to subscribers
+func (manager *Manager) publishNotification(notification pg.Notification) error {
+	var event schema.Event
+	if err := json.Unmarshal(notification.Payload, &event); err != nil {
+		return err
+	}
+	manager.events.publish(event)
+	return nil
+}
+
+func (hub *eventHub) subscribe(filter schema.EventRequest) *eventSubscriber {
+	hub.Lock()
+	defer hub.Unlock()
+
+	subscriber := &eventSubscriber{
+		filter: filter,
+		ch:     make(chan schema.Event, eventBufferSize),
+	}
+	if hub.subscribers == nil {
+		hub.subscribers = make(map[*eventSubscriber]struct{})
+	}
+	hub.subscribers[subscriber] = struct{}{}
+	return subscriber
+}
+
+func (hub *eventHub) unsubscribe(subscriber *eventSubscriber) {
+	hub.Lock()
+	defer hub.Unlock()
+
+	if _, exists := hub.subscribers[subscriber]; exists {
+		delete(hub.subscribers, subscriber)
+		close(subscriber.ch)
+	}
+}
+
+func (hub *eventHub) publish(event schema.Event) {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	for subscriber := range hub.subscribers {
+		if !subscriber.filter.Match(event) {
+			continue
+		}
+		select {
+		case subscriber.ch <- event:
+		default:
+			// Drop the event for a slow subscriber
+		}
+	}
+}
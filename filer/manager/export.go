@@ -0,0 +1,367 @@
+package manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	backend "github.com/mutablelogic/go-filer/backend"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ExportIndex streams the index of a volume as a gzipped tar archive, with the
+// objects, the metadata extracted from them or set by users, the linked
+// artwork and the search vectors. Nothing is written to the writer until the
+// volume is found, so errors returned before then can be reported to the
+// caller. The archive is not terminated when an error occurs later, so a
+// partial export cannot be imported.
+func (manager *Manager) ExportIndex(ctx context.Context, name string, w io.Writer) (err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ExportIndex",
+		attribute.String("name", name),
+	)
+	defer func() { endSpan(err) }()
+
+	// Get the volume
+	volume, err := manager.GetVolume(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	// Write the manifest
+	now := time.Now()
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	if data, err := json.Marshal(schema.IndexManifest{
+		Format:    schema.IndexExportFormat,
+		Version:   schema.IndexExportVersion,
+		Volume:    volume.Name,
+		CreatedAt: now,
+	}); err != nil {
+		return err
+	} else if err := writeIndexEntry(archive, schema.IndexManifestEntry, data, now); err != nil {
+		return err
+	}
+
+	// Write each page of objects, preceded by the artwork which has not been
+	// written yet
+	exported := make(map[schema.ArtworkKey]bool)
+	req := schema.IndexExportRequest{Volume: volume.Name}
+	for page := 1; ; page++ {
+		var list schema.IndexRecordList
+		if err := manager.PoolConn.List(ctx, &list, req); err != nil {
+			return pg.NormalizeError(err)
+		} else if len(list.Body) == 0 {
+			break
+		}
+		if err := manager.exportArtwork(ctx, archive, page, list.Body, exported, now); err != nil {
+			return err
+		}
+
+		// Write the objects
+		var data bytes.Buffer
+		encoder := json.NewEncoder(&data)
+		for _, record := range list.Body {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		if err := writeIndexEntry(archive, schema.IndexObjectEntryName(page), data.Bytes(), now); err != nil {
+			return err
+		}
+		req.After = list.Body[len(list.Body)-1].Path
+	}
+
+	// Write the end of the archive
+	return errors.Join(archive.Close(), gz.Close())
+}
+
+// ImportIndex reads an index export into a volume, which can have a different
+// name from the exported volume. Each object is checked against the backend,
+// and is imported when it is unchanged since the export. Objects which have
+// changed are queued for indexing, and objects which no longer exist are
+// skipped.
+func (manager *Manager) ImportIndex(ctx context.Context, name string, r io.Reader) (_ *schema.IndexImport, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ImportIndex",
+		attribute.String("name", name),
+	)
+	defer func() { endSpan(err) }()
+
+	// Get the mounted volume and backend, to check the objects
+	volume, backend, err := manager.mountedVolume(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the archive
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, gofiler.ErrBadParameter.Withf("invalid index export: %v", err)
+	}
+	defer gz.Close()
+
+	var manifest *schema.IndexManifest
+	result := schema.IndexImport{Volume: volume.Name}
+	artwork := make(map[schema.ArtworkKey]schema.ArtworkInfo)
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, gofiler.ErrBadParameter.Withf("invalid index export: %v", err)
+		} else if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// The manifest is the first entry
+		if manifest == nil {
+			if header.Name != schema.IndexManifestEntry {
+				return nil, gofiler.ErrBadParameter.With("invalid index export: missing manifest")
+			}
+			manifest = new(schema.IndexManifest)
+			if err := json.NewDecoder(archive).Decode(manifest); err != nil {
+				return nil, gofiler.ErrBadParameter.Withf("invalid index export manifest: %v", err)
+			} else if err := manifest.Validate(); err != nil {
+				return nil, err
+			}
+			result.Source = manifest.Volume
+			continue
+		}
+
+		// Read artwork metadata, artwork data and objects
+		if strings.HasPrefix(header.Name, schema.IndexArtworkData) {
+			if err := manager.importArtwork(ctx, archive, header, artwork); err != nil {
+				return nil, err
+			}
+			result.Artwork++
+		} else if ok, _ := path.Match(strings.ReplaceAll(schema.IndexArtworkEntry, "%06d", "*"), header.Name); ok {
+			if err := decodeIndexEntry(archive, header.Name, func(info *schema.ArtworkInfo) error {
+				artwork[info.ETag] = *info
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+		} else if ok, _ := path.Match(strings.ReplaceAll(schema.IndexObjectEntry, "%06d", "*"), header.Name); ok {
+			if err := decodeIndexEntry(archive, header.Name, func(record *schema.IndexRecord) error {
+				return manager.importIndexRecord(ctx, backend, volume.Name, record, &result)
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Check the archive was not empty
+	if manifest == nil {
+		return nil, gofiler.ErrBadParameter.With("invalid index export: missing manifest")
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// exportArtwork writes the metadata and data of the artwork linked to a page
+// of objects, which has not been written for a previous page. Artwork which
+// has been removed since the objects were read is unlinked from the records.
+func (manager *Manager) exportArtwork(ctx context.Context, archive *tar.Writer, page int, records []*schema.IndexRecord, exported map[schema.ArtworkKey]bool, now time.Time) error {
+	var keys []schema.ArtworkKey
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	for _, record := range records {
+		linked := record.Artwork[:0]
+		for _, key := range record.Artwork {
+			if !exported[key] {
+				var info schema.ArtworkInfo
+				if err := manager.PoolConn.Get(ctx, &info, schema.ArtworkInfoKey(key)); errors.Is(pg.NormalizeError(err), pg.ErrNotFound) {
+					continue
+				} else if err != nil {
+					return pg.NormalizeError(err)
+				} else if err := encoder.Encode(info); err != nil {
+					return err
+				}
+				exported[key] = true
+				keys = append(keys, key)
+			}
+			linked = append(linked, key)
+		}
+		record.Artwork = linked
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// Write the metadata, followed by the data of each artwork
+	if err := writeIndexEntry(archive, schema.IndexArtworkEntryName(page), data.Bytes(), now); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		var artwork schema.Artwork
+		if err := manager.PoolConn.Get(ctx, &artwork, key); err != nil {
+			return pg.NormalizeError(err)
+		} else if err := writeIndexEntry(archive, schema.IndexArtworkData+string(key), artwork.Data, artwork.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+// importArtwork inserts the artwork data from an archive entry, with the
+// metadata read from a previous entry. The key of the artwork is the hash of
+// the data, which is checked against the name of the entry.
+func (manager *Manager) importArtwork(ctx context.Context, r io.Reader, header *tar.Header, artwork map[schema.ArtworkKey]schema.ArtworkInfo) error {
+	key := schema.ArtworkKey(strings.TrimPrefix(header.Name, schema.IndexArtworkData))
+	info, exists := artwork[key]
+	if !exists {
+		return gofiler.ErrBadParameter.Withf("invalid index export: missing metadata for artwork %q", key)
+	} else if header.Size > schema.IndexArtworkMaxSize {
+		return gofiler.ErrBadParameter.Withf("invalid index export: artwork %q is too large", key)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, header.Size))
+	if err != nil {
+		return gofiler.ErrBadParameter.Withf("invalid index export: %v", err)
+	}
+
+	// Insert the artwork
+	var inserted schema.Artwork
+	if err := manager.PoolConn.Insert(ctx, &inserted, schema.ArtworkMeta{
+		Data:   data,
+		Type:   info.Type,
+		Width:  info.Width,
+		Height: info.Height,
+	}); err != nil {
+		return pg.NormalizeError(err)
+	} else if inserted.ETag != key {
+		return gofiler.ErrBadParameter.Withf("invalid index export: artwork data does not match key %q", key)
+	}
+	delete(artwork, key)
+
+	// Return success
+	return nil
+}
+
+// importIndexRecord imports an object when it is unchanged in the backend,
+// replacing the object in the index. Objects which have changed are queued
+// for indexing. The user metadata is imported for both, since it is set on
+// the path rather than extracted from the object.
+func (manager *Manager) importIndexRecord(ctx context.Context, backend backend.Backend, volume string, record *schema.IndexRecord, result *schema.IndexImport) error {
+	result.Objects++
+
+	// Check the object against the backend
+	key := schema.ObjectKey{Volume: volume, Path: record.Path}
+	object, err := backend.GetObject(ctx, schema.GetObjectRequest{ObjectKey: key})
+	if errors.Is(err, gofiler.ErrNotFound) {
+		result.Missing++
+		return nil
+	} else if err != nil {
+		return err
+	} else if !record.Matches(object) {
+		result.Changed++
+		if err := manager.Tx(ctx, func(conn pg.Conn) error {
+			return importUserMeta(ctx, conn, key, record.UserMeta)
+		}); err != nil {
+			return pg.NormalizeError(err)
+		}
+		return manager.enqueueIndexObject(ctx, key, false)
+	}
+
+	// Replace the object, metadata, user metadata, artwork links and search
+	// vector
+	if err := manager.Tx(ctx, func(conn pg.Conn) error {
+		if err := conn.Delete(ctx, nil, key); err != nil && !errors.Is(err, pg.ErrNotFound) {
+			return err
+		}
+		if err := conn.Insert(ctx, nil, record.Object(volume)); err != nil {
+			return err
+		}
+		for _, meta := range record.Meta {
+			if err := conn.With("volume", volume, "path", record.Path).Insert(ctx, nil, meta); err != nil {
+				return err
+			}
+		}
+		if err := importUserMeta(ctx, conn, key, record.UserMeta); err != nil {
+			return err
+		}
+		for _, artwork := range record.Artwork {
+			if err := conn.Insert(ctx, nil, schema.ObjectArtwork{ObjectKey: key, ArtworkKey: artwork}); err != nil {
+				return err
+			}
+		}
+		if record.Search != "" {
+			return conn.Insert(ctx, nil, schema.IndexSearch{ObjectKey: key, Search: record.Search})
+		}
+		return nil
+	}); err != nil {
+		return pg.NormalizeError(err)
+	}
+	result.Imported++
+
+	// Notify subscribers of the indexed object
+	return manager.notifyEvents(ctx, schema.Event{
+		Type:   schema.EventObjectIndex,
+		Volume: volume,
+		Path:   record.Path,
+	})
+}
+
+// importUserMeta sets the user metadata of an imported object, replacing the
+// value of keys which are already set
+func importUserMeta(ctx context.Context, conn pg.Conn, key schema.ObjectKey, meta []schema.Meta) error {
+	for _, meta := range meta {
+		if err := conn.With("volume", key.Volume, "path", key.Path).Insert(ctx, nil, schema.UserMeta(meta)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeIndexEntry writes an entry into an index export
+func writeIndexEntry(archive *tar.Writer, name string, data []byte, modtime time.Time) error {
+	if err := archive.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     int64(len(data)),
+		Mode:     0o644,
+		ModTime:  modtime,
+		Format:   tar.FormatPAX,
+	}); err != nil {
+		return err
+	}
+	_, err := archive.Write(data)
+	return err
+}
+
+// decodeIndexEntry reads newline-delimited JSON from an entry in an index
+// export, and calls a function for each value
+func decodeIndexEntry[T any](r io.Reader, name string, fn func(*T) error) error {
+	decoder := json.NewDecoder(r)
+	for {
+		var value T
+		if err := decoder.Decode(&value); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return gofiler.ErrBadParameter.Withf("invalid index export entry %q: %v", name, err)
+		} else if err := fn(&value); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	backend "github.com/mutablelogic/go-filer/backend"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// HELPERS
+
+// etagBackend is a backend which returns objects with etags
+type etagBackend struct {
+	backend.Backend
+	objects map[string]*schema.Object
+}
+
+func (b etagBackend) GetObject(_ context.Context, req schema.GetObjectRequest) (*schema.Object, error) {
+	if object, exists := b.objects[req.Path]; exists {
+		return object, nil
+	}
+	return nil, gofiler.ErrNotFound.Withf("object not found: %q", req.Path)
+}
+
+// userMeta returns the user metadata of an object as a map
+func userMeta(t *testing.T, manager *Manager, key schema.ObjectKey) map[string]string {
+	t.Helper()
+	var list schema.UserMetaList
+	if err := manager.PoolConn.List(context.Background(), &list, schema.UserMetaKey{ObjectKey: key}); err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]string, len(list))
+	for _, meta := range list {
+		result[meta.Key] = string(meta.Value)
+	}
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// ROUND TRIP
+
+func TestIndexExport_001(t *testing.T) {
+	ctx := context.Background()
+	rating := schema.Meta{Key: "rating", Value: json.RawMessage(`5`)}
+	title := schema.Meta{Key: "title", Value: json.RawMessage(`"extracted"`)}
+
+	// Index objects with extracted and user metadata, and export the index
+	source := testManager(t)
+	testVolume(t, source, "test", schema.VolumeMeta{})
+	for _, path := range []string{"same.txt", "changed.txt", "missing.txt"} {
+		object := testObject(t, source, "test", path, title)
+		if _, err := source.PatchObject(ctx, object.ObjectKey, []schema.Meta{rating}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var export bytes.Buffer
+	if err := source.ExportIndex(ctx, "test", &export); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change and remove objects after the export
+	if _, err := source.CreateObject(ctx, schema.CreateObjectRequest{
+		ObjectKey: schema.ObjectKey{Volume: "test", Path: "changed.txt"},
+		Body:      strings.NewReader("the object has changed since the export"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.DeleteObjects(ctx, schema.DeleteObjectsRequest{ObjectKey: schema.ObjectKey{Volume: "test", Path: "missing.txt"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Import the index into another database, with a volume on the same files
+	volume, err := source.GetVolume(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumeURL, err := url.Parse(volume.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := testManager(t)
+	if _, err := target.CreateVolume(ctx, volumeURL, schema.VolumeMeta{Enabled: types.Ptr(true)}); err != nil {
+		t.Fatal(err)
+	} else if _, err := target.volumes.New(ctx, volumeURL.String()); err != nil {
+		t.Fatal(err)
+	}
+	testIndexQueue(t, target)
+	result, err := target.ImportIndex(ctx, "test", &export)
+	if err != nil {
+		t.Fatal(err)
+	} else if result.Objects != 3 || result.Imported != 1 || result.Changed != 1 || result.Missing != 1 {
+		t.Errorf("got %+v, want 3 objects with 1 imported, 1 changed and 1 missing", result)
+	}
+
+	// Unchanged objects are imported with their metadata, and the user
+	// metadata of changed objects is kept for when they are indexed
+	tests := []struct {
+		path    string
+		indexed bool
+		user    bool
+	}{
+		{"same.txt", true, true},
+		{"changed.txt", false, true},
+		{"missing.txt", false, false},
+	}
+	for _, test := range tests {
+		key := schema.ObjectKey{Volume: "test", Path: test.path}
+		object := indexed(t, target, key.Volume, key.Path)
+		if (object != nil) != test.indexed {
+			t.Errorf("%q: got indexed=%v, want %v", test.path, object != nil, test.indexed)
+		} else if object != nil {
+			got := make(map[string]string, len(object.Meta))
+			for _, meta := range object.Meta {
+				got[meta.Key] = string(meta.Value)
+			}
+			if got["title"] != `"extracted"` || got["rating"] != `5` {
+				t.Errorf("%q: got metadata %v", test.path, got)
+			}
+		}
+		if got := userMeta(t, target, key)["rating"] == `5`; got != test.user {
+			t.Errorf("%q: got user metadata=%v, want %v", test.path, got, test.user)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// ETAGS
+
+func TestIndexImport_001(t *testing.T) {
+	ctx := context.Background()
+	manager := testManager(t)
+	testVolume(t, manager, "test", schema.VolumeMeta{})
+	testIndexQueue(t, manager)
+
+	// Records are imported when the object in the backend has the same etag,
+	// regardless of the modification time
+	modtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		record, object schema.ObjectAttr
+		imported       bool
+	}{
+		{"etag", schema.ObjectAttr{Size: 1, ETag: types.Ptr(`"a"`), ModTime: modtime}, schema.ObjectAttr{Size: 1, ETag: types.Ptr(`"a"`), ModTime: modtime}, true},
+		{"etag, modified", schema.ObjectAttr{Size: 1, ETag: types.Ptr(`"a"`), ModTime: modtime}, schema.ObjectAttr{Size: 1, ETag: types.Ptr(`"a"`), ModTime: modtime.Add(time.Hour)}, true},
+		{"etag mismatch", schema.ObjectAttr{Size: 1, ETag: types.Ptr(`"a"`), ModTime: modtime}, schema.ObjectAttr{Size: 1, ETag: types.Ptr(`"b"`), ModTime: modtime}, false},
+		{"size mismatch", schema.ObjectAttr{Size: 1, ETag: types.Ptr(`"a"`), ModTime: modtime}, schema.ObjectAttr{Size: 2, ETag: types.Ptr(`"a"`), ModTime: modtime}, false},
+		{"no etag", schema.ObjectAttr{Size: 1, ModTime: modtime}, schema.ObjectAttr{Size: 1, ETag: types.Ptr(`"b"`), ModTime: modtime}, true},
+	}
+	for i, test := range tests {
+		path := "object" + string(rune('a'+i))
+		backend := etagBackend{objects: map[string]*schema.Object{
+			path: {ObjectKey: schema.ObjectKey{Volume: "test", Path: path}, ObjectAttr: test.object},
+		}}
+		record := schema.IndexRecord{
+			Path:        path,
+			ContentType: "text/plain",
+			ObjectAttr:  test.record,
+			UserMeta:    []schema.Meta{{Key: "rating", Value: json.RawMessage(`5`)}},
+		}
+		var result schema.IndexImport
+		if err := manager.importIndexRecord(ctx, backend, "test", &record, &result); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if imported := result.Imported == 1; imported != test.imported {
+			t.Errorf("%s: got imported=%v, want %v", test.name, imported, test.imported)
+		} else if changed := result.Changed == 1; changed == test.imported {
+			t.Errorf("%s: got changed=%v, want %v", test.name, changed, !test.imported)
+		}
+		if got := indexed(t, manager, "test", path) != nil; got != test.imported {
+			t.Errorf("%s: got indexed=%v, want %v", test.name, got, test.imported)
+		}
+		if got := userMeta(t, manager, schema.ObjectKey{Volume: "test", Path: path})["rating"]; got != `5` {
+			t.Errorf("%s: got user metadata %q, want 5", test.name, got)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package manager
+
+import (
+	"context"
+	"errors"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// extractError is returned when metadata could not be extracted from an
+// object, and records the extractor which failed
+type extractError struct {
+	extractor string
+	err       error
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ListIndexFailures returns the objects in a volume which could not be
+// indexed, or which are ignored by the indexer, most recent first.
+func (manager *Manager) ListIndexFailures(ctx context.Context, req schema.IndexFailureListRequest) (_ *schema.IndexFailureList, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ListIndexFailures",
+		attribute.String("req", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check access
+	if err := checkVolumeAccess(ctx, req.Volume); err != nil {
+		return nil, err
+	}
+
+	var result schema.IndexFailureList
+	if err := manager.PoolConn.List(ctx, &result, &req); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else {
+		result.IndexFailureListRequest = req
+		result.OffsetLimit.Clamp(result.Count)
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// RetryIndexFailures queues the selected objects in a volume for indexing,
+// and returns the failures which were retried. Objects which were ignored are
+// no longer ignored. When there are no paths, all failures which are not
+// ignored are retried.
+func (manager *Manager) RetryIndexFailures(ctx context.Context, volume string, req schema.IndexFailureRequest) (_ *schema.IndexFailureList, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "RetryIndexFailures",
+		attribute.String("volume", volume),
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check access
+	if err := checkVolumeAccess(ctx, volume); err != nil {
+		return nil, err
+	}
+
+	// Select the failures to retry
+	var result schema.IndexFailureList
+	if err := manager.PoolConn.Update(ctx, &result, schema.IndexFailureRetry{Volume: volume, IndexFailureRequest: req}, nil); errors.Is(err, pg.ErrNotFound) {
+		// No failures to retry
+	} else if err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Queue the objects for indexing, even if they have not changed
+	for _, failure := range result.Body {
+		if err := manager.enqueueIndexObject(ctx, failure.ObjectKey, true); err != nil {
+			return nil, err
+		}
+	}
+
+	// Return success
+	result.Volume = volume
+	result.Count = uint64(len(result.Body))
+	return types.Ptr(result), nil
+}
+
+// IgnoreIndexFailures marks objects in a volume as ignored, so that they are
+// no longer indexed, and returns the ignored objects.
+func (manager *Manager) IgnoreIndexFailures(ctx context.Context, volume string, req schema.IndexFailureRequest) (_ *schema.IndexFailureList, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "IgnoreIndexFailures",
+		attribute.String("volume", volume),
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check access
+	if err := checkVolumeAccess(ctx, volume); err != nil {
+		return nil, err
+	}
+
+	// Ignore the objects
+	var result schema.IndexFailureList
+	if err := manager.PoolConn.Insert(ctx, &result, schema.IndexFailureIgnore{Volume: volume, IndexFailureRequest: req}); err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Return success
+	result.Volume = volume
+	result.Count = uint64(len(result.Body))
+	return types.Ptr(result), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// indexIgnored returns true if the object is ignored by the indexer
+func (manager *Manager) indexIgnored(ctx context.Context, key schema.ObjectKey) (bool, error) {
+	var failure schema.IndexFailure
+	if err := manager.PoolConn.Get(ctx, &failure, schema.IndexFailureKey(key)); errors.Is(err, pg.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return failure.Ignored, nil
+}
+
+// indexFailed records a failed attempt to index an object, or removes the
+// failure when the object was indexed or skipped
+func (manager *Manager) indexFailed(ctx context.Context, key schema.ObjectKey, indexErr error) error {
+	if indexErr == nil {
+		return manager.PoolConn.Delete(ctx, nil, schema.IndexFailureKey(key))
+	}
+	failure := schema.IndexFailureMeta{ObjectKey: key, Error: indexErr.Error()}
+	var extractErr extractError
+	if errors.As(indexErr, &extractErr) {
+		failure.Extractor = extractErr.extractor
+	}
+	return manager.PoolConn.Insert(ctx, nil, failure)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// ERROR
+
+func (e extractError) Error() string {
+	return e.err.Error()
+}
+
+func (e extractError) Unwrap() error {
+	return e.err
+}
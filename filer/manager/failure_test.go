@@ -0,0 +1,113 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// RETRY AND IGNORE
+
+func TestIndexFailures_001(t *testing.T) {
+	ctx := context.Background()
+	manager := testManager(t)
+	testVolume(t, manager, "test", schema.VolumeMeta{})
+	testIndexQueue(t, manager)
+	key := func(path string) schema.ObjectKey {
+		return schema.ObjectKey{Volume: "test", Path: path}
+	}
+
+	// Record failures, where a is attempted twice and c is indexed after failing
+	for _, path := range []string{"a", "a", "b", "c"} {
+		if err := manager.indexFailed(ctx, key(path), errors.New("failed")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := manager.indexFailed(ctx, key("c"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each step retries or ignores objects, and the failures are checked
+	// afterwards
+	type failure struct {
+		attempts uint64
+		ignored  bool
+	}
+	tests := []struct {
+		name   string
+		fn     func() (*schema.IndexFailureList, error)
+		result []string
+		want   map[string]failure
+	}{
+		{"failures", func() (*schema.IndexFailureList, error) {
+			return manager.ListIndexFailures(ctx, schema.IndexFailureListRequest{Volume: "test"})
+		}, []string{"a", "b"}, map[string]failure{"a": {2, false}, "b": {1, false}}},
+		{"ignore", func() (*schema.IndexFailureList, error) {
+			return manager.IgnoreIndexFailures(ctx, "test", schema.IndexFailureRequest{Paths: []string{"/a", "d"}})
+		}, []string{"a", "d"}, map[string]failure{"a": {2, true}, "b": {1, false}, "d": {0, true}}},
+		{"list ignored", func() (*schema.IndexFailureList, error) {
+			return manager.ListIndexFailures(ctx, schema.IndexFailureListRequest{Volume: "test", Ignored: types.Ptr(true)})
+		}, []string{"a", "d"}, map[string]failure{"a": {2, true}, "b": {1, false}, "d": {0, true}}},
+		{"retry not ignored", func() (*schema.IndexFailureList, error) {
+			return manager.RetryIndexFailures(ctx, "test", schema.IndexFailureRequest{})
+		}, []string{"b"}, map[string]failure{"a": {2, true}, "b": {1, false}, "d": {0, true}}},
+		{"retry ignored", func() (*schema.IndexFailureList, error) {
+			return manager.RetryIndexFailures(ctx, "test", schema.IndexFailureRequest{Paths: []string{"a"}})
+		}, []string{"a"}, map[string]failure{"a": {2, false}, "b": {1, false}, "d": {0, true}}},
+		{"retry none", func() (*schema.IndexFailureList, error) {
+			return manager.RetryIndexFailures(ctx, "test", schema.IndexFailureRequest{Paths: []string{"e"}})
+		}, nil, map[string]failure{"a": {2, false}, "b": {1, false}, "d": {0, true}}},
+		{"indexed", func() (*schema.IndexFailureList, error) {
+			for _, path := range []string{"b", "d"} {
+				if err := manager.indexFailed(ctx, key(path), nil); err != nil {
+					return nil, err
+				}
+			}
+			return manager.ListIndexFailures(ctx, schema.IndexFailureListRequest{Volume: "test"})
+		}, []string{"a", "d"}, map[string]failure{"a": {2, false}, "d": {0, true}}},
+	}
+	for _, test := range tests {
+		result, err := test.fn()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var paths []string
+		for _, failure := range result.Body {
+			paths = append(paths, failure.Path)
+		}
+		slices.Sort(paths)
+		if !slices.Equal(paths, test.result) {
+			t.Errorf("%s: got %v, want %v", test.name, paths, test.result)
+		}
+
+		// Check the failures, and whether the indexer ignores the objects
+		for _, path := range []string{"a", "b", "c", "d"} {
+			var got schema.IndexFailure
+			err := manager.PoolConn.Get(ctx, &got, schema.IndexFailureKey(key(path)))
+			want, exists := test.want[path]
+			if !exists {
+				if err == nil {
+					t.Errorf("%s: %q: unexpected failure", test.name, path)
+				}
+				continue
+			} else if err != nil {
+				t.Errorf("%s: %q: %v", test.name, path, err)
+				continue
+			}
+			if got.Attempts != want.attempts || got.Ignored != want.ignored {
+				t.Errorf("%s: %q: got attempts=%d ignored=%v, want attempts=%d ignored=%v", test.name, path, got.Attempts, got.Ignored, want.attempts, want.ignored)
+			}
+			if ignored, err := manager.indexIgnored(ctx, key(path)); err != nil {
+				t.Errorf("%s: %q: %v", test.name, path, err)
+			} else if ignored != want.ignored {
+				t.Errorf("%s: %q: got indexIgnored=%v, want %v", test.name, path, ignored, want.ignored)
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"path"
+	"sync"
+	"time"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	pgqueueschema "github.com/mutablelogic/go-pg/pgqueue/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// indexers are the indexers which have sent a heartbeat within the timeout,
+// and the volumes they have claimed, as of the last heartbeat
+type indexers struct {
+	sync.RWMutex
+	live   map[string]schema.IndexerMeta
+	claims map[string]string // volume to indexer
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ListIndexers returns the indexers which are registered, their capabilities
+// and the volumes they have claimed.
+func (manager *Manager) ListIndexers(ctx context.Context, req schema.IndexerListRequest) (_ *schema.IndexerList, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ListIndexers",
+		attribute.String("req", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check access
+	if grantedVolumes(ctx) != nil {
+		return nil, gofiler.ErrForbidden.With("token is restricted to volumes and cannot list indexers")
+	}
+
+	var result schema.IndexerList
+	if err := manager.PoolConn.List(ctx, &result, &req); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else {
+		result.IndexerListRequest = req
+		result.OffsetLimit.Clamp(result.Count)
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// heartbeat registers this instance as an indexer, and rebalances the claims
+// on volumes when no other indexer is rebalancing. Instances which are not
+// indexers only read the indexers and their claims.
+func (manager *Manager) heartbeat(ctx context.Context) error {
+	if manager.indexer {
+		if err := manager.PoolConn.Insert(ctx, nil, schema.IndexerMeta{
+			Name:         manager.indexerName,
+			Capabilities: manager.capabilities,
+		}); err != nil {
+			return err
+		} else if err := manager.PoolConn.Update(ctx, nil, schema.IndexerRebalance(schema.IndexerTimeout), nil); err != nil {
+			return err
+		}
+	}
+
+	// Read the indexers which are live, and their claims
+	live := make(map[string]schema.IndexerMeta)
+	claims := make(map[string]string)
+	for offset := uint64(0); ; {
+		var list schema.IndexerList
+		if err := manager.PoolConn.List(ctx, &list, &schema.IndexerListRequest{OffsetLimit: pg.OffsetLimit{Offset: offset}}); err != nil {
+			return err
+		} else if len(list.Body) == 0 {
+			break
+		}
+		for _, indexer := range list.Body {
+			if time.Since(indexer.HeartbeatAt) > schema.IndexerTimeout {
+				continue
+			}
+			live[indexer.Name] = indexer.IndexerMeta
+			for _, volume := range indexer.Volumes {
+				claims[volume] = indexer.Name
+			}
+		}
+		offset += uint64(len(list.Body))
+	}
+	manager.indexers.Lock()
+	manager.indexers.live, manager.indexers.claims = live, claims
+	manager.indexers.Unlock()
+
+	// Return success
+	return nil
+}
+
+// unregisterIndexer removes this instance as an indexer, which releases the
+// volumes it has claimed
+func (manager *Manager) unregisterIndexer(ctx context.Context) error {
+	if !manager.indexer {
+		return nil
+	}
+	return manager.PoolConn.Delete(ctx, nil, schema.IndexerName(manager.indexerName))
+}
+
+// claimed returns true when this instance reindexes a volume, which is when
+// this instance has claimed the volume, or no live indexer has claimed it
+func (manager *Manager) claimed(volume string) bool {
+	manager.indexers.RLock()
+	defer manager.indexers.RUnlock()
+	if owner, exists := manager.indexers.claims[volume]; exists {
+		if _, live := manager.indexers.live[owner]; live {
+			return owner == manager.indexerName
+		}
+	}
+	return true
+}
+
+// claimedVolumes returns the volumes claimed by this indexer, as of the last
+// heartbeat
+func (manager *Manager) claimedVolumes() []string {
+	if !manager.indexer {
+		return nil
+	}
+	manager.indexers.RLock()
+	defer manager.indexers.RUnlock()
+	var result []string
+	for volume, indexer := range manager.indexers.claims {
+		if indexer == manager.indexerName {
+			result = append(result, volume)
+		}
+	}
+	return result
+}
+
+// indexes returns true when this instance indexes an object in a volume. The
+// object is indexed by the indexer which has claimed the volume when it has
+// the capabilities required by the content type, otherwise by any live
+// indexer with the capabilities. When there are no live indexers, the object
+// is indexed by any instance.
+func (manager *Manager) indexes(key schema.ObjectKey) bool {
+	manager.indexers.RLock()
+	defer manager.indexers.RUnlock()
+	if len(manager.indexers.live) == 0 {
+		return true
+	} else if !manager.indexer {
+		return false
+	}
+
+	// Determine the capabilities from the content type of the path
+	var capabilities []string
+	if contentType, _, err := mime.ParseMediaType(mime.TypeByExtension(path.Ext(key.Path))); err == nil {
+		capabilities = manager.metadata.Capabilities(contentType)
+	}
+
+	// The owner of the volume indexes the object when it is capable
+	owner, claimed := manager.indexers.claims[key.Volume]
+	meta, live := manager.indexers.live[owner]
+	if claimed && live && meta.Capable(capabilities) {
+		return owner == manager.indexerName
+	}
+
+	// Otherwise this indexer indexes the object when it is capable, or the
+	// owner indexes it when no indexer is capable
+	self := schema.IndexerMeta{Name: manager.indexerName, Capabilities: manager.capabilities}
+	if self.Capable(capabilities) {
+		return true
+	}
+	for _, indexer := range manager.indexers.live {
+		if indexer.Capable(capabilities) {
+			return false
+		}
+	}
+	return !claimed || !live || owner == manager.indexerName
+}
+
+// deferIndexObject hands an object over to another indexer, by queuing the
+// object again after a delay
+func (manager *Manager) deferIndexObject(ctx context.Context, task indexObjectTask) error {
+	task.Deferred++
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	_, err = manager.queue.CreateTask(ctx, manager.volumeQueue(task.Volume), pgqueueschema.TaskMeta{
+		Payload:   payload,
+		DelayedAt: types.Ptr(time.Now().Add(schema.IndexerDeferDelay)),
+	})
+	return err
+}
@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// REBALANCE
+
+func TestIndexerRebalance_001(t *testing.T) {
+	ctx := context.Background()
+
+	// Two indexers share the database
+	a := testManager(t, WithIndexer(true))
+	a.indexerName = "a"
+	b := testSchemaManager(t, a.PoolConn, a.schema, WithIndexer(true))
+	b.indexerName = "b"
+	for _, name := range []string{"test1", "test2", "test3", "test4"} {
+		testVolume(t, a, name, schema.VolumeMeta{})
+	}
+
+	// Each step changes the indexers or volumes, and the number of volumes
+	// claimed by each indexer is checked afterwards
+	tests := []struct {
+		name string
+		fn   func() error
+		self *Manager // the instance which sent the last heartbeat
+		want map[string]int
+	}{
+		{"first indexer", func() error {
+			return a.heartbeat(ctx)
+		}, a, map[string]int{"a": 4}},
+		{"second indexer", func() error {
+			return b.heartbeat(ctx)
+		}, b, map[string]int{"a": 2, "b": 2}},
+		{"balanced", func() error {
+			return a.heartbeat(ctx)
+		}, a, map[string]int{"a": 2, "b": 2}},
+		{"volume disabled", func() error {
+			if _, err := a.UpdateVolume(ctx, "test4", schema.VolumeMeta{Enabled: types.Ptr(false)}); err != nil {
+				return err
+			}
+			return a.heartbeat(ctx)
+		}, a, map[string]int{"a": 2, "b": 1}},
+		{"indexer stopped", func() error {
+			if err := a.unregisterIndexer(ctx); err != nil {
+				return err
+			}
+			return b.heartbeat(ctx)
+		}, b, map[string]int{"b": 3}},
+		{"volume enabled", func() error {
+			if _, err := b.UpdateVolume(ctx, "test4", schema.VolumeMeta{Enabled: types.Ptr(true)}); err != nil {
+				return err
+			}
+			return b.heartbeat(ctx)
+		}, b, map[string]int{"b": 4}},
+	}
+	for _, test := range tests {
+		if err := test.fn(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		list, err := a.ListIndexers(ctx, schema.IndexerListRequest{})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		got := make(map[string]int)
+		claims := make(map[string]string)
+		for _, indexer := range list.Body {
+			got[indexer.Name] = len(indexer.Volumes)
+			for _, volume := range indexer.Volumes {
+				if owner, exists := claims[volume]; exists {
+					t.Errorf("%s: %q claimed by %q and %q", test.name, volume, owner, indexer.Name)
+				}
+				claims[volume] = indexer.Name
+			}
+		}
+		for name, want := range test.want {
+			if got[name] != want {
+				t.Errorf("%s: %q: got %d volumes, want %d", test.name, name, got[name], want)
+			}
+		}
+		if len(list.Body) != len(test.want) {
+			t.Errorf("%s: got %d indexers, want %d", test.name, len(list.Body), len(test.want))
+		}
+
+		// The instance which sent the last heartbeat reindexes only its own
+		// volumes
+		for volume, owner := range claims {
+			if claimed := test.self.claimed(volume); claimed != (owner == test.self.indexerName) {
+				t.Errorf("%s: %q: %q got claimed=%v", test.name, volume, test.self.indexerName, claimed)
+			}
+		}
+		if got := len(test.self.claimedVolumes()); got != test.want[test.self.indexerName] {
+			t.Errorf("%s: %q: got %d claimed volumes, want %d", test.name, test.self.indexerName, got, test.want[test.self.indexerName])
+		}
+	}
+}
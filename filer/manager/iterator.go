@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	// Packages
+	backend "github.com/mutablelogic/go-filer/backend"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// objectIterator returns the objects in a volume backend which match a
+// listing request one at a time, reading a page from the backend when the
+// previous page has been returned. The position of the next object can be
+// returned as a continuation token.
+type objectIterator struct {
+	backend  backend.Backend
+	iterator schema.ObjectListIterator
+	page     *schema.ListPosition // backend position of the current page
+	index    uint64               // index of the next object in the page
+	loaded   bool                 // true when the current page has been read
+	eof      bool                 // true when the current page is the last
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// newObjectIterator returns an iterator for the objects in a backend, which
+// resumes from the continuation token in the request, or else skips to the
+// offset
+func (manager *Manager) newObjectIterator(ctx context.Context, backend backend.Backend, req *schema.ObjectListRequest) (*objectIterator, error) {
+	self := &objectIterator{
+		backend: backend,
+		iterator: schema.ObjectListIterator{
+			Path:      req.Path,
+			Type:      req.Type,
+			Recursive: req.Recursive,
+		},
+	}
+
+	// Resume from the continuation token
+	if cursor, err := req.Cursor(manager.tokenKey); err != nil {
+		return nil, err
+	} else if cursor != nil {
+		self.iterator.Token = cursor.Backend
+		self.page = cursor.Backend
+		self.index = cursor.Skip
+		return self, nil
+	}
+
+	// Otherwise skip to the offset
+	for offset := req.Offset; offset > 0; offset-- {
+		if _, err := self.Next(ctx); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// Return success
+	return self, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Next returns the next object, or io.EOF when there are no more objects
+func (self *objectIterator) Next(ctx context.Context) (*schema.Object, error) {
+	for {
+		if self.loaded && self.index < uint64(len(self.iterator.Body)) {
+			object := self.iterator.Body[self.index]
+			self.index++
+			return object, nil
+		} else if self.eof {
+			return nil, io.EOF
+		}
+
+		// Read the next page, skipping objects when resuming within the page
+		if self.loaded {
+			self.page, self.index = self.iterator.Token, 0
+		}
+		err := self.backend.ListObjects(ctx, &self.iterator)
+		if errors.Is(err, io.EOF) {
+			self.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+		self.loaded = true
+	}
+}
+
+// Cursor returns the position of the next object, as the backend position of
+// the page and the index of the object in the page
+func (self *objectIterator) Cursor() schema.ObjectListCursor {
+	if self.loaded && !self.eof && self.index >= uint64(len(self.iterator.Body)) {
+		return schema.ObjectListCursor{Backend: self.iterator.Token}
+	}
+	return schema.ObjectListCursor{
+		Backend: self.page,
+		Skip:    self.index,
+	}
+}
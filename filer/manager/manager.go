@@ -2,6 +2,7 @@ package manager
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -29,8 +30,12 @@ type Manager struct {
 	volumes    *backendregistry.Registry
 	queue      *pgqueue.Manager
 	indexQueue *pgqueueschema.Queue
+	batchQueue *pgqueueschema.Queue
+	schedules  schedules
+	indexers   indexers
 	metadata   *metadatamanager.Manager
 	llm        *llm.Registry
+	events     eventHub
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -71,6 +76,7 @@ func New(ctx context.Context, pool pg.PoolConn, opts ...Opt) (_ *Manager, err er
 		pool = pool.WithQueries(queries).With(
 			"schema", self.schema,
 			"notify_channel", schema.NotifyChannel,
+			"event_channel", schema.EventChannel,
 		).(pg.PoolConn)
 	}
 
@@ -85,6 +91,21 @@ func New(ctx context.Context, pool pg.PoolConn, opts ...Opt) (_ *Manager, err er
 		self.PoolConn = pool
 	}
 
+	// Read the key which signs continuation tokens, unless it is derived from
+	// a passphrase, and store a random key when no instance has stored one
+	if self.tokenKey == nil {
+		var stored schema.CursorKey
+		key := make(schema.CursorKey, schema.CursorKeySize)
+		if _, err := rand.Read(key); err != nil {
+			endBootstrapSpan(err)
+			return nil, err
+		} else if err := pool.Insert(bootstrapCtx, &stored, key); err != nil {
+			endBootstrapSpan(err)
+			return nil, fmt.Errorf("cursor key: %w", err)
+		}
+		self.tokenKey = stored
+	}
+
 	// Register metrics
 	if self.metrics != nil {
 		err := errors.Join(
@@ -7,11 +7,13 @@ import (
 	"fmt"
 	"io"
 	"mime"
+	"path"
 	"strings"
 
 	// Packages
 	otel "github.com/mutablelogic/go-client/pkg/otel"
 	gofiler "github.com/mutablelogic/go-filer"
+	backend "github.com/mutablelogic/go-filer/backend"
 	schema "github.com/mutablelogic/go-filer/filer/schema"
 	pg "github.com/mutablelogic/go-pg"
 	pgqueueschema "github.com/mutablelogic/go-pg/pgqueue/schema"
@@ -28,18 +30,10 @@ func (manager *Manager) GetObject(ctx context.Context, req schema.ObjectKey) (_
 	)
 	defer func() { endSpan(err) }()
 
-	// Check the volume first
-	volume, err := manager.GetVolume(ctx, req.Volume)
+	// Get the mounted volume and backend
+	volume, backend, err := manager.mountedVolume(ctx, req.Volume)
 	if err != nil {
 		return nil, err
-	} else if types.Value(volume.Enabled) == false {
-		return nil, gofiler.ErrServiceUnavailable.Withf("volume %q is not mounted", req.Volume)
-	}
-
-	// Get the backend
-	backend := manager.volumes.Get(volume.Name)
-	if backend == nil {
-		return nil, gofiler.ErrServiceUnavailable.Withf("volume %q is not mounted", req.Volume)
 	}
 
 	// Get the object from the backend first
@@ -50,34 +44,387 @@ func (manager *Manager) GetObject(ctx context.Context, req schema.ObjectKey) (_
 		return nil, err
 	}
 
-	// If the volume is not indexed, or the index delta is zero, return the backend object directly
-	if types.Value(volume.IndexDelta) == 0 || volume.IndexedAt == nil {
-		return object, nil
+	// Return the object, merged with the index when available
+	return manager.indexedObject(ctx, volume, object)
+}
+
+// ReadObject returns a reader for the object content, together with the
+// object metadata. The caller is responsible for closing the reader.
+func (manager *Manager) ReadObject(ctx context.Context, req schema.ObjectKey) (_ io.ReadCloser, _ *schema.Object, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ReadObject",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Get the mounted volume and backend
+	volume, backend, err := manager.mountedVolume(ctx, req.Volume)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Get the metadata from the database (on error, return the object we have)
-	var result schema.Object
-	if err := manager.PoolConn.Get(ctx, &result, req); errors.Is(err, pg.ErrNotFound) {
-		// Kick off an indexing job for the object
-		return object, manager.enqueueIndexObject(ctx, object.ObjectKey, true)
+	// Open the object in the backend
+	reader, object, err := backend.ReadObject(ctx, schema.GetObjectRequest{
+		ObjectKey: req,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Merge with the index, but don't fail the read if the index is unavailable
+	if result, _ := manager.indexedObject(ctx, volume, object); result != nil {
+		object = result
+	}
+
+	// Return success
+	return reader, object, nil
+}
+
+// CreateObject writes the object content to the backend, and enqueues the
+// object for indexing when the volume is indexed
+func (manager *Manager) CreateObject(ctx context.Context, req schema.CreateObjectRequest) (_ *schema.Object, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "CreateObject",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Get the mounted volume and backend
+	volume, backend, err := manager.mountedVolume(ctx, req.Volume)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the path
+	if strings.Trim(req.Path, "/") == "" || strings.HasSuffix(req.Path, "/") {
+		return nil, gofiler.ErrBadParameter.Withf("invalid object path: %q", req.Path)
+	}
+
+	// Check the content type
+	if typ := strings.TrimSpace(req.ContentType); typ == "" {
+		req.ContentType = types.ContentTypeBinary
+	} else if t, _, err := mime.ParseMediaType(typ); err != nil || t == schema.ContentTypeDirectory {
+		return nil, gofiler.ErrBadParameter.Withf("invalid content type: %q", typ)
+	}
+
+	// Check the volume policy allows the write
+	if err := volume.CheckWrite(req.Path, req.ContentType); err != nil {
+		return nil, err
+	}
+
+	// When If-Match is set, the object being replaced is read for the audit
+	// record. The preconditions are checked by the backend as part of the
+	// write, which is atomic when the backend supports conditional writes.
+	var existing *schema.Object
+	if strings.TrimSpace(req.IfMatch) != "" {
+		existing, err = backend.GetObject(ctx, schema.GetObjectRequest{ObjectKey: req.ObjectKey})
+		if err != nil && !errors.Is(err, gofiler.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	// Write the object. The object already exists when If-None-Match: *
+	// fails, which is a failed precondition rather than a conflict.
+	object, err := backend.CreateObject(ctx, req)
+	if req.IfNotExists && errors.Is(err, gofiler.ErrConflict) {
+		return nil, gofiler.ErrPreconditionFailed.Withf("object already exists: %q", req.Path)
 	} else if err != nil {
+		return nil, err
+	}
+
+	// Record the write. The backend is not transactional, so the entry is
+	// written after the object.
+	if err := manager.audit(ctx, manager.PoolConn, schema.AuditObjectCreate, schema.AuditObjectTarget(object.ObjectKey), existing, object); err != nil {
+		return object, pg.NormalizeError(err)
+	}
+
+	// Notify subscribers of the new object
+	if err := manager.notifyEvents(ctx, schema.Event{
+		Type:   schema.EventObjectCreate,
+		Volume: object.Volume,
+		Path:   object.Path,
+	}); err != nil {
 		return object, err
 	}
 
-	// If the objects match, return the result from the database
-	if result.Matches(object) {
-		return types.Ptr(result), nil
+	// Index the object immediately when the volume is indexed
+	if types.Value(volume.IndexDelta) != 0 {
+		if err := manager.enqueueIndexObject(ctx, object.ObjectKey, true); err != nil {
+			return object, err
+		}
 	}
 
-	// Kick off an indexing job for the object, and return the object we have
-	if err := manager.enqueueIndexObject(ctx, object.ObjectKey, true); err != nil {
+	// Return success
+	return object, nil
+}
+
+// DeleteObjects deletes an object, or all objects under a prefix when
+// Recursive is set, and removes them from the index. The deleted objects
+// are returned.
+func (manager *Manager) DeleteObjects(ctx context.Context, req schema.DeleteObjectsRequest) (_ *schema.DeleteObjectsResponse, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "DeleteObjects",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Get the mounted volume and backend, and check the volume policy allows deletes
+	volume, backend, err := manager.mountedVolume(ctx, req.Volume)
+	if err != nil {
+		return nil, err
+	} else if err := volume.CheckDelete(); err != nil {
+		return nil, err
+	}
+
+	// Determine whether the path is an object or a prefix
+	var result schema.DeleteObjectsResponse
+	object, err := backend.GetObject(ctx, schema.GetObjectRequest{ObjectKey: req.ObjectKey})
+	switch {
+	case err == nil:
+		if ifmatch := strings.TrimSpace(req.IfMatch); ifmatch != "" && ifmatch != "*" && !schema.MatchETags(ifmatch, types.Value(object.ETag), true) {
+			return nil, gofiler.ErrPreconditionFailed.Withf("object has been modified: %q", req.Path)
+		}
+		if err := manager.deleteObjects(ctx, backend, req.ObjectKey, []schema.Object{types.Value(object)}, &result); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, gofiler.ErrNotFound) || errors.Is(err, gofiler.ErrBadParameter):
+		if req.IfMatch != "" {
+			return nil, gofiler.ErrPreconditionFailed.Withf("object does not exist: %q", req.Path)
+		}
+
+		// Delete the objects under the prefix one page at a time, as they are
+		// listed. The listing resumes after the last object, so it is not
+		// affected by the objects which have been deleted.
+		objects, err := manager.newObjectIterator(ctx, backend, &schema.ObjectListRequest{
+			Volume: req.Volume,
+			ObjectListFilters: schema.ObjectListFilters{
+				Path:      types.Ptr(req.Path),
+				Recursive: true,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		page := make([]schema.Object, 0, schema.ObjectListLimit)
+		for {
+			object, err := objects.Next(ctx)
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, err
+			} else if object.ContentType == schema.ContentTypeDirectory {
+				continue
+			} else if !req.Recursive {
+				return nil, gofiler.ErrBadParameter.Withf("%q is a prefix of objects, set recursive to delete them", req.Path)
+			}
+			if page = append(page, types.Value(object)); len(page) == schema.ObjectListLimit {
+				if err := manager.deleteObjects(ctx, backend, req.ObjectKey, page, &result); err != nil {
+					return nil, err
+				}
+				page = page[:0]
+			}
+		}
+		if err := manager.deleteObjects(ctx, backend, req.ObjectKey, page, &result); err != nil {
+			return nil, err
+		} else if result.Count == 0 {
+			return nil, gofiler.ErrNotFound.Withf("object not found: %q", req.Path)
+		}
+
+		// Remove what remains under the prefix, such as empty directories
+		if strings.Trim(req.Path, "/") != "" {
+			if err := backend.DeleteObjects(ctx, req); err != nil && !errors.Is(err, gofiler.ErrNotFound) {
+				return nil, err
+			}
+		}
+	default:
+		return nil, err
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// CopyObject copies the content of an object to another path, which may be
+// in another volume. The copy is written through CreateObject, so it is
+// indexed when the destination volume is indexed. A single audit entry is
+// recorded for the copy, with the source as the before summary.
+func (manager *Manager) CopyObject(ctx context.Context, src, dst schema.ObjectKey) (_ *schema.Object, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "CopyObject",
+		attribute.String("src", types.Stringify(src)),
+		attribute.String("dst", types.Stringify(dst)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the source and destination are different
+	if src.Volume == dst.Volume && strings.Trim(src.Path, "/") == strings.Trim(dst.Path, "/") {
+		return nil, gofiler.ErrBadParameter.Withf("cannot copy an object to itself: %q", src.Path)
+	}
+
+	// Open the source
+	reader, object, err := manager.ReadObject(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, reader.Close())
+	}()
+
+	// Write the destination, without auditing the write separately
+	innerCtx := withoutAudit(ctx)
+	result, err := manager.CreateObject(innerCtx, schema.CreateObjectRequest{
+		ObjectKey: dst,
+		Body:      reader,
+		ObjectMeta: schema.ObjectMeta{
+			ContentType: object.ContentType,
+		},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Copy the user metadata to the destination
+	var user schema.UserMetaList
+	if err := manager.PoolConn.List(ctx, &user, schema.UserMetaKey{ObjectKey: src}); err != nil {
+		return result, pg.NormalizeError(err)
+	} else if len(user) > 0 {
+		if result, err = manager.PatchObject(innerCtx, dst, user); err != nil {
+			return result, err
+		}
+	}
+
+	// Record the copy
+	if err := manager.audit(ctx, manager.PoolConn, schema.AuditObjectCopy, schema.AuditObjectTarget(dst), object, result); err != nil {
+		return result, pg.NormalizeError(err)
+	}
+
+	// Return success
+	return result, nil
+}
+
+// MoveObject copies an object to another path, and then deletes the source.
+// A single audit entry is recorded for the move.
+func (manager *Manager) MoveObject(ctx context.Context, src, dst schema.ObjectKey) (_ *schema.Object, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "MoveObject",
+		attribute.String("src", types.Stringify(src)),
+		attribute.String("dst", types.Stringify(dst)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the source can be deleted before the copy is made
+	if volume, err := manager.GetVolume(ctx, src.Volume); err != nil {
+		return nil, err
+	} else if err := volume.CheckDelete(); err != nil {
+		return nil, err
+	}
+
+	// Copy the object, and keep the source unless the copy succeeded
+	innerCtx := withoutAudit(ctx)
+	object, err := manager.CopyObject(innerCtx, src, dst)
+	if err != nil {
 		return object, err
 	}
 
-	// Return the backend object in preference to the database object, since the database object is stale
+	// Delete the source
+	deleted, err := manager.DeleteObjects(innerCtx, schema.DeleteObjectsRequest{ObjectKey: src})
+	if err != nil {
+		return object, err
+	}
+
+	// Record the move
+	if err := manager.audit(ctx, manager.PoolConn, schema.AuditObjectMove, schema.AuditObjectTarget(dst), deleted.Body, object); err != nil {
+		return object, pg.NormalizeError(err)
+	}
+
+	// Return success
 	return object, nil
 }
 
+// PatchObject sets or removes user metadata keys on an object, and returns
+// the object with the user metadata merged in. User metadata is kept when
+// the object is reindexed, and takes precedence over extracted metadata.
+func (manager *Manager) PatchObject(ctx context.Context, req schema.ObjectKey, meta []schema.Meta) (_ *schema.Object, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "PatchObject",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the object exists in the backend
+	if len(meta) == 0 {
+		return nil, gofiler.ErrBadParameter.With("no patch values")
+	}
+	volume, backend, err := manager.mountedVolume(ctx, req.Volume)
+	if err != nil {
+		return nil, err
+	} else if err := volume.CheckWrite(req.Path, ""); err != nil {
+		return nil, err
+	}
+	object, err := backend.GetObject(ctx, schema.GetObjectRequest{ObjectKey: req})
+	if err != nil {
+		return nil, err
+	}
+
+	// Set or remove the user metadata
+	if err := manager.Tx(ctx, func(conn pg.Conn) error {
+		var before, after schema.UserMetaList
+		if err := conn.List(ctx, &before, schema.UserMetaKey{ObjectKey: req}); err != nil {
+			return err
+		}
+		conn = conn.With("volume", req.Volume, "path", req.Path)
+		for _, meta := range meta {
+			var result schema.Meta
+			if meta.IsNull() {
+				if err := conn.Delete(ctx, &result, schema.UserMetaKey{ObjectKey: req, Key: meta.Key}); err != nil && !errors.Is(err, pg.ErrNotFound) {
+					return err
+				}
+			} else if err := conn.Insert(ctx, &result, schema.UserMeta(meta)); err != nil {
+				return err
+			}
+		}
+
+		// Record the change to the user metadata
+		if err := conn.List(ctx, &after, schema.UserMetaKey{ObjectKey: req}); err != nil {
+			return err
+		}
+		return manager.audit(ctx, conn, schema.AuditObjectPatch, schema.AuditObjectTarget(req), before, after)
+	}); err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Return the object, merged with the index when available
+	result, err := manager.indexedObject(ctx, volume, object)
+	if result != object {
+		return result, err
+	}
+
+	// The object is not in the index, so merge the user metadata with the
+	// backend metadata
+	var user schema.UserMetaList
+	if err := manager.PoolConn.List(ctx, &user, schema.UserMetaKey{ObjectKey: req}); err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+	object.Meta = schema.MergeMeta(object.Meta, user)
+
+	// Return success
+	return object, err
+}
+
+// ReindexObject enqueues an object to be indexed, whether or not it has
+// changed
+func (manager *Manager) ReindexObject(ctx context.Context, req schema.ObjectKey) (err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ReindexObject",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the object exists in the backend
+	_, backend, err := manager.mountedVolume(ctx, req.Volume)
+	if err != nil {
+		return err
+	} else if _, err := backend.GetObject(ctx, schema.GetObjectRequest{ObjectKey: req}); err != nil {
+		return err
+	}
+
+	// Enqueue the object
+	return manager.enqueueIndexObject(ctx, req, true)
+}
+
 func (manager *Manager) ListObjects(ctx context.Context, req schema.ObjectListRequest) (_ *schema.ObjectList, err error) {
 	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ListObjects",
 		attribute.String("req", types.Stringify(req)),
@@ -106,50 +453,59 @@ func (manager *Manager) ListObjects(ctx context.Context, req schema.ObjectListRe
 			return nil, gofiler.ErrServiceUnavailable.Withf("volume %q is not mounted", req.Volume)
 		}
 
-		offset := req.Offset
 		limit := uint64(schema.ObjectListLimit)
 		if req.Limit != nil {
 			limit = *req.Limit
 		}
 
-		iterator := &schema.ObjectListIterator{
-			Path:      req.Path,
-			Type:      req.Type,
-			Recursive: req.Recursive,
+		// Read objects until the limit, and return the position of the next
+		// object as the continuation token
+		objects, err := manager.newObjectIterator(ctx, b, &req)
+		if err != nil {
+			return nil, err
 		}
-
 		var result schema.ObjectList
-		n := uint64(0)
-	outer:
 		for {
-			err := b.ListObjects(ctx, iterator)
-			done := errors.Is(err, io.EOF)
-			if err != nil && !done {
+			cursor := objects.Cursor()
+			obj, err := objects.Next(ctx)
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
 				return nil, err
-			}
-			for _, obj := range iterator.Body {
-				if n >= offset {
-					if uint64(len(result.Body)) >= limit {
-						break outer
-					}
-					result.Body = append(result.Body, obj)
+			} else if uint64(len(result.Body)) >= limit {
+				if result.NextToken, err = req.NextToken(manager.tokenKey, cursor); err != nil {
+					return nil, err
 				}
-				n++
-			}
-			if done {
 				break
 			}
+			result.Body = append(result.Body, obj)
 		}
 
-		req.Path = iterator.Path // reflect normalised path back into the response
+		// Reflect the normalised path back into the response
+		if prefix := strings.Trim(path.Clean("/"+types.Value(req.Path)), "/"); prefix == "" {
+			req.Path = nil
+		} else {
+			req.Path = types.Ptr(prefix)
+		}
 		result.ObjectListRequest = req
 		result.OffsetLimit.Limit = types.Ptr(limit)
 		return types.Ptr(result), nil
 	}
 
-	// Use the database index to return the objects
-	// TODO: We're not respecting the recursive flag here, and maybe not the
-	// directory flag either?
+	// Use the database index to return the objects, with the path normalised
+	// in the same way as the backends. When continuing, the offset is ignored and the count is the number of
+	// objects after the continuation token
+	if prefix := strings.Trim(path.Clean("/"+types.Value(req.Path)), "/"); prefix == "" {
+		req.Path = nil
+	} else {
+		req.Path = types.Ptr(prefix)
+	}
+	if req.Token != "" {
+		req.Offset = 0
+	}
+	if _, err := req.Cursor(manager.tokenKey); err != nil {
+		return nil, err
+	}
 	var result schema.ObjectList
 	if err := manager.PoolConn.List(ctx, &result, &req); err != nil {
 		return nil, err
@@ -158,13 +514,184 @@ func (manager *Manager) ListObjects(ctx context.Context, req schema.ObjectListRe
 		result.OffsetLimit.Clamp(uint64(result.Count))
 	}
 
+	// Set the continuation token when there are more objects
+	if n := len(result.Body); n > 0 && uint64(result.Count) > req.Offset+uint64(n) {
+		if result.NextToken, err = req.NextToken(manager.tokenKey, schema.ObjectListCursor{After: result.Body[n-1].Path}); err != nil {
+			return nil, err
+		}
+	}
+
 	// Return success
 	return types.Ptr(result), nil
 }
 
+// StreamObjects calls fn for each object which matches the request, without
+// a page limit. Objects are read from the backend as they are iterated when
+// the volume is not indexed, otherwise from the index one page at a time.
+// The function is called synchronously, so a slow consumer slows the
+// listing, and the listing stops when it returns an error.
+func (manager *Manager) StreamObjects(ctx context.Context, req schema.ObjectListRequest, fn func(*schema.Object) error) (err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "StreamObjects",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the volume first
+	volume, b, err := manager.mountedVolume(ctx, req.Volume)
+	if err != nil {
+		return err
+	}
+
+	// When the volume is indexed, read the index one page at a time
+	if types.Value(volume.IndexDelta) != 0 && volume.IndexedAt != nil && volume.LastIndexedObjectAt != nil {
+		req.Limit = types.Ptr(uint64(schema.ObjectListLimit))
+		for {
+			result, err := manager.ListObjects(ctx, req)
+			if err != nil {
+				return err
+			}
+			for _, obj := range result.Body {
+				if err := fn(obj); err != nil {
+					return err
+				}
+			}
+			if result.NextToken == "" {
+				return nil
+			}
+			req.Token = result.NextToken
+		}
+	}
+
+	// Otherwise iterate the backend, resuming from any continuation token
+	objects, err := manager.newObjectIterator(ctx, b, &req)
+	if err != nil {
+		return err
+	}
+	for {
+		obj, err := objects.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		} else if err := fn(obj); err != nil {
+			return err
+		}
+	}
+}
+
+// IterateObjects lists the objects in a volume backend one page at a time,
+// bypassing the index, until io.EOF is returned
+func (manager *Manager) IterateObjects(ctx context.Context, volume string, iterator *schema.ObjectListIterator) (err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "IterateObjects",
+		attribute.String("volume", volume),
+		attribute.String("iterator", types.Stringify(iterator)),
+	)
+	defer func() {
+		if errors.Is(err, io.EOF) {
+			endSpan(nil)
+		} else {
+			endSpan(err)
+		}
+	}()
+
+	// Get the mounted volume and backend
+	_, backend, err := manager.mountedVolume(ctx, volume)
+	if err != nil {
+		return err
+	}
+
+	// Return the next page of objects
+	return backend.ListObjects(ctx, iterator)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// mountedVolume returns the volume and backend for a volume name, or
+// ErrServiceUnavailable if the volume is not enabled or not mounted.
+func (manager *Manager) mountedVolume(ctx context.Context, name string) (*schema.Volume, backend.Backend, error) {
+	volume, err := manager.GetVolume(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	} else if types.Value(volume.Enabled) == false {
+		return nil, nil, gofiler.ErrServiceUnavailable.Withf("volume %q is not mounted", name)
+	}
+	backend := manager.volumes.Get(volume.Name)
+	if backend == nil {
+		return nil, nil, gofiler.ErrServiceUnavailable.Withf("volume %q is not mounted", name)
+	}
+	return volume, backend, nil
+}
+
+// deleteObjects removes a page of objects from the index and then from the
+// backend, and notifies subscribers. The backend is not transactional, so the
+// objects are deleted from the backend once the index is committed, and are
+// indexed again by the next reindex when the backend fails. The first page of
+// deleted objects is added to the result.
+func (manager *Manager) deleteObjects(ctx context.Context, backend backend.Backend, target schema.ObjectKey, objects []schema.Object, result *schema.DeleteObjectsResponse) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	// Remove the objects from the index, and record the delete
+	if err := manager.Tx(ctx, func(conn pg.Conn) error {
+		for _, object := range objects {
+			if err := conn.Delete(ctx, nil, schema.DeleteObjectsRequest{ObjectKey: object.ObjectKey}); err != nil && !errors.Is(err, pg.ErrNotFound) {
+				return err
+			}
+		}
+		return manager.audit(ctx, conn, schema.AuditObjectDelete, schema.AuditObjectTarget(target), objects, nil)
+	}); err != nil {
+		return pg.NormalizeError(err)
+	}
+
+	// Delete the objects from the backend
+	events := make([]schema.Event, 0, len(objects))
+	for _, object := range objects {
+		if err := backend.DeleteObjects(ctx, schema.DeleteObjectsRequest{ObjectKey: object.ObjectKey}); err != nil && !errors.Is(err, gofiler.ErrNotFound) {
+			return err
+		}
+		events = append(events, schema.Event{
+			Type:   schema.EventObjectDelete,
+			Volume: object.Volume,
+			Path:   object.Path,
+		})
+		if result.Count++; len(result.Body) < schema.ObjectListLimit {
+			result.Body = append(result.Body, object)
+		}
+	}
+
+	// Notify subscribers of the deleted objects
+	return manager.notifyEvents(ctx, events...)
+}
+
+// indexedObject returns the indexed object when it matches the backend object,
+// or else enqueues the object for indexing and returns the backend object.
+func (manager *Manager) indexedObject(ctx context.Context, volume *schema.Volume, object *schema.Object) (*schema.Object, error) {
+	// If the volume is not indexed, or the index delta is zero, return the backend object directly
+	if types.Value(volume.IndexDelta) == 0 || volume.IndexedAt == nil {
+		return object, nil
+	}
+
+	// Get the metadata from the database (on error, return the object we have)
+	var result schema.Object
+	if err := manager.PoolConn.Get(ctx, &result, object.ObjectKey); errors.Is(err, pg.ErrNotFound) {
+		// Kick off an indexing job for the object
+		return object, manager.enqueueIndexObject(ctx, object.ObjectKey, true)
+	} else if err != nil {
+		return object, err
+	}
+
+	// If the objects match, return the result from the database
+	if result.Matches(object) {
+		return types.Ptr(result), nil
+	}
+
+	// Kick off an indexing job for the object, and return the backend object in
+	// preference to the database object, since the database object is stale
+	return object, manager.enqueueIndexObject(ctx, object.ObjectKey, true)
+}
+
 func (manager *Manager) touchObject(ctx context.Context, req schema.ObjectKey) (_ *schema.Object, err error) {
 	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "touchObject",
 		attribute.String("req", types.Stringify(req)),
@@ -180,15 +707,29 @@ func (manager *Manager) touchObject(ctx context.Context, req schema.ObjectKey) (
 	return types.Ptr(result), nil
 }
 
+// enqueueIndexObject queues an object for indexing
 func (manager *Manager) enqueueIndexObject(ctx context.Context, key schema.ObjectKey, force bool) error {
+	return manager.createIndexTask(ctx, indexObjectTask{ObjectKey: key, Force: force})
+}
+
+// enqueueIndexRun queues an object for indexing in an index run, and counts
+// the object in the progress of the run
+func (manager *Manager) enqueueIndexRun(ctx context.Context, key schema.ObjectKey, force bool) error {
+	if err := manager.createIndexTask(ctx, indexObjectTask{ObjectKey: key, Force: force, Run: true}); err != nil {
+		return err
+	}
+	return manager.PoolConn.Update(ctx, nil, schema.IndexRunEnqueue(key.Volume), nil)
+}
+
+func (manager *Manager) createIndexTask(ctx context.Context, task indexObjectTask) error {
 	if manager.indexQueue == nil {
 		return gofiler.ErrServiceUnavailable.With("index queue not available")
 	}
-	payload, err := json.Marshal(indexObjectTask{ObjectKey: key, Force: force})
+	payload, err := json.Marshal(task)
 	if err != nil {
 		return fmt.Errorf("failed to marshal index task: %w", err)
 	}
-	_, err = manager.queue.CreateTask(ctx, manager.indexQueue.Queue, pgqueueschema.TaskMeta{
+	_, err = manager.queue.CreateTask(ctx, manager.volumeQueue(task.Volume), pgqueueschema.TaskMeta{
 		Payload: payload,
 	})
 	return err
@@ -247,8 +788,7 @@ func (manager *Manager) createObject(ctx context.Context, req schema.ObjectCreat
 			if err := conn.Insert(ctx, &artwork, meta); err != nil {
 				return err
 			}
-			var link schema.ObjectArtwork
-			if err := conn.Insert(ctx, &link, schema.ObjectArtwork{
+			if err := conn.Insert(ctx, nil, schema.ObjectArtwork{
 				ObjectKey:  result.ObjectKey,
 				ArtworkKey: artwork.ETag,
 			}); err != nil {
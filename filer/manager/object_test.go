@@ -0,0 +1,210 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// DELETE
+
+func TestDeleteObjects_001(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		recursive bool
+		err       error
+		deleted   []string
+	}{
+		{"object", "a2", false, nil, []string{"a2"}},
+		{"prefix not recursive", "a", false, gofiler.ErrBadParameter, nil},
+		{"prefix", "a", true, nil, []string{"a/1", "a/b/2", "a/b/c/3"}},
+		{"prefix with slash", "/a/b/", true, nil, []string{"a/b/2", "a/b/c/3"}},
+		{"volume", "/", true, nil, []string{"a/1", "a/b/2", "a/b/c/3", "a2", "a2.txt", "b/4"}},
+		{"not found", "c", true, gofiler.ErrNotFound, nil},
+	}
+	paths := []string{"a/1", "a/b/2", "a/b/c/3", "a2", "a2.txt", "b/4"}
+	for _, test := range tests {
+		manager := testManager(t)
+		testVolume(t, manager, "test", schema.VolumeMeta{})
+		for _, path := range paths {
+			testObject(t, manager, "test", path)
+		}
+
+		// Delete the objects
+		result, err := manager.DeleteObjects(context.Background(), schema.DeleteObjectsRequest{
+			ObjectKey: schema.ObjectKey{Volume: "test", Path: test.path},
+			Recursive: test.recursive,
+		})
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if result.Count != uint64(len(test.deleted)) {
+			t.Errorf("%s: got %d deleted, want %d", test.name, result.Count, len(test.deleted))
+		}
+
+		// Check the objects which remain in the backend and the index
+		for _, path := range paths {
+			deleted := false
+			for _, d := range test.deleted {
+				deleted = deleted || d == path
+			}
+			_, err := manager.volumes.Get("test").GetObject(context.Background(), schema.GetObjectRequest{ObjectKey: schema.ObjectKey{Volume: "test", Path: path}})
+			if exists := err == nil; exists == deleted {
+				t.Errorf("%s: %q in backend: got %v, want %v", test.name, path, exists, !deleted)
+			}
+			if exists := indexed(t, manager, "test", path) != nil; exists == deleted {
+				t.Errorf("%s: %q in index: got %v, want %v", test.name, path, exists, !deleted)
+			}
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// USER METADATA
+
+func TestObjectMeta_001(t *testing.T) {
+	meta := func(key, value string) schema.Meta {
+		return schema.Meta{Key: key, Value: json.RawMessage(value)}
+	}
+	manager := testManager(t)
+	testVolume(t, manager, "test", schema.VolumeMeta{})
+	object := testObject(t, manager, "test", "a.txt", meta("title", `"extracted"`), meta("author", `"extracted"`))
+	key := object.ObjectKey
+
+	// Each step patches the user metadata or reindexes the object, and the
+	// metadata of the indexed object is checked
+	tests := []struct {
+		name    string
+		patch   []schema.Meta
+		reindex []schema.Meta
+		want    map[string]string
+	}{
+		{"extracted", nil, nil, map[string]string{"title": `"extracted"`, "author": `"extracted"`}},
+		{"user replaces extracted", []schema.Meta{meta("title", `"user"`)}, nil, map[string]string{"title": `"user"`, "author": `"extracted"`}},
+		{"user survives reindex", nil, []schema.Meta{meta("title", `"reindexed"`), meta("author", `"reindexed"`)}, map[string]string{"title": `"user"`, "author": `"reindexed"`}},
+		{"user key without case", []schema.Meta{meta("Author", `"user"`)}, nil, map[string]string{"title": `"user"`, "Author": `"user"`}},
+		{"user only", []schema.Meta{meta("rating", `5`)}, nil, map[string]string{"title": `"user"`, "Author": `"user"`, "rating": `5`}},
+		{"user removed", []schema.Meta{meta("title", `null`), meta("Author", `null`)}, nil, map[string]string{"title": `"reindexed"`, "author": `"reindexed"`, "rating": `5`}},
+	}
+	for _, test := range tests {
+		if test.patch != nil {
+			if _, err := manager.PatchObject(context.Background(), key, test.patch); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+		if test.reindex != nil {
+			testIndexObject(t, manager, object, test.reindex...)
+		}
+		result := indexed(t, manager, key.Volume, key.Path)
+		if result == nil {
+			t.Fatalf("%s: object not indexed", test.name)
+		}
+		got := make(map[string]string, len(result.Meta))
+		for _, meta := range result.Meta {
+			got[meta.Key] = string(meta.Value)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+		for key, value := range test.want {
+			if got[key] != value {
+				t.Errorf("%s: %q: got %s, want %s", test.name, key, got[key], value)
+			}
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIST
+
+func TestObjectList_001(t *testing.T) {
+	ctx := context.Background()
+	manager := testManager(t)
+	testVolume(t, manager, "test", schema.VolumeMeta{})
+	for _, path := range []string{"1", "a/2", "a/b/3", "a/b/c/4", "a2/5", "100%_a/6"} {
+		testObject(t, manager, "test", path)
+	}
+
+	// Prefixes match whole path components, and only immediate children are
+	// listed unless the listing is recursive
+	tests := []struct {
+		name    string
+		filters schema.ObjectListFilters
+		want    []string
+	}{
+		{"volume", schema.ObjectListFilters{}, []string{"1"}},
+		{"volume recursive", schema.ObjectListFilters{Recursive: true}, []string{"1", "100%_a/6", "a/2", "a/b/3", "a/b/c/4", "a2/5"}},
+		{"prefix", schema.ObjectListFilters{Path: types.Ptr("a")}, []string{"a/2"}},
+		{"prefix recursive", schema.ObjectListFilters{Path: types.Ptr("/a/"), Recursive: true}, []string{"a/2", "a/b/3", "a/b/c/4"}},
+		{"prefix escaped", schema.ObjectListFilters{Path: types.Ptr("100%_a"), Recursive: true}, []string{"100%_a/6"}},
+		{"directories", schema.ObjectListFilters{Type: types.Ptr(schema.ContentTypeDirectory)}, []string{"100%_a", "a", "a2"}},
+		{"directories prefix", schema.ObjectListFilters{Path: types.Ptr("a"), Type: types.Ptr(schema.ContentTypeDirectory)}, []string{"a/b"}},
+		{"directories recursive", schema.ObjectListFilters{Path: types.Ptr("a"), Type: types.Ptr(schema.ContentTypeDirectory), Recursive: true}, []string{"a/b", "a/b/c"}},
+	}
+	for _, test := range tests {
+		var list schema.ObjectList
+		if err := manager.PoolConn.List(ctx, &list, &schema.ObjectListRequest{Volume: "test", ObjectListFilters: test.filters}); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		var got []string
+		for _, object := range list.Body {
+			got = append(got, object.Path)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// POLICY
+
+func TestVolumePolicy_001(t *testing.T) {
+	tests := []struct {
+		name                  string
+		policy                schema.VolumePolicy
+		create, patch, remove error
+	}{
+		{"no policy", schema.VolumePolicy{}, nil, nil, nil},
+		{"read-only", schema.VolumePolicy{ReadOnly: types.Ptr(true)}, gofiler.ErrForbidden, gofiler.ErrForbidden, gofiler.ErrForbidden},
+		{"no-delete", schema.VolumePolicy{NoDelete: types.Ptr(true)}, nil, nil, gofiler.ErrForbidden},
+		{"paths", schema.VolumePolicy{Paths: []string{"/photos"}}, gofiler.ErrForbidden, gofiler.ErrForbidden, nil},
+		{"content types", schema.VolumePolicy{ContentTypes: []string{"image/*"}}, gofiler.ErrForbidden, nil, nil},
+	}
+	for _, test := range tests {
+		ctx := context.Background()
+		manager := testManager(t)
+		testVolume(t, manager, "test", schema.VolumeMeta{})
+		object := testObject(t, manager, "test", "a.txt")
+		if _, err := manager.UpdateVolume(ctx, "test", schema.VolumeMeta{VolumePolicy: test.policy}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		// Write, patch and delete the object
+		_, err := manager.CreateObject(ctx, schema.CreateObjectRequest{
+			ObjectKey:  object.ObjectKey,
+			Body:       strings.NewReader("b"),
+			ObjectMeta: schema.ObjectMeta{ContentType: "text/plain"},
+		})
+		checkErr(t, test.name+": write", err, test.create)
+		_, err = manager.PatchObject(ctx, object.ObjectKey, []schema.Meta{{Key: "rating", Value: json.RawMessage(`5`)}})
+		checkErr(t, test.name+": patch", err, test.patch)
+		_, err = manager.DeleteObjects(ctx, schema.DeleteObjectsRequest{ObjectKey: object.ObjectKey})
+		checkErr(t, test.name+": delete", err, test.remove)
+	}
+}
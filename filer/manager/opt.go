@@ -1,9 +1,17 @@
 package manager
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	// Packages
 	crypto "github.com/mutablelogic/go-auth/crypto"
 	client "github.com/mutablelogic/go-client"
+	gofiler "github.com/mutablelogic/go-filer"
 	schema "github.com/mutablelogic/go-filer/filer/schema"
 	metric "go.opentelemetry.io/otel/metric"
 	trace "go.opentelemetry.io/otel/trace"
@@ -22,6 +30,34 @@ type opt struct {
 	indexer     bool
 	passphrases *crypto.Passphrases
 	clientopts  []client.ClientOpt
+
+	// auditRetention is the period for which audit entries are kept, or
+	// zero to keep them indefinitely
+	auditRetention time.Duration
+
+	// batchRetention is the period for which finished batches are kept, or
+	// zero to keep them indefinitely
+	batchRetention time.Duration
+
+	// renditionRetention is the period for which cached renditions are kept,
+	// or zero to keep them indefinitely
+	renditionRetention time.Duration
+
+	// sweepThreshold is the fraction of the indexed objects in a volume which
+	// can be removed by a sweep, or zero to disable sweeping
+	sweepThreshold float64
+
+	// syncInterval is the interval between synchronizing the mounted volumes
+	// and their schedules with the database
+	syncInterval time.Duration
+
+	// indexerName is the name of this instance when it is an indexer, and
+	// capabilities are the capabilities it advertises to other indexers
+	indexerName  string
+	capabilities []string
+
+	// tokenKey is the key which signs continuation tokens
+	tokenKey []byte
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -37,6 +73,17 @@ func (o *opt) apply(opt []Opt) error {
 		}
 	}
 
+	// Derive the key which signs continuation tokens from the latest
+	// passphrase and the schema, so tokens can be used with any instance which
+	// shares the passphrase. Otherwise the key is read from the database when
+	// the manager is created.
+	if len(o.passphrases.Keys()) > 0 {
+		passphrase, _ := o.passphrases.Get(0)
+		mac := hmac.New(sha256.New, []byte(passphrase))
+		mac.Write([]byte(o.schema + ".token"))
+		o.tokenKey = mac.Sum(nil)
+	}
+
 	// Return success
 	return nil
 }
@@ -46,6 +93,15 @@ func (o *opt) defaults() {
 	o.indexer = false
 	o.passphrases = crypto.NewPassphrases()
 	o.clientopts = []client.ClientOpt{}
+	o.auditRetention = schema.AuditRetention
+	o.batchRetention = schema.BatchRetention
+	o.renditionRetention = schema.RenditionRetention
+	o.sweepThreshold = schema.SweepThreshold
+	o.syncInterval = schema.SyncInterval
+	o.indexerName = fmt.Sprint(os.Getpid())
+	if hostname, err := os.Hostname(); err == nil {
+		o.indexerName = hostname + "-" + o.indexerName
+	}
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -90,3 +146,80 @@ func WithPassphrase(version uint64, passphrase string) Opt {
 		return o.passphrases.Set(version, passphrase)
 	}
 }
+
+// WithAuditRetention sets the period for which audit log entries are kept.
+// Entries are kept indefinitely when the period is zero.
+func WithAuditRetention(retention time.Duration) Opt {
+	return func(o *opt) error {
+		if retention < 0 {
+			return gofiler.ErrBadParameter.With("audit retention cannot be negative")
+		}
+		o.auditRetention = retention
+		return nil
+	}
+}
+
+// WithBatchRetention sets the period for which finished batches and their
+// results are kept. Batches are kept indefinitely when the period is zero.
+func WithBatchRetention(retention time.Duration) Opt {
+	return func(o *opt) error {
+		if retention < 0 {
+			return gofiler.ErrBadParameter.With("batch retention cannot be negative")
+		}
+		o.batchRetention = retention
+		return nil
+	}
+}
+
+// WithRenditionRetention sets the period for which cached image renditions
+// are kept. Renditions are kept indefinitely when the period is zero.
+func WithRenditionRetention(retention time.Duration) Opt {
+	return func(o *opt) error {
+		if retention < 0 {
+			return gofiler.ErrBadParameter.With("rendition retention cannot be negative")
+		}
+		o.renditionRetention = retention
+		return nil
+	}
+}
+
+// WithSweepThreshold sets the fraction of the indexed objects in a volume which
+// can be removed when objects no longer in the volume are swept after a
+// reindex. Sweeping is disabled when the fraction is zero.
+func WithSweepThreshold(threshold float64) Opt {
+	return func(o *opt) error {
+		if threshold < 0 || threshold > 1 {
+			return gofiler.ErrBadParameter.With("sweep threshold must be between zero and one")
+		}
+		o.sweepThreshold = threshold
+		return nil
+	}
+}
+
+// WithSyncInterval sets the interval between synchronizing the mounted volumes
+// and their reindexing schedules with the database.
+func WithSyncInterval(interval time.Duration) Opt {
+	return func(o *opt) error {
+		if interval <= 0 {
+			return gofiler.ErrBadParameter.With("sync interval must be positive")
+		}
+		o.syncInterval = interval
+		return nil
+	}
+}
+
+// WithIndexerCapabilities sets the capabilities this instance advertises as an
+// indexer, such as the names of metadata extractors or llm. Objects which
+// require other capabilities are indexed by other indexers. An indexer without
+// capabilities indexes any object.
+func WithIndexerCapabilities(capabilities ...string) Opt {
+	return func(o *opt) error {
+		o.capabilities = o.capabilities[:0]
+		for _, capability := range capabilities {
+			if capability = strings.ToLower(strings.TrimSpace(capability)); capability != "" {
+				o.capabilities = append(o.capabilities, capability)
+			}
+		}
+		return nil
+	}
+}
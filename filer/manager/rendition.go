@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	image "github.com/mutablelogic/go-filer/metadata/image"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// GetArtworkRendition returns a rendition of an artwork, which is cached by
+// the artwork key and the rendition parameters. The key of the returned
+// rendition is its etag.
+func (manager *Manager) GetArtworkRendition(ctx context.Context, key schema.ArtworkKey, req schema.RenditionRequest) (_ *schema.Artwork, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "GetArtworkRendition",
+		attribute.String("key", string(key)),
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the artwork exists
+	var info schema.ArtworkInfo
+	if err := manager.PoolConn.Get(ctx, &info, schema.ArtworkInfoKey(key)); err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Return the rendition, creating it from the artwork data
+	return manager.rendition(ctx, string(info.ETag), req, func() (io.ReadCloser, error) {
+		var artwork schema.Artwork
+		if err := manager.PoolConn.Get(ctx, &artwork, key); err != nil {
+			return nil, pg.NormalizeError(err)
+		}
+		return io.NopCloser(bytes.NewReader(artwork.Data)), nil
+	})
+}
+
+// GetObjectRendition returns a rendition of an image object, which is cached
+// by the object etag and the rendition parameters. The key of the returned
+// rendition is its etag.
+func (manager *Manager) GetObjectRendition(ctx context.Context, key schema.ObjectKey, req schema.RenditionRequest) (_ *schema.Artwork, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "GetObjectRendition",
+		attribute.String("key", types.Stringify(key)),
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	// Get the object from the backend
+	_, backend, err := manager.mountedVolume(ctx, key.Volume)
+	if err != nil {
+		return nil, err
+	}
+	object, err := backend.GetObject(ctx, schema.GetObjectRequest{ObjectKey: key})
+	if err != nil {
+		return nil, err
+	} else if !strings.HasPrefix(object.ContentType, "image/") {
+		return nil, gofiler.ErrBadParameter.Withf("object is not an image: %q", key.Path)
+	}
+
+	// Objects without an etag are identified by their path, size and
+	// modification time
+	source := types.Value(object.ETag)
+	if source == "" {
+		source = fmt.Sprintf("%s:%s:%d:%d", object.Volume, object.Path, object.Size, object.ModTime.UnixNano())
+	}
+
+	// Return the rendition, creating it from the object content
+	return manager.rendition(ctx, source, req, func() (io.ReadCloser, error) {
+		reader, _, err := manager.ReadObject(ctx, key)
+		return reader, err
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// rendition returns a cached rendition of a source, or creates and caches
+// the rendition from the source image
+func (manager *Manager) rendition(ctx context.Context, source string, req schema.RenditionRequest, open func() (io.ReadCloser, error)) (_ *schema.Artwork, err error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	} else if req.Format != "" && !image.HasEncoder(req.Format) {
+		return nil, gofiler.ErrNotImplemented.Withf("%s encoding is not available", req.Format)
+	}
+
+	// Return the cached rendition
+	key := req.Key(source)
+	var result schema.Artwork
+	if err := manager.PoolConn.Get(ctx, &result, key); err == nil {
+		return types.Ptr(result), nil
+	} else if !errors.Is(err, pg.ErrNotFound) {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Create the rendition from the source image
+	reader, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, reader.Close())
+	}()
+	meta, err := image.CreateRendition(reader, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the rendition
+	if err := manager.PoolConn.Insert(ctx, &result, schema.Rendition{
+		Key:         key,
+		Source:      source,
+		ArtworkMeta: types.Value(meta),
+	}); err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// pruneRenditions removes renditions which were cached before the retention
+// period, and returns the number of renditions removed
+func (manager *Manager) pruneRenditions(ctx context.Context, retention time.Duration) (_ uint64, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "pruneRenditions",
+		attribute.String("retention", retention.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	var result schema.RenditionPruned
+	if err := manager.PoolConn.Delete(ctx, &result, schema.RenditionPrune{Retention: retention}); err != nil {
+		return 0, pg.NormalizeError(err)
+	}
+
+	// Return success
+	return uint64(result), nil
+}
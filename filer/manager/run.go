@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"sync"
 	"time"
 
 	// Packages
@@ -14,10 +15,12 @@ import (
 	gofiler "github.com/mutablelogic/go-filer"
 	backend "github.com/mutablelogic/go-filer/backend"
 	schema "github.com/mutablelogic/go-filer/filer/schema"
+	metadata "github.com/mutablelogic/go-filer/metadata"
 	llmschema "github.com/mutablelogic/go-llm/kernel/schema"
 	pg "github.com/mutablelogic/go-pg"
 	pgqueueschema "github.com/mutablelogic/go-pg/pgqueue/schema"
 	broadcaster "github.com/mutablelogic/go-pg/pkg/broadcaster"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
 	types "github.com/mutablelogic/go-server/pkg/types"
 	attribute "go.opentelemetry.io/otel/attribute"
 )
@@ -28,6 +31,14 @@ import (
 type indexObjectTask struct {
 	schema.ObjectKey
 	Force bool `json:"force"`
+
+	// Deferred is the number of times the object has been handed over to
+	// another indexer
+	Deferred int `json:"deferred,omitempty"`
+
+	// Run is true when the object was queued by an index run, and its result
+	// is counted in the progress of the run
+	Run bool `json:"run,omitempty"`
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -47,9 +58,7 @@ func (manager *Manager) Run(ctx context.Context, logger *slog.Logger) error {
 	if err := events.Subscribe(ctx, func(event broadcaster.ChangeNotification) {
 		switch event.Table {
 		case "volume":
-			if manager.indexer {
-				volumeChange <- event
-			}
+			volumeChange <- event
 		case "llmprovider", "credential":
 			providerChange <- event
 		default:
@@ -59,6 +68,12 @@ func (manager *Manager) Run(ctx context.Context, logger *slog.Logger) error {
 		return err
 	}
 
+	// Subscribe to volume and object events, which are delivered to event subscribers
+	eventChange, err := manager.PoolConn.Subscribe(ctx, schema.EventChannel)
+	if err != nil {
+		return err
+	}
+
 	// Syncronize the volume registry on startup, so that any existing volumes are loaded
 	if manager.indexer {
 		if err := manager.syncVolumes(ctx, logger); err != nil {
@@ -72,53 +87,203 @@ func (manager *Manager) Run(ctx context.Context, logger *slog.Logger) error {
 		return err
 	}
 
-	// Register a ticker to syncronize the volume registry every 5 minutes
+	// Register a ticker to syncronize the volume registry and the volume schedules
 	syncVolumesTicker := make(chan json.RawMessage, 100)
 	_, err = manager.queue.RegisterTicker(ctx, "sync-volumes-ticker", pgqueueschema.TickerMeta{
-		Interval: types.Ptr(time.Minute * 5),
+		Interval: types.Ptr(manager.syncInterval),
 	}, func(ctx context.Context, payload json.RawMessage) (any, error) {
 		syncVolumesTicker <- payload
 		return nil, nil
 	})
 
-	// Register a ticker to reindex volumes every 5 minutes
-	reindexVolumesTicker := make(chan json.RawMessage, 100)
-	_, err = manager.queue.RegisterTicker(ctx, "reindex-volumes-ticker", pgqueueschema.TickerMeta{
-		Interval: types.Ptr(time.Minute * 5),
+	// The ticker for each scheduled volume, and the heartbeat for the claimed
+	// volumes, queue the volume name, and the volume is reindexed when it is
+	// due. A volume is queued once until it is reindexed. Remove the ticker
+	// which reindexed one stale volume at a time, which has no callback.
+	reindexVolumes := make(chan string, 100)
+	var reindexPending sync.Map
+	queueReindex := func(ctx context.Context, name string) {
+		if _, pending := reindexPending.LoadOrStore(name, true); pending {
+			return
+		}
+		select {
+		case reindexVolumes <- name:
+		default:
+			reindexPending.Delete(name)
+			logger.WarnContext(ctx, "reindexing is busy, skipping volume", "name", name)
+		}
+	}
+	reindexVolumeFn := func(ctx context.Context, payload json.RawMessage) (any, error) {
+		var name string
+		if err := json.Unmarshal(payload, &name); err != nil {
+			return nil, gofiler.ErrInternalServerError.Withf("invalid payload: %v", err.Error())
+		}
+		queueReindex(ctx, name)
+		return nil, nil
+	}
+	if _, err := manager.queue.DeleteTicker(ctx, legacyReindexTicker); err != nil && !errors.Is(err, pg.ErrNotFound) && !errors.Is(err, httpresponse.ErrNotFound) {
+		logger.WarnContext(ctx, "failed to remove ticker", "ticker", legacyReindexTicker, "error", err.Error())
+	}
+
+	// Register a ticker to remove artwork which is not linked to any object
+	gcArtworkTicker := make(chan json.RawMessage, 100)
+	_, err = manager.queue.RegisterTicker(ctx, "gc-artwork-ticker", pgqueueschema.TickerMeta{
+		Interval: types.Ptr(schema.ArtworkGCInterval),
+	}, func(ctx context.Context, payload json.RawMessage) (any, error) {
+		gcArtworkTicker <- payload
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Register a ticker to remove audit entries older than the retention period
+	pruneAuditTicker := make(chan json.RawMessage, 100)
+	_, err = manager.queue.RegisterTicker(ctx, "audit-prune-ticker", pgqueueschema.TickerMeta{
+		Interval: types.Ptr(schema.AuditPruneInterval),
 	}, func(ctx context.Context, payload json.RawMessage) (any, error) {
-		reindexVolumesTicker <- payload
+		pruneAuditTicker <- payload
 		return nil, nil
 	})
+	if err != nil {
+		return err
+	}
 
-	// Register a worker to process volume indexing jobs
-	warnChan := make(chan error, 100)
-	indexQueue, err := manager.queue.RegisterQueue(ctx, "index-object", pgqueueschema.QueueMeta{
-		TTL:         types.Ptr(time.Duration(5 * time.Minute)),
-		Retries:     types.Ptr(uint64(3)),
-		RetryDelay:  types.Ptr(time.Minute),
-		Concurrency: types.Ptr(uint64(3)),
+	// Register a ticker to remove batches which finished before the retention period
+	pruneBatchTicker := make(chan json.RawMessage, 100)
+	_, err = manager.queue.RegisterTicker(ctx, "batch-prune-ticker", pgqueueschema.TickerMeta{
+		Interval: types.Ptr(schema.BatchPruneInterval),
 	}, func(ctx context.Context, payload json.RawMessage) (any, error) {
+		pruneBatchTicker <- payload
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Register a ticker to remove renditions cached before the retention period
+	pruneRenditionTicker := make(chan json.RawMessage, 100)
+	_, err = manager.queue.RegisterTicker(ctx, "rendition-prune-ticker", pgqueueschema.TickerMeta{
+		Interval: types.Ptr(schema.RenditionPruneInterval),
+	}, func(ctx context.Context, payload json.RawMessage) (any, error) {
+		pruneRenditionTicker <- payload
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Register a worker to process volume indexing jobs. Objects are indexed
+	// in the queue of each volume, or the shared queue when the volume does not
+	// have a queue.
+	warnChan := make(chan error, 100)
+	indexObjectFn := func(ctx context.Context, payload json.RawMessage) (any, error) {
 		// Get the payload
 		var task indexObjectTask
 		if err := json.Unmarshal(payload, &task); err != nil {
 			return nil, gofiler.ErrInternalServerError.Withf("invalid payload: %v", err.Error())
 		}
 
-		// Index the object
-		logger.DebugContext(ctx, "Index object", "object", types.Stringify(task.ObjectKey), "force", task.Force)
-		indexed, err := manager.indexObject(ctx, task.ObjectKey, task.Force)
+		// Hand the object over to the indexer which has claimed the volume, or
+		// which has the capabilities for the object. After a number of attempts,
+		// the object is indexed by this instance, and fails when this instance
+		// has not mounted the volume.
+		var indexed *schema.Object
+		var skipped bool
+		var err error
+		if !manager.indexes(task.ObjectKey) && task.Deferred < schema.IndexerMaxDefer {
+			logger.DebugContext(ctx, "Defer object", "object", types.Stringify(task.ObjectKey), "deferred", task.Deferred)
+			if err := manager.deferIndexObject(ctx, task); err != nil {
+				return nil, gofiler.ErrInternalServerError.Withf("failed to defer object: %v", err.Error())
+			}
+			return nil, nil
+		} else if !manager.indexes(task.ObjectKey) && manager.volumes.Get(task.Volume) == nil {
+			err = gofiler.ErrServiceUnavailable.Withf("no indexer took the object after %d attempts, and volume %q is not mounted", task.Deferred, task.Volume)
+		} else if skipped, err = manager.indexIgnored(ctx, task.ObjectKey); err != nil {
+			return nil, gofiler.ErrInternalServerError.Withf("failed to index object: %v", err.Error())
+		} else if skipped {
+			logger.DebugContext(ctx, "Ignore object", "object", types.Stringify(task.ObjectKey))
+		} else {
+			logger.DebugContext(ctx, "Index object", "object", types.Stringify(task.ObjectKey), "force", task.Force)
+			indexed, skipped, err = manager.indexObject(ctx, task.ObjectKey, task.Force)
+		}
+
+		// Record the result in the progress of the index run
+		failed := err != nil && indexed == nil
+		if task.Run {
+			result := schema.IndexRunResult{Volume: task.Volume, Skipped: skipped, Failed: failed}
+			if err != nil {
+				result.Error = fmt.Sprintf("%s: %v", task.Path, err)
+			}
+			if err := manager.PoolConn.Update(ctx, nil, result, nil); err != nil {
+				warnChan <- fmt.Errorf("index %q: %w", task.Path, err)
+			}
+		}
+
+		// Record a failed attempt, or remove the failure when indexed
+		var failure error
+		if failed {
+			failure = err
+		}
+		if err := manager.indexFailed(ctx, task.ObjectKey, failure); err != nil {
+			warnChan <- fmt.Errorf("index %q: %w", task.Path, err)
+		}
+
 		if err != nil && indexed == nil {
 			return nil, gofiler.ErrInternalServerError.Withf("failed to index object: %v", err.Error())
 		} else if err != nil {
 			warnChan <- fmt.Errorf("index %q: %w", task.Path, err)
 		}
 		return indexed, nil
-	})
+	}
+	indexQueue, err := manager.queue.RegisterQueue(ctx, schema.IndexQueue, indexQueueMeta(schema.IndexConcurrency), indexObjectFn)
 	if err != nil {
 		return err
 	}
 	manager.indexQueue = indexQueue
 
+	// Register the queue and reindexing ticker of each volume
+	syncSchedules := func() error {
+		return manager.syncSchedules(ctx, logger, indexObjectFn, reindexVolumeFn)
+	}
+	if err := syncSchedules(); err != nil {
+		return err
+	}
+
+	// Register this instance as an indexer, and read the indexers and the
+	// volumes they have claimed. Indexers send a heartbeat on every instance,
+	// so the ticker is not shared through the queue.
+	if err := manager.heartbeat(ctx); err != nil {
+		return err
+	}
+
+	// Register the batch queue. Operations which already have a result are
+	// skipped, so a batch interrupted by shutdown continues when retried.
+	batchQueue, err := manager.queue.RegisterQueue(ctx, "batch", pgqueueschema.QueueMeta{
+		TTL:         types.Ptr(time.Duration(time.Hour)),
+		Retries:     types.Ptr(uint64(3)),
+		RetryDelay:  types.Ptr(time.Minute),
+		Concurrency: types.Ptr(uint64(1)),
+	}, func(ctx context.Context, payload json.RawMessage) (any, error) {
+		// Get the payload
+		var task batchTask
+		if err := json.Unmarshal(payload, &task); err != nil {
+			return nil, gofiler.ErrInternalServerError.Withf("invalid payload: %v", err.Error())
+		}
+
+		// Run the batch
+		logger.DebugContext(ctx, "Run batch", "id", task.ID)
+		batch, err := manager.runBatch(ctx, task.ID)
+		if err != nil {
+			return nil, gofiler.ErrInternalServerError.Withf("failed to run batch: %v", err.Error())
+		}
+		return batch, nil
+	})
+	if err != nil {
+		return err
+	}
+	manager.batchQueue = batchQueue
+
 	// Allow graceful queue drain to cover task TTL plus pgqueue's force-release
 	// grace period, with a small buffer for cleanup/logging.
 	drainTimeout := types.Value(indexQueue.TTL) + time.Minute + 30*time.Second
@@ -136,11 +301,57 @@ func (manager *Manager) Run(ctx context.Context, logger *slog.Logger) error {
 		}
 	}()
 
+	// Reindex volumes in the background, one at a time, so that reindexing a
+	// volume does not delay the heartbeat or the events in the runloop
+	reindexDone := make(chan struct{})
+	go func(ctx context.Context) {
+		defer close(reindexDone)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case name := <-reindexVolumes:
+				logger.DebugContext(ctx, "Reindex volume", "name", name)
+				reindexPending.Delete(name)
+
+				// Reindex the volume when it is due
+				if err := manager.reindexVolume(ctx, name, logger); err != nil {
+					logger.ErrorContext(ctx, "failed to reindex volume", "name", name, "error", err.Error())
+				}
+			}
+		}
+	}(ctx)
+
+	// Send a heartbeat on its own ticker, and queue the claimed volumes for
+	// reindexing, which reindexes them when they are due
+	heartbeatDone := make(chan struct{})
+	go func(ctx context.Context) {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(schema.IndexerHeartbeat)
+		defer ticker.Stop()
+		for {
+			for _, name := range manager.claimedVolumes() {
+				queueReindex(ctx, name)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := manager.heartbeat(ctx); err != nil {
+					logger.ErrorContext(ctx, "failed to send indexer heartbeat", "error", err.Error())
+				}
+			}
+		}
+	}(ctx)
+
 	ctxDone := ctx.Done()
 	var shutdownTimer *time.Timer
 	var shutdownTimeout <-chan time.Time
 	syncVolumesTickerC := syncVolumesTicker
-	reindexVolumesTickerC := reindexVolumesTicker
+	gcArtworkTickerC := gcArtworkTicker
+	pruneAuditTickerC := pruneAuditTicker
+	pruneBatchTickerC := pruneBatchTicker
+	pruneRenditionTickerC := pruneRenditionTicker
 	defer func() {
 		if shutdownTimer != nil {
 			shutdownTimer.Stop()
@@ -155,9 +366,21 @@ func (manager *Manager) Run(ctx context.Context, logger *slog.Logger) error {
 			ctxDone = nil
 			volumeChange = nil
 			providerChange = nil
+			eventChange = nil
 			syncVolumesTickerC = nil
-			reindexVolumesTickerC = nil
+			gcArtworkTickerC = nil
+			pruneAuditTickerC = nil
+			pruneBatchTickerC = nil
+			pruneRenditionTickerC = nil
 			ctx = context.WithoutCancel(ctx)
+
+			// Wait for the heartbeat and reindexing to stop, then release the
+			// volumes claimed by this indexer to other indexers
+			<-heartbeatDone
+			<-reindexDone
+			if err := manager.unregisterIndexer(ctx); err != nil {
+				logger.WarnContext(ctx, "failed to unregister indexer", "name", manager.indexerName, "error", err.Error())
+			}
 			shutdownTimer = time.NewTimer(drainTimeout)
 			shutdownTimeout = shutdownTimer.C
 		case <-queueDone:
@@ -178,28 +401,77 @@ func (manager *Manager) Run(ctx context.Context, logger *slog.Logger) error {
 			if err := manager.syncLLMProviders(ctx, logger); err != nil {
 				logger.ErrorContext(ctx, "failed to sync LLM providers", "error", err.Error())
 			}
+		case notification, ok := <-eventChange:
+			if !ok {
+				eventChange = nil
+			} else if err := manager.publishNotification(notification); err != nil {
+				logger.WarnContext(ctx, "ignoring event", "error", err.Error())
+			}
 		case event := <-volumeChange:
 			logger.DebugContext(ctx, "Volume change", "event", types.Stringify(event))
 
-			// Syncronize the volume registry
-			if err := manager.syncVolumes(ctx, logger); err != nil {
-				logger.ErrorContext(ctx, "failed to sync volumes", "error", err.Error())
+			// Syncronize the volume registry and the volume schedules
+			if manager.indexer {
+				if err := manager.syncVolumes(ctx, logger); err != nil {
+					logger.ErrorContext(ctx, "failed to sync volumes", "error", err.Error())
+				}
+			}
+			if err := syncSchedules(); err != nil {
+				logger.ErrorContext(ctx, "failed to sync volume schedules", "error", err.Error())
 			}
 		case <-syncVolumesTickerC:
-			if manager.indexer {
-				logger.DebugContext(ctx, "Sync volumes ticker", "event", "sync-volumes-ticker")
+			logger.DebugContext(ctx, "Sync volumes ticker", "event", "sync-volumes-ticker")
 
-				// Syncronize the volume registry
+			// Syncronize the volume registry and the volume schedules
+			if manager.indexer {
 				if err := manager.syncVolumes(ctx, logger); err != nil {
 					logger.ErrorContext(ctx, "failed to sync volumes", "error", err.Error())
 				}
 			}
-		case <-reindexVolumesTickerC:
-			logger.DebugContext(ctx, "Reindex volumes ticker", "event", "reindex-volumes-ticker")
-
-			// Look for a volume that needs to be reindexed, and reindex it
-			if err := manager.reindexVolumes(ctx, logger); err != nil {
-				logger.ErrorContext(ctx, "failed to reindex volumes", "error", err.Error())
+			if err := syncSchedules(); err != nil {
+				logger.ErrorContext(ctx, "failed to sync volume schedules", "error", err.Error())
+			}
+		case <-gcArtworkTickerC:
+			logger.DebugContext(ctx, "Artwork garbage collection ticker", "event", "gc-artwork-ticker")
+
+			// Remove artwork which has not been linked within the grace period
+			if n, err := manager.gcArtwork(ctx, schema.ArtworkGracePeriod); err != nil {
+				logger.ErrorContext(ctx, "failed to remove unlinked artwork", "error", err.Error())
+			} else if n > 0 {
+				logger.InfoContext(ctx, "removed unlinked artwork", "count", n)
+			}
+		case <-pruneAuditTickerC:
+			if manager.auditRetention > 0 {
+				logger.DebugContext(ctx, "Audit prune ticker", "event", "audit-prune-ticker")
+
+				// Remove audit entries which are older than the retention period
+				if n, err := manager.pruneAudit(ctx, manager.auditRetention); err != nil {
+					logger.ErrorContext(ctx, "failed to prune audit log", "error", err.Error())
+				} else if n > 0 {
+					logger.InfoContext(ctx, "pruned audit log", "count", n)
+				}
+			}
+		case <-pruneBatchTickerC:
+			if manager.batchRetention > 0 {
+				logger.DebugContext(ctx, "Batch prune ticker", "event", "batch-prune-ticker")
+
+				// Remove batches which finished before the retention period
+				if n, err := manager.pruneBatches(ctx, manager.batchRetention); err != nil {
+					logger.ErrorContext(ctx, "failed to prune batches", "error", err.Error())
+				} else if n > 0 {
+					logger.InfoContext(ctx, "pruned batches", "count", n)
+				}
+			}
+		case <-pruneRenditionTickerC:
+			if manager.renditionRetention > 0 {
+				logger.DebugContext(ctx, "Rendition prune ticker", "event", "rendition-prune-ticker")
+
+				// Remove renditions which were cached before the retention period
+				if n, err := manager.pruneRenditions(ctx, manager.renditionRetention); err != nil {
+					logger.ErrorContext(ctx, "failed to prune renditions", "error", err.Error())
+				} else if n > 0 {
+					logger.InfoContext(ctx, "pruned renditions", "count", n)
+				}
 			}
 		}
 	}
@@ -208,7 +480,10 @@ func (manager *Manager) Run(ctx context.Context, logger *slog.Logger) error {
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
-func (manager *Manager) indexObject(ctx context.Context, key schema.ObjectKey, force bool) (_ *schema.Object, err error) {
+// indexObject reads the object from the backend and writes it to the index,
+// and returns the indexed object. Objects which are unchanged or have been
+// removed from the backend are skipped.
+func (manager *Manager) indexObject(ctx context.Context, key schema.ObjectKey, force bool) (_ *schema.Object, skipped bool, err error) {
 	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "indexObject",
 		attribute.String("object", types.Stringify(key)),
 		attribute.Bool("force", force),
@@ -218,7 +493,16 @@ func (manager *Manager) indexObject(ctx context.Context, key schema.ObjectKey, f
 	// Obtain the backend of the object - backend might be disabled, so don't error
 	backend := manager.volumes.Get(key.Volume)
 	if backend == nil {
-		return nil, nil
+		return nil, true, nil
+	}
+
+	// Get the indexing rules for the volume, and remove objects excluded by
+	// path from the index
+	var rules schema.VolumeRules
+	if err := manager.Get(ctx, &rules, schema.VolumeRulesName(key.Volume)); err != nil {
+		return nil, false, err
+	} else if !rules.Indexes(key.Path, "") {
+		return nil, true, manager.unindexObject(ctx, key)
 	}
 
 	// Read the object from the backend and extract metadata
@@ -227,38 +511,38 @@ func (manager *Manager) indexObject(ctx context.Context, key schema.ObjectKey, f
 	})
 	if errors.Is(err, gofiler.ErrNotFound) {
 		// Object no longer exists in the backend — remove it from the index
-		var deleted schema.Object
-		if delErr := manager.Tx(ctx, func(conn pg.Conn) error {
-			return conn.Delete(ctx, &deleted, key)
-		}); delErr != nil && !errors.Is(delErr, pg.ErrNotFound) {
-			return nil, delErr
-		}
-		return nil, nil
+		return nil, true, manager.unindexObject(ctx, key)
 	} else if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer func() {
 		err = errors.Join(err, reader.Close())
 	}()
 
+	// Remove objects excluded by content type from the index
+	if !rules.Indexes(object.Path, object.ContentType) {
+		return nil, true, manager.unindexObject(ctx, key)
+	}
+
 	// Unless forced, check if the object has changed and skip re-indexing if not
 	if !force {
 		existing, err := manager.GetObject(ctx, object.ObjectKey)
 		if errors.Is(err, pg.ErrNotFound) {
 			// Continue
 		} else if err != nil {
-			return nil, err
+			return nil, false, err
 		} else if (existing.ETag != nil && object.ETag != nil && types.Value(existing.ETag) == types.Value(object.ETag)) ||
 			(!existing.ModTime.IsZero() && !object.ModTime.IsZero() && existing.ModTime.Truncate(time.Second).Equal(object.ModTime.Truncate(time.Second))) {
 			// Object unchanged — touch indexed_at and return the refreshed object
-			return manager.touchObject(ctx, object.ObjectKey)
+			touched, err := manager.touchObject(ctx, object.ObjectKey)
+			return touched, err == nil, err
 		}
 	}
 
 	// Get the metadata for the object - hard error if nothing was extracted, warning otherwise
-	metadata, artwork, metaErr := manager.metadata.Get(ctx, object.ContentType, reader)
+	metadata, artwork, metaErr := manager.extractMetadata(ctx, rules, object, reader)
 	if metaErr != nil && metadata == nil {
-		return nil, metaErr
+		return nil, false, extractError{extractor: manager.metadata.Extractor(object.ContentType), err: metaErr}
 	}
 
 	// Create the object in a transaction, and touch the volume's indexed_at
@@ -271,50 +555,76 @@ func (manager *Manager) indexObject(ctx context.Context, key schema.ObjectKey, f
 		},
 	}, artwork)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	return result, metaErr
-}
 
-func (manager *Manager) reindexVolumes(ctx context.Context, logger *slog.Logger) error {
-	var result schema.VolumeList
-	if err := manager.List(ctx, &result, &schema.VolumeListRequest{
-		Enabled: types.Ptr(true),
-		Stale:   true,
-		OffsetLimit: pg.OffsetLimit{
-			Offset: 0,
-			Limit:  types.Ptr(uint64(1)),
-		},
+	// Notify subscribers of the indexed object
+	if err := manager.notifyEvents(ctx, schema.Event{
+		Type:   schema.EventObjectIndex,
+		Volume: result.Volume,
+		Path:   result.Path,
 	}); err != nil {
-		return err
+		return result, false, err
 	}
-	if len(result.Body) == 0 {
-		return nil
-	} else if types.Value(result.Body[0].Enabled) == false {
-		// Do nothing
-	} else if backend := manager.volumes.Get(result.Body[0].Name); backend == nil {
-		logger.WarnContext(ctx, "volume not found in registry", "name", result.Body[0].Name)
-	} else {
-		// Touch indexed_at before reindexing so concurrent workers will not pick
-		// this volume as stale in the same scheduling window.
-		var touched schema.Volume
-		if err := manager.Update(ctx, &touched, schema.VolumeTouch(backend.Name()), nil); err != nil {
-			return err
-		}
+	return result, false, metaErr
+}
 
-		// Create reindexing tasks for all objects in the volume
-		return manager.reindexVolumeInner(ctx, backend, logger)
+// unindexObject removes an object from the index, when it no longer exists
+// in the backend or is excluded by the indexing rules of the volume, and
+// notifies subscribers when it was indexed
+func (manager *Manager) unindexObject(ctx context.Context, key schema.ObjectKey) error {
+	var deleted schema.Object
+	if err := manager.Tx(ctx, func(conn pg.Conn) error {
+		return conn.Delete(ctx, &deleted, key)
+	}); errors.Is(err, pg.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
 	}
+	return manager.notifyEvents(ctx, schema.Event{
+		Type:   schema.EventObjectUnindex,
+		Volume: key.Volume,
+		Path:   key.Path,
+	})
+}
 
-	return nil
+// extractMetadata extracts metadata and artwork from an object, restricted by
+// the indexing rules of the volume. No metadata is extracted when the object
+// is larger than the maximum size, or the extractor is not enabled.
+func (manager *Manager) extractMetadata(ctx context.Context, rules schema.VolumeRules, object *schema.Object, r io.Reader) ([]schema.Meta, []*schema.ArtworkMeta, error) {
+	if extractor := manager.metadata.Extractor(object.ContentType); extractor != "" && !rules.Extracts(object.Size, extractor) {
+		return []schema.Meta{}, nil, nil
+	}
+	if rules.Summarize != nil {
+		ctx = metadata.WithSummarize(ctx, types.Value(rules.Summarize))
+	}
+	if timeout := types.Value(rules.Timeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return manager.metadata.Get(ctx, object.ContentType, r)
 }
 
+// reindexVolumeInner creates reindexing tasks for all objects in the volume.
+// Indexed objects are marked with a new sweep generation as they are listed,
+// and when the listing is complete, objects which were not seen are removed.
 func (manager *Manager) reindexVolumeInner(ctx context.Context, backend backend.Backend, logger *slog.Logger) error {
 	logger.DebugContext(ctx, "reindexing", "name", backend.Name())
 
+	// Get the indexing rules, and start a new sweep generation
+	var rules schema.VolumeRules
+	sweep := schema.ObjectSweep{Volume: backend.Name(), Threshold: manager.sweepThreshold}
+	if err := manager.Get(ctx, &rules, schema.VolumeRulesName(backend.Name())); err != nil {
+		return err
+	} else if err := manager.Update(ctx, &sweep, schema.VolumeSweep(backend.Name()), nil); err != nil {
+		return err
+	}
+
 	iterator := &schema.ObjectListIterator{
 		Recursive: true,
 	}
+	var seen uint64
 	for {
 		err := backend.ListObjects(ctx, iterator)
 		if errors.Is(err, io.EOF) {
@@ -322,13 +632,45 @@ func (manager *Manager) reindexVolumeInner(ctx context.Context, backend backend.
 		} else if err != nil {
 			return gofiler.ErrInternalServerError.Withf("backend %q failure: %v", backend.Name(), err.Error())
 		}
+		sweep.Paths = sweep.Paths[:0]
 		for _, object := range iterator.Body {
 			if object.ContentType == schema.ContentTypeDirectory {
 				continue
 			}
-			if err := manager.enqueueIndexObject(ctx, object.ObjectKey, false); err != nil {
+
+			// Objects excluded by the rules are not marked, and are swept
+			if !rules.Indexes(object.Path, object.ContentType) {
+				continue
+			}
+			if err := manager.enqueueIndexRun(ctx, object.ObjectKey, false); err != nil {
 				return err
 			}
+			sweep.Paths = append(sweep.Paths, object.Path)
+		}
+
+		// Mark the objects which have been seen
+		if len(sweep.Paths) > 0 {
+			if err := manager.Update(ctx, nil, sweep, nil); err != nil {
+				return err
+			}
+			seen += uint64(len(sweep.Paths))
+		}
+	}
+
+	// Remove the objects which were not seen in the listing. An empty listing
+	// is not swept, in case the backend is unavailable
+	if manager.sweepThreshold == 0 {
+		// Sweeping is disabled
+	} else if seen == 0 {
+		logger.DebugContext(ctx, "sweep skipped, no objects listed", "name", backend.Name())
+	} else {
+		var result schema.ObjectSweepResult
+		if err := manager.Delete(ctx, &result, sweep); err != nil {
+			return err
+		} else if result.Deleted < result.Unseen {
+			logger.WarnContext(ctx, "sweep skipped, too many objects not found", "name", backend.Name(), "unseen", result.Unseen, "total", result.Total)
+		} else if result.Deleted > 0 {
+			logger.InfoContext(ctx, "swept objects not found", "name", backend.Name(), "deleted", result.Deleted)
 		}
 	}
 
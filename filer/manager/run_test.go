@@ -0,0 +1,121 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pgqueueschema "github.com/mutablelogic/go-pg/pgqueue/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// HELPERS
+
+// testIndexQueue registers the queue for indexing objects, so that objects
+// can be queued for indexing. The tasks are not run.
+func testIndexQueue(t *testing.T, manager *Manager) {
+	t.Helper()
+	queue, err := manager.queue.RegisterQueue(context.Background(), schema.IndexQueue, pgqueueschema.QueueMeta{
+		TTL: types.Ptr(time.Duration(5 * time.Minute)),
+	}, func(context.Context, json.RawMessage) (any, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.indexQueue = queue
+}
+
+// testIndexOnly adds an object to the index which is not in the volume
+func testIndexOnly(t *testing.T, manager *Manager, volume, path string) {
+	t.Helper()
+	testIndexObject(t, manager, &schema.Object{
+		ObjectKey:  schema.ObjectKey{Volume: volume, Path: path},
+		ObjectMeta: schema.ObjectMeta{ContentType: "text/plain"},
+		ObjectAttr: schema.ObjectAttr{Size: int64(len(path))},
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// SWEEP
+
+func TestSweep_001(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		objects   []string // objects in the volume, which are indexed
+		missing   []string // objects in the index which are not in the volume
+		swept     bool
+	}{
+		{"missing removed", 0.5, []string{"a", "b/c", "d"}, []string{"e"}, true},
+		{"at threshold", 0.5, []string{"a", "b"}, []string{"c", "d"}, true},
+		{"over threshold", 0.5, []string{"a"}, []string{"b", "c"}, false},
+		{"sweep disabled", 0, []string{"a", "b", "c"}, []string{"d"}, false},
+		{"empty listing", 1, nil, []string{"a"}, false},
+	}
+	for _, test := range tests {
+		manager := testManager(t, WithSweepThreshold(test.threshold))
+		backend := testVolume(t, manager, "test", schema.VolumeMeta{})
+		testIndexQueue(t, manager)
+		for _, path := range test.objects {
+			testObject(t, manager, "test", path)
+		}
+		for _, path := range test.missing {
+			testIndexOnly(t, manager, "test", path)
+		}
+
+		// Reindex the volume, and check the objects in the index
+		if err := manager.reindexVolumeInner(context.Background(), backend, slog.Default()); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		for _, path := range test.objects {
+			if indexed(t, manager, "test", path) == nil {
+				t.Errorf("%s: %q was removed", test.name, path)
+			}
+		}
+		for _, path := range test.missing {
+			if swept := indexed(t, manager, "test", path) == nil; swept != test.swept {
+				t.Errorf("%s: %q: got swept=%v, want %v", test.name, path, swept, test.swept)
+			}
+		}
+	}
+}
+
+func TestSweep_002(t *testing.T) {
+	ctx := context.Background()
+	manager := testManager(t)
+	testVolume(t, manager, "test", schema.VolumeMeta{})
+	testIndexOnly(t, manager, "test", "before")
+
+	// Objects which are indexed after a sweep starts have the new generation,
+	// and are not removed by the sweep
+	sweep := schema.ObjectSweep{Volume: "test", Threshold: 1}
+	if err := manager.Update(ctx, &sweep, schema.VolumeSweep("test"), nil); err != nil {
+		t.Fatal(err)
+	}
+	testIndexOnly(t, manager, "test", "during")
+
+	var result schema.ObjectSweepResult
+	if err := manager.Delete(ctx, &result, sweep); err != nil {
+		t.Fatal(err)
+	} else if result.Total != 2 || result.Unseen != 1 || result.Deleted != 1 {
+		t.Errorf("got %+v, want 2 total, 1 unseen and 1 deleted", result)
+	}
+	tests := []struct {
+		path    string
+		indexed bool
+	}{
+		{"before", false},
+		{"during", true},
+	}
+	for _, test := range tests {
+		if got := indexed(t, manager, "test", test.path) != nil; got != test.indexed {
+			t.Errorf("%q: got indexed=%v, want %v", test.path, got, test.indexed)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	pgqueueschema "github.com/mutablelogic/go-pg/pgqueue/schema"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// schedules are the queues and tickers of volumes which have a callback in
+// this instance, and whether the queue or ticker exists in the database.
+// Another instance can remove a queue or ticker before this instance does,
+// in which case the callback remains until the queue or ticker is restored.
+type schedules struct {
+	sync.RWMutex
+	tasks map[string]bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// The ticker which reindexed one stale volume at a time, which is replaced
+	// by a ticker for each volume
+	legacyReindexTicker = "reindex-volumes-ticker"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// indexQueueMeta returns the queue configuration for indexing objects, with
+// the maximum number of objects indexed at once
+func indexQueueMeta(concurrency uint64) pgqueueschema.QueueMeta {
+	return pgqueueschema.QueueMeta{
+		TTL:         types.Ptr(time.Duration(5 * time.Minute)),
+		Retries:     types.Ptr(uint64(3)),
+		RetryDelay:  types.Ptr(time.Minute),
+		Concurrency: types.Ptr(concurrency),
+	}
+}
+
+// volumeQueue returns the queue for indexing objects in a volume, or the
+// shared index queue when the volume does not have a queue
+func (manager *Manager) volumeQueue(volume string) string {
+	name := schema.VolumeQueue(volume)
+	manager.schedules.RLock()
+	defer manager.schedules.RUnlock()
+	if manager.schedules.tasks[name] {
+		return name
+	}
+	return manager.indexQueue.Queue
+}
+
+// syncSchedules registers a queue for indexing objects in each enabled volume,
+// with the concurrency of the volume schedule, and a ticker for reindexing
+// each enabled volume which has a schedule. The queues and tickers of volumes
+// which have been disabled, deleted or are no longer scheduled are removed.
+func (manager *Manager) syncSchedules(ctx context.Context, logger *slog.Logger, indexFn, reindexFn pgqueueschema.TaskFunc) error {
+	queues := make(map[string]pgqueueschema.QueueMeta)
+	tickers := make(map[string]pgqueueschema.TickerMeta)
+
+	// Determine the queues and tickers from the volumes
+	var offset uint64
+	for {
+		volumes, err := manager.ListVolumes(ctx, schema.VolumeListRequest{
+			Enabled:     types.Ptr(true),
+			OffsetLimit: pg.OffsetLimit{Offset: offset},
+		})
+		if err != nil {
+			return err
+		} else if len(volumes.Body) == 0 {
+			break
+		}
+		for _, volume := range volumes.Body {
+			queues[schema.VolumeQueue(volume.Name)] = indexQueueMeta(volume.Schedule.IndexConcurrency())
+			if volume.Scheduled() {
+				payload, err := json.Marshal(volume.Name)
+				if err != nil {
+					return err
+				}
+				tickers[schema.VolumeTicker(volume.Name)] = pgqueueschema.TickerMeta{
+					Interval: types.Ptr(schema.ScheduleInterval),
+					Payload:  payload,
+				}
+			}
+		}
+		offset += uint64(len(volumes.Body))
+	}
+
+	// Get the queues and tickers of volumes which exist
+	existing, err := manager.listVolumeTasks(ctx)
+	if err != nil {
+		return err
+	}
+
+	manager.schedules.Lock()
+	defer manager.schedules.Unlock()
+	if manager.schedules.tasks == nil {
+		manager.schedules.tasks = make(map[string]bool)
+	}
+	for name := range manager.schedules.tasks {
+		_, exists := existing[name]
+		manager.schedules.tasks[name] = exists
+	}
+
+	// Register the queues and tickers, or update them when the callback is
+	// already registered
+	var result error
+	for name, meta := range queues {
+		if exists, registered := manager.schedules.tasks[name]; !registered {
+			if _, err := manager.queue.RegisterQueue(ctx, name, meta, indexFn); err != nil {
+				result = errors.Join(result, err)
+			} else {
+				manager.schedules.tasks[name] = true
+				logger.DebugContext(ctx, "registered volume queue", "queue", name, "concurrency", types.Value(meta.Concurrency))
+			}
+		} else if exists {
+			if _, err := manager.queue.UpdateQueue(ctx, name, meta); err != nil {
+				result = errors.Join(result, err)
+			}
+		}
+	}
+	for name, meta := range tickers {
+		if exists, registered := manager.schedules.tasks[name]; !registered {
+			if _, err := manager.queue.RegisterTicker(ctx, name, meta, reindexFn); err != nil {
+				result = errors.Join(result, err)
+			} else {
+				manager.schedules.tasks[name] = true
+				logger.DebugContext(ctx, "registered volume ticker", "ticker", name)
+			}
+		} else if exists {
+			if _, err := manager.queue.UpdateTicker(ctx, name, meta); err != nil {
+				result = errors.Join(result, err)
+			}
+		}
+	}
+
+	// Remove the queues and tickers which are no longer required. Removing a
+	// queue removes the tasks which are waiting in the queue.
+	for name, ticker := range existing {
+		if _, required := queues[name]; required {
+			continue
+		} else if _, required := tickers[name]; required {
+			continue
+		}
+		if ticker {
+			_, err = manager.queue.DeleteTicker(ctx, name)
+		} else {
+			_, err = manager.queue.DeleteQueue(ctx, name)
+		}
+		if errors.Is(err, pg.ErrNotFound) {
+			// Removed by another instance
+			continue
+		} else if err != nil && !errors.Is(err, httpresponse.ErrNotFound) {
+			result = errors.Join(result, err)
+			continue
+		}
+		delete(manager.schedules.tasks, name)
+		logger.DebugContext(ctx, "removed volume schedule", "name", name)
+	}
+
+	// Return any errors
+	return result
+}
+
+// listVolumeTasks returns the names of the queues and tickers of volumes
+// which exist in the database, and whether each is a ticker
+func (manager *Manager) listVolumeTasks(ctx context.Context) (map[string]bool, error) {
+	result := make(map[string]bool)
+	for offset := uint64(0); ; {
+		queues, err := manager.queue.ListQueues(ctx, pgqueueschema.QueueListRequest{OffsetLimit: pg.OffsetLimit{Offset: offset}})
+		if err != nil {
+			return nil, err
+		} else if len(queues.Body) == 0 {
+			break
+		}
+		for _, queue := range queues.Body {
+			if schema.IsVolumeQueue(queue.Queue) {
+				result[queue.Queue] = false
+			}
+		}
+		offset += uint64(len(queues.Body))
+	}
+	for offset := uint64(0); ; {
+		tickers, err := manager.queue.ListTickers(ctx, pgqueueschema.TickerListRequest{OffsetLimit: pg.OffsetLimit{Offset: offset}})
+		if err != nil {
+			return nil, err
+		} else if len(tickers.Body) == 0 {
+			break
+		}
+		for _, ticker := range tickers.Body {
+			if schema.IsVolumeTicker(ticker.Ticker) {
+				result[ticker.Ticker] = true
+			}
+		}
+		offset += uint64(len(tickers.Body))
+	}
+	return result, nil
+}
+
+// reindexVolume reindexes a volume when it is due to be reindexed by its
+// schedule, and the volume is not claimed by another indexer
+func (manager *Manager) reindexVolume(ctx context.Context, name string, logger *slog.Logger) error {
+	if !manager.claimed(name) {
+		return nil
+	}
+
+	var volume schema.Volume
+	if err := manager.Get(ctx, &volume, schema.VolumeName(name)); errors.Is(err, pg.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if !volume.Due(time.Now()) {
+		return nil
+	}
+
+	backend := manager.volumes.Get(volume.Name)
+	if backend == nil {
+		if manager.indexer {
+			logger.WarnContext(ctx, "volume not found in registry", "name", volume.Name)
+		}
+		return nil
+	}
+
+	// Touch indexed_at before reindexing so other instances will not reindex
+	// the volume in the same scheduling window, and start a new index run to
+	// track the progress
+	if err := manager.Tx(ctx, func(conn pg.Conn) error {
+		if err := conn.Update(ctx, nil, schema.VolumeTouch(backend.Name()), nil); err != nil {
+			return err
+		}
+		return conn.Insert(ctx, nil, schema.IndexRunStart(backend.Name()))
+	}); err != nil {
+		return err
+	}
+
+	// Create reindexing tasks for all objects in the volume
+	return manager.reindexVolumeInner(ctx, backend, logger)
+}
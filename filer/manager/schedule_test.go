@@ -0,0 +1,209 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// HELPERS
+
+// scheduledVolume returns an enabled volume with a cron expression, last
+// indexed at a time
+func scheduledVolume(cron string, indexedAt time.Time) schema.Volume {
+	var volume schema.Volume
+	volume.Name = "test"
+	volume.Enabled = types.Ptr(true)
+	volume.Schedule.Cron = types.Ptr(cron)
+	volume.IndexedAt = types.Ptr(indexedAt)
+	return volume
+}
+
+// nextDue returns the first minute after a time at which a volume with a
+// cron expression is due to be reindexed, or the zero time if it is not due
+// within a year. A volume which is due remains due, so the time is found by
+// bisection.
+func nextDue(cron string, from time.Time) time.Time {
+	volume := scheduledVolume(cron, from)
+	lo, hi := from, from.AddDate(1, 0, 0)
+	if !volume.Due(hi) {
+		return time.Time{}
+	}
+	for hi.Sub(lo) > time.Minute {
+		mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Minute)
+		if volume.Due(mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi
+}
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// VALIDATE
+
+func TestScheduleValidate_001(t *testing.T) {
+	tests := []struct {
+		cron, quiet string
+		valid       bool
+	}{
+		{"*/15 * * * *", "", true},
+		{"0 9-17 * * mon-fri", "", true},
+		{"0 0 1 JAN,jul *", "", true},
+		{"0 0 * * 7", "", true},
+		{"@daily", "22:00-06:00", true},
+		{"", "08:00-18:00", true},
+		{"* * * *", "", false},
+		{"60 * * * *", "", false},
+		{"0 24 * * *", "", false},
+		{"0 0 0 * *", "", false},
+		{"0 0 * * 8", "", false},
+		{"*/0 * * * *", "", false},
+		{"5-1 * * * *", "", false},
+		{"0 0 * foo *", "", false},
+		{"@never", "", false},
+		{"", "08:00-08:00", false},
+		{"", "25:00-01:00", false},
+		{"", "08:00", false},
+	}
+	for _, test := range tests {
+		schedule := schema.VolumeSchedule{Cron: types.Ptr(test.cron), QuietHours: types.Ptr(test.quiet)}
+		if err := schedule.Validate(); (err == nil) != test.valid {
+			t.Errorf("cron %q, quiet %q: expected valid=%v, got %v", test.cron, test.quiet, test.valid, err)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CRON
+
+func TestScheduleCron_001(t *testing.T) {
+	// 1 January 2024 is a Monday
+	tests := []struct {
+		name string
+		cron string
+		from time.Time
+		want time.Time
+	}{
+		// Steps
+		{"step", "*/15 * * * *", date(2024, 1, 1, 10, 7), date(2024, 1, 1, 10, 15)},
+		{"step from value", "5/20 * * * *", date(2024, 1, 1, 10, 30), date(2024, 1, 1, 10, 45)},
+		{"step hours", "0 */6 * * *", date(2024, 1, 1, 7, 0), date(2024, 1, 1, 12, 0)},
+		{"step range", "10-20/5 * * * *", date(2024, 1, 1, 10, 16), date(2024, 1, 1, 10, 20)},
+
+		// Ranges and lists
+		{"range", "0 9-17 * * *", date(2024, 1, 1, 17, 30), date(2024, 1, 2, 9, 0)},
+		{"weekdays", "0 0 * * 1-5", date(2024, 1, 6, 12, 0), date(2024, 1, 8, 0, 0)},
+		{"list", "0 0 1,15 * *", date(2024, 1, 2, 0, 0), date(2024, 1, 15, 0, 0)},
+
+		// Names and macros
+		{"day name", "0 0 * * sat", date(2024, 1, 1, 0, 0), date(2024, 1, 6, 0, 0)},
+		{"month name", "0 0 1 MAR *", date(2024, 1, 1, 0, 0), date(2024, 3, 1, 0, 0)},
+		{"day name range", "0 0 * * mon-wed", date(2024, 1, 4, 0, 0), date(2024, 1, 8, 0, 0)},
+		{"macro", "@weekly", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 0, 0)},
+
+		// Sunday is either zero or seven
+		{"sunday 0", "0 0 * * 0", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 0, 0)},
+		{"sunday 7", "0 0 * * 7", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 0, 0)},
+		{"sunday name", "0 0 * * sun", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 0, 0)},
+		{"sunday range", "0 0 * * 6-7", date(2024, 1, 6, 0, 0), date(2024, 1, 7, 0, 0)},
+
+		// When both the day of the month and the day of the week are
+		// restricted, either can match
+		{"dom or dow", "0 0 13 * fri", date(2024, 1, 1, 0, 0), date(2024, 1, 5, 0, 0)},
+		{"dom before dow", "0 0 8 * fri", date(2024, 1, 6, 0, 0), date(2024, 1, 8, 0, 0)},
+		{"dow only", "0 0 * * fri", date(2024, 1, 6, 0, 0), date(2024, 1, 12, 0, 0)},
+		{"dom only", "0 0 13 * *", date(2024, 1, 1, 0, 0), date(2024, 1, 13, 0, 0)},
+		{"dom step and dow", "0 0 */10 * mon", date(2024, 1, 1, 0, 0), date(2024, 3, 11, 0, 0)},
+
+		// Never
+		{"never", "0 0 30 2 *", date(2024, 1, 1, 0, 0), time.Time{}},
+	}
+	for _, test := range tests {
+		if got := nextDue(test.cron, test.from); !got.Equal(test.want) {
+			t.Errorf("%s: %q from %v: got %v, want %v", test.name, test.cron, test.from, got, test.want)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// QUIET HOURS
+
+func TestScheduleQuietHours_001(t *testing.T) {
+	tests := []struct {
+		quiet string
+		now   time.Time
+		due   bool
+	}{
+		// Window within a day
+		{"08:00-18:00", date(2024, 1, 1, 7, 59), true},
+		{"08:00-18:00", date(2024, 1, 1, 8, 0), false},
+		{"08:00-18:00", date(2024, 1, 1, 17, 59), false},
+		{"08:00-18:00", date(2024, 1, 1, 18, 0), true},
+
+		// Window which wraps past midnight
+		{"22:00-06:00", date(2024, 1, 1, 21, 59), true},
+		{"22:00-06:00", date(2024, 1, 1, 22, 0), false},
+		{"22:00-06:00", date(2024, 1, 1, 23, 30), false},
+		{"22:00-06:00", date(2024, 1, 2, 0, 0), false},
+		{"22:00-06:00", date(2024, 1, 2, 5, 59), false},
+		{"22:00-06:00", date(2024, 1, 2, 6, 0), true},
+		{"22:00-06:00", date(2024, 1, 2, 12, 0), true},
+	}
+	for _, test := range tests {
+		var volume schema.Volume
+		volume.Enabled = types.Ptr(true)
+		volume.IndexDelta = types.Ptr(time.Hour)
+		volume.Schedule.QuietHours = types.Ptr(test.quiet)
+		if due := volume.Due(test.now); due != test.due {
+			t.Errorf("quiet %q at %v: got %v, want %v", test.quiet, test.now.Format("15:04"), due, test.due)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// DAYLIGHT SAVING TIME
+
+func TestScheduleDST_001(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone not available:", err)
+	}
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return date(year, month, day, hour, minute).In(loc)
+	}
+
+	// Clocks go forward at 02:00 EST on 10 March 2024, and back at
+	// 02:00 EDT on 3 November 2024
+	tests := []struct {
+		name string
+		cron string
+		from time.Time
+		want time.Time
+	}{
+		// A time skipped when the clocks go forward is scheduled after the change
+		{"skipped", "30 2 * * *", at(2024, 3, 9, 8, 0), at(2024, 3, 10, 7, 30)},
+		{"after skipped", "30 2 * * *", at(2024, 3, 10, 7, 30), at(2024, 3, 11, 6, 30)},
+		{"forward", "*/30 * * * *", at(2024, 3, 10, 6, 30), at(2024, 3, 10, 7, 0)},
+
+		// A time repeated when the clocks go back is scheduled once
+		{"before repeated", "30 1 * * *", at(2024, 11, 3, 4, 0), at(2024, 11, 3, 5, 30)},
+		{"repeated", "30 1 * * *", at(2024, 11, 3, 5, 30), at(2024, 11, 4, 6, 30)},
+		{"repeated later", "50 1 * * *", at(2024, 11, 3, 6, 45), at(2024, 11, 4, 6, 50)},
+		{"back", "0 * * * *", at(2024, 11, 3, 5, 0), at(2024, 11, 3, 7, 0)},
+	}
+	for _, test := range tests {
+		if got := nextDue(test.cron, test.from); !got.Equal(test.want) {
+			t.Errorf("%s: %q from %v: got %v, want %v", test.name, test.cron, test.from, got.In(loc), test.want)
+		}
+	}
+}
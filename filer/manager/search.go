@@ -19,6 +19,17 @@ func (manager *Manager) Search(ctx context.Context, req schema.SearchListRequest
 	)
 	defer func() { endSpan(err) }()
 
+	// Search only the volumes which are accessible
+	if volumes := grantedVolumes(ctx); volumes != nil && len(req.Volumes) == 0 {
+		req.Volumes = volumes
+	} else {
+		for _, volume := range req.Volumes {
+			if err := checkVolumeAccess(ctx, volume); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	var result schema.SearchList
 	if err := manager.PoolConn.List(ctx, &result, &req); err != nil {
 		return nil, err
@@ -30,3 +41,30 @@ func (manager *Manager) Search(ctx context.Context, req schema.SearchListRequest
 	// Return success
 	return types.Ptr(result), nil
 }
+
+// StreamSearch calls fn for each search result, in rank order and without a
+// page limit. Results are read one page at a time, and the search stops
+// when fn returns an error.
+func (manager *Manager) StreamSearch(ctx context.Context, req schema.SearchListRequest, fn func(*schema.SearchResult) error) (err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "StreamSearch",
+		attribute.String("req", types.Stringify(req)),
+	)
+	defer func() { endSpan(err) }()
+
+	req.Limit = types.Ptr(uint64(schema.SearchListLimit))
+	for {
+		result, err := manager.Search(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, item := range result.Body {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		req.Offset += uint64(len(result.Body))
+		if len(result.Body) == 0 || req.Offset >= result.Count {
+			return nil
+		}
+	}
+}
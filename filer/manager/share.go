@@ -0,0 +1,259 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// CreateShare creates a share link for an object, or for the objects under
+// a prefix when the path ends with a slash or is not an object. The
+// password is stored as a salted hash.
+func (manager *Manager) CreateShare(ctx context.Context, req schema.ShareCreate) (_ *schema.Share, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "CreateShare",
+		attribute.String("meta", req.ShareMeta.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the volume is accessible
+	if err := checkVolumeAccess(ctx, req.Volume); err != nil {
+		return nil, err
+	}
+
+	// Determine whether the path is an object or a prefix
+	if path := strings.Trim(req.Path, "/"); path == "" {
+		req.Path = "/"
+	} else if strings.HasSuffix(req.Path, "/") {
+		req.Path = "/" + path + "/"
+	} else if _, err := manager.GetObject(ctx, schema.ObjectKey{Volume: req.Volume, Path: "/" + path}); errors.Is(err, gofiler.ErrNotFound) || errors.Is(err, gofiler.ErrBadParameter) {
+		req.Path = "/" + path + "/"
+	} else if err != nil {
+		return nil, err
+	} else {
+		req.Path = "/" + path
+	}
+
+	// Generate the token and hash the password
+	token, err := schema.NewShareToken()
+	if err != nil {
+		return nil, err
+	}
+	password, err := schema.NewSharePassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Insert the share link
+	var result schema.Share
+	if err := manager.PoolConn.Tx(ctx, func(conn pg.Conn) error {
+		if err := conn.Insert(ctx, &result, schema.ShareInsert{ShareMeta: req.ShareMeta, Token: token, PasswordHash: password}); err != nil {
+			return err
+		}
+		return manager.audit(ctx, conn, schema.AuditShareCreate, schema.AuditShareTarget(result.ID), nil, &result)
+	}); err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// GetShare returns a share link by id
+func (manager *Manager) GetShare(ctx context.Context, id schema.ShareID) (_ *schema.Share, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "GetShare",
+		attribute.String("id", types.Stringify(id)),
+	)
+	defer func() { endSpan(err) }()
+
+	var result schema.Share
+	if err := manager.PoolConn.Get(ctx, &result, id); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else if err := checkVolumeAccess(ctx, result.Volume); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// ListShares returns a paginated list of share links. Tokens restricted to
+// volumes only list the share links for those volumes.
+func (manager *Manager) ListShares(ctx context.Context, req schema.ShareListRequest) (_ *schema.ShareList, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ListShares",
+		attribute.String("req", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	// Restrict the list to the accessible volumes
+	if req.Volume != "" {
+		if err := checkVolumeAccess(ctx, req.Volume); err != nil {
+			return nil, err
+		}
+	}
+	req.Volumes = grantedVolumes(ctx)
+
+	var result schema.ShareList
+	if err := manager.PoolConn.List(ctx, &result, &req); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else {
+		result.ShareListRequest = req
+		result.OffsetLimit.Clamp(result.Count)
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// DeleteShare revokes a share link, and returns the deleted share link
+func (manager *Manager) DeleteShare(ctx context.Context, id schema.ShareID) (_ *schema.Share, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "DeleteShare",
+		attribute.String("id", types.Stringify(id)),
+	)
+	defer func() { endSpan(err) }()
+
+	var result schema.Share
+	if err := manager.PoolConn.Tx(ctx, func(conn pg.Conn) error {
+		if err := conn.Get(ctx, &result, id); err != nil {
+			return err
+		} else if err := checkVolumeAccess(ctx, result.Volume); err != nil {
+			return err
+		}
+		if err := conn.Delete(ctx, &result, id); err != nil {
+			return err
+		}
+		return manager.audit(ctx, conn, schema.AuditShareDelete, schema.AuditShareTarget(id), &result, nil)
+	}); err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// ReadShare opens an object through a share link. For a prefix, the path is
+// relative to the prefix. The object is returned with its path relative to
+// the share link, and without the volume name. The download is not counted,
+// call CountShare when the content is downloaded.
+func (manager *Manager) ReadShare(ctx context.Context, token schema.ShareToken, password, path string) (_ io.ReadCloser, _ *schema.Object, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ReadShare",
+		attribute.String("path", path),
+	)
+	defer func() { endSpan(err) }()
+
+	// Get the share link and the object path
+	share, err := manager.getShare(ctx, token, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	objectPath, err := share.ObjectPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Open the object
+	reader, object, err := manager.ReadObject(ctx, schema.ObjectKey{Volume: share.Volume, Path: objectPath})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Return success
+	return reader, sharedObject(share, object), nil
+}
+
+// CountShare counts a download through a share link, which fails when the
+// share link has expired or the download limit has been reached
+func (manager *Manager) CountShare(ctx context.Context, token schema.ShareToken) (_ *schema.Share, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "CountShare")
+	defer func() { endSpan(err) }()
+
+	var result schema.Share
+	if err := manager.PoolConn.Get(ctx, &result, schema.ShareDownload(token)); errors.Is(err, pg.ErrNotFound) {
+		return nil, gofiler.ErrForbidden.With("share link download limit has been reached")
+	} else if err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// ListShare returns the objects and folders under a path in a share link
+// for a prefix, with paths relative to the share link
+func (manager *Manager) ListShare(ctx context.Context, token schema.ShareToken, password, path string, req pg.OffsetLimit) (_ *schema.ObjectList, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ListShare",
+		attribute.String("path", path),
+	)
+	defer func() { endSpan(err) }()
+
+	// Get the share link and the prefix to list
+	share, err := manager.getShare(ctx, token, password)
+	if err != nil {
+		return nil, err
+	} else if !share.IsPrefix() {
+		return nil, gofiler.ErrBadParameter.With("share link is not for a folder")
+	}
+	prefix, err := share.ObjectPath(strings.TrimSuffix(path, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	// List the objects
+	result, err := manager.ListObjects(ctx, schema.ObjectListRequest{
+		Volume:      share.Volume,
+		OffsetLimit: req,
+		ObjectListFilters: schema.ObjectListFilters{
+			Path: types.Ptr(prefix),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Make the paths relative to the share link
+	result.Volume = ""
+	result.Path = types.Ptr(share.RelativePath(prefix))
+	for i, object := range result.Body {
+		result.Body[i] = sharedObject(share, object)
+	}
+
+	// Return success
+	return result, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// getShare returns a share link by token, when it has not expired, the
+// download limit has not been reached and the password matches
+func (manager *Manager) getShare(ctx context.Context, token schema.ShareToken, password string) (*schema.Share, error) {
+	var share schema.Share
+	if err := manager.PoolConn.Get(ctx, &share, token); errors.Is(err, pg.ErrNotFound) {
+		return nil, gofiler.ErrNotFound.With("share link not found")
+	} else if err != nil {
+		return nil, pg.NormalizeError(err)
+	} else if err := share.Check(password); err != nil {
+		return nil, err
+	}
+	return types.Ptr(share), nil
+}
+
+// sharedObject returns a copy of an object with its path relative to the
+// share link, and without the volume name
+func sharedObject(share *schema.Share, object *schema.Object) *schema.Object {
+	result := types.Value(object)
+	result.Volume = ""
+	result.Path = share.RelativePath(object.Path)
+	return types.Ptr(result)
+}
@@ -0,0 +1,321 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	// Packages
+	authmiddleware "github.com/mutablelogic/go-auth/auth/middleware"
+	authschema "github.com/mutablelogic/go-auth/auth/schema"
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// principal is a principal authenticated outside the go-auth middleware
+type principal struct {
+	user *authschema.UserInfo
+	key  *authschema.Key
+}
+
+type principalContextKey int
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	principalKey principalContextKey = iota
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+var _ authmiddleware.KeyAuthenticator = (*Manager)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// CreateToken creates an API token, and returns the token with its value.
+// Only a hash of the value is stored, so the value cannot be retrieved later.
+func (manager *Manager) CreateToken(ctx context.Context, meta schema.TokenMeta) (_ *schema.Token, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "CreateToken",
+		attribute.String("meta", meta.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	// A token restricted to volumes can only create tokens for those volumes,
+	// and a token can only grant the scopes it holds
+	if volumes := grantedVolumes(ctx); volumes != nil {
+		if len(meta.Volumes) == 0 {
+			meta.Volumes = volumes
+		}
+		for _, volume := range meta.Volumes {
+			if err := checkVolumeAccess(ctx, volume); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := checkGrants(ctx, schema.Token{TokenMeta: meta}); err != nil {
+		return nil, err
+	}
+
+	// Generate the token value
+	value, hash, err := schema.NewTokenValue()
+	if err != nil {
+		return nil, err
+	}
+
+	// Insert the token
+	var result schema.Token
+	if err := manager.PoolConn.Tx(ctx, func(conn pg.Conn) error {
+		if err := conn.Insert(ctx, &result, schema.TokenCreate{TokenMeta: meta, Hash: hash}); err != nil {
+			return err
+		}
+		return manager.audit(ctx, conn, schema.AuditTokenCreate, schema.AuditTokenTarget(result.ID), nil, &result)
+	}); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else {
+		result.Value = value
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// BootstrapToken creates an admin token when no tokens exist, so that the
+// first token can be created when authentication is enabled. It returns nil
+// when tokens already exist.
+func (manager *Manager) BootstrapToken(ctx context.Context) (_ *schema.Token, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "BootstrapToken")
+	defer func() { endSpan(err) }()
+
+	// Return if any tokens exist
+	tokens, err := manager.ListTokens(ctx, schema.TokenListRequest{OffsetLimit: pg.OffsetLimit{Limit: types.Ptr(uint64(1))}})
+	if err != nil {
+		return nil, err
+	} else if tokens.Count > 0 {
+		return nil, nil
+	}
+
+	// Create the admin token
+	return manager.CreateToken(ctx, schema.TokenMeta{
+		Name:   schema.ScopeAdmin,
+		Scopes: []string{schema.ScopeAdmin},
+	})
+}
+
+// GetToken returns an API token by id, without its value
+func (manager *Manager) GetToken(ctx context.Context, id schema.TokenID) (_ *schema.Token, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "GetToken",
+		attribute.String("id", types.Stringify(id)),
+	)
+	defer func() { endSpan(err) }()
+
+	var result schema.Token
+	if err := manager.PoolConn.Get(ctx, &result, id); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else if err := checkTokenAccess(ctx, result); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// ListTokens returns a paginated list of API tokens, without their values
+func (manager *Manager) ListTokens(ctx context.Context, req schema.TokenListRequest) (_ *schema.TokenList, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "ListTokens",
+		attribute.String("req", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	// Restrict the list to tokens for the accessible volumes
+	req.Volumes = grantedVolumes(ctx)
+
+	var result schema.TokenList
+	if err := manager.PoolConn.List(ctx, &result, &req); err != nil {
+		return nil, pg.NormalizeError(err)
+	} else {
+		result.TokenListRequest = req
+		result.OffsetLimit.Clamp(result.Count)
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// DeleteToken revokes an API token, and returns the deleted token
+func (manager *Manager) DeleteToken(ctx context.Context, id schema.TokenID) (_ *schema.Token, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "DeleteToken",
+		attribute.String("id", types.Stringify(id)),
+	)
+	defer func() { endSpan(err) }()
+
+	var result schema.Token
+	if err := manager.PoolConn.Tx(ctx, func(conn pg.Conn) error {
+		var token schema.Token
+		if err := conn.Get(ctx, &token, id); err != nil {
+			return err
+		} else if err := checkGrants(ctx, token); err != nil {
+			return err
+		}
+		if err := conn.Delete(ctx, &result, id); err != nil {
+			return err
+		}
+		return manager.audit(ctx, conn, schema.AuditTokenDelete, schema.AuditTokenTarget(id), &result, nil)
+	}); err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// AuthenticateKey returns the principal for an API token value, with the
+// scopes granted to the token. It implements the go-auth key authenticator,
+// so that the token is checked by the go-auth API key middleware.
+func (manager *Manager) AuthenticateKey(ctx context.Context, value string) (_ *authschema.UserInfo, _ *authschema.Key, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "AuthenticateKey")
+	defer func() { endSpan(err) }()
+
+	// Get the token by hash, which fails for expired tokens
+	var token schema.Token
+	if err := manager.PoolConn.Get(ctx, &token, schema.TokenHash(schema.TokenValueHash(value))); errors.Is(err, pg.ErrNotFound) {
+		return nil, nil, gofiler.ErrUnauthorized.With("invalid or expired token")
+	} else if err != nil {
+		return nil, nil, pg.NormalizeError(err)
+	}
+
+	// Return the principal
+	user, key := tokenPrincipal(token)
+	return user, key, nil
+}
+
+// WithPrincipal returns a context with a principal which has been
+// authenticated outside the go-auth middleware, such as the token an S3
+// access key is bound to, so that the volumes it can access are restricted
+// and its mutations are audited in the same way as API requests
+func WithPrincipal(ctx context.Context, user *authschema.UserInfo, key *authschema.Key) context.Context {
+	return context.WithValue(ctx, principalKey, principal{user, key})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// tokenPrincipal returns the principal for a token, with the scopes granted
+// to the token
+func tokenPrincipal(token schema.Token) (*authschema.UserInfo, *authschema.Key) {
+	return &authschema.UserInfo{
+		Name:   token.Name,
+		Scopes: token.Grants(),
+	}, &authschema.Key{
+		CreatedAt: token.CreatedAt,
+		KeyMeta: authschema.KeyMeta{
+			Name:      strconv.FormatUint(uint64(token.ID), 10) + ":" + token.Name,
+			ExpiresAt: token.ExpiresAt,
+		},
+	}
+}
+
+// userFromContext returns the principal authenticated in the context, by
+// the go-auth middleware or with WithPrincipal, or nil
+func userFromContext(ctx context.Context) *authschema.UserInfo {
+	if user := authmiddleware.UserFromContext(ctx); user != nil {
+		return user
+	}
+	if principal, ok := ctx.Value(principalKey).(principal); ok {
+		return principal.user
+	}
+	return nil
+}
+
+// keyFromContext returns the API key authenticated in the context, by the
+// go-auth middleware or with WithPrincipal, or nil
+func keyFromContext(ctx context.Context) *authschema.Key {
+	if key := authmiddleware.KeyFromContext(ctx); key != nil {
+		return key
+	}
+	if principal, ok := ctx.Value(principalKey).(principal); ok {
+		return principal.key
+	}
+	return nil
+}
+
+// tokenFromContext returns the identifier of the token authenticated in the
+// context, or nil when the server has no authentication
+func tokenFromContext(ctx context.Context) *schema.TokenID {
+	key := keyFromContext(ctx)
+	if key == nil {
+		return nil
+	}
+	id, _, ok := strings.Cut(key.Name, ":")
+	if !ok {
+		return nil
+	} else if id, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return nil
+	} else {
+		return types.Ptr(schema.TokenID(id))
+	}
+}
+
+// grantedVolumes returns the volumes which the token authenticated in the
+// context is restricted to, or nil when access is not restricted
+func grantedVolumes(ctx context.Context) []string {
+	if user := userFromContext(ctx); user != nil {
+		return schema.GrantedVolumes(user.Scopes)
+	}
+	return nil
+}
+
+// checkGrants returns an error when the token authenticated in the context
+// does not hold all the scopes and volumes granted to another token
+func checkGrants(ctx context.Context, token schema.Token) error {
+	user := userFromContext(ctx)
+	if user == nil {
+		return nil
+	}
+	for _, scope := range token.Grants() {
+		if !slices.Contains(user.Scopes, scope) {
+			return gofiler.ErrForbidden.Withf("token does not grant %q", scope)
+		}
+	}
+	if grantedVolumes(ctx) != nil && len(token.Volumes) == 0 {
+		return gofiler.ErrForbidden.With("token does not grant access to all volumes")
+	}
+	return nil
+}
+
+// checkTokenAccess returns an error when the token authenticated in the
+// context is restricted to volumes, and another token is not restricted to
+// a subset of those volumes
+func checkTokenAccess(ctx context.Context, token schema.Token) error {
+	if grantedVolumes(ctx) == nil {
+		return nil
+	} else if len(token.Volumes) == 0 {
+		return gofiler.ErrForbidden.With("token does not grant access to all volumes")
+	}
+	for _, volume := range token.Volumes {
+		if err := checkVolumeAccess(ctx, volume); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkVolumeAccess returns an error when the token authenticated in the
+// context is restricted to other volumes
+func checkVolumeAccess(ctx context.Context, name string) error {
+	if volumes := grantedVolumes(ctx); volumes != nil && !slices.Contains(volumes, name) {
+		return gofiler.ErrForbidden.Withf("token does not grant access to volume %q", name)
+	}
+	return nil
+}
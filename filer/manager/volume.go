@@ -2,6 +2,7 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"net/url"
 
 	// Packages
@@ -23,12 +24,38 @@ func (manager *Manager) GetVolume(ctx context.Context, name string) (_ *schema.V
 	)
 	defer func() { endSpan(err) }()
 
-	// Get the volume record from the database
+	// Check the volume is accessible, and get the volume record from the database
 	var result schema.Volume
-	if err := manager.Get(ctx, &result, schema.VolumeName(name)); err != nil {
+	if err := checkVolumeAccess(ctx, name); err != nil {
+		return nil, err
+	} else if err := manager.Get(ctx, &result, schema.VolumeName(name)); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return types.Ptr(result), nil
+}
+
+// GetVolumeIndex returns the progress of the most recent index run for a volume.
+func (manager *Manager) GetVolumeIndex(ctx context.Context, name string) (_ *schema.VolumeIndex, err error) {
+	ctx, endSpan := otel.StartSpan(manager.tracer, ctx, "GetVolumeIndex",
+		attribute.String("name", name),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the volume exists and is accessible
+	if _, err := manager.GetVolume(ctx, name); err != nil {
 		return nil, err
 	}
 
+	// Get the index progress
+	var result schema.VolumeIndex
+	if err := manager.Get(ctx, &result, schema.VolumeIndexName(name)); errors.Is(err, pg.ErrNotFound) {
+		return nil, gofiler.ErrNotFound.Withf("volume %q has not been indexed", name)
+	} else if err != nil {
+		return nil, pg.NormalizeError(err)
+	}
+
 	// Return success
 	return types.Ptr(result), nil
 }
@@ -41,14 +68,34 @@ func (manager *Manager) UpdateVolume(ctx context.Context, name string, meta sche
 	)
 	defer func() { endSpan(err) }()
 
+	// Check the volume is accessible
+	if err := checkVolumeAccess(ctx, name); err != nil {
+		return nil, err
+	}
+
 	var volume schema.Volume
 	if err := manager.Tx(ctx, func(conn pg.Conn) error {
+		var before schema.Volume
+		if err := conn.Get(ctx, &before, schema.VolumeName(name)); err != nil {
+			return err
+		}
 		if err := conn.Update(ctx, &volume, schema.VolumeName(name), meta); err != nil {
 			return err
 		}
 
-		// Return success
-		return nil
+		// Record the change
+		if err := manager.audit(ctx, conn, schema.AuditVolumeUpdate, schema.AuditVolumeTarget(name), &before, &volume); err != nil {
+			return err
+		}
+
+		// Notify subscribers of the change
+		event := schema.Event{Type: schema.EventVolumeUpdate, Volume: volume.Name}
+		if enabled := types.Value(volume.Enabled); enabled && !types.Value(before.Enabled) {
+			event.Type = schema.EventVolumeMount
+		} else if !enabled && types.Value(before.Enabled) {
+			event.Type = schema.EventVolumeUnmount
+		}
+		return manager.notifyEventsConn(ctx, conn, event)
 	}); err != nil {
 		return nil, err
 	}
@@ -64,6 +111,9 @@ func (manager *Manager) ListVolumes(ctx context.Context, req schema.VolumeListRe
 	)
 	defer func() { endSpan(err) }()
 
+	// Return only the volumes which are accessible
+	req.Names = grantedVolumes(ctx)
+
 	resp := schema.VolumeList{VolumeListRequest: req}
 	if err := manager.PoolConn.List(ctx, &resp, &req); err != nil {
 		return nil, err
@@ -80,6 +130,11 @@ func (manager *Manager) DeleteVolume(ctx context.Context, name string) (_ *schem
 	)
 	defer func() { endSpan(err) }()
 
+	// Check the volume is accessible
+	if err := checkVolumeAccess(ctx, name); err != nil {
+		return nil, err
+	}
+
 	var volume schema.Volume
 	if err := manager.Tx(ctx, func(conn pg.Conn) error {
 		// Get the volume record from the database
@@ -97,8 +152,11 @@ func (manager *Manager) DeleteVolume(ctx context.Context, name string) (_ *schem
 			return err
 		}
 
-		// Return success
-		return nil
+		// Record the change, and notify subscribers
+		if err := manager.audit(ctx, conn, schema.AuditVolumeDelete, schema.AuditVolumeTarget(name), &volume, nil); err != nil {
+			return err
+		}
+		return manager.notifyEventsConn(ctx, conn, schema.Event{Type: schema.EventVolumeUnmount, Volume: volume.Name})
 	}); err != nil {
 		return nil, err
 	}
@@ -122,15 +180,28 @@ func (manager *Manager) CreateVolume(ctx context.Context, url *url.URL, meta sch
 	name, err := manager.volumes.Validate(ctx, url)
 	if err != nil {
 		return nil, err
+	} else if err := checkVolumeAccess(ctx, name); err != nil {
+		return nil, err
 	}
 
 	// Insert the volume record in the database - which then syncs the volume with the volume registry
 	var result schema.Volume
 	if err := manager.Tx(ctx, func(conn pg.Conn) error {
-		return conn.With("name", name).Insert(ctx, &result, schema.VolumeCreate{
+		if err := conn.With("name", name).Insert(ctx, &result, schema.VolumeCreate{
 			URL:        url.String(),
 			VolumeMeta: meta,
-		})
+		}); err != nil {
+			return err
+		} else if err := manager.audit(ctx, conn, schema.AuditVolumeCreate, schema.AuditVolumeTarget(name), nil, &result); err != nil {
+			return err
+		}
+
+		// Notify subscribers of the new volume
+		event := schema.Event{Type: schema.EventVolumeUpdate, Volume: result.Name}
+		if types.Value(result.Enabled) {
+			event.Type = schema.EventVolumeMount
+		}
+		return manager.notifyEventsConn(ctx, conn, event)
 	}); err != nil {
 		return nil, err
 	}
@@ -160,6 +231,11 @@ func (manager *Manager) ReindexVolume(ctx context.Context, name string, req sche
 		return gofiler.ErrServiceUnavailable.Withf("volume %q is not indexed", volume.Name)
 	}
 
+	// Start a new index run to track the progress
+	if err := manager.PoolConn.Insert(ctx, nil, schema.IndexRunStart(volume.Name)); err != nil {
+		return pg.NormalizeError(err)
+	}
+
 	// Iterate through the objects in the volume, and reindex them according to the provided filters
 	var list schema.ObjectListRequest
 	list.Volume = volume.Name
@@ -174,14 +250,17 @@ func (manager *Manager) ReindexVolume(ctx context.Context, name string, req sche
 
 		for _, object := range objects.Body {
 			if object.ContentType != schema.ContentTypeDirectory {
-				if err := manager.enqueueIndexObject(ctx, object.ObjectKey, true); err != nil {
+				if err := manager.enqueueIndexRun(ctx, object.ObjectKey, true); err != nil {
 					return err
 				}
 			}
 		}
 
-		// Iterate the list offset for the next iteration
-		list.Offset += uint64(len(objects.Body))
+		// Continue from the next page
+		if objects.NextToken == "" {
+			break
+		}
+		list.Token = objects.NextToken
 	}
 
 	// Return success
@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"mime"
+	"net/url"
+	"strings"
+
+	// Packages
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ArchiveRequest is a request to download all objects under a path prefix
+// as a single archive
+type ArchiveRequest struct {
+	ObjectKey
+	Format string  `json:"format,omitempty" enum:"zip,tar.gz" default:"zip" help:"Archive format (zip or tar.gz)"`
+	Type   *string `json:"type,omitempty" help:"Content type filter, either a full content type or a major or minor type"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	ArchiveFormatZip   = "zip"
+	ArchiveFormatTarGz = "tar.gz"
+
+	ContentTypeZip  = "application/zip"
+	ContentTypeGzip = "application/gzip"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ContentType returns the content type of the archive, or an empty string
+// if the format is not supported
+func (r ArchiveRequest) ContentType() string {
+	switch r.Format {
+	case "", ArchiveFormatZip:
+		return ContentTypeZip
+	case ArchiveFormatTarGz, "tgz":
+		return ContentTypeGzip
+	default:
+		return ""
+	}
+}
+
+// Filename returns the filename of the archive, based on the volume and
+// the last element of the path prefix
+func (r ArchiveRequest) Filename() string {
+	name := r.Volume
+	if prefix := strings.Trim(r.Path, "/"); prefix != "" {
+		name = prefix[strings.LastIndex(prefix, "/")+1:]
+	}
+	if r.ContentType() == ContentTypeGzip {
+		return name + "." + ArchiveFormatTarGz
+	}
+	return name + "." + ArchiveFormatZip
+}
+
+// MatchContentType returns true if the content type matches the filter, which
+// is either a full content type, or a major or minor type. An empty filter
+// matches all content types. Parameters are ignored, and the types are
+// compared without case.
+func MatchContentType(filter, contentType string) bool {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return true
+	}
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = t
+	} else {
+		return false
+	}
+	if strings.Contains(filter, "/") {
+		t, _, err := mime.ParseMediaType(filter)
+		return err == nil && t == contentType
+	}
+	major, minor, _ := strings.Cut(contentType, "/")
+	filter = strings.ToLower(filter)
+	return filter == major || filter == minor
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r ArchiveRequest) String() string {
+	return types.Stringify(r)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// QUERY
+
+func (r ArchiveRequest) Query() url.Values {
+	query := url.Values{}
+	if r.Format != "" {
+		query.Set("format", r.Format)
+	}
+	if contentType := types.Value(r.Type); contentType != "" {
+		query.Set("type", contentType)
+	}
+	return query
+}
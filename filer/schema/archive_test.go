@@ -0,0 +1,61 @@
+package schema
+
+import (
+	"testing"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// FILTER
+
+func TestMatchContentType_001(t *testing.T) {
+	tests := []struct {
+		filter, contentType string
+		want                bool
+	}{
+		{"", "image/jpeg", true},
+		{"", "invalid", true},
+		{"image/jpeg", "image/jpeg", true},
+		{"Image/JPEG", "image/jpeg; quality=high", true},
+		{"image/jpeg; quality=high", "image/jpeg", true},
+		{"image/png", "image/jpeg", false},
+		{"image", "image/jpeg", true},
+		{"IMAGE", "image/png", true},
+		{"jpeg", "image/jpeg", true},
+		{"pdf", "application/pdf", true},
+		{"application", "image/jpeg", false},
+		{"jpe", "image/jpeg", false},
+		{"image", "invalid", false},
+		{"image/*", "image/jpeg", false},
+	}
+	for _, test := range tests {
+		if got := MatchContentType(test.filter, test.contentType); got != test.want {
+			t.Errorf("filter %q, type %q: got %v, want %v", test.filter, test.contentType, got, test.want)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// FORMAT
+
+func TestArchiveRequest_001(t *testing.T) {
+	tests := []struct {
+		volume, path, format string
+		contentType, name    string
+	}{
+		{"media", "", "", ContentTypeZip, "media.zip"},
+		{"media", "/", ArchiveFormatZip, ContentTypeZip, "media.zip"},
+		{"media", "/photos/2024/", ArchiveFormatZip, ContentTypeZip, "2024.zip"},
+		{"media", "photos", ArchiveFormatTarGz, ContentTypeGzip, "photos.tar.gz"},
+		{"media", "/photos", "tgz", ContentTypeGzip, "photos.tar.gz"},
+		{"media", "/photos", "rar", "", "photos.zip"},
+	}
+	for _, test := range tests {
+		req := ArchiveRequest{ObjectKey: ObjectKey{Volume: test.volume, Path: test.path}, Format: test.format}
+		if got := req.ContentType(); got != test.contentType {
+			t.Errorf("format %q: got content type %q, want %q", test.format, got, test.contentType)
+		}
+		if got := req.Filename(); got != test.name {
+			t.Errorf("path %q, format %q: got filename %q, want %q", test.path, test.format, got, test.name)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package schema
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"net/url"
+	"strconv"
 	"time"
 
 	// Packages
@@ -65,6 +67,41 @@ type ObjectArtwork struct {
 	ArtworkKey ArtworkKey `json:"artwork_key"`
 }
 
+// ArtworkListRequest represents a request to list artwork, optionally only
+// the artwork which is linked, or not linked, to an object
+type ArtworkListRequest struct {
+	pg.OffsetLimit
+	Orphaned *bool `json:"orphaned,omitempty" help:"List only artwork which is not linked (true) or is linked (false) to an object"`
+}
+
+// ArtworkList represents a list of artwork metadata
+type ArtworkList struct {
+	ArtworkListRequest
+	Count uint64         `json:"count,omitempty"`
+	Body  []*ArtworkInfo `json:"body,omitempty"`
+}
+
+// ArtworkGC selects artwork which is not linked to any object, and which was
+// created before the grace period
+type ArtworkGC struct {
+	Grace time.Duration
+}
+
+// ArtworkKeys is a list of artwork keys
+type ArtworkKeys []ArtworkKey
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// ArtworkGracePeriod is the time after which artwork which is not linked
+	// to any object is removed, so uploaded artwork can be linked first
+	ArtworkGracePeriod = 24 * time.Hour
+
+	// ArtworkGCInterval is the interval between removing unlinked artwork
+	ArtworkGCInterval = time.Hour
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -72,6 +109,10 @@ func (o Artwork) String() string {
 	return types.Stringify(o)
 }
 
+func (o ArtworkInfo) String() string {
+	return types.Stringify(o)
+}
+
 func (k ArtworkMeta) String() string {
 	return types.Stringify(k)
 }
@@ -80,6 +121,31 @@ func (o ObjectArtwork) String() string {
 	return types.Stringify(o)
 }
 
+func (r ArtworkListRequest) String() string {
+	return types.Stringify(r)
+}
+
+func (l ArtworkList) String() string {
+	return types.Stringify(l)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// QUERY
+
+func (r ArtworkListRequest) Query() url.Values {
+	query := url.Values{}
+	if r.Offset > 0 {
+		query.Set("offset", types.Stringify(r.Offset))
+	}
+	if r.Limit != nil {
+		query.Set("limit", types.Stringify(types.Value(r.Limit)))
+	}
+	if r.Orphaned != nil {
+		query.Set("orphaned", strconv.FormatBool(*r.Orphaned))
+	}
+	return query
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // SELECTOR
 
@@ -96,6 +162,41 @@ func (k ArtworkInfoKey) Select(bind *pg.Bind, op pg.Op) (string, error) {
 	}
 }
 
+func (r *ArtworkListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	r.OffsetLimit.Bind(bind, ArtworkListLimit)
+
+	// Filter by whether the artwork is linked to an object
+	switch {
+	case r.Orphaned == nil:
+		bind.Set("where", "")
+	case *r.Orphaned:
+		bind.Set("where", `WHERE NOT "linked"`)
+	default:
+		bind.Set("where", `WHERE "linked"`)
+	}
+
+	switch op {
+	case pg.List:
+		return bind.Query("filer.artwork_list"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported ArtworkListRequest operation %q", op)
+	}
+}
+
+func (r ArtworkGC) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if r.Grace < 0 {
+		return "", gofiler.ErrBadParameter.With("negative grace period")
+	} else {
+		bind.Set("grace", r.Grace.Seconds())
+	}
+	switch op {
+	case pg.Delete:
+		return bind.Query("filer.artwork_gc"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported ArtworkGC operation %q", op)
+	}
+}
+
 func (k ArtworkKey) Select(bind *pg.Bind, op pg.Op) (string, error) {
 	if k == "" {
 		return "", httpresponse.ErrBadRequest.With("missing artwork key")
@@ -173,6 +274,28 @@ func (o *ArtworkInfo) Scan(row pg.Row) error {
 	)
 }
 
+func (l *ArtworkList) Scan(row pg.Row) error {
+	var info ArtworkInfo
+	if err := info.Scan(row); err != nil {
+		return err
+	}
+	l.Body = append(l.Body, &info)
+	return nil
+}
+
+func (l *ArtworkList) ScanCount(row pg.Row) error {
+	return row.Scan(&l.Count)
+}
+
+func (k *ArtworkKeys) Scan(row pg.Row) error {
+	var key ArtworkKey
+	if err := row.Scan(&key); err != nil {
+		return err
+	}
+	*k = append(*k, key)
+	return nil
+}
+
 func (o *ObjectArtwork) Scan(row pg.Row) error {
 	return row.Scan(
 		&o.Volume,
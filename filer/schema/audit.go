@@ -0,0 +1,396 @@
+package schema
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// AuditMeta is an entry in the audit log, which records a change made to a
+// volume, object, credential or token
+type AuditMeta struct {
+	Actor     string          `json:"actor,omitempty"`
+	Operation string          `json:"operation"`
+	Target    string          `json:"target"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+// Audit is an entry in the audit log, as returned from the database
+type Audit struct {
+	ID uint64 `json:"id"`
+	AuditMeta
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditListRequest struct {
+	Actor     string     `json:"actor,omitempty" help:"Return entries for an actor"`
+	Operation string     `json:"operation,omitempty" help:"Return entries for an operation, such as object.delete"`
+	Target    string     `json:"target,omitempty" help:"Return entries for targets which start with this prefix, such as object:photos/"`
+	RequestID string     `json:"request_id,omitempty" name:"request-id" help:"Return entries for a request identifier"`
+	Since     *time.Time `json:"since,omitempty" help:"Return entries recorded at or after this time"`
+	Until     *time.Time `json:"until,omitempty" help:"Return entries recorded before this time"`
+	pg.OffsetLimit
+}
+
+type AuditList struct {
+	AuditListRequest
+	Count uint64   `json:"count,omitempty"`
+	Body  []*Audit `json:"body,omitempty"`
+}
+
+// AuditPrune selects audit entries which are older than the retention period
+type AuditPrune struct {
+	Retention time.Duration
+}
+
+// AuditPruned is the number of audit entries removed
+type AuditPruned uint64
+
+// auditObject is the summary of an object recorded in the audit log, which
+// excludes the metadata and artwork
+type auditObject struct {
+	ObjectKey
+	ContentType string `json:"type,omitempty"`
+	ObjectAttr
+}
+
+// auditObjects is the summary of the objects under a prefix recorded in the
+// audit log
+type auditObjects struct {
+	Count int   `json:"count"`
+	Size  int64 `json:"size"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Operations recorded in the audit log
+const (
+	AuditVolumeCreate     = "volume.create"
+	AuditVolumeUpdate     = "volume.update"
+	AuditVolumeDelete     = "volume.delete"
+	AuditObjectCreate     = "object.create"
+	AuditObjectDelete     = "object.delete"
+	AuditObjectCopy       = "object.copy"
+	AuditObjectMove       = "object.move"
+	AuditObjectPatch      = "object.patch"
+	AuditCredentialSet    = "credential.set"
+	AuditCredentialDelete = "credential.delete"
+	AuditTokenCreate      = "token.create"
+	AuditTokenDelete      = "token.delete"
+	AuditShareCreate      = "share.create"
+	AuditShareDelete      = "share.delete"
+)
+
+const (
+	// AuditRetention is the default period for which audit entries are kept
+	AuditRetention = 90 * 24 * time.Hour
+
+	// AuditPruneInterval is the interval between removing old audit entries
+	AuditPruneInterval = time.Hour
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewAudit returns an audit entry for an operation on a target, with a
+// summary of the target before and after the operation. The before or after
+// value is nil when the target did not exist.
+func NewAudit(operation, target string, before, after any) (AuditMeta, error) {
+	audit := AuditMeta{
+		Operation: operation,
+		Target:    target,
+	}
+	if data, err := auditSummary(before); err != nil {
+		return audit, err
+	} else {
+		audit.Before = data
+	}
+	if data, err := auditSummary(after); err != nil {
+		return audit, err
+	} else {
+		audit.After = data
+	}
+	return audit, nil
+}
+
+// AuditVolumeTarget returns the audit target for a volume
+func AuditVolumeTarget(name string) string {
+	return "volume:" + name
+}
+
+// AuditObjectTarget returns the audit target for an object, or for the
+// objects under a prefix
+func AuditObjectTarget(key ObjectKey) string {
+	return "object:" + key.Volume + ":" + key.Path
+}
+
+// AuditCredentialTarget returns the audit target for a credential
+func AuditCredentialTarget(key CredentialKey) string {
+	return "credential:" + key.Key
+}
+
+// AuditTokenTarget returns the audit target for a token
+func AuditTokenTarget(id TokenID) string {
+	return "token:" + types.Stringify(id)
+}
+
+// AuditShareTarget returns the audit target for a share link
+func AuditShareTarget(id ShareID) string {
+	return "share:" + types.Stringify(id)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (a Audit) String() string {
+	return types.Stringify(a)
+}
+
+func (a AuditMeta) String() string {
+	return types.Stringify(a)
+}
+
+func (r AuditListRequest) String() string {
+	return types.Stringify(r)
+}
+
+func (l AuditList) String() string {
+	return types.Stringify(l)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// QUERY
+
+func (r AuditListRequest) Query() url.Values {
+	query := url.Values{}
+	if r.Actor != "" {
+		query.Set("actor", r.Actor)
+	}
+	if r.Operation != "" {
+		query.Set("operation", r.Operation)
+	}
+	if r.Target != "" {
+		query.Set("target", r.Target)
+	}
+	if r.RequestID != "" {
+		query.Set("request_id", r.RequestID)
+	}
+	if r.Since != nil {
+		query.Set("since", r.Since.Format(time.RFC3339))
+	}
+	if r.Until != nil {
+		query.Set("until", r.Until.Format(time.RFC3339))
+	}
+	if r.Offset > 0 {
+		query.Set("offset", types.Stringify(r.Offset))
+	}
+	if r.Limit != nil {
+		query.Set("limit", types.Stringify(types.Value(r.Limit)))
+	}
+	return query
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// TABLE OUTPUT
+
+func (a Audit) Header() []string {
+	return []string{"ID", "Time", "Actor", "Operation", "Target", "Request ID"}
+}
+
+func (a Audit) Width(col int) int {
+	return 0
+}
+
+func (a Audit) Cell(col int) string {
+	switch col {
+	case 0:
+		return types.Stringify(a.ID)
+	case 1:
+		return a.CreatedAt.Format(time.RFC3339)
+	case 2:
+		return a.Actor
+	case 3:
+		return a.Operation
+	case 4:
+		return a.Target
+	case 5:
+		return a.RequestID
+	default:
+		return ""
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// SELECTOR
+
+func (r *AuditListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	bind.Del("where")
+	if r.Actor != "" {
+		bind.Append("where", `"actor" = `+bind.Set("actor", r.Actor))
+	}
+	if r.Operation != "" {
+		bind.Append("where", `"operation" = `+bind.Set("operation", r.Operation))
+	}
+	if r.Target != "" {
+		bind.Append("where", `starts_with("target", `+bind.Set("target", r.Target)+`)`)
+	}
+	if r.RequestID != "" {
+		bind.Append("where", `"request_id" = `+bind.Set("request_id", r.RequestID))
+	}
+	if r.Since != nil {
+		bind.Append("where", `"created_at" >= `+bind.Set("since", r.Since))
+	}
+	if r.Until != nil {
+		bind.Append("where", `"created_at" < `+bind.Set("until", r.Until))
+	}
+	if where := bind.Join("where", " AND "); where != "" {
+		bind.Set("where", `WHERE `+where)
+	} else {
+		bind.Set("where", "")
+	}
+
+	// Bind offset and limit
+	r.OffsetLimit.Bind(bind, AuditListLimit)
+
+	switch op {
+	case pg.List:
+		return bind.Query("filer.audit_list"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported AuditListRequest operation %q", op)
+	}
+}
+
+func (r AuditPrune) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if r.Retention <= 0 {
+		return "", gofiler.ErrBadParameter.With("audit retention must be positive")
+	} else {
+		bind.Set("retention", r.Retention.Seconds())
+	}
+
+	switch op {
+	case pg.Delete:
+		return bind.Query("filer.audit_prune"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported AuditPrune operation %q", op)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (a *Audit) Scan(row pg.Row) error {
+	var actor, requestID *string
+	var before, after []byte
+	if err := row.Scan(&a.ID, &a.CreatedAt, &actor, &a.Operation, &a.Target, &before, &after, &requestID); err != nil {
+		return err
+	}
+	a.Actor = types.Value(actor)
+	a.RequestID = types.Value(requestID)
+	a.Before = json.RawMessage(before)
+	a.After = json.RawMessage(after)
+	return nil
+}
+
+func (l *AuditList) Scan(row pg.Row) error {
+	var audit Audit
+	if err := audit.Scan(row); err != nil {
+		return err
+	}
+	l.Body = append(l.Body, &audit)
+	return nil
+}
+
+func (l *AuditList) ScanCount(row pg.Row) error {
+	return row.Scan(&l.Count)
+}
+
+func (n *AuditPruned) Scan(row pg.Row) error {
+	return row.Scan((*uint64)(n))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+func (a AuditMeta) Insert(bind *pg.Bind) (string, error) {
+	if a.Operation == "" {
+		return "", gofiler.ErrInternalServerError.With("missing audit operation")
+	}
+	if a.Target == "" {
+		return "", gofiler.ErrInternalServerError.With("missing audit target")
+	}
+	bind.Set("actor", types.TrimStringPtr(&a.Actor))
+	bind.Set("operation", a.Operation)
+	bind.Set("target", a.Target)
+	bind.Set("before", auditJSON(a.Before))
+	bind.Set("after", auditJSON(a.After))
+	bind.Set("request_id", types.TrimStringPtr(&a.RequestID))
+
+	// Return the query
+	return bind.Query("filer.audit_insert"), nil
+}
+
+func (a AuditMeta) Update(_ *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("AuditMeta: update: the audit log is append-only")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// auditSummary returns the JSON summary of a value for the audit log.
+// Objects are summarised without their metadata, and token values and
+// share link tokens are never recorded.
+func auditSummary(v any) (json.RawMessage, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case *Object:
+		if v == nil {
+			return nil, nil
+		}
+		return auditSummary(auditObject{ObjectKey: v.ObjectKey, ContentType: v.ContentType, ObjectAttr: v.ObjectAttr})
+	case []Object:
+		if len(v) == 1 {
+			return auditSummary(&v[0])
+		}
+		summary := auditObjects{Count: len(v)}
+		for _, object := range v {
+			summary.Size += object.Size
+		}
+		return auditSummary(summary)
+	case *Token:
+		if v == nil {
+			return nil, nil
+		}
+		token := *v
+		token.Value = ""
+		return json.Marshal(token)
+	case *Share:
+		if v == nil {
+			return nil, nil
+		}
+		share := *v
+		share.Token = ""
+		return json.Marshal(share)
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// auditJSON returns nil for an empty JSON value, so that it is stored as NULL
+func auditJSON(data json.RawMessage) any {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,319 @@
+package schema
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// BatchOperation is a single operation on an object, or on all objects under
+// a path prefix when deleting recursively
+type BatchOperation struct {
+	Op string `json:"op" enum:"delete,copy,move,patch,reindex" help:"Operation"`
+	ObjectKey
+	Dest      *ObjectKey `json:"dest,omitempty" help:"Destination of a copy or move"`
+	Recursive bool       `json:"recursive,omitempty" help:"Delete all objects under a path prefix"`
+	Meta      []Meta     `json:"meta,omitempty" help:"Metadata to set with patch"`
+}
+
+// BatchRequest is a list of operations, which are run in order
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations" validate:"required"`
+	Continue   bool             `json:"continue,omitempty" help:"Continue after an operation fails, rather than aborting the batch"`
+	Async      bool             `json:"async,omitempty" help:"Run the batch as a background job, regardless of the number of operations"`
+	Token      *TokenID         `json:"-"` // Token which created the batch, which the background job runs as
+}
+
+// BatchResult is the result of a single operation
+type BatchResult struct {
+	Index int    `json:"index"`
+	Op    string `json:"op"`
+	ObjectKey
+	Error string `json:"error,omitempty"`
+}
+
+type BatchKey struct {
+	ID uint64 `json:"id" arg:"" help:"Batch identifier"`
+}
+
+// Batch is the progress and results of a batch. Batches which run as a
+// background job have an identifier, which is used to poll for progress.
+type Batch struct {
+	ID         uint64        `json:"id,omitempty"`
+	Status     string        `json:"status"`
+	Continue   bool          `json:"continue,omitempty"`
+	Total      int           `json:"total"`
+	Completed  int           `json:"completed"`
+	Failed     int           `json:"failed"`
+	CreatedAt  time.Time     `json:"created_at,omitzero"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Token      *TokenID      `json:"token,omitempty"`
+	Results    []BatchResult `json:"results,omitempty"`
+}
+
+// BatchStatus sets the status of a batch
+type BatchStatus string
+
+// BatchOperations are the operations of a batch, which are read by the
+// background job
+type BatchOperations []BatchOperation
+
+// BatchPrune selects batches which finished before the retention period
+type BatchPrune struct {
+	Retention time.Duration
+}
+
+// BatchPruned is the number of batches removed
+type BatchPruned uint64
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	BatchOpDelete  = "delete"
+	BatchOpCopy    = "copy"
+	BatchOpMove    = "move"
+	BatchOpPatch   = "patch"
+	BatchOpReindex = "reindex"
+)
+
+const (
+	BatchStatusPending   = "pending"
+	BatchStatusRunning   = "running"
+	BatchStatusCompleted = "completed"
+	BatchStatusAborted   = "aborted"
+)
+
+const (
+	// BatchRetention is the default period for which finished batches are kept
+	BatchRetention = 7 * 24 * time.Hour
+
+	// BatchPruneInterval is the interval between removing finished batches
+	BatchPruneInterval = time.Hour
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r BatchRequest) String() string {
+	return types.Stringify(r)
+}
+
+func (b Batch) String() string {
+	return types.Stringify(b)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Validate checks the operations of a batch request
+func (r BatchRequest) Validate() error {
+	if len(r.Operations) == 0 {
+		return gofiler.ErrBadParameter.With("missing operations")
+	} else if len(r.Operations) > MaxBatchOperations {
+		return gofiler.ErrBadParameter.Withf("too many operations: %d (max %d)", len(r.Operations), MaxBatchOperations)
+	}
+	for i, op := range r.Operations {
+		if err := op.Validate(); err != nil {
+			return gofiler.ErrBadParameter.Withf("operation %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks an operation has the fields it needs
+func (op BatchOperation) Validate() error {
+	if op.Volume == "" {
+		return gofiler.ErrBadParameter.With("missing volume")
+	} else if strings.Trim(op.Path, "/") == "" && !(op.Op == BatchOpDelete && op.Recursive) {
+		return gofiler.ErrBadParameter.With("missing path")
+	}
+	switch op.Op {
+	case BatchOpDelete, BatchOpReindex:
+		return nil
+	case BatchOpCopy, BatchOpMove:
+		if op.Dest == nil || op.Dest.Volume == "" || strings.Trim(op.Dest.Path, "/") == "" {
+			return gofiler.ErrBadParameter.With("missing destination")
+		}
+		return nil
+	case BatchOpPatch:
+		if len(op.Meta) == 0 {
+			return gofiler.ErrBadParameter.With("missing metadata")
+		}
+		return nil
+	default:
+		return gofiler.ErrBadParameter.Withf("invalid operation: %q", op.Op)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SELECTOR
+
+func (k BatchKey) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if k.ID == 0 {
+		return "", gofiler.ErrBadParameter.With("missing batch id")
+	} else {
+		bind.Set("id", k.ID)
+	}
+
+	switch op {
+	case pg.Get:
+		if bind.Has("operations") {
+			return bind.Query("filer.batch_operations"), nil
+		} else {
+			return bind.Query("filer.batch_get"), nil
+		}
+	case pg.Update:
+		return bind.Query("filer.batch_patch"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported BatchKey operation %q", op)
+	}
+}
+
+func (r BatchPrune) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if r.Retention <= 0 {
+		return "", gofiler.ErrBadParameter.With("batch retention must be positive")
+	} else {
+		bind.Set("retention", r.Retention.Seconds())
+	}
+
+	switch op {
+	case pg.Delete:
+		return bind.Query("filer.batch_prune"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported BatchPrune operation %q", op)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READER
+
+// Expected column order: id, status, continue, total, completed, failed,
+// created_at, finished_at, token, results.
+func (b *Batch) Scan(row pg.Row) error {
+	var results []byte
+	if err := row.Scan(&b.ID, &b.Status, &b.Continue, &b.Total, &b.Completed, &b.Failed, &b.CreatedAt, &b.FinishedAt, &b.Token, &results); err != nil {
+		return err
+	}
+	b.Results = nil
+	if len(results) > 0 {
+		if err := json.Unmarshal(results, &b.Results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Expected column order: operations.
+func (o *BatchOperations) Scan(row pg.Row) error {
+	var operations []byte
+	if err := row.Scan(&operations); err != nil {
+		return err
+	}
+	return json.Unmarshal(operations, o)
+}
+
+func (n *BatchPruned) Scan(row pg.Row) error {
+	return row.Scan((*uint64)(n))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+func (r BatchRequest) Insert(bind *pg.Bind) (string, error) {
+	operations, err := json.Marshal(r.Operations)
+	if err != nil {
+		return "", err
+	}
+	bind.Set("operations", string(operations))
+	bind.Set("continue", r.Continue)
+	bind.Set("total", len(r.Operations))
+	bind.Set("token", r.Token)
+
+	// Return the query
+	return bind.Query("filer.batch_insert"), nil
+}
+
+func (r BatchRequest) Update(bind *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("batch request update is not supported")
+}
+
+// Insert records the result of an operation, replacing any previous result
+// when a job is retried. The batch is bound as "id".
+func (r BatchResult) Insert(bind *pg.Bind) (string, error) {
+	if !bind.Has("id") {
+		return "", gofiler.ErrInternalServerError.With("batch result requires a batch id binding")
+	}
+	result, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	bind.Set("index", r.Index)
+	bind.Set("failed", r.Error != "")
+	bind.Set("result", string(result))
+
+	// Return the query
+	return bind.Query("filer.batch_result_insert"), nil
+}
+
+func (r BatchResult) Update(bind *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("batch result update is not supported")
+}
+
+func (s BatchStatus) Insert(bind *pg.Bind) (string, error) {
+	return "", gofiler.ErrNotImplemented.With("batch status insert is not supported")
+}
+
+func (s BatchStatus) Update(bind *pg.Bind) error {
+	bind.Del("patch")
+	switch status := string(s); status {
+	case BatchStatusRunning:
+		bind.Append("patch", `"status" = `+bind.Set("status", status))
+	case BatchStatusCompleted, BatchStatusAborted:
+		bind.Append("patch", `"status" = `+bind.Set("status", status))
+		bind.Append("patch", `"finished_at" = now()`)
+	default:
+		return gofiler.ErrBadParameter.Withf("invalid batch status: %q", status)
+	}
+	bind.Set("patch", bind.Join("patch", ", "))
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TABLE OUTPUT
+
+func (r BatchResult) Header() []string {
+	return []string{"#", "Op", "Volume", "Path", "Error"}
+}
+
+func (r BatchResult) Width(col int) int {
+	return 0
+}
+
+func (r BatchResult) Cell(col int) string {
+	switch col {
+	case 0:
+		return strconv.Itoa(r.Index)
+	case 1:
+		return r.Op
+	case 2:
+		return r.Volume
+	case 3:
+		return r.Path
+	case 4:
+		return r.Error
+	default:
+		return ""
+	}
+}
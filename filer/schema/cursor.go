@@ -0,0 +1,184 @@
+package schema
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ListPosition is the position of a backend listing, from which the next page
+// is read. Backends which list in a stable order resume after the last path
+// returned, and other backends resume from their own continuation string.
+type ListPosition struct {
+	After        string `json:"a,omitempty"` // last path returned
+	Continuation string `json:"c,omitempty"` // backend continuation string
+}
+
+// ObjectListCursor is the position in an object listing which is encoded in
+// a signed continuation token. Listings from a backend resume from the
+// backend position of the page containing the next object, and listings from
+// the index resume after the last path returned.
+type ObjectListCursor struct {
+	Filter  string        `json:"f"`           // fingerprint of the request filters
+	Backend *ListPosition `json:"b,omitempty"` // backend position of the page containing the next object
+	Skip    uint64        `json:"s,omitempty"` // number of objects to skip in that page
+	After   string        `json:"a,omitempty"` // last path returned from the index
+}
+
+// CursorKey is the key which signs continuation tokens. A key is stored in
+// the database when no passphrase is configured, so that continuation tokens
+// can be used with any instance.
+type CursorKey []byte
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// maxPosition is the maximum length of a path or continuation string in
+	// a backend position
+	maxPosition = 1024
+
+	// cursorSignatureSize is the number of bytes of the HMAC in a
+	// continuation token
+	cursorSignatureSize = 16
+
+	// CursorKeySize is the size of the key which signs continuation tokens
+	CursorKeySize = sha256.Size
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Cursor returns the position decoded from the continuation token, or nil
+// when there is no continuation token, and keeps it for selecting from the
+// index. An error is returned when the token was not signed with the key, is
+// invalid, or was returned for a request with different filters.
+func (r *ObjectListRequest) Cursor(key []byte) (*ObjectListCursor, error) {
+	r.cursor = nil
+	if r.Token == "" {
+		return nil, nil
+	}
+
+	// Decode the token and check the signature
+	var cursor ObjectListCursor
+	payload, signature, _ := strings.Cut(r.Token, ".")
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, gofiler.ErrBadParameter.With("invalid continuation token")
+	} else if mac, err := base64.RawURLEncoding.DecodeString(signature); err != nil || !hmac.Equal(mac, cursorSignature(key, data)) {
+		return nil, gofiler.ErrBadParameter.With("invalid continuation token")
+	} else if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, gofiler.ErrBadParameter.With("invalid continuation token")
+	} else if cursor.Filter != r.fingerprint() {
+		return nil, gofiler.ErrBadParameter.With("continuation token does not match the request")
+	} else if err := cursor.validate(); err != nil {
+		return nil, err
+	}
+
+	// Return the cursor
+	r.cursor = types.Ptr(cursor)
+	return r.cursor, nil
+}
+
+// NextToken returns the continuation token for a position in the listing
+// for this request, signed with the key
+func (r ObjectListRequest) NextToken(key []byte, cursor ObjectListCursor) (string, error) {
+	cursor.Filter = r.fingerprint()
+	if err := cursor.validate(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(cursorSignature(key, data)), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (k *CursorKey) Scan(row pg.Row) error {
+	return row.Scan((*[]byte)(k))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+// Insert stores the key, unless a key has already been stored, and returns
+// the stored key
+func (k CursorKey) Insert(bind *pg.Bind) (string, error) {
+	if len(k) < CursorKeySize {
+		return "", gofiler.ErrBadParameter.With("cursor key is too short")
+	} else {
+		bind.Set("key", []byte(k))
+	}
+
+	// Return the query
+	return bind.Query("filer.cursor_key"), nil
+}
+
+func (k CursorKey) Update(bind *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("cursor key update is not supported")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// decodedCursor returns the position decoded by Cursor, which needs to be
+// called before selecting from the index with a continuation token
+func (r ObjectListRequest) decodedCursor() (*ObjectListCursor, error) {
+	if r.Token != "" && r.cursor == nil {
+		return nil, gofiler.ErrInternalServerError.With("continuation token has not been decoded")
+	}
+	return r.cursor, nil
+}
+
+// validate checks the positions in a cursor are paths which a listing could
+// have returned, and backend positions are within bounds
+func (c ObjectListCursor) validate() error {
+	if c.Backend != nil && (len(c.Backend.After) > maxPosition || len(c.Backend.Continuation) > maxPosition) {
+		return gofiler.ErrBadParameter.With("invalid continuation token")
+	} else if !validListPath(c.After) {
+		return gofiler.ErrBadParameter.With("invalid continuation token")
+	}
+	return nil
+}
+
+// validListPath returns true when a path is empty, or normalised in the same
+// way as the paths returned in a listing
+func validListPath(p string) bool {
+	return p == "" || strings.Trim(path.Clean("/"+p), "/") == p
+}
+
+// cursorSignature returns the truncated HMAC of a continuation token payload
+func cursorSignature(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)[:cursorSignatureSize]
+}
+
+// fingerprint returns a hash of the request filters, so that a continuation
+// token cannot be used with a different listing. The path is normalised, as
+// it is returned normalised in the listing.
+func (r ObjectListRequest) fingerprint() string {
+	hash := sha256.New()
+	prefix := strings.Trim(path.Clean("/"+types.Value(r.Path)), "/")
+	for _, value := range []string{r.Volume, prefix, types.Value(r.Type), strconv.FormatBool(r.Recursive)} {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
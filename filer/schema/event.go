@@ -0,0 +1,132 @@
+package schema
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Event is a change to a volume or object. Volume events are emitted when a
+// volume is mounted, unmounted or updated, and object events are emitted when
+// an object is created or deleted, or added to or removed from the index.
+type Event struct {
+	Type   string    `json:"type"`
+	Volume string    `json:"volume"`
+	Path   string    `json:"path,omitempty"`
+	Time   time.Time `json:"time,omitzero"`
+}
+
+// Events is a list of events which are published together
+type Events []Event
+
+// EventRequest filters the events returned by an event stream
+type EventRequest struct {
+	Volume string   `json:"volume,omitempty" help:"Volume name"`
+	Prefix string   `json:"prefix,omitempty" help:"Object path prefix"`
+	Type   []string `json:"type,omitempty" help:"Event types, either a full type (object.create) or a category (object)"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	EventVolumeMount   = "volume.mount"
+	EventVolumeUnmount = "volume.unmount"
+	EventVolumeUpdate  = "volume.update"
+	EventObjectCreate  = "object.create"
+	EventObjectDelete  = "object.delete"
+	EventObjectIndex   = "object.index"
+	EventObjectUnindex = "object.unindex"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (e Event) String() string {
+	return types.Stringify(e)
+}
+
+func (r EventRequest) String() string {
+	return types.Stringify(r)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// QUERY
+
+func (r EventRequest) Query() url.Values {
+	values := url.Values{}
+	if r.Volume != "" {
+		values.Set("volume", r.Volume)
+	}
+	if r.Prefix != "" {
+		values.Set("prefix", r.Prefix)
+	}
+	for _, typ := range r.Type {
+		values.Add("type", typ)
+	}
+	return values
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Match returns true if the event passes the filter. The prefix matches
+// whole path elements, so "a/b" matches "a/b" and "a/b/c" but not "a/bc".
+// Volume events match a prefix filter, so that clients are informed when
+// a volume is unmounted.
+func (r EventRequest) Match(event Event) bool {
+	if r.Volume != "" && r.Volume != event.Volume {
+		return false
+	}
+	if prefix := strings.Trim(r.Prefix, "/"); prefix != "" && event.Path != "" {
+		path := strings.Trim(event.Path, "/")
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			return false
+		}
+	}
+	if len(r.Type) > 0 {
+		category, _, _ := strings.Cut(event.Type, ".")
+		if !slices.Contains(r.Type, event.Type) && !slices.Contains(r.Type, category) {
+			return false
+		}
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+func (e Events) Insert(bind *pg.Bind) (string, error) {
+	payloads := make([]string, 0, len(e))
+	for _, event := range e {
+		if event.Type == "" || event.Volume == "" {
+			return "", gofiler.ErrBadParameter.With("missing event type or volume")
+		}
+		if event.Time.IsZero() {
+			event.Time = time.Now()
+		}
+		if data, err := json.Marshal(event); err != nil {
+			return "", err
+		} else {
+			payloads = append(payloads, string(data))
+		}
+	}
+	bind.Set("payloads", payloads)
+
+	// Return the query
+	return bind.Query("filer.event_notify"), nil
+}
+
+func (e Events) Update(bind *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("Events update is not supported")
+}
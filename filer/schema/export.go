@@ -0,0 +1,243 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// IndexManifest is the first entry in an index export, which identifies the
+// format and the volume which was exported
+type IndexManifest struct {
+	Format    string    `json:"format"`
+	Version   uint64    `json:"version"`
+	Volume    string    `json:"volume"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IndexRecord is an indexed object in an index export, with the metadata
+// extracted from the object, the metadata set by users, the keys of the
+// linked artwork and the search vector. The path is relative to the volume,
+// so the record can be imported into a volume with another name.
+type IndexRecord struct {
+	Path        string `json:"path"`
+	ContentType string `json:"type"`
+	ObjectAttr
+	Meta     []Meta       `json:"meta,omitempty"`
+	UserMeta []Meta       `json:"user_meta,omitempty"`
+	Artwork  []ArtworkKey `json:"artwork,omitempty"`
+	Search   string       `json:"search,omitempty"`
+}
+
+// IndexExportRequest selects the next page of indexed objects in a volume
+// for export, after a path
+type IndexExportRequest struct {
+	Volume string
+	After  string
+}
+
+// IndexRecordList is a page of indexed objects for export
+type IndexRecordList struct {
+	Body []*IndexRecord
+}
+
+// IndexSearch is the search vector of an imported object
+type IndexSearch struct {
+	ObjectKey
+	Search string
+}
+
+// IndexImport is the result of importing an index export into a volume.
+// Objects are imported when they are unchanged in the backend, and objects
+// which have changed are queued for indexing.
+type IndexImport struct {
+	Volume   string `json:"volume"`
+	Source   string `json:"source,omitempty"`
+	Objects  uint64 `json:"objects"`
+	Imported uint64 `json:"imported"`
+	Changed  uint64 `json:"changed,omitempty"`
+	Missing  uint64 `json:"missing,omitempty"`
+	Artwork  uint64 `json:"artwork,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// IndexExportFormat and IndexExportVersion identify the format of an index
+	// export in the manifest
+	IndexExportFormat  = "filer-index"
+	IndexExportVersion = 1
+
+	// An index export is a gzipped tar archive. The manifest is followed by a
+	// page of artwork metadata, the artwork data and a page of objects, for
+	// each page of objects in the volume. Artwork is exported once, before the
+	// first object it is linked to.
+	IndexManifestEntry = "manifest.json"
+	IndexArtworkEntry  = "artwork-%06d.ndjson"
+	IndexArtworkData   = "artwork/"
+	IndexObjectEntry   = "objects-%06d.ndjson"
+
+	// IndexArtworkMaxSize is the maximum size of artwork data in an import
+	IndexArtworkMaxSize = 32 << 20
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (m IndexManifest) String() string {
+	return types.Stringify(m)
+}
+
+func (r IndexRecord) String() string {
+	return types.Stringify(r)
+}
+
+func (r IndexImport) String() string {
+	return types.Stringify(r)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// IndexExportFilename returns the filename of the index export of a volume
+func IndexExportFilename(volume string) string {
+	return volume + ".index." + ArchiveFormatTarGz
+}
+
+// IndexArtworkEntryName returns the entry name for a page of artwork metadata
+func IndexArtworkEntryName(page int) string {
+	return fmt.Sprintf(IndexArtworkEntry, page)
+}
+
+// IndexObjectEntryName returns the entry name for a page of objects
+func IndexObjectEntryName(page int) string {
+	return fmt.Sprintf(IndexObjectEntry, page)
+}
+
+// Validate checks the manifest is an index export which can be imported
+func (m IndexManifest) Validate() error {
+	if m.Format != IndexExportFormat {
+		return gofiler.ErrBadParameter.Withf("not an index export: %q", m.Format)
+	} else if m.Version != IndexExportVersion {
+		return gofiler.ErrBadParameter.Withf("unsupported index export version: %d", m.Version)
+	}
+	return nil
+}
+
+// Matches returns true when the object in the backend is unchanged since the
+// record was exported. The etags are compared when both are known, otherwise
+// the size and modification time are compared.
+func (r IndexRecord) Matches(object *Object) bool {
+	if object == nil || object.Size != r.Size {
+		return false
+	}
+	if etag := types.Value(r.ETag); etag != "" && types.Value(object.ETag) != "" {
+		return MatchETags(etag, types.Value(object.ETag), false)
+	}
+	return !r.ModTime.IsZero() && !object.ModTime.IsZero() && r.ModTime.Truncate(time.Second).Equal(object.ModTime.Truncate(time.Second))
+}
+
+// Object returns the record as an object in a volume
+func (r IndexRecord) Object(volume string) ObjectCreate {
+	return ObjectCreate{
+		ObjectKey:  ObjectKey{Volume: volume, Path: r.Path},
+		ObjectMeta: ObjectMeta{ContentType: r.ContentType, Meta: r.Meta},
+		ObjectAttr: r.ObjectAttr,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SELECTOR
+
+func (r IndexExportRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if !types.IsIdentifier(r.Volume) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", r.Volume)
+	}
+	bind.Set("volume", r.Volume)
+	bind.Set("after", r.After)
+	bind.Set("limit", IndexExportLimit)
+
+	switch op {
+	case pg.List:
+		return bind.Query("filer.index_export"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported IndexExportRequest operation %q", op)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (r *IndexRecord) Scan(row pg.Row) error {
+	var volume string
+	var meta, userMeta []byte
+	var artwork []string
+	var search *string
+	if err := row.Scan(&volume, &r.Path, &r.Size, &r.ContentType, &r.ETag, &r.ModTime, &meta, &userMeta, &artwork, &search); err != nil {
+		return err
+	}
+	r.Meta, r.UserMeta, r.Artwork = nil, nil, nil
+	if len(meta) > 0 {
+		if err := json.Unmarshal(meta, &r.Meta); err != nil {
+			return err
+		}
+	}
+	if len(userMeta) > 0 {
+		if err := json.Unmarshal(userMeta, &r.UserMeta); err != nil {
+			return err
+		}
+	}
+	for _, key := range artwork {
+		r.Artwork = append(r.Artwork, ArtworkKey(key))
+	}
+	r.Search = types.Value(search)
+	return nil
+}
+
+func (l *IndexRecordList) Scan(row pg.Row) error {
+	var record IndexRecord
+	if err := record.Scan(row); err != nil {
+		return err
+	}
+	l.Body = append(l.Body, &record)
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+// Insert replaces the search vector of an imported object
+func (s IndexSearch) Insert(bind *pg.Bind) (string, error) {
+	if s.Volume == "" {
+		return "", gofiler.ErrBadParameter.With("missing object volume")
+	} else {
+		bind.Set("volume", s.Volume)
+	}
+	if s.Path == "" {
+		return "", gofiler.ErrBadParameter.With("missing object path")
+	} else {
+		bind.Set("path", s.Path)
+	}
+	if search := strings.TrimSpace(s.Search); search == "" {
+		return "", gofiler.ErrBadParameter.With("missing search vector")
+	} else {
+		bind.Set("search", search)
+	}
+
+	// Return the query
+	return bind.Query("filer.index_search_import"), nil
+}
+
+func (s IndexSearch) Update(_ *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("IndexSearch: update: not supported")
+}
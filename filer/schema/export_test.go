@@ -0,0 +1,89 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	// Packages
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// MATCHES
+
+func TestIndexRecordMatches_001(t *testing.T) {
+	modtime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	object := func(size int64, etag string, modtime time.Time) *Object {
+		var o Object
+		o.Size = size
+		if etag != "" {
+			o.ETag = types.Ptr(etag)
+		}
+		o.ModTime = modtime
+		return &o
+	}
+	record := func(size int64, etag string, modtime time.Time) IndexRecord {
+		var r IndexRecord
+		r.Size = size
+		if etag != "" {
+			r.ETag = types.Ptr(etag)
+		}
+		r.ModTime = modtime
+		return r
+	}
+	tests := []struct {
+		name   string
+		record IndexRecord
+		object *Object
+		want   bool
+	}{
+		{"no object", record(10, `"a"`, modtime), nil, false},
+		{"etag", record(10, `"a"`, modtime), object(10, `"a"`, modtime), true},
+		{"weak etag", record(10, `W/"a"`, modtime), object(10, `"a"`, modtime), true},
+		{"etag mismatch", record(10, `"a"`, modtime), object(10, `"b"`, modtime), false},
+		{"etag mismatch, same time", record(10, `"a"`, modtime), object(10, `"b"`, modtime.Add(time.Hour)), false},
+		{"size mismatch", record(10, `"a"`, modtime), object(11, `"a"`, modtime), false},
+		{"no etag, same time", record(10, "", modtime), object(10, `"a"`, modtime.Add(time.Millisecond)), true},
+		{"no object etag, same time", record(10, `"a"`, modtime), object(10, "", modtime), true},
+		{"no etag, different time", record(10, "", modtime), object(10, `"a"`, modtime.Add(time.Second)), false},
+		{"no etag, no time", record(10, "", time.Time{}), object(10, "", time.Time{}), false},
+	}
+	for _, test := range tests {
+		if got := test.record.Matches(test.object); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// JSON
+
+func TestIndexRecordJSON_001(t *testing.T) {
+	var record IndexRecord
+	record.Path = "/a.txt"
+	record.ContentType = "text/plain"
+	record.Size = 10
+	record.ETag = types.Ptr(`"a"`)
+	record.Meta = []Meta{{Key: "title", Value: json.RawMessage(`"extracted"`)}}
+	record.UserMeta = []Meta{{Key: "title", Value: json.RawMessage(`"user"`)}}
+
+	// The user metadata survives a round trip, separately from the extracted metadata
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got IndexRecord
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Meta) != 1 || string(got.Meta[0].Value) != `"extracted"` {
+		t.Errorf("meta: got %v", got.Meta)
+	}
+	if len(got.UserMeta) != 1 || string(got.UserMeta[0].Value) != `"user"` {
+		t.Errorf("user_meta: got %v", got.UserMeta)
+	}
+	if !got.Matches(&Object{ObjectAttr: record.ObjectAttr}) {
+		t.Errorf("expected the record to match its own attributes")
+	}
+}
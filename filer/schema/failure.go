@@ -0,0 +1,279 @@
+package schema
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// IndexFailureMeta is a failed attempt to index an object
+type IndexFailureMeta struct {
+	ObjectKey
+	Extractor string `json:"extractor,omitempty"` // the metadata extractor, when extraction failed
+	Error     string `json:"error,omitempty"`
+}
+
+// IndexFailure is an object which could not be indexed, or which is ignored
+// by the indexer
+type IndexFailure struct {
+	IndexFailureMeta
+	Attempts  uint64    `json:"attempts"`
+	Ignored   bool      `json:"ignored,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IndexFailureKey selects the failure for an object
+type IndexFailureKey ObjectKey
+
+type IndexFailureListRequest struct {
+	Volume  string `json:"volume" arg:"" help:"Volume name"`
+	Ignored *bool  `json:"ignored,omitempty" help:"Return only ignored, or only failed, objects" negatable:""`
+	pg.OffsetLimit
+}
+
+type IndexFailureList struct {
+	IndexFailureListRequest
+	Count uint64          `json:"count,omitempty"`
+	Body  []*IndexFailure `json:"body,omitempty"`
+}
+
+// IndexFailureRequest selects objects in a volume to retry or ignore
+type IndexFailureRequest struct {
+	Paths []string `json:"paths,omitempty" arg:"" optional:"" help:"Object paths"`
+}
+
+// IndexFailureRetry selects failures to retry, which are no longer ignored.
+// All failures which are not ignored are selected when there are no paths.
+type IndexFailureRetry struct {
+	Volume string
+	IndexFailureRequest
+}
+
+// IndexFailureIgnore selects objects which are ignored by the indexer
+type IndexFailureIgnore struct {
+	Volume string
+	IndexFailureRequest
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (f IndexFailure) String() string {
+	return types.Stringify(f)
+}
+
+func (r IndexFailureListRequest) String() string {
+	return types.Stringify(r)
+}
+
+func (l IndexFailureList) String() string {
+	return types.Stringify(l)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// QUERY
+
+func (r IndexFailureListRequest) Query() url.Values {
+	query := url.Values{}
+	if r.Ignored != nil {
+		query.Set("ignored", types.Stringify(*r.Ignored))
+	}
+	if r.Offset > 0 {
+		query.Set("offset", types.Stringify(r.Offset))
+	}
+	if r.Limit != nil {
+		query.Set("limit", types.Stringify(types.Value(r.Limit)))
+	}
+	return query
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// TABLE OUTPUT
+
+func (f IndexFailure) Header() []string {
+	return []string{"Path", "Extractor", "Attempts", "Updated", "Error"}
+}
+
+func (f IndexFailure) Width(col int) int {
+	return 0
+}
+
+func (f IndexFailure) Cell(col int) string {
+	switch col {
+	case 0:
+		return f.Path
+	case 1:
+		return f.Extractor
+	case 2:
+		if f.Ignored {
+			return "ignored"
+		}
+		return types.Stringify(f.Attempts)
+	case 3:
+		return f.UpdatedAt.Format(time.RFC3339)
+	case 4:
+		return f.Error
+	default:
+		return ""
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// SELECTOR
+
+func (k IndexFailureKey) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if k.Volume == "" {
+		return "", httpresponse.ErrBadRequest.With("missing object volume")
+	} else {
+		bind.Set("volume", k.Volume)
+	}
+	if k.Path == "" {
+		return "", httpresponse.ErrBadRequest.With("missing object path")
+	} else {
+		bind.Set("path", k.Path)
+	}
+
+	switch op {
+	case pg.Get:
+		return bind.Query("filer.index_failure_get"), nil
+	case pg.Delete:
+		return bind.Query("filer.index_failure_delete"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported IndexFailureKey operation %q", op)
+	}
+}
+
+func (r *IndexFailureListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if !types.IsIdentifier(r.Volume) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", r.Volume)
+	}
+
+	bind.Del("where")
+	bind.Append("where", `"volume" = `+bind.Set("volume", r.Volume))
+	if r.Ignored != nil {
+		bind.Append("where", `"ignored" = `+bind.Set("ignored", types.Value(r.Ignored)))
+	}
+	bind.Set("where", `WHERE `+bind.Join("where", " AND "))
+
+	// Bind offset and limit
+	r.OffsetLimit.Bind(bind, IndexFailureListLimit)
+
+	switch op {
+	case pg.List:
+		return bind.Query("filer.index_failure_list"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported IndexFailureListRequest operation %q", op)
+	}
+}
+
+func (r IndexFailureRetry) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if !types.IsIdentifier(r.Volume) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", r.Volume)
+	}
+	bind.Set("volume", r.Volume)
+	if paths := r.paths(); len(paths) == 0 {
+		bind.Set("where", `"ignored" = FALSE`)
+	} else {
+		bind.Set("where", `"path" = ANY(CAST(`+bind.Set("paths", paths)+` AS TEXT[]))`)
+	}
+
+	switch op {
+	case pg.Update:
+		return bind.Query("filer.index_failure_retry"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported IndexFailureRetry operation %q", op)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (f *IndexFailure) Scan(row pg.Row) error {
+	var extractor, message *string
+	if err := row.Scan(&f.Volume, &f.Path, &extractor, &message, &f.Attempts, &f.Ignored, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		return err
+	}
+	f.Extractor = types.Value(extractor)
+	f.Error = types.Value(message)
+	return nil
+}
+
+func (l *IndexFailureList) Scan(row pg.Row) error {
+	var failure IndexFailure
+	if err := failure.Scan(row); err != nil {
+		return err
+	}
+	l.Body = append(l.Body, &failure)
+	return nil
+}
+
+func (l *IndexFailureList) ScanCount(row pg.Row) error {
+	return row.Scan(&l.Count)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+func (f IndexFailureMeta) Insert(bind *pg.Bind) (string, error) {
+	if f.Volume == "" {
+		return "", httpresponse.ErrBadRequest.With("missing object volume")
+	}
+	if f.Path == "" {
+		return "", httpresponse.ErrBadRequest.With("missing object path")
+	}
+	bind.Set("volume", f.Volume)
+	bind.Set("path", f.Path)
+	bind.Set("extractor", types.TrimStringPtr(&f.Extractor))
+	bind.Set("error", types.TrimStringPtr(&f.Error))
+
+	// Return the query
+	return bind.Query("filer.index_failure_upsert"), nil
+}
+
+func (f IndexFailureMeta) Update(_ *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("IndexFailureMeta: update: not supported")
+}
+
+func (r IndexFailureIgnore) Insert(bind *pg.Bind) (string, error) {
+	paths := r.paths()
+	if !types.IsIdentifier(r.Volume) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", r.Volume)
+	} else if len(paths) == 0 {
+		return "", httpresponse.ErrBadRequest.With("missing object paths")
+	}
+	bind.Set("volume", r.Volume)
+	bind.Set("paths", paths)
+
+	// Return the query
+	return bind.Query("filer.index_failure_ignore"), nil
+}
+
+func (r IndexFailureIgnore) Update(_ *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("IndexFailureIgnore: update: not supported")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// paths returns the object paths without leading or trailing slashes, and
+// without empty paths
+func (r IndexFailureRequest) paths() []string {
+	result := make([]string, 0, len(r.Paths))
+	for _, p := range r.Paths {
+		if p = strings.Trim(path.Clean("/"+p), "/"); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,250 @@
+package schema
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// IndexerMeta is an instance which indexes content, and the capabilities it
+// advertises, such as the names of metadata extractors or llm. An indexer
+// without capabilities indexes any content.
+type IndexerMeta struct {
+	Name         string   `json:"name"`
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// Indexer is an indexer which sends heartbeats, and the volumes it has
+// claimed
+type Indexer struct {
+	IndexerMeta
+	StartedAt   time.Time `json:"started_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	Volumes     []string  `json:"volumes,omitempty"`
+}
+
+// IndexerName selects an indexer by name
+type IndexerName string
+
+// IndexerRebalance removes indexers which have not sent a heartbeat within
+// the timeout, and balances the claims on enabled volumes between the
+// remaining indexers
+type IndexerRebalance time.Duration
+
+type IndexerListRequest struct {
+	pg.OffsetLimit
+}
+
+type IndexerList struct {
+	IndexerListRequest
+	Count uint64     `json:"count,omitempty"`
+	Body  []*Indexer `json:"body,omitempty"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// IndexerHeartbeat is the interval between indexer heartbeats, and
+	// rebalancing the claims on volumes
+	IndexerHeartbeat = 15 * time.Second
+
+	// IndexerTimeout is the period after the last heartbeat when an indexer
+	// is removed, and the volumes it has claimed are claimed by other indexers
+	IndexerTimeout = 4 * IndexerHeartbeat
+
+	// IndexerDeferDelay is the delay before an object is indexed, when it has
+	// been handed over to another indexer
+	IndexerDeferDelay = 5 * time.Second
+
+	// IndexerMaxDefer is the number of times an object is handed over to another
+	// indexer, before it is indexed by any indexer which has mounted the volume
+	IndexerMaxDefer = 10
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (i Indexer) String() string {
+	return types.Stringify(i)
+}
+
+func (r IndexerListRequest) String() string {
+	return types.Stringify(r)
+}
+
+func (l IndexerList) String() string {
+	return types.Stringify(l)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// QUERY
+
+func (r IndexerListRequest) Query() url.Values {
+	query := url.Values{}
+	if r.Offset > 0 {
+		query.Set("offset", types.Stringify(r.Offset))
+	}
+	if r.Limit != nil {
+		query.Set("limit", types.Stringify(types.Value(r.Limit)))
+	}
+	return query
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// TABLE OUTPUT
+
+func (i Indexer) Header() []string {
+	return []string{"Indexer", "Capabilities", "Started", "Heartbeat", "Volumes"}
+}
+
+func (i Indexer) Width(col int) int {
+	return 0
+}
+
+func (i Indexer) Cell(col int) string {
+	switch col {
+	case 0:
+		return i.Name
+	case 1:
+		if len(i.Capabilities) == 0 {
+			return "any"
+		}
+		return strings.Join(i.Capabilities, ",")
+	case 2:
+		return i.StartedAt.Format(time.RFC3339)
+	case 3:
+		return i.HeartbeatAt.Format(time.RFC3339)
+	case 4:
+		return strings.Join(i.Volumes, ",")
+	default:
+		return ""
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Capable returns true when the indexer has all the capabilities. An indexer
+// without capabilities is capable of indexing any content.
+func (i IndexerMeta) Capable(capabilities []string) bool {
+	if len(i.Capabilities) == 0 {
+		return true
+	}
+	for _, capability := range capabilities {
+		if !slices.Contains(i.Capabilities, capability) {
+			return false
+		}
+	}
+	return true
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// SELECTOR
+
+func (n IndexerName) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if name := strings.TrimSpace(string(n)); name == "" {
+		return "", gofiler.ErrBadParameter.With("missing indexer name")
+	} else {
+		bind.Set("name", name)
+	}
+
+	switch op {
+	case pg.Delete:
+		return bind.Query("filer.indexer_delete"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported IndexerName operation %q", op)
+	}
+}
+
+func (r IndexerRebalance) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if r <= 0 {
+		return "", gofiler.ErrBadParameter.With("indexer timeout must be positive")
+	}
+	bind.Set("timeout", time.Duration(r))
+
+	switch op {
+	case pg.Update:
+		return bind.Query("filer.indexer_rebalance"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported IndexerRebalance operation %q", op)
+	}
+}
+
+func (r *IndexerListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	// Bind offset and limit
+	r.OffsetLimit.Bind(bind, IndexerListLimit)
+
+	switch op {
+	case pg.List:
+		return bind.Query("filer.indexer_list"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported IndexerListRequest operation %q", op)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (i *Indexer) Scan(row pg.Row) error {
+	i.Capabilities, i.Volumes = nil, nil
+	if err := row.Scan(&i.Name, &i.Capabilities, &i.StartedAt, &i.HeartbeatAt, &i.Volumes); err != nil {
+		return err
+	}
+	if len(i.Capabilities) == 0 {
+		i.Capabilities = nil
+	}
+	if len(i.Volumes) == 0 {
+		i.Volumes = nil
+	}
+	return nil
+}
+
+func (l *IndexerList) Scan(row pg.Row) error {
+	var indexer Indexer
+	if err := indexer.Scan(row); err != nil {
+		return err
+	}
+	l.Body = append(l.Body, &indexer)
+	return nil
+}
+
+func (l *IndexerList) ScanCount(row pg.Row) error {
+	return row.Scan(&l.Count)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+// Insert registers the indexer, or records a heartbeat when the indexer is
+// already registered
+func (i IndexerMeta) Insert(bind *pg.Bind) (string, error) {
+	if name := strings.TrimSpace(i.Name); name == "" {
+		return "", gofiler.ErrBadParameter.With("missing indexer name")
+	} else {
+		bind.Set("name", name)
+	}
+	capabilities := make([]string, 0, len(i.Capabilities))
+	for _, capability := range i.Capabilities {
+		if capability = strings.ToLower(strings.TrimSpace(capability)); capability != "" && !slices.Contains(capabilities, capability) {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	bind.Set("capabilities", capabilities)
+
+	// Return the query
+	return bind.Query("filer.indexer_heartbeat"), nil
+}
+
+func (i IndexerMeta) Update(_ *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("IndexerMeta: update: not supported")
+}
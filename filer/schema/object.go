@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"path"
 	"reflect"
 	"regexp"
 	"strings"
@@ -68,16 +69,28 @@ type ObjectCreate struct {
 type CreateObjectRequest struct {
 	ObjectKey
 	Body        io.Reader `json:"-"`
-	IfNotExists bool      // if true, fail with ErrConflict when the object already exists
+	IfNotExists bool      // if true, fail with ErrConflict when the object already exists (ErrPreconditionFailed from the manager)
+	IfMatch     string    `json:"-"` // if set, fail with ErrPreconditionFailed unless the existing object matches one of the etags
 	ObjectMeta
 }
 
+// CreateObjectsRequest is a multipart request to upload files under a prefix
+type CreateObjectsRequest struct {
+	Files []types.File `json:"file" validate:"required"`
+}
+
+// CreateObjectsResponse is the list of objects created by a multipart upload
+type CreateObjectsResponse struct {
+	Body []Object `json:"body,omitempty"` // list of created objects
+}
+
 type GetObjectRequest struct {
 	ObjectKey
 }
 
 type ReadObjectRequest struct {
 	ObjectKey
+	Download bool `json:"download,omitempty" help:"Return the object as an attachment"`
 }
 
 type DeleteObjectRequest struct {
@@ -86,10 +99,13 @@ type DeleteObjectRequest struct {
 
 type DeleteObjectsRequest struct {
 	ObjectKey
+	Recursive bool   `json:"recursive,omitempty" short:"r" help:"Delete all objects under a path prefix"` // required to delete a prefix
+	IfMatch   string `json:"-"`                                                                           // if set, fail with ErrPreconditionFailed unless the object matches one of the etags
 }
 
 type DeleteObjectsResponse struct {
-	Body []Object `json:"body,omitempty"` // list of deleted objects
+	Count uint64   `json:"count"`          // number of deleted objects
+	Body  []Object `json:"body,omitempty"` // deleted objects, up to the list limit
 }
 
 type ObjectListFilters struct {
@@ -102,23 +118,55 @@ type ObjectListRequest struct {
 	Volume string `json:"volume" arg:"" required:""` // Volume name to filter by
 	pg.OffsetLimit
 	ObjectListFilters
+	Token string `json:"token,omitempty" name:"continue" help:"Continuation token from a previous page, which is used instead of the offset"`
+
+	// cursor is the position decoded from the token
+	cursor *ObjectListCursor
 }
 
 type ObjectListIterator struct {
-	Path      *string   `json:"path,omitempty"`                             // Path prefix within the backend
-	Type      *string   `json:"type,omitempty"`                             // optional content type to filter by. If text/directory, will return directories, rather than objects
-	Recursive bool      `json:"recursive,omitempty" short:"r" negatable:""` // List all objects or directories recursively, otherwise list only immediate children
-	Token     any       `json:"-"`                                          // optional token to continue listing from a previous request
-	Body      []*Object `json:"body,omitempty"`                             // page of objects or folders returned by the backend
+	Path      *string       `json:"path,omitempty"`                             // Path prefix within the backend
+	Type      *string       `json:"type,omitempty"`                             // optional content type to filter by. If text/directory, will return directories, rather than objects
+	Recursive bool          `json:"recursive,omitempty" short:"r" negatable:""` // List all objects or directories recursively, otherwise list only immediate children
+	Token     *ListPosition `json:"-"`                                          // optional position to continue listing from a previous request
+	Body      []*Object     `json:"body,omitempty"`                             // page of objects or folders returned by the backend
+}
+
+// ObjectSweep marks the objects seen in a complete listing of a volume with
+// the sweep generation, and removes the objects which were not seen
+type ObjectSweep struct {
+	Volume     string
+	Generation uint64
+	Paths      []string // paths of the objects seen, when marking
+	Threshold  float64  // the fraction of indexed objects which can be removed
+}
+
+// VolumeSweep starts a new sweep generation for a volume
+type VolumeSweep string
+
+// ObjectSweepResult is the result of removing the objects not seen in a sweep
+type ObjectSweepResult struct {
+	Total   uint64 // number of indexed objects in the volume
+	Unseen  uint64 // number of indexed objects not seen in the listing
+	Deleted uint64 // number of objects removed
 }
 
 type ObjectList struct {
 	ObjectListRequest
-	Count int       `json:"count,omitempty"` // total number of matching objects, before offset/limit
-	Body  []*Object `json:"body,omitempty"`  // page of objects; nil when Limit==0 (count-only)
+	Count     int       `json:"count,omitempty"`      // total number of matching objects, before offset/limit
+	Body      []*Object `json:"body,omitempty"`       // page of objects; nil when Limit==0 (count-only)
+	NextToken string    `json:"next_token,omitempty"` // continuation token for the next page, or empty on the last page
 }
 
+const (
+	// SweepThreshold is the default fraction of the indexed objects in a
+	// volume which can be removed by a sweep. Sweeps which would remove more
+	// are skipped, in case the listing was incomplete.
+	SweepThreshold = 0.5
+)
+
 var (
+	likeEscape         = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`) // escapes LIKE patterns
 	metaKeyLeadInvalid = regexp.MustCompile(`^[^A-Za-z_]+`)
 	metaKeyBodyInvalid = regexp.MustCompile(`[^A-Za-z0-9_-]`)
 )
@@ -227,6 +275,29 @@ func (o Object) Matches(other *Object) bool {
 	return matched
 }
 
+// MatchETags returns true if the etag matches one of the comma-separated
+// etags in the header, or the header is "*" and the etag is not empty. When
+// strong is true, weak etags never match.
+func MatchETags(header, etag string, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return etag != ""
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for part := range strings.SplitSeq(header, ",") {
+		part = strings.TrimSpace(part)
+		if strong && strings.HasPrefix(part, "W/") {
+			continue
+		}
+		if strings.Trim(strings.TrimPrefix(part, "W/"), `"`) ==
+			strings.Trim(strings.TrimPrefix(etag, "W/"), `"`) {
+			return true
+		}
+	}
+	return false
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -242,10 +313,18 @@ func (r CreateObjectRequest) String() string {
 	return types.Stringify(r)
 }
 
+func (r CreateObjectsResponse) String() string {
+	return types.Stringify(r)
+}
+
 func (r GetObjectRequest) String() string {
 	return types.Stringify(r)
 }
 
+func (r ReadObjectRequest) String() string {
+	return types.Stringify(r)
+}
+
 func (r ObjectListFilters) String() string {
 	return types.Stringify(r)
 }
@@ -293,6 +372,9 @@ func (r ObjectListRequest) Query() url.Values {
 	if r.Limit != nil {
 		query.Set("limit", types.Stringify(types.Value(r.Limit)))
 	}
+	if r.Token != "" {
+		query.Set("token", r.Token)
+	}
 	return query
 }
 
@@ -369,6 +451,7 @@ func (o ObjectCreate) Insert(bind *pg.Bind) (string, error) {
 	} else {
 		bind.Set("size", o.Size)
 	}
+	bind.Set("etag", o.ETag)
 	if contentType := strings.TrimSpace(o.ContentType); contentType == "" {
 		return "", gofiler.ErrBadParameter.With("missing content type")
 	} else {
@@ -481,10 +564,28 @@ func (l *ObjectList) Scan(row pg.Row) error {
 	return nil
 }
 
+func (r *DeleteObjectsResponse) Scan(row pg.Row) error {
+	var object Object
+	if err := object.Scan(row); err != nil {
+		return err
+	}
+	r.Body = append(r.Body, object)
+	r.Count++
+	return nil
+}
+
 func (l *ObjectList) ScanCount(row pg.Row) error {
 	return row.Scan(&l.Count)
 }
 
+func (s *ObjectSweep) Scan(row pg.Row) error {
+	return row.Scan(&s.Generation)
+}
+
+func (r *ObjectSweepResult) Scan(row pg.Row) error {
+	return row.Scan(&r.Total, &r.Unseen, &r.Deleted)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // SELECTOR
 
@@ -512,6 +613,36 @@ func (k ObjectKey) Select(bind *pg.Bind, op pg.Op) (string, error) {
 	}
 }
 
+func (r DeleteObjectsRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if r.Volume == "" {
+		return "", httpresponse.ErrBadRequest.With("missing object volume")
+	} else {
+		bind.Set("volume", r.Volume)
+	}
+
+	// Match the path, and when recursive, all paths under the prefix
+	path := strings.Trim(r.Path, "/")
+	bind.Set("path", path)
+	if r.Recursive {
+		if path == "" {
+			bind.Set("prefix", "")
+		} else {
+			bind.Set("prefix", path+"/")
+		}
+	} else if path == "" {
+		return "", httpresponse.ErrBadRequest.With("missing object path")
+	} else {
+		bind.Set("prefix", nil)
+	}
+
+	switch op {
+	case pg.Delete:
+		return bind.Query("filer.object_delete_prefix"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported DeleteObjectsRequest operation %q", op)
+	}
+}
+
 func (k ObjectTouch) Select(bind *pg.Bind, op pg.Op) (string, error) {
 	object := ObjectKey(k)
 	if object.Volume == "" {
@@ -533,6 +664,44 @@ func (k ObjectTouch) Select(bind *pg.Bind, op pg.Op) (string, error) {
 	}
 }
 
+func (v VolumeSweep) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if !types.IsIdentifier(string(v)) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", v)
+	}
+	bind.Set("volume", string(v))
+
+	switch op {
+	case pg.Update:
+		return bind.Query("filer.volume_sweep"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported VolumeSweep operation %q", op)
+	}
+}
+
+func (s ObjectSweep) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if !types.IsIdentifier(s.Volume) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", s.Volume)
+	} else if s.Generation == 0 {
+		return "", gofiler.ErrBadParameter.With("missing sweep generation")
+	}
+	bind.Set("volume", s.Volume)
+	bind.Set("generation", s.Generation)
+
+	switch op {
+	case pg.Update:
+		bind.Set("paths", s.Paths)
+		return bind.Query("filer.object_sweep_mark"), nil
+	case pg.Delete:
+		if s.Threshold < 0 || s.Threshold > 1 {
+			return "", gofiler.ErrBadParameter.Withf("invalid sweep threshold: %v", s.Threshold)
+		}
+		bind.Set("threshold", s.Threshold)
+		return bind.Query("filer.object_sweep"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported ObjectSweep operation %q", op)
+	}
+}
+
 func (r *ObjectListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
 	bind.Del("where")
 
@@ -543,21 +712,52 @@ func (r *ObjectListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
 		bind.Append("where", `o."volume" = `+bind.Set("volume", volume))
 	}
 
-	// Path
-	if path := strings.TrimSpace(types.Value(r.Path)); path != "" {
-		path := types.NormalisePath(path)
-		bind.Append("where", `o."path" = `+bind.Set("path", path))
-	}
-
-	// Type
-	if contentType := strings.TrimSpace(types.Value(r.Type)); contentType != "" {
-		// If type has a '/' then treat as full content type, otherwise match major or minor type.
-		if strings.Contains(contentType, "/") {
-			bind.Append("where", `o."type" = `+bind.Set("type", contentType))
+	// Path prefix, which is normalised in the same way as the backends, so
+	// that paths are relative to the volume and directories have no
+	// trailing slash
+	prefix := strings.Trim(path.Clean("/"+strings.TrimSpace(types.Value(r.Path))), "/")
+	if prefix != "" {
+		prefix += "/"
+		bind.Append("where", `o."path" LIKE `+bind.Set("prefix_like", likeEscape.Replace(prefix)+"%"))
+	}
+	bind.Set("prefix", prefix)
+	bind.Set("recursive", r.Recursive)
+
+	// Directories are synthesised from the paths of objects under the prefix,
+	// so the remaining filters apply to the directory rather than the object
+	directories := types.Value(r.Type) == ContentTypeDirectory
+	if directories {
+		bind.Del("cursor")
+		if cursor, err := r.decodedCursor(); err != nil {
+			return "", err
+		} else if cursor != nil && cursor.After != "" {
+			bind.Set("cursor", `WHERE d."path" > `+bind.Set("after", cursor.After))
 		} else {
-			bind.Append("where", `(o."type" LIKE `+bind.Set("type_major", contentType+"/%")+
-				` OR o."type" LIKE `+bind.Set("type_minor", "%/"+contentType)+
-				`)`)
+			bind.Set("cursor", "")
+		}
+	} else {
+		// Only immediate children of the prefix, unless recursive
+		if !r.Recursive {
+			bind.Append("where", `strpos(substr(o."path", char_length(@prefix) + 1), '/') = 0`)
+		}
+
+		// Continue after the last path returned
+		if cursor, err := r.decodedCursor(); err != nil {
+			return "", err
+		} else if cursor != nil && cursor.After != "" {
+			bind.Append("where", `o."path" > `+bind.Set("after", cursor.After))
+		}
+
+		// Type
+		if contentType := strings.TrimSpace(types.Value(r.Type)); contentType != "" {
+			// If type has a '/' then treat as full content type, otherwise match major or minor type.
+			if strings.Contains(contentType, "/") {
+				bind.Append("where", `o."type" = `+bind.Set("type", contentType))
+			} else {
+				bind.Append("where", `(o."type" LIKE `+bind.Set("type_major", contentType+"/%")+
+					` OR o."type" LIKE `+bind.Set("type_minor", "%/"+contentType)+
+					`)`)
+			}
 		}
 	}
 
@@ -570,8 +770,10 @@ func (r *ObjectListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
 
 	r.OffsetLimit.Bind(bind, ObjectListLimit)
 
-	switch op {
-	case pg.List:
+	switch {
+	case op == pg.List && directories:
+		return bind.Query("filer.object_list_directory"), nil
+	case op == pg.List:
 		return bind.Query("filer.object_list"), nil
 	default:
 		return "", gofiler.ErrInternalServerError.Withf("unsupported ObjectListRequest operation %q", op)
@@ -0,0 +1,136 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	// Packages
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// DELETE
+
+func TestDeleteObjectsRequest_001(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		recursive bool
+		valid     bool
+		wantPath  string
+		prefix    any
+	}{
+		{"object", "/a/b.txt", false, true, "a/b.txt", nil},
+		{"prefix", "/a/", true, true, "a", "a/"},
+		{"prefix without slash", "a", true, true, "a", "a/"},
+		{"volume", "/", true, true, "", ""},
+		{"empty volume", "", true, true, "", ""},
+		{"volume not recursive", "/", false, false, "", nil},
+		{"empty not recursive", "", false, false, "", nil},
+	}
+	for _, test := range tests {
+		bind := pg.NewBind()
+		req := DeleteObjectsRequest{ObjectKey: ObjectKey{Volume: "test", Path: test.path}, Recursive: test.recursive}
+		_, err := req.Select(bind, pg.Delete)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid=%v, got %v", test.name, test.valid, err)
+			continue
+		} else if err != nil {
+			continue
+		}
+		if got := bind.Get("path"); got != test.wantPath {
+			t.Errorf("%s: got path %v, want %q", test.name, got, test.wantPath)
+		}
+		if got := bind.Get("prefix"); got != test.prefix {
+			t.Errorf("%s: got prefix %v, want %v", test.name, got, test.prefix)
+		}
+	}
+}
+
+func TestDeleteObjectsRequest_002(t *testing.T) {
+	// The volume is required, and only deletes are supported
+	if _, err := (DeleteObjectsRequest{ObjectKey: ObjectKey{Path: "/a"}}).Select(pg.NewBind(), pg.Delete); err == nil {
+		t.Error("expected an error without a volume")
+	}
+	if _, err := (DeleteObjectsRequest{ObjectKey: ObjectKey{Volume: "test", Path: "/a"}}).Select(pg.NewBind(), pg.List); err == nil {
+		t.Error("expected an error for a list operation")
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIST
+
+func TestObjectListRequest_001(t *testing.T) {
+	tests := []struct {
+		name       string
+		filters    ObjectListFilters
+		query      string
+		prefix     string
+		prefixLike any
+		children   bool // only immediate children of the prefix
+	}{
+		{"volume", ObjectListFilters{}, "filer.object_list", "", nil, true},
+		{"volume recursive", ObjectListFilters{Recursive: true}, "filer.object_list", "", nil, false},
+		{"prefix", ObjectListFilters{Path: types.Ptr("/a/b/")}, "filer.object_list", "a/b/", "a/b/%", true},
+		{"prefix recursive", ObjectListFilters{Path: types.Ptr("a/b"), Recursive: true}, "filer.object_list", "a/b/", "a/b/%", false},
+		{"prefix cleaned", ObjectListFilters{Path: types.Ptr("/a/./c/../b//")}, "filer.object_list", "a/b/", "a/b/%", true},
+		{"prefix escaped", ObjectListFilters{Path: types.Ptr("/100%_a")}, "filer.object_list", "100%_a/", `100\%\_a/%`, true},
+		{"root prefix", ObjectListFilters{Path: types.Ptr("/")}, "filer.object_list", "", nil, true},
+		{"directories", ObjectListFilters{Path: types.Ptr("/a"), Type: types.Ptr(ContentTypeDirectory)}, "filer.object_list_directory", "a/", "a/%", false},
+		{"directories recursive", ObjectListFilters{Recursive: true, Type: types.Ptr(ContentTypeDirectory)}, "filer.object_list_directory", "", nil, false},
+	}
+	for _, test := range tests {
+		bind := pg.NewBind()
+		req := ObjectListRequest{Volume: "test", ObjectListFilters: test.filters}
+		if _, err := req.Select(bind, pg.List); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := bind.Get(pg.TraceSpanNameArg); got != test.query {
+			t.Errorf("%s: got query %v, want %q", test.name, got, test.query)
+		}
+		if got := bind.Get("prefix"); got != test.prefix {
+			t.Errorf("%s: got prefix %v, want %q", test.name, got, test.prefix)
+		}
+		if got := bind.Get("prefix_like"); got != test.prefixLike {
+			t.Errorf("%s: got prefix_like %v, want %v", test.name, got, test.prefixLike)
+		}
+		if got := bind.Get("recursive"); got != test.filters.Recursive {
+			t.Errorf("%s: got recursive %v, want %v", test.name, got, test.filters.Recursive)
+		}
+		if got := strings.Contains(fmt.Sprint(bind.Get("where")), "strpos"); got != test.children {
+			t.Errorf("%s: got children only %v, want %v", test.name, got, test.children)
+		}
+	}
+}
+
+func TestObjectListRequest_002(t *testing.T) {
+	tests := []struct {
+		contentType string
+		binds       []string
+	}{
+		{"image/jpeg", []string{"type"}},
+		{"image", []string{"type_major", "type_minor"}},
+	}
+	for _, test := range tests {
+		bind := pg.NewBind()
+		req := ObjectListRequest{Volume: "test", ObjectListFilters: ObjectListFilters{Type: types.Ptr(test.contentType)}}
+		if _, err := req.Select(bind, pg.List); err != nil {
+			t.Errorf("%q: %v", test.contentType, err)
+			continue
+		}
+		for _, key := range test.binds {
+			if !bind.Has(key) {
+				t.Errorf("%q: expected bind %q", test.contentType, key)
+			}
+		}
+	}
+
+	// The volume name is required
+	req := ObjectListRequest{Volume: "../test"}
+	if _, err := req.Select(pg.NewBind(), pg.List); err == nil {
+		t.Error("expected an error for an invalid volume")
+	}
+}
@@ -1,11 +1,18 @@
 -- filer.volume
 CREATE TABLE IF NOT EXISTS ${"schema"}."volume" (
-    "name"        TEXT NOT NULL,
-    "url"         TEXT NOT NULL,
-    "enabled"     BOOLEAN NOT NULL DEFAULT TRUE,
-    "index_delta" INTERVAL,
-    "created_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
-    "indexed_at"  TIMESTAMPTZ,
+    "name"          TEXT NOT NULL,
+    "url"           TEXT NOT NULL,
+    "enabled"       BOOLEAN NOT NULL DEFAULT TRUE,
+    "index_delta"   INTERVAL,
+    "readonly"      BOOLEAN NOT NULL DEFAULT FALSE,
+    "nodelete"      BOOLEAN NOT NULL DEFAULT FALSE,
+    "content_types" TEXT[] NOT NULL DEFAULT '{}',
+    "paths"         TEXT[] NOT NULL DEFAULT '{}',
+    "generation"    BIGINT NOT NULL DEFAULT 0,
+    "rules"         JSONB NOT NULL DEFAULT '{}',
+    "schedule"      JSONB NOT NULL DEFAULT '{}',
+    "created_at"    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "indexed_at"    TIMESTAMPTZ,
     PRIMARY KEY ("name"),
     CHECK ("name" ~ '^[a-z0-9_][a-z0-9_.-]{1,61}[a-z0-9_]$'),
     UNIQUE ("url")
@@ -14,6 +21,13 @@ CREATE TABLE IF NOT EXISTS ${"schema"}."volume" (
 -- filer.volume.migration
 ALTER TABLE ${"schema"}."volume" DROP CONSTRAINT IF EXISTS volume_name_check;
 ALTER TABLE ${"schema"}."volume" ADD CONSTRAINT volume_name_check CHECK ("name" ~ '^[a-z0-9_][a-z0-9_.-]{1,61}[a-z0-9_]$');
+ALTER TABLE ${"schema"}."volume" ADD COLUMN IF NOT EXISTS "readonly" BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE ${"schema"}."volume" ADD COLUMN IF NOT EXISTS "nodelete" BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE ${"schema"}."volume" ADD COLUMN IF NOT EXISTS "content_types" TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE ${"schema"}."volume" ADD COLUMN IF NOT EXISTS "paths" TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE ${"schema"}."volume" ADD COLUMN IF NOT EXISTS "generation" BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE ${"schema"}."volume" ADD COLUMN IF NOT EXISTS "rules" JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE ${"schema"}."volume" ADD COLUMN IF NOT EXISTS "schedule" JSONB NOT NULL DEFAULT '{}';
 
 -- filter.object
 CREATE TABLE IF NOT EXISTS ${"schema"}."object" (
@@ -24,10 +38,17 @@ CREATE TABLE IF NOT EXISTS ${"schema"}."object" (
     "etag"        TEXT,
     "modified_at" TIMESTAMPTZ NOT NULL,
     "indexed_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "generation" BIGINT NOT NULL DEFAULT 0,
     PRIMARY KEY ("volume", "path"),
     FOREIGN KEY ("volume") REFERENCES ${"schema"}."volume"("name") ON DELETE CASCADE
 );
 
+-- filer.object.migration
+ALTER TABLE ${"schema"}."object" ADD COLUMN IF NOT EXISTS "generation" BIGINT NOT NULL DEFAULT 0;
+
+-- filer.object.index
+CREATE INDEX IF NOT EXISTS idx_object_path_prefix ON ${"schema"}."object"("volume", "path" text_pattern_ops);
+
 -- filter.meta
 CREATE TABLE IF NOT EXISTS ${"schema"}."meta" (
     "volume"      TEXT NOT NULL,
@@ -39,6 +60,42 @@ CREATE TABLE IF NOT EXISTS ${"schema"}."meta" (
     FOREIGN KEY ("volume", "path") REFERENCES ${"schema"}."object"("volume", "path") ON DELETE CASCADE
 );
 
+-- filer.user_meta
+CREATE TABLE IF NOT EXISTS ${"schema"}."user_meta" (
+    "volume"      TEXT NOT NULL,
+    "path"        TEXT NOT NULL,
+    "key"         TEXT NOT NULL,
+    "value"       JSONB NOT NULL,
+    "updated_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("volume", "path", "key"),
+    CHECK ("key" ~ '^[A-Za-z_][A-Za-z0-9_-]*$'),
+    FOREIGN KEY ("volume") REFERENCES ${"schema"}."volume"("name") ON DELETE CASCADE
+);
+
+-- filer.object_meta.function
+CREATE OR REPLACE FUNCTION ${"schema"}.object_meta(meta_volume TEXT, meta_path TEXT)
+RETURNS JSONB AS $$
+  SELECT
+    COALESCE(jsonb_agg(jsonb_build_object('key', m."key", 'value', m."value") ORDER BY m."key"), '[]'::jsonb)
+  FROM (
+    SELECT DISTINCT ON (lower(u."key"))
+      u."key", u."value"
+    FROM (
+      SELECT "key", "value", 0 AS "priority"
+      FROM ${"schema"}."user_meta"
+      WHERE "volume" = meta_volume
+      AND "path" = meta_path
+      UNION ALL
+      SELECT "key", "value", 1 AS "priority"
+      FROM ${"schema"}."meta"
+      WHERE "volume" = meta_volume
+      AND "path" = meta_path
+    ) AS u
+    ORDER BY
+      lower(u."key"), u."priority"
+  ) AS m
+$$ LANGUAGE sql STABLE;
+
 -- filer.artwork
 CREATE TABLE IF NOT EXISTS ${"schema"}."artwork" (
     "etag"        TEXT NOT NULL,
@@ -63,6 +120,21 @@ CREATE TABLE IF NOT EXISTS ${"schema"}."object_artwork" (
 -- filer.object_artwork.index
 CREATE INDEX IF NOT EXISTS idx_object_artwork_etag ON ${"schema"}."object_artwork"("etag");
 
+-- filer.rendition
+CREATE TABLE IF NOT EXISTS ${"schema"}."rendition" (
+    "key"         TEXT NOT NULL,
+    "source"      TEXT NOT NULL,
+    "data"        BYTEA NOT NULL,
+    "type"        TEXT NOT NULL,
+    "width"       INT NOT NULL,
+    "height"      INT NOT NULL,
+    "created_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("key")
+);
+
+-- filer.rendition.index
+CREATE INDEX IF NOT EXISTS idx_rendition_source ON ${"schema"}."rendition"("source");
+
 -- filer.search
 CREATE TABLE IF NOT EXISTS ${"schema"}."search" (
     "volume"     TEXT NOT NULL,
@@ -91,24 +163,18 @@ BEGIN
       setweight(to_tsvector('english', COALESCE(o."type", '')), 'B') ||
       setweight(to_tsvector('english', COALESCE((
         SELECT string_agg(m."value"::TEXT, ' ')
-        FROM ${"schema"}."meta" AS m
-        WHERE m."volume" = o."volume"
-        AND m."path" = o."path"
-        AND lower(m."key") = 'title'
+        FROM jsonb_to_recordset(${"schema"}.object_meta(o."volume", o."path")) AS m("key" TEXT, "value" JSONB)
+        WHERE lower(m."key") = 'title'
       ), '')), 'A') ||
       setweight(to_tsvector('english', COALESCE((
         SELECT string_agg(m."value"::TEXT, ' ')
-        FROM ${"schema"}."meta" AS m
-        WHERE m."volume" = o."volume"
-        AND m."path" = o."path"
-        AND lower(m."key") = 'tags'
+        FROM jsonb_to_recordset(${"schema"}.object_meta(o."volume", o."path")) AS m("key" TEXT, "value" JSONB)
+        WHERE lower(m."key") = 'tags'
       ), '')), 'A') ||
       setweight(to_tsvector('english', COALESCE((
         SELECT string_agg(m."value"::TEXT, ' ')
-        FROM ${"schema"}."meta" AS m
-        WHERE m."volume" = o."volume"
-        AND m."path" = o."path"
-        AND lower(m."key") NOT IN ('title', 'tags')
+        FROM jsonb_to_recordset(${"schema"}.object_meta(o."volume", o."path")) AS m("key" TEXT, "value" JSONB)
+        WHERE lower(m."key") NOT IN ('title', 'tags')
       ), '')), 'D')
   INTO search_tsv
   FROM ${"schema"}."object" AS o
@@ -181,6 +247,15 @@ DO $$ BEGIN
   EXECUTE FUNCTION ${"schema"}.meta_search_update();
 END $$;
 
+-- filer.user_meta.search.trigger
+DO $$ BEGIN
+  DROP TRIGGER IF EXISTS user_meta_search_update ON ${"schema"}."user_meta";
+  CREATE TRIGGER user_meta_search_update
+  AFTER INSERT OR UPDATE OR DELETE ON ${"schema"}."user_meta"
+  FOR EACH ROW
+  EXECUTE FUNCTION ${"schema"}.meta_search_update();
+END $$;
+
 -- filer.credential
 CREATE TABLE IF NOT EXISTS ${"schema"}."credential" (
   "key"                TEXT NOT NULL,
@@ -203,6 +278,223 @@ CREATE TABLE IF NOT EXISTS ${"schema"}."llmprovider" (
   FOREIGN KEY ("credential") REFERENCES ${"schema"}."credential"("key") ON DELETE RESTRICT
 );
 
+-- filer.token
+CREATE TABLE IF NOT EXISTS ${"schema"}."token" (
+  "id"                 BIGSERIAL NOT NULL,
+  "name"               TEXT NOT NULL,
+  "hash"               TEXT NOT NULL,          -- sha256 of the token, which is not stored
+  "scopes"             TEXT[] NOT NULL,        -- read, write, admin, credential
+  "volumes"            TEXT[] NOT NULL DEFAULT '{}', -- volumes the token is restricted to, or empty for all volumes
+  "created_at"         TIMESTAMPTZ NOT NULL DEFAULT now(),
+  "expires_at"         TIMESTAMPTZ,
+  "used_at"            TIMESTAMPTZ,
+  PRIMARY KEY ("id"),
+  UNIQUE ("hash")
+);
+
+-- filer.audit
+CREATE TABLE IF NOT EXISTS ${"schema"}."audit" (
+  "id"                 BIGSERIAL NOT NULL,
+  "created_at"         TIMESTAMPTZ NOT NULL DEFAULT now(),
+  "actor"              TEXT,                   -- token which made the change, or NULL without authentication
+  "operation"          TEXT NOT NULL,          -- for example, object.delete
+  "target"             TEXT NOT NULL,          -- for example, object:volume:/path
+  "before"             JSONB,
+  "after"              JSONB,
+  "request_id"         TEXT,
+  PRIMARY KEY ("id")
+);
+
+-- filer.audit.index
+CREATE INDEX IF NOT EXISTS audit_created_at_idx ON ${"schema"}."audit" ("created_at");
+
+-- filer.audit.function
+CREATE OR REPLACE FUNCTION ${"schema"}.audit_append_only()
+RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+-- filer.audit.trigger
+DO $$ BEGIN
+  DROP TRIGGER IF EXISTS audit_append_only ON ${"schema"}."audit";
+  CREATE TRIGGER audit_append_only
+  BEFORE UPDATE ON ${"schema"}."audit"
+  FOR EACH ROW
+  EXECUTE FUNCTION ${"schema"}.audit_append_only();
+END $$;
+
+-- filer.share
+CREATE TABLE IF NOT EXISTS ${"schema"}."share" (
+  "id"                 BIGSERIAL NOT NULL,
+  "token"              TEXT NOT NULL,          -- random token which is part of the share link
+  "volume"             TEXT NOT NULL,
+  "path"               TEXT NOT NULL,          -- object path, or a prefix ending with a slash
+  "expires_at"         TIMESTAMPTZ,
+  "password"           TEXT,                   -- salted hash of the password, or NULL
+  "max_downloads"      BIGINT,                 -- download limit, or NULL for no limit
+  "downloads"          BIGINT NOT NULL DEFAULT 0,
+  "created_at"         TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  UNIQUE ("token"),
+  FOREIGN KEY ("volume") REFERENCES ${"schema"}."volume"("name") ON DELETE CASCADE
+);
+
+-- filer.index_run
+CREATE TABLE IF NOT EXISTS ${"schema"}."index_run" (
+    "volume"      TEXT NOT NULL,
+    "started_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "updated_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "total"       BIGINT NOT NULL DEFAULT 0,  -- objects queued for indexing since the run started
+    "succeeded"   BIGINT NOT NULL DEFAULT 0,
+    "skipped"     BIGINT NOT NULL DEFAULT 0,  -- unchanged or removed objects
+    "error"       TEXT,                       -- last indexing error
+    "error_at"    TIMESTAMPTZ,
+    PRIMARY KEY ("volume"),
+    FOREIGN KEY ("volume") REFERENCES ${"schema"}."volume"("name") ON DELETE CASCADE
+);
+
+-- filer.index_failure
+-- Objects which could not be indexed, and objects which are ignored by the
+-- indexer. Failures are removed when the object is indexed.
+CREATE TABLE IF NOT EXISTS ${"schema"}."index_failure" (
+    "volume"     TEXT NOT NULL,
+    "path"       TEXT NOT NULL,
+    "extractor"  TEXT,
+    "error"      TEXT,
+    "attempts"   BIGINT NOT NULL DEFAULT 0,
+    "ignored"    BOOLEAN NOT NULL DEFAULT FALSE,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "updated_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("volume", "path"),
+    FOREIGN KEY ("volume") REFERENCES ${"schema"}."volume"("name") ON DELETE CASCADE
+);
+
+-- filer.indexer
+-- Instances which index content, and the capabilities they advertise.
+-- Indexers which stop sending heartbeats are removed when claims are
+-- rebalanced.
+CREATE TABLE IF NOT EXISTS ${"schema"}."indexer" (
+    "name"          TEXT NOT NULL,
+    "capabilities"  TEXT[] NOT NULL DEFAULT '{}',
+    "started_at"    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "heartbeat_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("name")
+);
+
+-- filer.indexer_claim
+-- The indexer which reindexes a volume, and extracts metadata from its
+-- objects. Claims are released when the indexer or the volume is removed.
+-- Claims are on whole volumes: key ranges within a volume are not claimed,
+-- and the objects of a volume are shared out only by capabilities.
+CREATE TABLE IF NOT EXISTS ${"schema"}."indexer_claim" (
+    "volume"      TEXT NOT NULL,
+    "indexer"     TEXT NOT NULL,
+    "claimed_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("volume"),
+    FOREIGN KEY ("volume") REFERENCES ${"schema"}."volume"("name") ON DELETE CASCADE,
+    FOREIGN KEY ("indexer") REFERENCES ${"schema"}."indexer"("name") ON DELETE CASCADE
+);
+
+-- filer.indexer_rebalance.function
+-- Removes indexers which have not sent a heartbeat within the timeout, and
+-- balances the claims on enabled volumes between the remaining indexers.
+-- Indexers rebalance in turn, serialized by an advisory lock, and an indexer
+-- which cannot take the lock skips rebalancing. Returns the number of volumes
+-- which were claimed.
+CREATE OR REPLACE FUNCTION ${"schema"}.indexer_rebalance(timeout INTERVAL)
+RETURNS BIGINT AS $$
+DECLARE
+    indexers BIGINT;
+    share    BIGINT;
+    claimed  BIGINT := 0;
+    unclaimed RECORD;
+BEGIN
+    IF NOT pg_try_advisory_xact_lock(hashtext('${"schema"}.indexer_rebalance')) THEN
+        RETURN 0;
+    END IF;
+
+    -- Remove indexers which have stopped, which releases their claims
+    DELETE FROM ${"schema"}."indexer" WHERE "heartbeat_at" < NOW() - timeout;
+
+    -- Release claims on disabled volumes
+    DELETE FROM ${"schema"}."indexer_claim" AS c
+    USING ${"schema"}."volume" AS v
+    WHERE c."volume" = v."name" AND v."enabled" = FALSE;
+
+    SELECT COUNT(*) INTO indexers FROM ${"schema"}."indexer";
+    IF indexers = 0 THEN
+        RETURN 0;
+    END IF;
+
+    -- Release the most recent claims of indexers with more than their share
+    SELECT CEIL(COUNT(*)::NUMERIC / indexers) INTO share FROM ${"schema"}."volume" WHERE "enabled" = TRUE;
+    DELETE FROM ${"schema"}."indexer_claim"
+    WHERE "volume" IN (
+        SELECT "volume" FROM (
+            SELECT "volume", ROW_NUMBER() OVER (PARTITION BY "indexer" ORDER BY "claimed_at", "volume") AS "n"
+            FROM ${"schema"}."indexer_claim"
+        ) AS r
+        WHERE r."n" > share
+    );
+
+    -- Claim each unclaimed volume for the indexer with the fewest claims
+    FOR unclaimed IN
+        SELECT v."name" FROM ${"schema"}."volume" AS v
+        WHERE v."enabled" = TRUE AND NOT EXISTS (
+            SELECT 1 FROM ${"schema"}."indexer_claim" AS c WHERE c."volume" = v."name"
+        )
+        ORDER BY v."name"
+    LOOP
+        INSERT INTO ${"schema"}."indexer_claim" ("volume", "indexer")
+        SELECT unclaimed."name", i."name"
+        FROM ${"schema"}."indexer" AS i
+        LEFT JOIN ${"schema"}."indexer_claim" AS c ON c."indexer" = i."name"
+        GROUP BY i."name"
+        ORDER BY COUNT(c."volume"), i."name"
+        LIMIT 1;
+        claimed := claimed + 1;
+    END LOOP;
+
+    RETURN claimed;
+END;
+$$ LANGUAGE plpgsql;
+
+-- filer.batch
+CREATE TABLE IF NOT EXISTS ${"schema"}."batch" (
+    "id"          BIGSERIAL NOT NULL,
+    "operations"  JSONB NOT NULL,
+    "continue"    BOOLEAN NOT NULL DEFAULT FALSE,
+    "total"       INT NOT NULL,
+    "status"      TEXT NOT NULL DEFAULT 'pending',
+    "created_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "finished_at" TIMESTAMPTZ,
+    "token"       BIGINT,                     -- token which created the batch, or NULL without authentication
+    PRIMARY KEY ("id")
+);
+
+-- filer.batch_result
+CREATE TABLE IF NOT EXISTS ${"schema"}."batch_result" (
+    "batch"       BIGINT NOT NULL,
+    "index"       INT NOT NULL,
+    "failed"      BOOLEAN NOT NULL,
+    "result"      JSONB NOT NULL,
+    PRIMARY KEY ("batch", "index"),
+    FOREIGN KEY ("batch") REFERENCES ${"schema"}."batch"("id") ON DELETE CASCADE
+);
+
+-- filer.cursor_key
+-- The key which signs continuation tokens when no passphrase is configured,
+-- created by the first instance so that all instances share it
+CREATE TABLE IF NOT EXISTS ${"schema"}."cursor_key" (
+    "id"          BOOLEAN NOT NULL DEFAULT TRUE,
+    "key"         BYTEA NOT NULL,
+    "created_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id"),
+    CHECK ("id")
+);
+
 -- filer.notify.function
 CREATE OR REPLACE FUNCTION ${"schema"}.notify_table()
 RETURNS trigger AS $$
@@ -250,5 +542,3 @@ DO $$ BEGIN
   FOR EACH STATEMENT
   EXECUTE FUNCTION ${"schema"}.notify_table();
 END $$;
-
-
@@ -1,6 +1,6 @@
 -- filer.volume_get
 SELECT
-	v."name", v."url", v."enabled", v."index_delta", v."created_at", v."indexed_at",
+	v."name", v."url", v."enabled", v."index_delta", v."readonly", v."nodelete", v."content_types", v."paths", v."rules", v."schedule", v."created_at", v."indexed_at",
 	COALESCE((
 		SELECT COUNT(*)
 		FROM ${"schema"}."object" AS o
@@ -19,7 +19,7 @@ WHERE
 
 -- filer.volume_list
 SELECT
-	v."name", v."url", v."enabled", v."index_delta", v."created_at", v."indexed_at",
+	v."name", v."url", v."enabled", v."index_delta", v."readonly", v."nodelete", v."content_types", v."paths", v."rules", v."schedule", v."created_at", v."indexed_at",
 	COALESCE((
 		SELECT COUNT(*)
 		FROM ${"schema"}."object" AS o
@@ -37,16 +37,16 @@ ${where}
 -- filer.volume_insert
 WITH inserted AS (
 	INSERT INTO ${"schema"}."volume" (
-		"name", "url", "enabled", "index_delta"
+		"name", "url", "enabled", "index_delta", "readonly", "nodelete", "content_types", "paths", "rules", "schedule"
 	)
 	VALUES (
-		@name, @url, CAST(@enabled AS BOOLEAN), CAST(@index_delta AS INTERVAL)
+		@name, @url, CAST(@enabled AS BOOLEAN), CAST(@index_delta AS INTERVAL), CAST(@readonly AS BOOLEAN), CAST(@nodelete AS BOOLEAN), @content_types, @paths, CAST(@rules AS JSONB), CAST(@schedule AS JSONB)
 	)
 	RETURNING
-		"name", "url", "enabled", "index_delta", "created_at", "indexed_at"
+		"name", "url", "enabled", "index_delta", "readonly", "nodelete", "content_types", "paths", "rules", "schedule", "created_at", "indexed_at"
 )
 SELECT
-	i."name", i."url", i."enabled", i."index_delta", i."created_at", i."indexed_at",
+	i."name", i."url", i."enabled", i."index_delta", i."readonly", i."nodelete", i."content_types", i."paths", i."rules", i."schedule", i."created_at", i."indexed_at",
 	COALESCE((
 		SELECT COUNT(*)
 		FROM ${"schema"}."object" AS o
@@ -69,10 +69,10 @@ WITH patched AS (
 	WHERE
 		"name" = @name
 	RETURNING
-		"name", "url", "enabled", "index_delta", "created_at", "indexed_at"
+		"name", "url", "enabled", "index_delta", "readonly", "nodelete", "content_types", "paths", "rules", "schedule", "created_at", "indexed_at"
 )
 SELECT
-	p."name", p."url", p."enabled", p."index_delta", p."created_at", p."indexed_at",
+	p."name", p."url", p."enabled", p."index_delta", p."readonly", p."nodelete", p."content_types", p."paths", p."rules", p."schedule", p."created_at", p."indexed_at",
 	COALESCE((
 		SELECT COUNT(*)
 		FROM ${"schema"}."object" AS o
@@ -87,6 +87,15 @@ FROM
 	patched AS p
 ;
 
+-- filer.volume_rules
+SELECT
+	"rules"
+FROM
+	${"schema"}."volume"
+WHERE
+	"name" = @name
+;
+
 -- filer.volume_touch
 WITH touched AS (
 	UPDATE ${"schema"}."volume"
@@ -95,10 +104,10 @@ WITH touched AS (
 	WHERE
 		"name" = @name
 	RETURNING
-		"name", "url", "enabled", "index_delta", "created_at", "indexed_at"
+		"name", "url", "enabled", "index_delta", "readonly", "nodelete", "content_types", "paths", "rules", "schedule", "created_at", "indexed_at"
 )
 SELECT
-	t."name", t."url", t."enabled", t."index_delta", t."created_at", t."indexed_at",
+	t."name", t."url", t."enabled", t."index_delta", t."readonly", t."nodelete", t."content_types", t."paths", t."rules", t."schedule", t."created_at", t."indexed_at",
 	COALESCE((
 		SELECT COUNT(*)
 		FROM ${"schema"}."object" AS o
@@ -117,25 +126,214 @@ FROM
 WITH deleted AS (
 	DELETE FROM ${"schema"}."volume"
 	WHERE "name" = @name
-	RETURNING "name", "url", "enabled", "index_delta", "created_at", "indexed_at"
+	RETURNING "name", "url", "enabled", "index_delta", "readonly", "nodelete", "content_types", "paths", "rules", "schedule", "created_at", "indexed_at"
 )
 SELECT
-	d."name", d."url", d."enabled", d."index_delta", d."created_at", d."indexed_at",
+	d."name", d."url", d."enabled", d."index_delta", d."readonly", d."nodelete", d."content_types", d."paths", d."rules", d."schedule", d."created_at", d."indexed_at",
 	0::BIGINT AS "objects",
 	NULL::TIMESTAMPTZ AS "last_indexed_object_at"
 FROM
 	deleted AS d
 ;
 
+-- filer.volume_index
+-- Returns the progress of the last index run for a volume. Queued and running
+-- counts are read from the unfinished tasks in the index queue, which are not
+-- removed when the queue is cleaned.
+SELECT
+	r."started_at", r."updated_at", r."total", t."queued", t."running", r."succeeded", r."skipped", r."error", r."error_at"
+FROM
+	${"schema"}."index_run" AS r
+CROSS JOIN LATERAL (
+	SELECT
+		COUNT(*) FILTER (WHERE "started_at" IS NULL AND "retries" > 0) AS "queued",
+		COUNT(*) FILTER (WHERE "started_at" IS NOT NULL) AS "running"
+	FROM
+		${"schema"}."task"
+	WHERE
+		("queue" = @index_queue OR "queue" LIKE @index_queue || '-%')
+	AND "finished_at" IS NULL
+	AND "created_at" >= r."started_at"
+	AND "payload"->>'volume' = r."volume"
+) AS t
+WHERE
+	r."volume" = @name
+;
+
+-- filer.index_run_start
+INSERT INTO ${"schema"}."index_run" (
+	"volume"
+) VALUES (
+	@volume
+)
+ON CONFLICT ("volume") DO UPDATE
+SET
+	"started_at" = now(),
+	"updated_at" = now(),
+	"total" = 0,
+	"succeeded" = 0,
+	"skipped" = 0,
+	"error" = NULL,
+	"error_at" = NULL
+;
+
+-- filer.index_run_enqueue
+UPDATE ${"schema"}."index_run"
+SET
+	"total" = "total" + 1,
+	"updated_at" = now()
+WHERE
+	"volume" = @volume
+;
+
+-- filer.index_run_result
+UPDATE ${"schema"}."index_run"
+SET
+	"succeeded" = "succeeded" + CAST(@succeeded AS BIGINT),
+	"skipped" = "skipped" + CAST(@skipped AS BIGINT),
+	"error" = COALESCE(CAST(@error AS TEXT), "error"),
+	"error_at" = CASE WHEN CAST(@error AS TEXT) IS NULL THEN "error_at" ELSE now() END,
+	"updated_at" = now()
+WHERE
+	"volume" = @volume
+;
+
+-- filer.volume_sweep
+-- Starts a new sweep generation for a volume
+UPDATE ${"schema"}."volume"
+SET
+	"generation" = "generation" + 1
+WHERE
+	"name" = @volume
+RETURNING
+	"generation"
+;
+
+-- filer.object_sweep_mark
+-- Marks the objects seen in a listing with the sweep generation
+UPDATE ${"schema"}."object"
+SET
+	"generation" = @generation
+WHERE
+	"volume" = @volume
+AND
+	"path" = ANY(CAST(@paths AS TEXT[]))
+AND
+	"generation" < @generation
+;
+
+-- filer.object_sweep
+-- Removes the objects not seen in the sweep generation, unless more than the
+-- threshold fraction of the indexed objects would be removed. Metadata and
+-- artwork links are removed with the objects.
+WITH counts AS (
+	SELECT
+		COUNT(*) AS "total",
+		COUNT(*) FILTER (WHERE "generation" < @generation) AS "unseen"
+	FROM
+		${"schema"}."object"
+	WHERE
+		"volume" = @volume
+), deleted AS (
+	DELETE FROM ${"schema"}."object" AS o
+	USING
+		counts AS c
+	WHERE
+		o."volume" = @volume
+	AND
+		o."generation" < @generation
+	AND
+		c."unseen" <= c."total" * CAST(@threshold AS DOUBLE PRECISION)
+	RETURNING
+		o."path"
+)
+SELECT
+	c."total", c."unseen", (SELECT COUNT(*) FROM deleted) AS "deleted"
+FROM
+	counts AS c
+;
+
+-- filer.index_failure_get
+SELECT
+	"volume", "path", "extractor", "error", "attempts", "ignored", "created_at", "updated_at"
+FROM
+	${"schema"}."index_failure"
+WHERE
+	"volume" = @volume
+AND
+	"path" = @path
+;
+
+-- filer.index_failure_list
+SELECT
+	"volume", "path", "extractor", "error", "attempts", "ignored", "created_at", "updated_at"
+FROM
+	${"schema"}."index_failure"
+${where}
+ORDER BY
+	"updated_at" DESC, "path"
+
+-- filer.index_failure_upsert
+INSERT INTO ${"schema"}."index_failure" AS f (
+	"volume", "path", "extractor", "error", "attempts"
+)
+VALUES (
+	@volume, @path, @extractor, @error, 1
+)
+ON CONFLICT ("volume", "path") DO UPDATE
+SET
+	"extractor" = EXCLUDED."extractor",
+	"error" = EXCLUDED."error",
+	"attempts" = f."attempts" + 1,
+	"updated_at" = now()
+RETURNING
+	"volume", "path", "extractor", "error", "attempts", "ignored", "created_at", "updated_at"
+;
+
+-- filer.index_failure_delete
+-- Failures are removed when an object is indexed, but ignored objects remain
+DELETE FROM ${"schema"}."index_failure"
+WHERE
+	"volume" = @volume
+AND
+	"path" = @path
+AND
+	"ignored" = FALSE
+;
+
+-- filer.index_failure_retry
+UPDATE ${"schema"}."index_failure"
+SET
+	"ignored" = FALSE,
+	"updated_at" = now()
+WHERE
+	"volume" = @volume
+AND
+	${where}
+RETURNING
+	"volume", "path", "extractor", "error", "attempts", "ignored", "created_at", "updated_at"
+;
+
+-- filer.index_failure_ignore
+INSERT INTO ${"schema"}."index_failure" AS f (
+	"volume", "path", "ignored"
+)
+SELECT
+	@volume, p."path", TRUE
+FROM
+	unnest(CAST(@paths AS TEXT[])) AS p("path")
+ON CONFLICT ("volume", "path") DO UPDATE
+SET
+	"ignored" = TRUE,
+	"updated_at" = now()
+RETURNING
+	"volume", "path", "extractor", "error", "attempts", "ignored", "created_at", "updated_at"
+;
+
 -- filer.object_get
 SELECT
 	o."volume", o."path", o."size", o."type", o."etag", o."modified_at",
-	COALESCE((
-		SELECT jsonb_agg(jsonb_build_object('key', m."key", 'value', m."value") ORDER BY m."key")
-		FROM ${"schema"}."meta" AS m
-		WHERE m."volume" = o."volume"
-		AND m."path" = o."path"
-	), '[]'::jsonb) AS "meta",
+	${"schema"}.object_meta(o."volume", o."path") AS "meta",
 	COALESCE((
 		SELECT jsonb_agg(jsonb_build_object('key', oa."etag", 'type', a."type", 'width', a."width", 'height', a."height", 'created_at', a."created_at") ORDER BY oa."etag")
 		FROM ${"schema"}."object_artwork" AS oa
@@ -163,15 +361,37 @@ WITH deleted AS (
 )
 SELECT
 	d."volume", d."path", d."size", d."type", d."etag", d."modified_at",
-	COALESCE((
-		SELECT jsonb_agg(jsonb_build_object('key', m."key", 'value', m."value") ORDER BY m."key")
-		FROM ${"schema"}."meta" AS m
-		WHERE m."volume" = d."volume"
-		AND m."path" = d."path"
-	), '[]'::jsonb) AS "meta",
+	${"schema"}.object_meta(d."volume", d."path") AS "meta",
+	'[]'::jsonb AS "artwork"
+FROM
+	deleted AS d
+;
+
+-- filer.object_delete_prefix
+-- User metadata is removed with the objects, since they are deleted from the volume
+WITH deleted AS (
+	DELETE FROM ${"schema"}."object"
+	WHERE
+		"volume" = @volume
+	AND
+		("path" = @path OR starts_with("path", CAST(@prefix AS TEXT)))
+	RETURNING
+		"volume", "path", "size", "type", "etag", "modified_at"
+), user_meta AS (
+	DELETE FROM ${"schema"}."user_meta"
+	WHERE
+		"volume" = @volume
+	AND
+		("path" = @path OR starts_with("path", CAST(@prefix AS TEXT)))
+)
+SELECT
+	d."volume", d."path", d."size", d."type", d."etag", d."modified_at",
+	${"schema"}.object_meta(d."volume", d."path") AS "meta",
 	'[]'::jsonb AS "artwork"
 FROM
 	deleted AS d
+ORDER BY
+	d."path"
 ;
 
 -- filer.object_patch
@@ -188,12 +408,7 @@ WITH patched AS (
 )
 SELECT
 	p."volume", p."path", p."size", p."type", p."etag", p."modified_at",
-	COALESCE((
-		SELECT jsonb_agg(jsonb_build_object('key', m."key", 'value', m."value") ORDER BY m."key")
-		FROM ${"schema"}."meta" AS m
-		WHERE m."volume" = p."volume"
-		AND m."path" = p."path"
-	), '[]'::jsonb) AS "meta",
+	${"schema"}.object_meta(p."volume", p."path") AS "meta",
 	COALESCE((
 		SELECT jsonb_agg(jsonb_build_object('key', oa."etag", 'type', a."type", 'width', a."width", 'height', a."height", 'created_at', a."created_at") ORDER BY oa."etag")
 		FROM ${"schema"}."object_artwork" AS oa
@@ -219,12 +434,7 @@ WITH touched AS (
 )
 SELECT
 	t."volume", t."path", t."size", t."type", t."etag", t."modified_at",
-	COALESCE((
-		SELECT jsonb_agg(jsonb_build_object('key', m."key", 'value', m."value") ORDER BY m."key")
-		FROM ${"schema"}."meta" AS m
-		WHERE m."volume" = t."volume"
-		AND m."path" = t."path"
-	), '[]'::jsonb) AS "meta",
+	${"schema"}.object_meta(t."volume", t."path") AS "meta",
 	COALESCE((
 		SELECT jsonb_agg(jsonb_build_object('key', oa."etag", 'type', a."type", 'width', a."width", 'height', a."height", 'created_at', a."created_at") ORDER BY oa."etag")
 		FROM ${"schema"}."object_artwork" AS oa
@@ -239,12 +449,7 @@ FROM
 -- filer.object_list
 SELECT
 	o."volume", o."path", o."size", o."type", o."etag", o."modified_at",
-	COALESCE((
-		SELECT jsonb_agg(jsonb_build_object('key', m."key", 'value', m."value") ORDER BY m."key")
-		FROM ${"schema"}."meta" AS m
-		WHERE m."volume" = o."volume"
-		AND m."path" = o."path"
-	), '[]'::jsonb) AS "meta",
+	${"schema"}.object_meta(o."volume", o."path") AS "meta",
 	COALESCE((
 		SELECT jsonb_agg(jsonb_build_object('key', oa."etag", 'type', a."type", 'width', a."width", 'height', a."height", 'created_at', a."created_at") ORDER BY oa."etag")
 		FROM ${"schema"}."object_artwork" AS oa
@@ -258,32 +463,55 @@ ${where}
 ORDER BY
 	o."volume", o."path"
 
+-- filer.object_list_directory
+SELECT
+	d."volume", d."path", 0::BIGINT AS "size", 'text/directory' AS "type", NULL::TEXT AS "etag", d."modified_at",
+	'[]'::jsonb AS "meta", '[]'::jsonb AS "artwork"
+FROM (
+	SELECT
+		o."volume",
+		@prefix || array_to_string((string_to_array(substr(o."path", char_length(@prefix) + 1), '/'))[1:s."depth"], '/') AS "path",
+		MAX(o."modified_at") AS "modified_at"
+	FROM
+		${"schema"}."object" AS o
+	CROSS JOIN LATERAL
+		generate_series(1, CASE
+			WHEN @recursive THEN cardinality(string_to_array(substr(o."path", char_length(@prefix) + 1), '/')) - 1
+			ELSE LEAST(cardinality(string_to_array(substr(o."path", char_length(@prefix) + 1), '/')) - 1, 1)
+		END) AS s("depth")
+	${where}
+	GROUP BY
+		o."volume", 2
+) AS d
+${cursor}
+ORDER BY
+	d."volume", d."path"
+
 
 -- filer.object_upsert
+-- Objects are marked with the current sweep generation of the volume, so that
+-- objects indexed during a sweep are not removed
 WITH upserted AS (
 	INSERT INTO ${"schema"}."object" (
-		"volume", "path", "size", "type", "etag", "modified_at"
+		"volume", "path", "size", "type", "etag", "modified_at", "generation"
 	)
 	VALUES (
-		@volume, @path, @size, @type, @etag, @modified_at
+		@volume, @path, @size, @type, @etag, @modified_at,
+		COALESCE((SELECT v."generation" FROM ${"schema"}."volume" AS v WHERE v."name" = @volume), 0)
 	)
 	ON CONFLICT ("volume", "path") DO UPDATE
 	SET
 		"size" = EXCLUDED."size",
 		"type" = EXCLUDED."type",
 		"etag" = EXCLUDED."etag",
-		"modified_at" = EXCLUDED."modified_at"
+		"modified_at" = EXCLUDED."modified_at",
+		"generation" = GREATEST(${"schema"}."object"."generation", EXCLUDED."generation")
 	RETURNING
 		"volume", "path", "size", "type", "etag", "modified_at"
 )
 SELECT
 	u."volume", u."path", u."size", u."type", u."etag", u."modified_at",
-	COALESCE((
-		SELECT jsonb_agg(jsonb_build_object('key', m."key", 'value', m."value") ORDER BY m."key")
-		FROM ${"schema"}."meta" AS m
-		WHERE m."volume" = u."volume"
-		AND m."path" = u."path"
-	), '[]'::jsonb) AS "meta",
+	${"schema"}.object_meta(u."volume", u."path") AS "meta",
 	COALESCE((
 		SELECT jsonb_agg(jsonb_build_object('key', oa."etag", 'type', a."type", 'width', a."width", 'height', a."height", 'created_at', a."created_at") ORDER BY oa."etag")
 		FROM ${"schema"}."object_artwork" AS oa
@@ -298,12 +526,7 @@ FROM
 -- filer.search_list
 SELECT
 	o."volume", o."path", o."size", o."type", o."etag", o."modified_at",
-	COALESCE((
-		SELECT jsonb_agg(jsonb_build_object('key', m."key", 'value', m."value") ORDER BY m."key")
-		FROM ${"schema"}."meta" AS m
-		WHERE m."volume" = o."volume"
-		AND m."path" = o."path"
-	), '[]'::jsonb) AS "meta",
+	${"schema"}.object_meta(o."volume", o."path") AS "meta",
 	COALESCE((
 		SELECT jsonb_agg(jsonb_build_object('key', oa."etag", 'type', a."type", 'width', a."width", 'height', a."height", 'created_at', a."created_at") ORDER BY oa."etag")
 		FROM ${"schema"}."object_artwork" AS oa
@@ -339,11 +562,61 @@ WHERE
 ;
 
 -- filer.artwork_delete
-DELETE FROM ${"schema"}."artwork"
-WHERE
-	"etag" = @etag
-RETURNING
+-- Renditions of the artwork are removed with it
+WITH deleted AS (
+	DELETE FROM ${"schema"}."artwork"
+	WHERE
+		"etag" = @etag
+	RETURNING
+		"etag", "data", "type", "width", "height", "created_at"
+), renditions AS (
+	DELETE FROM ${"schema"}."rendition"
+	WHERE
+		"source" IN (SELECT "etag" FROM deleted)
+)
+SELECT
 	"etag", "data", "type", "width", "height", "created_at"
+FROM
+	deleted
+;
+
+-- filer.artwork_list
+SELECT
+	"etag", "type", "width", "height", "created_at"
+FROM (
+	SELECT
+		a."etag", a."type", a."width", a."height", a."created_at",
+		EXISTS (
+			SELECT 1 FROM ${"schema"}."object_artwork" AS oa WHERE oa."etag" = a."etag"
+		) AS "linked"
+	FROM
+		${"schema"}."artwork" AS a
+) AS a
+${where}
+ORDER BY
+	"created_at" DESC, "etag"
+
+-- filer.artwork_gc
+-- Artwork which is not linked to any object is removed after the grace period,
+-- together with its renditions
+WITH deleted AS (
+	DELETE FROM ${"schema"}."artwork" AS a
+	WHERE
+		a."created_at" < now() - make_interval(secs => CAST(@grace AS DOUBLE PRECISION))
+	AND NOT EXISTS (
+		SELECT 1 FROM ${"schema"}."object_artwork" AS oa WHERE oa."etag" = a."etag"
+	)
+	RETURNING
+		a."etag"
+), renditions AS (
+	DELETE FROM ${"schema"}."rendition"
+	WHERE
+		"source" IN (SELECT "etag" FROM deleted)
+)
+SELECT
+	"etag"
+FROM
+	deleted
 ;
 
 -- filer.artwork_upsert
@@ -382,6 +655,48 @@ RETURNING
 	"volume", "path", "etag"
 ;
 
+-- filer.rendition_get
+SELECT
+	"key", "data", "type", "width", "height", "created_at"
+FROM
+	${"schema"}."rendition"
+WHERE
+	"key" = @key
+;
+
+-- filer.rendition_upsert
+INSERT INTO ${"schema"}."rendition" (
+	"key", "source", "data", "type", "width", "height"
+)
+VALUES (
+	@key, @source, @data, @type, CAST(@width AS INT), CAST(@height AS INT)
+)
+ON CONFLICT ("key") DO UPDATE SET
+	"data" = EXCLUDED."data",
+	"type" = EXCLUDED."type",
+	"width" = EXCLUDED."width",
+	"height" = EXCLUDED."height",
+	"created_at" = now()
+RETURNING
+	"key", "data", "type", "width", "height", "created_at"
+;
+
+-- filer.rendition_prune
+-- Renditions cached before the retention period are removed, and are created
+-- again when requested
+WITH deleted AS (
+	DELETE FROM ${"schema"}."rendition"
+	WHERE
+		"created_at" < now() - make_interval(secs => CAST(@retention AS DOUBLE PRECISION))
+	RETURNING
+		"key"
+)
+SELECT
+	COUNT(*)
+FROM
+	deleted
+;
+
 -- filer.meta_upsert
 INSERT INTO ${"schema"}."meta" (
 	"volume", "path", "key", "value"
@@ -396,6 +711,45 @@ RETURNING
 	"volume", "path", "key", "value"
 ;
 
+-- filer.user_meta_upsert
+INSERT INTO ${"schema"}."user_meta" (
+	"volume", "path", "key", "value"
+)
+VALUES (
+	@volume, @path, @key, CAST(@value AS JSONB)
+)
+ON CONFLICT ("volume", "path", "key") DO UPDATE
+SET
+	"value" = EXCLUDED."value",
+	"updated_at" = now()
+RETURNING
+	"volume", "path", "key", "value"
+;
+
+-- filer.user_meta_delete
+DELETE FROM ${"schema"}."user_meta"
+WHERE
+	"volume" = @volume
+AND
+	"path" = @path
+AND
+	"key" = @key
+RETURNING
+	"volume", "path", "key", "value"
+;
+
+-- filer.user_meta_list
+SELECT
+	"volume", "path", "key", "value"
+FROM
+	${"schema"}."user_meta"
+WHERE
+	"volume" = @volume
+AND
+	"path" = @path
+ORDER BY
+	"key"
+
 -- credential.list
 SELECT
 	"key", "updated_at"
@@ -448,6 +802,154 @@ RETURNING
 	"key", "updated_at"
 ;
 
+-- token.insert
+INSERT INTO ${"schema"}."token" (
+	"name", "hash", "scopes", "volumes", "expires_at"
+)
+VALUES (
+	@name, @hash, @scopes, @volumes, @expires_at
+)
+RETURNING
+	"id", "name", "scopes", "volumes", "created_at", "expires_at", "used_at"
+;
+
+-- token.get
+SELECT
+	"id", "name", "scopes", "volumes", "created_at", "expires_at", "used_at"
+FROM
+	${"schema"}."token"
+WHERE
+	"id" = @id
+;
+
+-- token.authenticate
+-- Returns an unexpired token by hash, and records when it was used
+UPDATE ${"schema"}."token"
+SET
+	"used_at" = now()
+WHERE
+	"hash" = @hash
+AND
+	("expires_at" IS NULL OR "expires_at" > now())
+RETURNING
+	"id", "name", "scopes", "volumes", "created_at", "expires_at", "used_at"
+;
+
+-- token.list
+SELECT
+	"id", "name", "scopes", "volumes", "created_at", "expires_at", "used_at"
+FROM
+	${"schema"}."token"
+${where}
+ORDER BY
+	"id"
+
+-- token.delete
+DELETE FROM ${"schema"}."token"
+WHERE
+	"id" = @id
+RETURNING
+	"id", "name", "scopes", "volumes", "created_at", "expires_at", "used_at"
+;
+
+-- filer.audit_insert
+INSERT INTO ${"schema"}."audit" (
+	"actor", "operation", "target", "before", "after", "request_id"
+)
+VALUES (
+	@actor, @operation, @target, CAST(@before AS JSONB), CAST(@after AS JSONB), @request_id
+)
+RETURNING
+	"id", "created_at", "actor", "operation", "target", "before", "after", "request_id"
+;
+
+-- filer.audit_list
+SELECT
+	"id", "created_at", "actor", "operation", "target", "before", "after", "request_id"
+FROM
+	${"schema"}."audit"
+${where}
+ORDER BY
+	"id" DESC
+
+-- filer.audit_prune
+-- Entries older than the retention period are removed, which is the only
+-- change allowed to the audit log
+WITH deleted AS (
+	DELETE FROM ${"schema"}."audit"
+	WHERE
+		"created_at" < now() - make_interval(secs => CAST(@retention AS DOUBLE PRECISION))
+	RETURNING
+		"id"
+)
+SELECT
+	COUNT(*)
+FROM
+	deleted
+;
+
+-- filer.share_insert
+INSERT INTO ${"schema"}."share" (
+	"token", "volume", "path", "expires_at", "password", "max_downloads"
+)
+VALUES (
+	@token, @volume, @path, @expires_at, @password, @max_downloads
+)
+RETURNING
+	"id", "token", "volume", "path", "expires_at", "password", "max_downloads", "downloads", "created_at"
+;
+
+-- filer.share_get
+SELECT
+	"id", "token", "volume", "path", "expires_at", "password", "max_downloads", "downloads", "created_at"
+FROM
+	${"schema"}."share"
+WHERE
+	"id" = @id
+;
+
+-- filer.share_get_token
+SELECT
+	"id", "token", "volume", "path", "expires_at", "password", "max_downloads", "downloads", "created_at"
+FROM
+	${"schema"}."share"
+WHERE
+	"token" = @token
+;
+
+-- filer.share_download
+-- Counts a download, when the share link has not expired and the download
+-- limit has not been reached
+UPDATE ${"schema"}."share"
+SET
+	"downloads" = "downloads" + 1
+WHERE
+	"token" = @token
+AND
+	("expires_at" IS NULL OR "expires_at" > now())
+AND
+	("max_downloads" IS NULL OR "downloads" < "max_downloads")
+RETURNING
+	"id", "token", "volume", "path", "expires_at", "password", "max_downloads", "downloads", "created_at"
+;
+
+-- filer.share_list
+SELECT
+	"id", "token", "volume", "path", "expires_at", "password", "max_downloads", "downloads", "created_at"
+FROM
+	${"schema"}."share"
+${where}
+ORDER BY
+	"id"
+
+-- filer.share_delete
+DELETE FROM ${"schema"}."share"
+WHERE
+	"id" = @id
+RETURNING
+	"id", "token", "volume", "path", "expires_at", "password", "max_downloads", "downloads", "created_at"
+;
+
 -- llmprovider.get
 SELECT
 	"name", "provider", "url", "credential", "created_at"
@@ -500,3 +1002,207 @@ RETURNING
 	"name", "provider", "url", "credential", "created_at"
 ;
 
+
+-- filer.event_notify
+SELECT
+	pg_notify(${'event_channel'}, "payload")
+FROM
+	unnest(CAST(@payloads AS TEXT[])) AS "payload"
+;
+
+-- filer.cursor_key
+-- Returns the key which signs continuation tokens, creating it when it does
+-- not exist
+WITH inserted AS (
+	INSERT INTO ${"schema"}."cursor_key" (
+		"key"
+	)
+	VALUES (
+		@key
+	)
+	ON CONFLICT ("id") DO NOTHING
+	RETURNING
+		"key"
+)
+SELECT "key" FROM inserted
+UNION ALL
+SELECT "key" FROM ${"schema"}."cursor_key"
+LIMIT 1
+;
+
+-- filer.batch_insert
+WITH inserted AS (
+	INSERT INTO ${"schema"}."batch" (
+		"operations", "continue", "total", "token"
+	)
+	VALUES (
+		CAST(@operations AS JSONB), @continue, @total, @token
+	)
+	RETURNING
+		"id", "status", "continue", "total", "created_at", "finished_at", "token"
+)
+SELECT
+	i."id", i."status", i."continue", i."total", 0, 0, i."created_at", i."finished_at", i."token", '[]'::jsonb
+FROM
+	inserted AS i
+;
+
+-- filer.batch_get
+SELECT
+	b."id", b."status", b."continue", b."total",
+	(SELECT COUNT(*) FROM ${"schema"}."batch_result" AS r WHERE r."batch" = b."id"),
+	(SELECT COUNT(*) FROM ${"schema"}."batch_result" AS r WHERE r."batch" = b."id" AND r."failed"),
+	b."created_at", b."finished_at", b."token",
+	COALESCE((
+		SELECT jsonb_agg(r."result" ORDER BY r."index")
+		FROM ${"schema"}."batch_result" AS r
+		WHERE r."batch" = b."id"
+	), '[]'::jsonb)
+FROM
+	${"schema"}."batch" AS b
+WHERE
+	b."id" = @id
+;
+
+-- filer.batch_operations
+SELECT
+	"operations"
+FROM
+	${"schema"}."batch"
+WHERE
+	"id" = @id
+;
+
+-- filer.batch_patch
+WITH patched AS (
+	UPDATE ${"schema"}."batch"
+	SET
+		${patch}
+	WHERE
+		"id" = @id
+	RETURNING
+		"id", "status", "continue", "total", "created_at", "finished_at", "token"
+)
+SELECT
+	p."id", p."status", p."continue", p."total",
+	(SELECT COUNT(*) FROM ${"schema"}."batch_result" AS r WHERE r."batch" = p."id"),
+	(SELECT COUNT(*) FROM ${"schema"}."batch_result" AS r WHERE r."batch" = p."id" AND r."failed"),
+	p."created_at", p."finished_at", p."token",
+	'[]'::jsonb
+FROM
+	patched AS p
+;
+
+-- filer.batch_result_insert
+INSERT INTO ${"schema"}."batch_result" (
+	"batch", "index", "failed", "result"
+)
+VALUES (
+	@id, @index, @failed, CAST(@result AS JSONB)
+)
+ON CONFLICT ("batch", "index") DO UPDATE
+SET
+	"failed" = EXCLUDED."failed",
+	"result" = EXCLUDED."result"
+;
+
+-- filer.batch_prune
+-- Batches which finished before the retention period are removed, with
+-- their results
+WITH deleted AS (
+	DELETE FROM ${"schema"}."batch"
+	WHERE
+		"finished_at" < now() - make_interval(secs => CAST(@retention AS DOUBLE PRECISION))
+	RETURNING
+		"id"
+)
+SELECT
+	COUNT(*)
+FROM
+	deleted
+;
+
+-- filer.indexer_heartbeat
+INSERT INTO ${"schema"}."indexer" AS i (
+	"name", "capabilities"
+)
+VALUES (
+	@name, CAST(@capabilities AS TEXT[])
+)
+ON CONFLICT ("name") DO UPDATE
+SET
+	"capabilities" = EXCLUDED."capabilities",
+	"heartbeat_at" = now()
+;
+
+-- filer.indexer_delete
+DELETE FROM ${"schema"}."indexer"
+WHERE
+	"name" = @name
+;
+
+-- filer.indexer_rebalance
+SELECT ${"schema"}.indexer_rebalance(CAST(@timeout AS INTERVAL));
+
+-- filer.indexer_list
+SELECT
+	i."name", i."capabilities", i."started_at", i."heartbeat_at",
+	ARRAY(
+		SELECT c."volume"
+		FROM ${"schema"}."indexer_claim" AS c
+		WHERE c."indexer" = i."name"
+		ORDER BY c."volume"
+	) AS "volumes"
+FROM
+	${"schema"}."indexer" AS i
+ORDER BY
+	i."name"
+
+-- filer.index_export
+SELECT
+	o."volume", o."path", o."size", o."type", o."etag", o."modified_at",
+	COALESCE((
+		SELECT jsonb_agg(jsonb_build_object('key', m."key", 'value', m."value") ORDER BY m."key")
+		FROM ${"schema"}."meta" AS m
+		WHERE m."volume" = o."volume"
+		AND m."path" = o."path"
+	), '[]'::jsonb) AS "meta",
+	COALESCE((
+		SELECT jsonb_agg(jsonb_build_object('key', u."key", 'value', u."value") ORDER BY u."key")
+		FROM ${"schema"}."user_meta" AS u
+		WHERE u."volume" = o."volume"
+		AND u."path" = o."path"
+	), '[]'::jsonb) AS "user_meta",
+	ARRAY(
+		SELECT oa."etag"
+		FROM ${"schema"}."object_artwork" AS oa
+		WHERE oa."volume" = o."volume"
+		AND oa."path" = o."path"
+		ORDER BY oa."etag"
+	) AS "artwork",
+	s."tsv"::TEXT AS "search"
+FROM
+	${"schema"}."object" AS o
+LEFT JOIN
+	${"schema"}."search" AS s ON s."volume" = o."volume" AND s."path" = o."path"
+WHERE
+	o."volume" = @volume
+AND
+	o."path" > @after
+ORDER BY
+	o."path"
+LIMIT
+	@limit
+
+-- filer.index_search_import
+INSERT INTO ${"schema"}."search" (
+	"volume", "path", "tsv", "indexed_at"
+)
+VALUES (
+	@volume, @path, CAST(@search AS TSVECTOR), now()
+)
+ON CONFLICT ("volume", "path") DO UPDATE
+SET
+	"tsv" = EXCLUDED."tsv",
+	"indexed_at" = EXCLUDED."indexed_at"
+;
@@ -0,0 +1,202 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// RenditionRequest is the size and format of an image rendition. When only
+// the width or height is set, the other is calculated from the aspect ratio
+// of the source image. Images are never scaled up.
+type RenditionRequest struct {
+	Width  uint64 `json:"width,omitempty" help:"Maximum width in pixels"`
+	Height uint64 `json:"height,omitempty" help:"Maximum height in pixels"`
+	Fit    string `json:"fit,omitempty" enum:"contain,cover,fill" default:"contain" help:"Fit mode: contain scales within the bounds, cover scales and crops to fill the bounds, fill stretches to the bounds"`
+	Format string `json:"format,omitempty" enum:"jpeg,png,webp" help:"Output format. Defaults to png for lossless sources, or jpeg otherwise"`
+}
+
+// RenditionKey is the key of a cached rendition, which is a hash of the
+// source etag and the rendition parameters
+type RenditionKey string
+
+// Rendition is a cached rendition of an artwork or an image object
+type Rendition struct {
+	Key    RenditionKey `json:"key"`
+	Source string       `json:"source"`
+	ArtworkMeta
+}
+
+// RenditionPrune selects renditions which were cached before the retention
+// period
+type RenditionPrune struct {
+	Retention time.Duration
+}
+
+// RenditionPruned is the number of renditions removed
+type RenditionPruned uint64
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	RenditionFitContain = "contain"
+	RenditionFitCover   = "cover"
+	RenditionFitFill    = "fill"
+
+	RenditionFormatJPEG = "jpeg"
+	RenditionFormatPNG  = "png"
+	RenditionFormatWebP = "webp"
+
+	// MaxRenditionSize is the maximum width or height of a rendition
+	MaxRenditionSize = 4096
+
+	// RenditionRetention is the default period for which cached renditions
+	// are kept
+	RenditionRetention = 30 * 24 * time.Hour
+
+	// RenditionPruneInterval is the interval between removing cached renditions
+	RenditionPruneInterval = time.Hour
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r RenditionRequest) String() string {
+	return types.Stringify(r)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Validate checks the rendition parameters
+func (r RenditionRequest) Validate() error {
+	if r.Width == 0 && r.Height == 0 {
+		return gofiler.ErrBadParameter.With("missing width or height")
+	} else if r.Width > MaxRenditionSize || r.Height > MaxRenditionSize {
+		return gofiler.ErrBadParameter.Withf("width and height must not exceed %d", MaxRenditionSize)
+	}
+	switch r.Fit {
+	case "", RenditionFitContain, RenditionFitCover, RenditionFitFill:
+	default:
+		return gofiler.ErrBadParameter.Withf("invalid fit: %q", r.Fit)
+	}
+	switch r.Format {
+	case "", RenditionFormatJPEG, RenditionFormatPNG, RenditionFormatWebP:
+	default:
+		return gofiler.ErrBadParameter.Withf("invalid format: %q", r.Format)
+	}
+	return nil
+}
+
+// Key returns the cache key of a rendition of a source with an etag
+func (r RenditionRequest) Key(source string) RenditionKey {
+	fit := r.Fit
+	if fit == "" {
+		fit = RenditionFitContain
+	}
+	h := sha256.Sum256(fmt.Appendf(nil, "%s\x00%d\x00%d\x00%s\x00%s", source, r.Width, r.Height, fit, r.Format))
+	return RenditionKey(hex.EncodeToString(h[:]))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// QUERY
+
+func (r RenditionRequest) Query() url.Values {
+	query := url.Values{}
+	if r.Width > 0 {
+		query.Set("width", strconv.FormatUint(r.Width, 10))
+	}
+	if r.Height > 0 {
+		query.Set("height", strconv.FormatUint(r.Height, 10))
+	}
+	if r.Fit != "" {
+		query.Set("fit", r.Fit)
+	}
+	if r.Format != "" {
+		query.Set("format", r.Format)
+	}
+	return query
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SELECTOR
+
+func (k RenditionKey) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if k == "" {
+		return "", httpresponse.ErrBadRequest.With("missing rendition key")
+	} else {
+		bind.Set("key", k)
+	}
+	switch op {
+	case pg.Get:
+		return bind.Query("filer.rendition_get"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported RenditionKey operation %q", op)
+	}
+}
+
+func (r RenditionPrune) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if r.Retention <= 0 {
+		return "", gofiler.ErrBadParameter.With("rendition retention must be positive")
+	} else {
+		bind.Set("retention", r.Retention.Seconds())
+	}
+
+	switch op {
+	case pg.Delete:
+		return bind.Query("filer.rendition_prune"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported RenditionPrune operation %q", op)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (n *RenditionPruned) Scan(row pg.Row) error {
+	return row.Scan((*uint64)(n))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+func (r Rendition) Insert(bind *pg.Bind) (string, error) {
+	if r.Key == "" {
+		return "", gofiler.ErrBadParameter.With("missing rendition key")
+	} else {
+		bind.Set("key", r.Key)
+	}
+	if r.Source == "" {
+		return "", gofiler.ErrBadParameter.With("missing rendition source")
+	} else {
+		bind.Set("source", r.Source)
+	}
+	if len(r.Data) == 0 {
+		return "", gofiler.ErrBadParameter.With("missing rendition data")
+	} else {
+		bind.Set("data", r.Data)
+	}
+	bind.Set("type", r.Type)
+	bind.Set("width", r.Width)
+	bind.Set("height", r.Height)
+
+	// Return the query
+	return bind.Query("filer.rendition_upsert"), nil
+}
+
+func (r Rendition) Update(bind *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("Rendition update is not supported")
+}
@@ -0,0 +1,211 @@
+package schema
+
+import (
+	"encoding/json"
+	"mime"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// VolumeRules restricts how objects in a volume are indexed. Objects which
+// are excluded by path or content type are not indexed, and the other rules
+// restrict how metadata is extracted from the objects which are indexed. When
+// patching a volume, nil fields are unchanged and empty lists remove the rule.
+type VolumeRules struct {
+	Include    []string       `json:"include,omitempty" help:"Path globs of objects to index, such as /photos/*"`
+	Exclude    []string       `json:"exclude,omitempty" help:"Path globs of objects not to index. Globs without a slash match any path element, such as node_modules"`
+	SkipTypes  []string       `json:"skip_types,omitempty" name:"skip-types" help:"Content types of objects not to index, such as video/*"`
+	MaxSize    *int64         `json:"max_size,omitempty" name:"max-size" help:"Maximum size in bytes of objects from which metadata is extracted, or zero for no limit"`
+	Extractors []string       `json:"extractors,omitempty" help:"Metadata extractors to use, such as image or pdf, or all extractors when empty"`
+	Summarize  *bool          `json:"summarize,omitempty" help:"Summarise objects with an LLM when extracting metadata" negatable:""`
+	Timeout    *time.Duration `json:"timeout,omitempty" help:"Timeout for extracting metadata from an object, or zero for no timeout"`
+}
+
+// VolumeRulesName selects the indexing rules of a volume by name
+type VolumeRulesName string
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r VolumeRules) String() string {
+	return types.Stringify(r)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Validate checks the path globs, content types and limits of the rules
+func (r VolumeRules) Validate() error {
+	for _, pattern := range slices.Concat(r.Include, r.Exclude) {
+		if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+			return gofiler.ErrBadParameter.Withf("invalid path pattern: %q", pattern)
+		}
+	}
+	for _, pattern := range r.SkipTypes {
+		if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+			return gofiler.ErrBadParameter.Withf("invalid content type pattern: %q", pattern)
+		}
+	}
+	for _, extractor := range r.Extractors {
+		if strings.TrimSpace(extractor) == "" {
+			return gofiler.ErrBadParameter.With("invalid extractor: empty name")
+		}
+	}
+	if types.Value(r.MaxSize) < 0 {
+		return gofiler.ErrBadParameter.With("max_size must be non-negative")
+	}
+	if types.Value(r.Timeout) < 0 {
+		return gofiler.ErrBadParameter.With("timeout must be non-negative")
+	}
+	return nil
+}
+
+// Indexes returns true when an object with a path and content type is
+// indexed. A path is included when the path, or one of its parent
+// directories, matches a path glob. The content type is not checked when
+// empty.
+func (r VolumeRules) Indexes(objectPath, contentType string) bool {
+	objectPath = path.Clean("/" + objectPath)
+	if len(r.Include) > 0 && !slices.ContainsFunc(r.Include, func(pattern string) bool {
+		return matchRule(pattern, objectPath)
+	}) {
+		return false
+	}
+	if slices.ContainsFunc(r.Exclude, func(pattern string) bool {
+		return matchRule(pattern, objectPath)
+	}) {
+		return false
+	}
+	if contentType = strings.TrimSpace(contentType); contentType != "" && len(r.SkipTypes) > 0 {
+		if t, _, err := mime.ParseMediaType(contentType); err == nil {
+			contentType = t
+		}
+		if slices.ContainsFunc(r.SkipTypes, func(pattern string) bool {
+			ok, _ := path.Match(strings.ToLower(strings.TrimSpace(pattern)), strings.ToLower(contentType))
+			return ok
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Extracts returns true when metadata is extracted from an object with a
+// size, using the named extractor
+func (r VolumeRules) Extracts(size int64, extractor string) bool {
+	if maxSize := types.Value(r.MaxSize); maxSize > 0 && size > maxSize {
+		return false
+	}
+	if extractor == "" {
+		return false
+	}
+	if len(r.Extractors) > 0 && !slices.ContainsFunc(r.Extractors, func(name string) bool {
+		return strings.EqualFold(strings.TrimSpace(name), extractor)
+	}) {
+		return false
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// matchRule returns true if the path, or one of its parent directories,
+// matches the glob. A glob without a slash matches any element of the path.
+func matchRule(pattern, objectPath string) bool {
+	if pattern = strings.TrimSpace(pattern); strings.Contains(pattern, "/") {
+		return matchPath(path.Clean("/"+pattern), objectPath)
+	}
+	for _, elem := range strings.Split(strings.Trim(objectPath, "/"), "/") {
+		if ok, _ := path.Match(pattern, elem); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// patch returns the rules which are set as a JSON object, including empty
+// lists which remove a rule
+func (r VolumeRules) patch() (string, error) {
+	patch := make(map[string]any)
+	if r.Include != nil {
+		patch["include"] = r.Include
+	}
+	if r.Exclude != nil {
+		patch["exclude"] = r.Exclude
+	}
+	if r.SkipTypes != nil {
+		patch["skip_types"] = r.SkipTypes
+	}
+	if r.MaxSize != nil {
+		patch["max_size"] = r.MaxSize
+	}
+	if r.Extractors != nil {
+		patch["extractors"] = r.Extractors
+	}
+	if r.Summarize != nil {
+		patch["summarize"] = r.Summarize
+	}
+	if r.Timeout != nil {
+		patch["timeout"] = r.Timeout
+	}
+	if len(patch) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(patch)
+	return string(data), err
+}
+
+// decode reads the rules from JSON, and removes empty lists
+func (r *VolumeRules) decode(data []byte) error {
+	*r = VolumeRules{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, r); err != nil {
+			return err
+		}
+	}
+	for _, list := range []*[]string{&r.Include, &r.Exclude, &r.SkipTypes, &r.Extractors} {
+		if len(*list) == 0 {
+			*list = nil
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SELECTOR
+
+func (v VolumeRulesName) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if !types.IsIdentifier(string(v)) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", v)
+	}
+	bind.Set("name", string(v))
+
+	switch op {
+	case pg.Get:
+		return bind.Query("filer.volume_rules"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported VolumeRulesName operation %q", op)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (r *VolumeRules) Scan(row pg.Row) error {
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		return err
+	}
+	return r.decode(data)
+}
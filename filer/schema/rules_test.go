@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"testing"
+
+	// Packages
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// VALIDATE
+
+func TestVolumeRulesValidate_001(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules VolumeRules
+		valid bool
+	}{
+		{"empty", VolumeRules{}, true},
+		{"globs", VolumeRules{Include: []string{"/photos/*"}, Exclude: []string{"node_modules"}, SkipTypes: []string{"video/*"}}, true},
+		{"invalid include", VolumeRules{Include: []string{"/photos/["}}, false},
+		{"empty exclude", VolumeRules{Exclude: []string{" "}}, false},
+		{"invalid type", VolumeRules{SkipTypes: []string{"video/["}}, false},
+		{"empty extractor", VolumeRules{Extractors: []string{""}}, false},
+		{"negative size", VolumeRules{MaxSize: types.Ptr(int64(-1))}, false},
+	}
+	for _, test := range tests {
+		if err := test.rules.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid=%v, got %v", test.name, test.valid, err)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// INDEXES
+
+func TestVolumeRulesIndexes_001(t *testing.T) {
+	rules := VolumeRules{
+		Include:   []string{"/photos", "/docs/*.pdf"},
+		Exclude:   []string{"node_modules", "/photos/private"},
+		SkipTypes: []string{"video/*", "Application/X-Tar"},
+	}
+	tests := []struct {
+		path, contentType string
+		want              bool
+	}{
+		// Include matches the path or a parent directory
+		{"/photos/a.jpg", "image/jpeg", true},
+		{"/photos/2024/a.jpg", "image/jpeg", true},
+		{"photos/a.jpg", "", true},
+		{"/docs/a.pdf", "application/pdf", true},
+		{"/docs/a.txt", "text/plain", false},
+		{"/photographs/a.jpg", "image/jpeg", false},
+		{"/music/a.mp3", "audio/mpeg", false},
+
+		// Exclude without a slash matches any path element
+		{"/photos/node_modules/a.jpg", "image/jpeg", false},
+		{"/photos/private/a.jpg", "image/jpeg", false},
+		{"/photos/privately/a.jpg", "image/jpeg", true},
+
+		// Skipped content types ignore case and parameters
+		{"/photos/a.mp4", "video/mp4", false},
+		{"/photos/a.tar", "application/x-tar; charset=binary", false},
+		{"/photos/a.txt", "text/plain; charset=utf-8", true},
+	}
+	for _, test := range tests {
+		if got := rules.Indexes(test.path, test.contentType); got != test.want {
+			t.Errorf("%q (%q): got %v, want %v", test.path, test.contentType, got, test.want)
+		}
+	}
+}
+
+func TestVolumeRulesIndexes_002(t *testing.T) {
+	// Everything is indexed without rules
+	var rules VolumeRules
+	for _, path := range []string{"/", "/a", "/a/b/c.txt", "node_modules/a"} {
+		if !rules.Indexes(path, "application/octet-stream") {
+			t.Errorf("%q: expected to be indexed", path)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// EXTRACTS
+
+func TestVolumeRulesExtracts_001(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     VolumeRules
+		size      int64
+		extractor string
+		want      bool
+	}{
+		{"no rules", VolumeRules{}, 1 << 30, "image", true},
+		{"no extractor", VolumeRules{}, 0, "", false},
+		{"under limit", VolumeRules{MaxSize: types.Ptr(int64(100))}, 100, "image", true},
+		{"over limit", VolumeRules{MaxSize: types.Ptr(int64(100))}, 101, "image", false},
+		{"zero limit", VolumeRules{MaxSize: types.Ptr(int64(0))}, 1 << 30, "image", true},
+		{"listed", VolumeRules{Extractors: []string{"pdf", " Image "}}, 0, "image", true},
+		{"not listed", VolumeRules{Extractors: []string{"pdf"}}, 0, "image", false},
+	}
+	for _, test := range tests {
+		if got := test.rules.Extracts(test.size, test.extractor); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
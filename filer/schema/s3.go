@@ -0,0 +1,187 @@
+package schema
+
+import (
+	"encoding/xml"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// S3AccessKey is the value of a credential which is an access key for the
+// S3-compatible API, where the credential key is the access key id. Other
+// credentials cannot be used as access keys. The key is bound to an API
+// token, and grants the same scopes and volumes as the token until the
+// token expires or is deleted.
+type S3AccessKey struct {
+	Type   string  `json:"type"` // always "s3"
+	Secret string  `json:"secret"`
+	Token  TokenID `json:"token"`
+}
+
+// S3Error is the error response of the S3-compatible API
+type S3Error struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource,omitempty"`
+	RequestId string   `xml:"RequestId,omitempty"`
+}
+
+// S3ListBucketsResult is the response of ListBuckets, where each bucket
+// is a volume
+type S3ListBucketsResult struct {
+	XMLName xml.Name   `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult"`
+	Owner   S3Owner    `xml:"Owner"`
+	Buckets []S3Bucket `xml:"Buckets>Bucket"`
+}
+
+type S3Owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+type S3Bucket struct {
+	Name         string    `xml:"Name"`
+	CreationDate time.Time `xml:"CreationDate"`
+}
+
+// S3LocationConstraint is the response of GetBucketLocation
+type S3LocationConstraint struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LocationConstraint"`
+	Location string   `xml:",chardata"`
+}
+
+// S3ListObjectsResult is the response of ListObjects and ListObjectsV2
+type S3ListObjectsResult struct {
+	XMLName               xml.Name         `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string           `xml:"Name"`
+	Prefix                string           `xml:"Prefix"`
+	Delimiter             string           `xml:"Delimiter,omitempty"`
+	MaxKeys               int              `xml:"MaxKeys"`
+	EncodingType          string           `xml:"EncodingType,omitempty"`
+	IsTruncated           bool             `xml:"IsTruncated"`
+	Marker                *string          `xml:"Marker,omitempty"`
+	NextMarker            string           `xml:"NextMarker,omitempty"`
+	KeyCount              *int             `xml:"KeyCount,omitempty"`
+	ContinuationToken     string           `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string           `xml:"NextContinuationToken,omitempty"`
+	StartAfter            string           `xml:"StartAfter,omitempty"`
+	Contents              []S3Object       `xml:"Contents"`
+	CommonPrefixes        []S3CommonPrefix `xml:"CommonPrefixes"`
+}
+
+type S3Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+	StorageClass string    `xml:"StorageClass"`
+}
+
+type S3CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// S3Delete is the request body of DeleteObjects
+type S3Delete struct {
+	XMLName xml.Name             `xml:"Delete"`
+	Quiet   bool                 `xml:"Quiet"`
+	Objects []S3ObjectIdentifier `xml:"Object"`
+}
+
+type S3ObjectIdentifier struct {
+	Key string `xml:"Key"`
+}
+
+// S3DeleteResult is the response of DeleteObjects
+type S3DeleteResult struct {
+	XMLName xml.Name             `xml:"http://s3.amazonaws.com/doc/2006-03-01/ DeleteResult"`
+	Deleted []S3ObjectIdentifier `xml:"Deleted"`
+	Errors  []S3DeleteError      `xml:"Error"`
+}
+
+type S3DeleteError struct {
+	Key     string `xml:"Key"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// S3InitiateMultipartUploadResult is the response of CreateMultipartUpload
+type S3InitiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ InitiateMultipartUploadResult"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadId string   `xml:"UploadId"`
+}
+
+// S3CompleteMultipartUpload is the request body of CompleteMultipartUpload
+type S3CompleteMultipartUpload struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []S3Part `xml:"Part"`
+}
+
+type S3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// S3CompleteMultipartUploadResult is the response of CompleteMultipartUpload
+type S3CompleteMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CompleteMultipartUploadResult"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	ContentTypeXML = "application/xml"
+
+	// CredentialTypeS3 is the type of a credential which is an S3 access key
+	CredentialTypeS3 = "s3"
+
+	// S3 error codes
+	S3ErrAccessDenied          = "AccessDenied"
+	S3ErrBadDigest             = "BadDigest"
+	S3ErrEntityTooSmall        = "EntityTooSmall"
+	S3ErrInternalError         = "InternalError"
+	S3ErrInvalidAccessKeyId    = "InvalidAccessKeyId"
+	S3ErrInvalidArgument       = "InvalidArgument"
+	S3ErrInvalidPart           = "InvalidPart"
+	S3ErrInvalidPartOrder      = "InvalidPartOrder"
+	S3ErrInvalidRange          = "InvalidRange"
+	S3ErrInvalidRequest        = "InvalidRequest"
+	S3ErrMalformedXML          = "MalformedXML"
+	S3ErrMethodNotAllowed      = "MethodNotAllowed"
+	S3ErrNoSuchBucket          = "NoSuchBucket"
+	S3ErrNoSuchKey             = "NoSuchKey"
+	S3ErrNoSuchUpload          = "NoSuchUpload"
+	S3ErrNotImplemented        = "NotImplemented"
+	S3ErrPreconditionFailed    = "PreconditionFailed"
+	S3ErrRequestTimeTooSkewed  = "RequestTimeTooSkewed"
+	S3ErrServiceUnavailable    = "ServiceUnavailable"
+	S3ErrSignatureDoesNotMatch = "SignatureDoesNotMatch"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Validate checks the credential is an S3 access key with a secret, which
+// is bound to a token
+func (k S3AccessKey) Validate() error {
+	if k.Type != CredentialTypeS3 {
+		return gofiler.ErrBadParameter.With("credential is not an S3 access key")
+	} else if k.Secret == "" {
+		return gofiler.ErrBadParameter.With("missing secret access key")
+	} else if k.Token == 0 {
+		return gofiler.ErrBadParameter.With("missing token for S3 access key")
+	}
+	return nil
+}
@@ -0,0 +1,430 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// VolumeSchedule determines when a volume is reindexed, and how many objects
+// in the volume are indexed at once. A volume with a cron expression is
+// reindexed at the scheduled times, otherwise when the last index is older
+// than the index delta. When patching a volume, nil fields are unchanged and
+// empty values remove the setting.
+type VolumeSchedule struct {
+	Cron        *string `json:"cron,omitempty" help:"Cron expression for reindexing the volume, such as \"0 2 * * *\" for 2am daily, in place of the index delta"`
+	QuietHours  *string `json:"quiet_hours,omitempty" name:"quiet-hours" help:"Local time window in which the volume is not reindexed, such as 08:00-18:00"`
+	Concurrency *uint64 `json:"concurrency,omitempty" help:"Maximum number of objects in the volume indexed at once, or zero for the default"`
+}
+
+// cronSchedule is a parsed cron expression, with a bit set for each
+// minute, hour, day of the month, month and day of the week which matches
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// cronField is the range of values of a field in a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // names of the values, starting at min
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// ScheduleInterval is the interval between checking whether a volume is
+	// due to be reindexed, which is the resolution of a cron expression
+	ScheduleInterval = time.Minute
+
+	// SyncInterval is the default interval between synchronizing the mounted
+	// volumes and their schedules with the database
+	SyncInterval = 5 * time.Minute
+
+	// IndexConcurrency is the default number of objects in a volume which are
+	// indexed at once
+	IndexConcurrency = 3
+
+	// Prefixes of the queue and ticker for each volume
+	volumeQueuePrefix  = IndexQueue + "-"
+	volumeTickerPrefix = "reindex-volume-"
+
+	// maxTaskName is the maximum length of a queue or ticker name
+	maxTaskName = 64
+)
+
+var (
+	cronFields = []cronField{
+		{name: "minute", min: 0, max: 59},
+		{name: "hour", min: 0, max: 23},
+		{name: "day of month", min: 1, max: 31},
+		{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+		{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+	}
+	cronMacros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (s VolumeSchedule) String() string {
+	var parts []string
+	if cron := strings.TrimSpace(types.Value(s.Cron)); cron != "" {
+		parts = append(parts, "cron="+cron)
+	}
+	if quiet := strings.TrimSpace(types.Value(s.QuietHours)); quiet != "" {
+		parts = append(parts, "quiet="+quiet)
+	}
+	if concurrency := types.Value(s.Concurrency); concurrency > 0 {
+		parts = append(parts, "concurrency="+types.Stringify(concurrency))
+	}
+	return strings.Join(parts, " ")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// VolumeQueue returns the name of the queue for indexing objects in a volume
+func VolumeQueue(name string) string {
+	return taskName(volumeQueuePrefix, name)
+}
+
+// VolumeTicker returns the name of the ticker for reindexing a volume
+func VolumeTicker(name string) string {
+	return taskName(volumeTickerPrefix, name)
+}
+
+// IsVolumeQueue returns true if the queue name is the queue of a volume
+func IsVolumeQueue(queue string) bool {
+	return strings.HasPrefix(queue, volumeQueuePrefix)
+}
+
+// IsVolumeTicker returns true if the ticker name is the ticker of a volume
+func IsVolumeTicker(ticker string) bool {
+	return strings.HasPrefix(ticker, volumeTickerPrefix)
+}
+
+// Validate checks the cron expression and quiet hours of the schedule
+func (s VolumeSchedule) Validate() error {
+	if cron := strings.TrimSpace(types.Value(s.Cron)); cron != "" {
+		if _, err := parseCron(cron); err != nil {
+			return gofiler.ErrBadParameter.Withf("invalid cron expression %q: %v", cron, err)
+		}
+	}
+	if quiet := strings.TrimSpace(types.Value(s.QuietHours)); quiet != "" {
+		if _, _, err := parseQuietHours(quiet); err != nil {
+			return gofiler.ErrBadParameter.Withf("invalid quiet hours %q: %v", quiet, err)
+		}
+	}
+	return nil
+}
+
+// IndexConcurrency returns the maximum number of objects in the volume which
+// are indexed at once
+func (s VolumeSchedule) IndexConcurrency() uint64 {
+	if concurrency := types.Value(s.Concurrency); concurrency > 0 {
+		return concurrency
+	}
+	return IndexConcurrency
+}
+
+// Scheduled returns true when the volume is reindexed on a schedule, either
+// with a cron expression or an index delta
+func (v VolumeMeta) Scheduled() bool {
+	return strings.TrimSpace(types.Value(v.Schedule.Cron)) != "" || v.IndexDelta != nil
+}
+
+// Due returns true when an enabled volume is due to be reindexed at a time,
+// and the time is not within the quiet hours of the volume
+func (v Volume) Due(now time.Time) bool {
+	if !types.Value(v.Enabled) || !v.Scheduled() {
+		return false
+	}
+	if quiet := strings.TrimSpace(types.Value(v.Schedule.QuietHours)); quiet != "" {
+		if start, end, err := parseQuietHours(quiet); err == nil && inQuietHours(start, end, now) {
+			return false
+		}
+	}
+	if v.IndexedAt == nil {
+		return true
+	}
+	if cron := strings.TrimSpace(types.Value(v.Schedule.Cron)); cron != "" {
+		schedule, err := parseCron(cron)
+		if err != nil {
+			return false
+		}
+		next := schedule.next(v.IndexedAt.In(now.Location()))
+		return !next.IsZero() && !next.After(now)
+	}
+	return !v.IndexedAt.Add(types.Value(v.IndexDelta)).After(now)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - SCHEDULE
+
+// taskName returns a queue or ticker name for a volume. Names which are too
+// long, or contain characters which are not allowed, have a hash of the volume
+// name appended.
+func taskName(prefix, name string) string {
+	safe := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+	if safe == name && len(prefix)+len(name) <= maxTaskName {
+		return prefix + name
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	suffix := fmt.Sprintf("-%08x", hash.Sum32())
+	if n := maxTaskName - len(prefix) - len(suffix); len(safe) > n {
+		safe = safe[:n]
+	}
+	return prefix + safe + suffix
+}
+
+// patch returns the schedule settings which are set as a JSON object,
+// including empty values which remove a setting
+func (s VolumeSchedule) patch() (string, error) {
+	patch := make(map[string]any)
+	if s.Cron != nil {
+		patch["cron"] = strings.TrimSpace(*s.Cron)
+	}
+	if s.QuietHours != nil {
+		patch["quiet_hours"] = strings.TrimSpace(*s.QuietHours)
+	}
+	if s.Concurrency != nil {
+		patch["concurrency"] = s.Concurrency
+	}
+	if len(patch) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(patch)
+	return string(data), err
+}
+
+// decode reads the schedule from JSON, and removes empty values
+func (s *VolumeSchedule) decode(data []byte) error {
+	*s = VolumeSchedule{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, s); err != nil {
+			return err
+		}
+	}
+	s.Cron = types.TrimStringPtr(s.Cron)
+	s.QuietHours = types.TrimStringPtr(s.QuietHours)
+	if types.Value(s.Concurrency) == 0 {
+		s.Concurrency = nil
+	}
+	return nil
+}
+
+// parseQuietHours parses a window of local time as HH:MM-HH:MM, and returns
+// the start and end as minutes since midnight. The window wraps around
+// midnight when the end is before the start.
+func parseQuietHours(window string) (int, int, error) {
+	from, to, ok := strings.Cut(window, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected HH:MM-HH:MM")
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("empty window")
+	}
+	return start, end, nil
+}
+
+// parseClock parses a time of day as HH:MM, and returns the minutes since
+// midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", strings.TrimSpace(value))
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// inQuietHours returns true if the local time is within the window
+func inQuietHours(start, end int, now time.Time) bool {
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - CRON
+
+// parseCron parses a cron expression with five fields (minute, hour, day of
+// the month, month and day of the week) or a macro such as @daily
+func parseCron(expr string) (*cronSchedule, error) {
+	if macro, exists := cronMacros[strings.ToLower(strings.TrimSpace(expr))]; exists {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("expected %d fields", len(cronFields))
+	}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		value, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = value
+	}
+
+	// Sunday is either zero or seven
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parse returns the bit set of values matched by a field, which is a list of
+// values, ranges or wildcards, each optionally with a step
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, step := part, 1
+		if before, after, ok := strings.Cut(part, "/"); ok {
+			if n, err := strconv.Atoi(after); err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, part)
+			} else {
+				expr, step = before, n
+			}
+		}
+
+		// Determine the range of values
+		var lo, hi int
+		switch {
+		case expr == "*":
+			lo, hi = f.min, f.max
+		case strings.Contains(expr, "-"):
+			from, to, _ := strings.Cut(expr, "-")
+			var err error
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			} else if hi, err = f.value(to); err != nil {
+				return 0, err
+			} else if hi < lo {
+				return 0, fmt.Errorf("invalid range in %s field %q", f.name, part)
+			}
+		default:
+			var err error
+			if lo, err = f.value(expr); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if step > 1 {
+				hi = f.max
+			}
+		}
+
+		// Set the bits
+		for value := lo; value <= hi; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// value parses a number or name in a field
+func (f cronField) value(value string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(value, name) {
+			return f.min + i, nil
+		}
+	}
+	if n, err := strconv.Atoi(value); err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("invalid %s %q", f.name, value)
+	} else {
+		return n, nil
+	}
+}
+
+// next returns the first scheduled time after a time, or the zero time if
+// there is no scheduled time within five years. Times are matched on the wall
+// clock in the location of the time, so a time which is skipped when the
+// clocks go forward is scheduled after the change, and a time which is
+// repeated when the clocks go back is scheduled once.
+func (c *cronSchedule) next(t time.Time) time.Time {
+	// Step through the wall clock as UTC, which has no daylight saving time
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	limit := wall.AddDate(5, 0, 0)
+	for wall.Before(limit) {
+		if c.month&(1<<uint(wall.Month())) == 0 {
+			wall = time.Date(wall.Year(), wall.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		} else if !c.matchDay(wall) {
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, time.UTC)
+		} else if c.hour&(1<<uint(wall.Hour())) == 0 {
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour()+1, 0, 0, 0, time.UTC)
+		} else if c.minute&(1<<uint(wall.Minute())) == 0 {
+			wall = wall.Add(time.Minute)
+		} else {
+			// A wall clock which is skipped when the clocks go forward is
+			// moved forward by the change
+			next := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, t.Location())
+			if skipped := wall.Sub(time.Date(next.Year(), next.Month(), next.Day(), next.Hour(), next.Minute(), 0, 0, time.UTC)); skipped > 0 {
+				next = next.Add(skipped)
+			}
+			if next.After(t) {
+				return next
+			}
+
+			// The wall clock was repeated when the clocks went back
+			wall = wall.Add(time.Minute)
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay returns true if the day matches. When both the day of the month
+// and the day of the week are restricted, either can match.
+func (c *cronSchedule) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
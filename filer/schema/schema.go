@@ -10,6 +10,7 @@ import (
 const (
 	DefaultSchema = "filer"
 	NotifyChannel = "filer_changes"
+	EventChannel  = "filer_events"
 
 	// Content types which should move to the types package
 	ContentTypeDirectory           = "text/directory"
@@ -18,18 +19,35 @@ const (
 	ContentIfNoneMatchHeader       = "If-None-Match"
 	ContentIfModifiedSinceHeader   = "If-Modified-Since"
 	ContentIfUnmodifiedSinceHeader = "If-Unmodified-Since"
+	ContentIfRangeHeader           = "If-Range"
+	RangeHeader                    = "Range"
+	ContentRangeResponseHeader     = "Content-Range"
+	ContentAcceptRangesHeader      = "Accept-Ranges"
+	ContentTypeByteRanges          = "multipart/byteranges"
 )
 
 const (
-	VolumeListLimit      = 100
-	ObjectListLimit      = 1000
-	CredentialListLimit  = 100
-	MetadataListLimit    = 100
-	LLMProviderListLimit = 100
-	SearchListLimit      = 25
+	VolumeListLimit       = 100
+	ObjectListLimit       = 1000
+	CredentialListLimit   = 100
+	MetadataListLimit     = 100
+	LLMProviderListLimit  = 100
+	ArtworkListLimit      = 100
+	TokenListLimit        = 100
+	AuditListLimit        = 100
+	ShareListLimit        = 100
+	IndexFailureListLimit = 100
+	IndexerListLimit      = 100
+	IndexExportLimit      = 1000
+	SearchListLimit       = 25
 
 	// MaxUploadFiles is the maximum number of files accepted in a single multipart upload request.
 	MaxUploadFiles = 1000
+
+	// MaxBatchOperations is the maximum number of operations in a batch, and
+	// batches with more than BatchSyncLimit operations run as a background job.
+	MaxBatchOperations = 10000
+	BatchSyncLimit     = 100
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,396 @@
+package schema
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	// Packages
+	crypto "github.com/mutablelogic/go-auth/crypto"
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ShareID is the identifier of a share link
+type ShareID uint64
+
+// ShareMeta contains the writable fields of a share link. The path is an
+// object, or a prefix when it ends with a slash.
+type ShareMeta struct {
+	Volume       string     `json:"volume" arg:"" help:"Volume name"`
+	Path         string     `json:"path" arg:"" help:"Path of an object, or a prefix ending with a slash"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" help:"Expiry time of the share link"`
+	MaxDownloads *uint64    `json:"max_downloads,omitempty" name:"max-downloads" help:"Maximum number of downloads through the share link"`
+}
+
+// ShareCreate contains the values required to create a share link
+type ShareCreate struct {
+	ShareMeta
+	Password string `json:"password,omitempty" help:"Password required to use the share link"`
+}
+
+// ShareInsert contains the values required to create a share row, with the
+// random token and the hash of the password
+type ShareInsert struct {
+	ShareMeta
+	Token        string
+	PasswordHash string
+}
+
+// Share is a link which allows content in a volume to be read without
+// authentication, using the token
+type Share struct {
+	ID ShareID `json:"id"`
+	ShareMeta
+	Token     string    `json:"token,omitempty" readonly:""`
+	Protected bool      `json:"protected,omitempty" readonly:""`
+	Downloads uint64    `json:"downloads" readonly:""`
+	CreatedAt time.Time `json:"created_at" readonly:""`
+
+	// The password hash, which is never returned
+	password string
+}
+
+// ShareToken selects a share link by its token
+type ShareToken string
+
+// ShareDownload counts a download through a share link, when the link has
+// not expired and the download limit has not been reached
+type ShareDownload string
+
+type ShareListRequest struct {
+	Volume  string   `json:"volume,omitempty" help:"Return share links for a volume"`
+	Volumes []string `json:"-" kong:"-"` // Restrict the list to these volumes
+	pg.OffsetLimit
+}
+
+type ShareList struct {
+	ShareListRequest
+	Count uint64   `json:"count,omitempty"`
+	Body  []*Share `json:"body,omitempty"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// SharePath is the path of the handler which serves content through
+	// share links, without authentication
+	SharePath = "shared"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (s Share) String() string {
+	return types.Stringify(s)
+}
+
+func (s ShareMeta) String() string {
+	return types.Stringify(s)
+}
+
+func (r ShareListRequest) String() string {
+	return types.Stringify(r)
+}
+
+func (l ShareList) String() string {
+	return types.Stringify(l)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// QUERY
+
+func (r ShareListRequest) Query() url.Values {
+	query := url.Values{}
+	if r.Volume != "" {
+		query.Set("volume", r.Volume)
+	}
+	if r.Offset > 0 {
+		query.Set("offset", types.Stringify(r.Offset))
+	}
+	if r.Limit != nil {
+		query.Set("limit", types.Stringify(types.Value(r.Limit)))
+	}
+	return query
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// TABLE OUTPUT
+
+func (s Share) Header() []string {
+	return []string{"ID", "Volume", "Path", "Token", "Expires At", "Downloads", "Protected"}
+}
+
+func (s Share) Width(col int) int {
+	return 0
+}
+
+func (s Share) Cell(col int) string {
+	switch col {
+	case 0:
+		return types.Stringify(s.ID)
+	case 1:
+		return s.Volume
+	case 2:
+		return s.Path
+	case 3:
+		return s.Token
+	case 4:
+		if s.ExpiresAt == nil {
+			return ""
+		}
+		return s.ExpiresAt.Format(time.RFC3339)
+	case 5:
+		if s.MaxDownloads == nil {
+			return types.Stringify(s.Downloads)
+		}
+		return types.Stringify(s.Downloads) + "/" + types.Stringify(types.Value(s.MaxDownloads))
+	case 6:
+		if s.Protected {
+			return "yes"
+		}
+		return ""
+	default:
+		return ""
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// NewShareToken returns a new random share link token
+func NewShareToken() (string, error) {
+	data := make([]byte, 24)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// NewSharePassword returns the salted hash of a share link password, or an
+// empty string when there is no password
+func NewSharePassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		return "", err
+	}
+	key := crypto.DeriveKey(password, salt)
+	return hex.EncodeToString(salt) + "$" + hex.EncodeToString(key[:]), nil
+}
+
+// Validate checks the volume, path, expiry and download limit
+func (s ShareMeta) Validate() error {
+	if !types.IsIdentifier(s.Volume) {
+		return gofiler.ErrBadParameter.Withf("invalid volume name %q", s.Volume)
+	}
+	if s.ExpiresAt != nil && !s.ExpiresAt.After(time.Now()) {
+		return gofiler.ErrBadParameter.With("share link expiry must be in the future")
+	}
+	if s.MaxDownloads != nil && types.Value(s.MaxDownloads) == 0 {
+		return gofiler.ErrBadParameter.With("share link download limit must be positive")
+	}
+	return nil
+}
+
+// IsPrefix returns true when the share link is for the objects under a
+// prefix, rather than a single object
+func (s ShareMeta) IsPrefix() bool {
+	return strings.HasSuffix(s.Path, "/")
+}
+
+// ObjectPath returns the path of an object shared through the link, where
+// rel is the path relative to a shared prefix. An error is returned when
+// the path is outside of the prefix.
+func (s Share) ObjectPath(rel string) (string, error) {
+	if !s.IsPrefix() {
+		if strings.Trim(rel, "/") != "" {
+			return "", gofiler.ErrNotFound.Withf("%q is not shared", rel)
+		}
+		return s.Path, nil
+	}
+	result := path.Clean(s.Path + strings.TrimPrefix(rel, "/"))
+	if strings.HasSuffix(rel, "/") && result != "/" {
+		result += "/"
+	}
+	if !strings.HasPrefix(result+"/", s.Path) {
+		return "", gofiler.ErrNotFound.Withf("%q is not shared", rel)
+	}
+	return result, nil
+}
+
+// RelativePath returns the path of an object relative to a shared prefix.
+// Object paths from the backends have no leading slash.
+func (s Share) RelativePath(objectPath string) string {
+	if !s.IsPrefix() {
+		return "/" + path.Base(objectPath)
+	}
+	return "/" + strings.TrimPrefix("/"+strings.TrimPrefix(objectPath, "/"), s.Path)
+}
+
+// Check returns an error when the share link has expired, the download
+// limit has been reached or the password does not match
+func (s Share) Check(password string) error {
+	if s.ExpiresAt != nil && !s.ExpiresAt.After(time.Now()) {
+		return gofiler.ErrNotFound.With("share link has expired")
+	}
+	if s.MaxDownloads != nil && s.Downloads >= types.Value(s.MaxDownloads) {
+		return gofiler.ErrForbidden.With("share link download limit has been reached")
+	}
+	if s.password == "" {
+		return nil
+	} else if password == "" {
+		return gofiler.ErrUnauthorized.With("share link requires a password")
+	}
+	salt, hash, ok := strings.Cut(s.password, "$")
+	if !ok {
+		return gofiler.ErrInternalServerError.With("invalid share link password")
+	}
+	data, err := hex.DecodeString(salt)
+	if err != nil {
+		return gofiler.ErrInternalServerError.With("invalid share link password")
+	}
+	key := crypto.DeriveKey(password, data)
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(key[:])), []byte(hash)) != 1 {
+		return gofiler.ErrUnauthorized.With("incorrect share link password")
+	}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// SELECTOR
+
+func (id ShareID) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if id == 0 {
+		return "", gofiler.ErrBadParameter.With("missing share link id")
+	} else {
+		bind.Set("id", id)
+	}
+
+	switch op {
+	case pg.Get:
+		return bind.Query("filer.share_get"), nil
+	case pg.Delete:
+		return bind.Query("filer.share_delete"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported ShareID operation %q", op)
+	}
+}
+
+func (t ShareToken) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if t == "" {
+		return "", gofiler.ErrBadParameter.With("missing share link token")
+	} else {
+		bind.Set("token", string(t))
+	}
+
+	switch op {
+	case pg.Get:
+		return bind.Query("filer.share_get_token"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported ShareToken operation %q", op)
+	}
+}
+
+func (t ShareDownload) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if t == "" {
+		return "", gofiler.ErrBadParameter.With("missing share link token")
+	} else {
+		bind.Set("token", string(t))
+	}
+
+	// Getting a share link for download also counts the download
+	switch op {
+	case pg.Get:
+		return bind.Query("filer.share_download"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported ShareDownload operation %q", op)
+	}
+}
+
+func (r *ShareListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	bind.Del("where")
+	if r.Volume != "" {
+		bind.Append("where", `"volume" = `+bind.Set("volume", r.Volume))
+	}
+	if r.Volumes != nil {
+		bind.Append("where", `"volume" = ANY(`+bind.Set("volumes", r.Volumes)+`)`)
+	}
+	if where := bind.Join("where", " AND "); where != "" {
+		bind.Set("where", `WHERE `+where)
+	} else {
+		bind.Set("where", "")
+	}
+
+	// Bind offset and limit
+	r.OffsetLimit.Bind(bind, ShareListLimit)
+
+	switch op {
+	case pg.List:
+		return bind.Query("filer.share_list"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported ShareListRequest operation %q", op)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (s *Share) Scan(row pg.Row) error {
+	var password *string
+	if err := row.Scan(&s.ID, &s.Token, &s.Volume, &s.Path, &s.ExpiresAt, &password, &s.MaxDownloads, &s.Downloads, &s.CreatedAt); err != nil {
+		return err
+	}
+	s.password = types.Value(password)
+	s.Protected = s.password != ""
+	return nil
+}
+
+func (l *ShareList) Scan(row pg.Row) error {
+	var share Share
+	if err := share.Scan(row); err != nil {
+		return err
+	}
+	l.Body = append(l.Body, &share)
+	return nil
+}
+
+func (l *ShareList) ScanCount(row pg.Row) error {
+	return row.Scan(&l.Count)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+func (s ShareInsert) Insert(bind *pg.Bind) (string, error) {
+	if err := s.ShareMeta.Validate(); err != nil {
+		return "", err
+	}
+	if s.Token == "" {
+		return "", gofiler.ErrInternalServerError.With("missing share link token")
+	}
+	bind.Set("token", s.Token)
+	bind.Set("volume", s.Volume)
+	bind.Set("path", s.Path)
+	bind.Set("expires_at", s.ExpiresAt)
+	bind.Set("password", types.TrimStringPtr(&s.PasswordHash))
+	bind.Set("max_downloads", s.MaxDownloads)
+
+	// Return the query
+	return bind.Query("filer.share_insert"), nil
+}
+
+func (s ShareInsert) Update(_ *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("ShareInsert: update: not supported")
+}
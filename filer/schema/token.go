@@ -0,0 +1,342 @@
+package schema
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// TokenID is the identifier of an API token
+type TokenID uint64
+
+// TokenMeta contains the writable fields of an API token
+type TokenMeta struct {
+	Name      string     `json:"name" arg:"" help:"Token name"`
+	Scopes    []string   `json:"scopes" help:"Scopes granted to the token: read, write, admin or credential"`
+	Volumes   []string   `json:"volumes,omitempty" help:"Volumes the token is restricted to, or all volumes when empty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" help:"Expiry time of the token"`
+}
+
+// Token is an API token. The token value is only returned when the token is
+// created, as only a hash of the value is stored.
+type Token struct {
+	ID TokenID `json:"id"`
+	TokenMeta
+	Value     string     `json:"token,omitempty" readonly:""`
+	CreatedAt time.Time  `json:"created_at" readonly:""`
+	UsedAt    *time.Time `json:"used_at,omitempty" readonly:""`
+}
+
+// TokenCreate contains the values required to create a token row
+type TokenCreate struct {
+	TokenMeta
+	Hash string
+}
+
+// TokenHash selects an unexpired token by the hash of its value
+type TokenHash string
+
+type TokenListRequest struct {
+	pg.OffsetLimit
+
+	// Volumes restricts the tokens returned to those restricted to a subset
+	// of the volumes, when not nil
+	Volumes []string `json:"-" kong:"-"`
+}
+
+type TokenList struct {
+	TokenListRequest
+	Count uint64   `json:"count,omitempty"`
+	Body  []*Token `json:"body,omitempty"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// SecurityTokenAuth is the name of the API token security scheme
+	SecurityTokenAuth = "tokenAuth"
+
+	// Scopes granted to API tokens. The write scope implies the read scope,
+	// and the admin scope implies all other scopes.
+	ScopeRead       = "read"
+	ScopeWrite      = "write"
+	ScopeAdmin      = "admin"
+	ScopeCredential = "credential"
+
+	// ScopeVolumePrefix is the prefix of the scopes which restrict a token
+	// to particular volumes
+	ScopeVolumePrefix = "volume:"
+
+	// TokenPrefix is the prefix of token values, which makes them easier to
+	// recognise when leaked
+	TokenPrefix = "filer_"
+)
+
+var (
+	scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin, ScopeCredential}
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (t Token) String() string {
+	return types.Stringify(t)
+}
+
+func (t TokenMeta) String() string {
+	return types.Stringify(t)
+}
+
+func (t TokenListRequest) String() string {
+	return types.Stringify(t)
+}
+
+func (t TokenList) String() string {
+	return types.Stringify(t)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// QUERY
+
+func (r TokenListRequest) Query() url.Values {
+	query := url.Values{}
+	if r.Offset > 0 {
+		query.Set("offset", types.Stringify(r.Offset))
+	}
+	if r.Limit != nil {
+		query.Set("limit", types.Stringify(types.Value(r.Limit)))
+	}
+	return query
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// TABLE OUTPUT
+
+func (t Token) Header() []string {
+	return []string{"ID", "Name", "Scopes", "Volumes", "Expires At", "Used At"}
+}
+
+func (t Token) Width(col int) int {
+	return 0
+}
+
+func (t Token) Cell(col int) string {
+	switch col {
+	case 0:
+		return types.Stringify(t.ID)
+	case 1:
+		return t.Name
+	case 2:
+		return strings.Join(t.Scopes, ",")
+	case 3:
+		return strings.Join(t.Volumes, ",")
+	case 4:
+		if t.ExpiresAt == nil {
+			return ""
+		}
+		return t.ExpiresAt.Format(time.RFC3339)
+	case 5:
+		if t.UsedAt == nil {
+			return ""
+		}
+		return t.UsedAt.Format(time.RFC3339)
+	default:
+		return ""
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// NewTokenValue returns a new random token value, and the hash of the value
+// which is stored
+func NewTokenValue() (string, string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", "", err
+	}
+	value := TokenPrefix + hex.EncodeToString(data)
+	return value, TokenValueHash(value), nil
+}
+
+// TokenValueHash returns the hash of a token value
+func TokenValueHash(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
+
+// Validate checks the token name, scopes and expiry
+func (t TokenMeta) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return gofiler.ErrBadParameter.With("missing token name")
+	}
+	if len(t.Scopes) == 0 {
+		return gofiler.ErrBadParameter.With("missing token scopes")
+	}
+	for _, scope := range t.Scopes {
+		if !slices.Contains(scopes, scope) {
+			return gofiler.ErrBadParameter.Withf("invalid scope %q, expected one of %q", scope, scopes)
+		}
+	}
+	for _, volume := range t.Volumes {
+		if !types.IsIdentifier(volume) {
+			return gofiler.ErrBadParameter.Withf("invalid volume name %q", volume)
+		}
+	}
+	if t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now()) {
+		return gofiler.ErrBadParameter.With("token expiry must be in the future")
+	}
+	return nil
+}
+
+// Grants returns the scopes granted to the token, including the scopes
+// implied by other scopes, and a volume scope for each volume the token is
+// restricted to
+func (t Token) Grants() []string {
+	var result []string
+	for _, scope := range t.Scopes {
+		switch scope {
+		case ScopeAdmin:
+			result = append(result, scopes...)
+		case ScopeWrite:
+			result = append(result, ScopeWrite, ScopeRead)
+		default:
+			result = append(result, scope)
+		}
+	}
+	for _, volume := range t.Volumes {
+		result = append(result, ScopeVolumePrefix+volume)
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// GrantedVolumes returns the volumes which granted scopes are restricted
+// to, or nil when the scopes are not restricted to particular volumes
+func GrantedVolumes(scopes []string) []string {
+	var result []string
+	for _, scope := range scopes {
+		if volume, ok := strings.CutPrefix(scope, ScopeVolumePrefix); ok {
+			result = append(result, volume)
+		}
+	}
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// SELECTOR
+
+func (id TokenID) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if id == 0 {
+		return "", gofiler.ErrBadParameter.With("missing token id")
+	} else {
+		bind.Set("id", id)
+	}
+
+	switch op {
+	case pg.Get:
+		return bind.Query("token.get"), nil
+	case pg.Delete:
+		return bind.Query("token.delete"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported TokenID operation %q", op)
+	}
+}
+
+func (h TokenHash) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if h == "" {
+		return "", gofiler.ErrBadParameter.With("missing token hash")
+	} else {
+		bind.Set("hash", string(h))
+	}
+
+	// Getting a token by hash also records when the token was used
+	switch op {
+	case pg.Get:
+		return bind.Query("token.authenticate"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported TokenHash operation %q", op)
+	}
+}
+
+func (r *TokenListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if r.Volumes != nil {
+		bind.Set("where", `WHERE cardinality("volumes") > 0 AND "volumes" <@ `+bind.Set("volumes", r.Volumes))
+	} else {
+		bind.Set("where", "")
+	}
+
+	// Bind offset and limit
+	r.OffsetLimit.Bind(bind, TokenListLimit)
+
+	switch op {
+	case pg.List:
+		return bind.Query("token.list"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported TokenListRequest operation %q", op)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (t *Token) Scan(row pg.Row) error {
+	t.Volumes = nil
+	if err := row.Scan(&t.ID, &t.Name, &t.Scopes, &t.Volumes, &t.CreatedAt, &t.ExpiresAt, &t.UsedAt); err != nil {
+		return err
+	}
+	if len(t.Volumes) == 0 {
+		t.Volumes = nil
+	}
+	return nil
+}
+
+func (l *TokenList) Scan(row pg.Row) error {
+	var token Token
+	if err := token.Scan(row); err != nil {
+		return err
+	}
+	l.Body = append(l.Body, &token)
+	return nil
+}
+
+func (l *TokenList) ScanCount(row pg.Row) error {
+	return row.Scan(&l.Count)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+func (t TokenCreate) Insert(bind *pg.Bind) (string, error) {
+	if err := t.TokenMeta.Validate(); err != nil {
+		return "", err
+	}
+	if t.Hash == "" {
+		return "", gofiler.ErrInternalServerError.With("missing token hash")
+	}
+	bind.Set("name", strings.TrimSpace(t.Name))
+	bind.Set("hash", t.Hash)
+	bind.Set("scopes", t.Scopes)
+	bind.Set("volumes", append([]string{}, t.Volumes...))
+	bind.Set("expires_at", t.ExpiresAt)
+
+	// Return the query
+	return bind.Query("token.insert"), nil
+}
+
+func (t TokenCreate) Update(_ *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("TokenCreate: update: not supported")
+}
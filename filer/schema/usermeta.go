@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	pg "github.com/mutablelogic/go-pg"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// UserMeta is a metadata key set by a user on an object. User metadata is
+// stored separately from extracted metadata, so it is kept when the object
+// is reindexed, and takes precedence over extracted metadata with the same key.
+type UserMeta Meta
+
+// UserMetaKey selects the user metadata of an object, or a single key when
+// the key is set
+type UserMetaKey struct {
+	ObjectKey
+	Key string `json:"key,omitempty"`
+}
+
+// UserMetaList is the user metadata of an object
+type UserMetaList []Meta
+
+// ObjectPatchRequest sets user metadata keys on an object. A key with a null
+// or empty value is removed.
+type ObjectPatchRequest struct {
+	Meta []Meta `json:"meta" help:"Metadata keys to set, or remove when the value is null"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r ObjectPatchRequest) String() string {
+	return types.Stringify(r)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// IsNull returns true if the metadata value is empty or null, which removes
+// the key when patching an object
+func (m Meta) IsNull() bool {
+	value := bytes.TrimSpace(m.Value)
+	return len(value) == 0 || bytes.Equal(value, []byte("null"))
+}
+
+// MergeMeta returns metadata with the user metadata merged in. User values
+// replace values with the same key, compared without case, and the result
+// is sorted by key.
+func MergeMeta(meta, user []Meta) []Meta {
+	result := make([]Meta, 0, len(meta)+len(user))
+	result = append(result, user...)
+	for _, m := range meta {
+		if !slices.ContainsFunc(user, func(u Meta) bool {
+			return strings.EqualFold(u.Key, m.Key)
+		}) {
+			result = append(result, m)
+		}
+	}
+	slices.SortFunc(result, func(a, b Meta) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SELECTOR
+
+func (k UserMetaKey) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if k.Volume == "" {
+		return "", httpresponse.ErrBadRequest.With("missing object volume")
+	} else {
+		bind.Set("volume", k.Volume)
+	}
+	if k.Path == "" {
+		return "", httpresponse.ErrBadRequest.With("missing object path")
+	} else {
+		bind.Set("path", k.Path)
+	}
+
+	switch op {
+	case pg.List:
+		return bind.Query("filer.user_meta_list"), nil
+	case pg.Delete:
+		if key := sanitizeMetaKey(k.Key); key == "" {
+			return "", httpresponse.ErrBadRequest.With("missing metadata key")
+		} else {
+			bind.Set("key", strings.ToLower(key))
+		}
+		return bind.Query("filer.user_meta_delete"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported UserMetaKey operation %q", op)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WRITER
+
+// Insert sets a user metadata key. Keys are stored in lowercase, as with
+// S3 metadata, so that a key is only set once on an object.
+func (m UserMeta) Insert(bind *pg.Bind) (string, error) {
+	if volume, ok := bind.Get("volume").(string); !ok || strings.TrimSpace(volume) == "" {
+		return "", gofiler.ErrBadParameter.With("missing object volume")
+	}
+	if path, ok := bind.Get("path").(string); !ok || strings.TrimSpace(path) == "" {
+		return "", gofiler.ErrBadParameter.With("missing object path")
+	}
+	if key := sanitizeMetaKey(m.Key); key == "" {
+		return "", gofiler.ErrBadParameter.With("missing metadata key")
+	} else {
+		bind.Set("key", strings.ToLower(key))
+	}
+	if Meta(m).IsNull() {
+		return "", gofiler.ErrBadParameter.Withf("missing value for metadata key %q", m.Key)
+	} else {
+		bind.Set("value", m.Value)
+	}
+
+	// Return the query
+	return bind.Query("filer.user_meta_upsert"), nil
+}
+
+func (m UserMeta) Update(bind *pg.Bind) error {
+	return gofiler.ErrBadParameter.With("user meta update is not supported; use insert")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READER
+
+func (l *UserMetaList) Scan(row pg.Row) error {
+	var meta Meta
+	if err := meta.Scan(row); err != nil {
+		return err
+	}
+	*l = append(*l, meta)
+	return nil
+}
@@ -0,0 +1,40 @@
+package schema
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// MERGE
+
+func TestMergeMeta_001(t *testing.T) {
+	meta := func(pairs ...string) []Meta {
+		var result []Meta
+		for i := 0; i+1 < len(pairs); i += 2 {
+			result = append(result, Meta{Key: pairs[i], Value: json.RawMessage(pairs[i+1])})
+		}
+		return result
+	}
+	tests := []struct {
+		name       string
+		meta, user []Meta
+		want       []Meta
+	}{
+		{"empty", nil, nil, meta()},
+		{"extracted only", meta("title", `"a"`), nil, meta("title", `"a"`)},
+		{"user only", nil, meta("title", `"b"`), meta("title", `"b"`)},
+		{"user replaces", meta("title", `"a"`, "width", `10`), meta("title", `"b"`), meta("title", `"b"`, "width", `10`)},
+		{"case", meta("Title", `"a"`), meta("title", `"b"`), meta("title", `"b"`)},
+		{"sorted", meta("z", `1`, "b", `2`), meta("a", `3`), meta("a", `3`, "b", `2`, "z", `1`)},
+	}
+	for _, test := range tests {
+		got := MergeMeta(test.meta, test.user)
+		if !slices.EqualFunc(got, test.want, func(a, b Meta) bool {
+			return a.Key == b.Key && string(a.Value) == string(b.Value)
+		}) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package schema
 
 import (
+	"encoding/json"
 	"fmt"
+	"mime"
 	"net/url"
+	"path"
+	"slices"
 	"strings"
 	"time"
 
@@ -21,6 +25,19 @@ type VolumeTouch string
 type VolumeMeta struct {
 	Enabled    *bool          `json:"enabled,omitempty" negatable:""`
 	IndexDelta *time.Duration `json:"delta,omitempty"` // if non-zero, forces a full re-index if the last index is older than this duration
+	VolumePolicy
+	Rules    VolumeRules    `json:"rules,omitzero" embed:"" prefix:"index-"`
+	Schedule VolumeSchedule `json:"schedule,omitzero" embed:"" prefix:"schedule-"`
+}
+
+// VolumePolicy restricts the changes which can be made to objects in a
+// volume through the filer. When patching a volume, nil fields are unchanged
+// and empty lists remove the restriction.
+type VolumePolicy struct {
+	ReadOnly     *bool    `json:"readonly,omitempty" help:"Disallow writes and deletes" negatable:""`
+	NoDelete     *bool    `json:"nodelete,omitempty" help:"Disallow deletes" negatable:""`
+	ContentTypes []string `json:"content_types,omitempty" help:"Content types allowed for writes, such as image/*"`
+	Paths        []string `json:"paths,omitempty" help:"Path globs allowed for writes, such as /photos/*"`
 }
 
 type VolumeCreate struct {
@@ -37,10 +54,48 @@ type Volume struct {
 	LastIndexedObjectAt *time.Time `json:"last_indexed_object_at,omitempty"`
 }
 
+// VolumeIndex is the progress of the last index run for a volume. The run
+// starts when the volume is reindexed, and counts the objects queued for
+// indexing since then.
+type VolumeIndex struct {
+	Status      string     `json:"status"` // running or done
+	StartedAt   time.Time  `json:"started_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Total       uint64     `json:"total"`     // objects queued for indexing since the run started
+	Queued      uint64     `json:"queued"`    // objects waiting to be indexed, including retries
+	Running     uint64     `json:"running"`   // objects being indexed
+	Succeeded   uint64     `json:"succeeded"` // objects indexed
+	Failed      uint64     `json:"failed"`    // objects which could not be indexed after all retries
+	Skipped     uint64     `json:"skipped"`   // objects which were unchanged or removed
+	ETA         *time.Time `json:"eta,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// VolumeIndexName selects the progress of the last index run for a volume
+type VolumeIndexName string
+
+// IndexRunStart starts an index run for a volume, resetting the progress
+type IndexRunStart string
+
+// IndexRunEnqueue counts an object queued for indexing in a volume
+type IndexRunEnqueue string
+
+// IndexRunResult counts the result of indexing an object in a volume
+type IndexRunResult struct {
+	Volume  string
+	Skipped bool   // the object was unchanged or removed
+	Error   string // the indexing error, or a warning when the object was indexed
+	Failed  bool   // the object was not indexed, and the task will be retried or fail
+}
+
 type VolumeListRequest struct {
 	Enabled *bool `json:"enabled,omitempty" help:"returns only enabled or disabled volumes" negatable:""`
 	Stale   bool  `json:"stale,omitzero" help:"returns volumes that need to be re-indexed" negatable:""`
 	pg.OffsetLimit
+
+	// Names restricts the volumes returned, when not nil
+	Names []string `json:"-" kong:"-"`
 }
 
 type VolumeList struct {
@@ -49,6 +104,18 @@ type VolumeList struct {
 	Body  []*Volume `json:"body,omitempty"`
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// IndexQueue is the name of the queue for indexing objects
+	IndexQueue = "index-object"
+
+	// Status of an index run
+	IndexStatusRunning = "running"
+	IndexStatusDone    = "done"
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -60,6 +127,32 @@ func (v VolumeMeta) String() string {
 	return types.Stringify(v)
 }
 
+func (p VolumePolicy) String() string {
+	var parts []string
+	if types.Value(p.ReadOnly) {
+		parts = append(parts, "readonly")
+	} else if types.Value(p.NoDelete) {
+		parts = append(parts, "nodelete")
+	}
+	if len(p.ContentTypes) > 0 {
+		parts = append(parts, "types="+strings.Join(p.ContentTypes, ","))
+	}
+	if len(p.Paths) > 0 {
+		parts = append(parts, "paths="+strings.Join(p.Paths, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// String returns the progress of the index run, as the number of objects
+// completed out of the number queued
+func (i VolumeIndex) String() string {
+	str := fmt.Sprintf("%s %d/%d", i.Status, i.Completed(), i.Total)
+	if i.Failed > 0 {
+		str += fmt.Sprintf(" (%d failed)", i.Failed)
+	}
+	return str
+}
+
 func (v VolumeListRequest) String() string {
 	return types.Stringify(v)
 }
@@ -92,7 +185,7 @@ func (r VolumeListRequest) Query() url.Values {
 // TABLE OUTPUT
 
 func (r Volume) Header() []string {
-	return []string{"Volume", "URL", "Enabled", "Created At", "Indexed Objects", "Index Delta", "Indexed At", "Last Indexed Object At"}
+	return []string{"Volume", "URL", "Enabled", "Created At", "Indexed Objects", "Schedule", "Indexed At", "Last Indexed Object At", "Policy"}
 }
 
 func (r Volume) Width(col int) int {
@@ -119,7 +212,9 @@ func (r Volume) Cell(col int) string {
 			return ""
 		}
 	case 5:
-		if r.IndexDelta == nil {
+		if cron := types.Value(r.Schedule.Cron); cron != "" {
+			return cron
+		} else if r.IndexDelta == nil {
 			return "disabled"
 		}
 		return r.IndexDelta.String()
@@ -133,25 +228,178 @@ func (r Volume) Cell(col int) string {
 			return ""
 		}
 		return r.LastIndexedObjectAt.Format(time.RFC3339)
+	case 8:
+		return r.VolumePolicy.String()
 	default:
 		return ""
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Validate checks the content types and path globs of the policy
+func (p VolumePolicy) Validate() error {
+	for _, pattern := range p.ContentTypes {
+		if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+			return gofiler.ErrBadParameter.Withf("invalid content type pattern: %q", pattern)
+		}
+	}
+	for _, pattern := range p.Paths {
+		if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+			return gofiler.ErrBadParameter.Withf("invalid path pattern: %q", pattern)
+		}
+	}
+	return nil
+}
+
+// CheckWrite returns ErrForbidden when the policy does not allow an object
+// to be written with a content type. A path is allowed when the path, or one
+// of its parent directories, matches a path glob. The content type is not
+// checked when empty, which is the case when only metadata is written.
+func (p VolumePolicy) CheckWrite(objectPath, contentType string) error {
+	if types.Value(p.ReadOnly) {
+		return gofiler.ErrForbidden.With("volume is read-only")
+	}
+	if len(p.Paths) > 0 {
+		objectPath = path.Clean("/" + objectPath)
+		if !slices.ContainsFunc(p.Paths, func(pattern string) bool {
+			return matchPath(path.Clean("/"+pattern), objectPath)
+		}) {
+			return gofiler.ErrForbidden.Withf("volume does not allow writes to %q", objectPath)
+		}
+	}
+	if contentType = strings.TrimSpace(contentType); contentType != "" && len(p.ContentTypes) > 0 {
+		if t, _, err := mime.ParseMediaType(contentType); err == nil {
+			contentType = t
+		}
+		if !slices.ContainsFunc(p.ContentTypes, func(pattern string) bool {
+			ok, _ := path.Match(strings.ToLower(strings.TrimSpace(pattern)), strings.ToLower(contentType))
+			return ok
+		}) {
+			return gofiler.ErrForbidden.Withf("volume does not allow content type %q", contentType)
+		}
+	}
+	return nil
+}
+
+// CheckDelete returns ErrForbidden when the policy does not allow objects to
+// be deleted
+func (p VolumePolicy) CheckDelete() error {
+	if types.Value(p.ReadOnly) {
+		return gofiler.ErrForbidden.With("volume is read-only")
+	} else if types.Value(p.NoDelete) {
+		return gofiler.ErrForbidden.With("volume does not allow deletes")
+	}
+	return nil
+}
+
+// Completed returns the number of objects which have been indexed, skipped
+// or have failed
+func (i VolumeIndex) Completed() uint64 {
+	return i.Succeeded + i.Skipped + i.Failed
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// matchPath returns true if the path, or one of its parent directories,
+// matches the glob
+func matchPath(pattern, objectPath string) bool {
+	for {
+		if ok, _ := path.Match(pattern, objectPath); ok {
+			return true
+		} else if objectPath == "/" {
+			return false
+		}
+		objectPath = path.Dir(objectPath)
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // READER
 
 func (v *Volume) Scan(row pg.Row) error {
-	return row.Scan(
+	var rules, schedule []byte
+	v.ContentTypes, v.Paths = nil, nil
+	if err := row.Scan(
 		&v.Name,
 		&v.URL,
 		&v.Enabled,
 		&v.IndexDelta,
+		&v.ReadOnly,
+		&v.NoDelete,
+		&v.ContentTypes,
+		&v.Paths,
+		&rules,
+		&schedule,
 		&v.CreatedAt,
 		&v.IndexedAt,
 		&v.Objects,
 		&v.LastIndexedObjectAt,
-	)
+	); err != nil {
+		return err
+	}
+	if err := v.Rules.decode(rules); err != nil {
+		return err
+	}
+	if err := v.Schedule.decode(schedule); err != nil {
+		return err
+	}
+	if len(v.ContentTypes) == 0 {
+		v.ContentTypes = nil
+	}
+	if len(v.Paths) == 0 {
+		v.Paths = nil
+	}
+	return nil
+}
+
+// Scan reads the progress of an index run, and derives the number of
+// failed objects, the status and the estimated completion time
+func (i *VolumeIndex) Scan(row pg.Row) error {
+	var lastError *string
+	if err := row.Scan(
+		&i.StartedAt,
+		&i.UpdatedAt,
+		&i.Total,
+		&i.Queued,
+		&i.Running,
+		&i.Succeeded,
+		&i.Skipped,
+		&lastError,
+		&i.LastErrorAt,
+	); err != nil {
+		return err
+	}
+	i.LastError = types.Value(lastError)
+
+	// Objects which are no longer queued, but did not succeed and were not
+	// skipped, have failed
+	pending := i.Queued + i.Running
+	if done := i.Succeeded + i.Skipped + pending; i.Total > done {
+		i.Failed = i.Total - done
+	} else {
+		i.Failed = 0
+	}
+
+	// The run is done when there are no pending objects
+	i.ETA = nil
+	if pending == 0 {
+		i.Status = IndexStatusDone
+		return nil
+	} else {
+		i.Status = IndexStatusRunning
+	}
+
+	// Estimate the completion time from the rate at which objects have completed
+	if completed := i.Completed(); completed > 0 {
+		remaining := float64(time.Since(i.StartedAt)) * float64(pending) / float64(completed)
+		i.ETA = types.Ptr(time.Now().Add(time.Duration(remaining)).Truncate(time.Second))
+	}
+
+	// Return success
+	return nil
 }
 
 func (v *VolumeList) Scan(row pg.Row) error {
@@ -205,6 +453,58 @@ func (v VolumeTouch) Select(bind *pg.Bind, op pg.Op) (string, error) {
 	}
 }
 
+func (v VolumeIndexName) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(string(v)))
+	if !types.IsIdentifier(name) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", name)
+	}
+	bind.Set("name", name)
+	bind.Set("index_queue", IndexQueue)
+
+	switch op {
+	case pg.Get:
+		return bind.Query("filer.volume_index"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported VolumeIndexName operation %q", op)
+	}
+}
+
+func (v IndexRunEnqueue) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if !types.IsIdentifier(string(v)) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", v)
+	}
+	bind.Set("volume", string(v))
+
+	switch op {
+	case pg.Update:
+		return bind.Query("filer.index_run_enqueue"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported IndexRunEnqueue operation %q", op)
+	}
+}
+
+func (r IndexRunResult) Select(bind *pg.Bind, op pg.Op) (string, error) {
+	if !types.IsIdentifier(r.Volume) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", r.Volume)
+	}
+	bind.Set("volume", r.Volume)
+	bind.Set("succeeded", 0)
+	bind.Set("skipped", 0)
+	if r.Skipped {
+		bind.Set("skipped", 1)
+	} else if !r.Failed {
+		bind.Set("succeeded", 1)
+	}
+	bind.Set("error", types.TrimStringPtr(&r.Error))
+
+	switch op {
+	case pg.Update:
+		return bind.Query("filer.index_run_result"), nil
+	default:
+		return "", gofiler.ErrInternalServerError.Withf("unsupported IndexRunResult operation %q", op)
+	}
+}
+
 func (v *VolumeListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
 	bind.Set("orderby", "ORDER BY created_at DESC")
 
@@ -218,6 +518,9 @@ func (v *VolumeListRequest) Select(bind *pg.Bind, op pg.Op) (string, error) {
 		bind.Append("where", `"index_delta" IS NOT NULL`)
 		bind.Append("where", `("indexed_at" IS NULL OR "indexed_at" < (NOW() - "index_delta"))`)
 	}
+	if v.Names != nil {
+		bind.Append("where", `"name" = ANY(`+bind.Set("names", v.Names)+`)`)
+	}
 	if where := bind.Join("where", " AND "); where != "" {
 		bind.Set("where", `WHERE `+where)
 	} else {
@@ -259,6 +562,33 @@ func (v VolumeCreate) Insert(bind *pg.Bind) (string, error) {
 
 	bind.Set("index_delta", v.IndexDelta)
 
+	// Set the access policy
+	if err := v.VolumePolicy.Validate(); err != nil {
+		return "", err
+	}
+	bind.Set("readonly", types.Value(v.ReadOnly))
+	bind.Set("nodelete", types.Value(v.NoDelete))
+	bind.Set("content_types", append([]string{}, v.ContentTypes...))
+	bind.Set("paths", append([]string{}, v.Paths...))
+
+	// Set the indexing rules
+	if err := v.Rules.Validate(); err != nil {
+		return "", err
+	} else if data, err := json.Marshal(v.Rules); err != nil {
+		return "", err
+	} else {
+		bind.Set("rules", string(data))
+	}
+
+	// Set the schedule
+	if err := v.Schedule.Validate(); err != nil {
+		return "", err
+	} else if data, err := json.Marshal(v.Schedule); err != nil {
+		return "", err
+	} else {
+		bind.Set("schedule", string(data))
+	}
+
 	return bind.Query("filer.volume_insert"), nil
 }
 
@@ -281,6 +611,38 @@ func (v VolumeMeta) Update(bind *pg.Bind) error {
 		}
 	}
 
+	if err := v.VolumePolicy.Validate(); err != nil {
+		return err
+	}
+	if v.ReadOnly != nil {
+		bind.Append("patch", `"readonly" = `+bind.Set("readonly", v.ReadOnly))
+	}
+	if v.NoDelete != nil {
+		bind.Append("patch", `"nodelete" = `+bind.Set("nodelete", v.NoDelete))
+	}
+	if v.ContentTypes != nil {
+		bind.Append("patch", `"content_types" = `+bind.Set("content_types", v.ContentTypes))
+	}
+	if v.Paths != nil {
+		bind.Append("patch", `"paths" = `+bind.Set("paths", v.Paths))
+	}
+
+	if err := v.Rules.Validate(); err != nil {
+		return err
+	} else if rules, err := v.Rules.patch(); err != nil {
+		return err
+	} else if rules != "" {
+		bind.Append("patch", `"rules" = "rules" || CAST(`+bind.Set("rules", rules)+` AS JSONB)`)
+	}
+
+	if err := v.Schedule.Validate(); err != nil {
+		return err
+	} else if schedule, err := v.Schedule.patch(); err != nil {
+		return err
+	} else if schedule != "" {
+		bind.Append("patch", `"schedule" = "schedule" || CAST(`+bind.Set("schedule", schedule)+` AS JSONB)`)
+	}
+
 	if patch := bind.Join("patch", ", "); patch == "" {
 		return gofiler.ErrBadParameter.With("no patch values")
 	} else {
@@ -289,3 +651,17 @@ func (v VolumeMeta) Update(bind *pg.Bind) error {
 
 	return nil
 }
+
+func (v IndexRunStart) Insert(bind *pg.Bind) (string, error) {
+	if !types.IsIdentifier(string(v)) {
+		return "", gofiler.ErrBadParameter.Withf("invalid volume name: %q", v)
+	}
+	bind.Set("volume", string(v))
+
+	// Return the query
+	return bind.Query("filer.index_run_start"), nil
+}
+
+func (v IndexRunStart) Update(_ *pg.Bind) error {
+	return gofiler.ErrNotImplemented.With("IndexRunStart: update: not supported")
+}
@@ -0,0 +1,69 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// POLICY
+
+func TestVolumePolicyCheckWrite_001(t *testing.T) {
+	tests := []struct {
+		name              string
+		policy            VolumePolicy
+		path, contentType string
+		allowed           bool
+	}{
+		{"no policy", VolumePolicy{}, "/a.txt", "text/plain", true},
+		{"read-only", VolumePolicy{ReadOnly: types.Ptr(true)}, "/a.txt", "text/plain", false},
+		{"read-only metadata", VolumePolicy{ReadOnly: types.Ptr(true)}, "/a.txt", "", false},
+		{"not read-only", VolumePolicy{ReadOnly: types.Ptr(false)}, "/a.txt", "text/plain", true},
+		{"no-delete", VolumePolicy{NoDelete: types.Ptr(true)}, "/a.txt", "text/plain", true},
+
+		// Paths match the path or a parent directory
+		{"path", VolumePolicy{Paths: []string{"/photos/*"}}, "/photos/a.jpg", "image/jpeg", true},
+		{"parent path", VolumePolicy{Paths: []string{"/photos"}}, "photos/2024/a.jpg", "image/jpeg", true},
+		{"other path", VolumePolicy{Paths: []string{"/photos"}}, "/photographs/a.jpg", "image/jpeg", false},
+
+		// Content types ignore case and parameters, and are not checked when empty
+		{"type", VolumePolicy{ContentTypes: []string{"image/*"}}, "/a.jpg", "IMAGE/JPEG", true},
+		{"type parameters", VolumePolicy{ContentTypes: []string{"text/plain"}}, "/a.txt", "text/plain; charset=utf-8", true},
+		{"other type", VolumePolicy{ContentTypes: []string{"image/*"}}, "/a.txt", "text/plain", false},
+		{"metadata", VolumePolicy{ContentTypes: []string{"image/*"}}, "/a.txt", "", true},
+	}
+	for _, test := range tests {
+		err := test.policy.CheckWrite(test.path, test.contentType)
+		if test.allowed && err != nil {
+			t.Errorf("%s: expected allowed, got %v", test.name, err)
+		} else if !test.allowed && !errors.Is(err, gofiler.ErrForbidden) {
+			t.Errorf("%s: expected forbidden, got %v", test.name, err)
+		}
+	}
+}
+
+func TestVolumePolicyCheckDelete_001(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  VolumePolicy
+		allowed bool
+	}{
+		{"no policy", VolumePolicy{}, true},
+		{"read-only", VolumePolicy{ReadOnly: types.Ptr(true)}, false},
+		{"no-delete", VolumePolicy{NoDelete: types.Ptr(true)}, false},
+		{"not no-delete", VolumePolicy{NoDelete: types.Ptr(false)}, true},
+		{"paths", VolumePolicy{Paths: []string{"/photos"}}, true},
+	}
+	for _, test := range tests {
+		err := test.policy.CheckDelete()
+		if test.allowed && err != nil {
+			t.Errorf("%s: expected allowed, got %v", test.name, err)
+		} else if !test.allowed && !errors.Is(err, gofiler.ErrForbidden) {
+			t.Errorf("%s: expected forbidden, got %v", test.name, err)
+		}
+	}
+}
@@ -34,6 +34,11 @@ func (e *htmlextractor) MediaType() *regexp.Regexp {
 	return regexp.MustCompile(`^text/(x)?html$`)
 }
 
+// Summarizes returns true, since the content is summarised with an LLM
+func (e *htmlextractor) Summarizes() bool {
+	return true
+}
+
 func (e *htmlextractor) ExtractMetadata(ctx context.Context, r io.Reader) ([]schema.Meta, []*schema.ArtworkMeta, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, nil, err
@@ -4,11 +4,9 @@ import (
 	"bytes"
 	"image"
 	_ "image/gif"
-	"image/jpeg"
-	"image/png"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
-	"slices"
-	"strings"
 
 	// Packages
 	schema "github.com/mutablelogic/go-filer/filer/schema"
@@ -70,23 +68,14 @@ func CreateArtwork(r io.Reader) (*schema.ArtworkMeta, []schema.Meta, error) {
 	xdraw.BiLinear.Scale(dst, dst.Rect, img, img.Bounds(), xdraw.Over, nil)
 
 	// If the original was a lossless format, encode as png; otherwise encode as jpeg
-	var buf bytes.Buffer
-	var contentType string
-	if slices.Contains(strings.Split(PNGFormats, ","), format) {
-		if err = png.Encode(&buf, dst); err != nil {
-			return nil, kv, err
-		}
-		contentType = "image/png"
-	} else {
-		if err = jpeg.Encode(&buf, dst, nil); err != nil {
-			return nil, kv, err
-		}
-		contentType = "image/jpeg"
+	data, contentType, err := encodeImage(dst, defaultFormat(format))
+	if err != nil {
+		return nil, kv, err
 	}
 
 	// Return the artwork meta
 	return &schema.ArtworkMeta{
-		Data:   buf.Bytes(),
+		Data:   data,
 		Type:   contentType,
 		Width:  uint64(width),
 		Height: uint64(height),
@@ -61,7 +61,12 @@ func init() {
 	metadata.RegisterExtractor(new(imageextractor))
 }
 
+// NewImageSummarizer returns a summarizer, or nil when summarising has been
+// disabled in the context
 func NewImageSummarizer(ctx context.Context) (*imagesummarizer, error) {
+	if !metadata.Summarize(ctx) {
+		return nil, nil
+	}
 	ollamaMu.Lock()
 	defer ollamaMu.Unlock()
 
@@ -87,6 +92,11 @@ func (e *imageextractor) MediaType() *regexp.Regexp {
 	return regexp.MustCompile(`^image/.*`)
 }
 
+// Summarizes returns true, since the content is summarised with an LLM
+func (e *imageextractor) Summarizes() bool {
+	return true
+}
+
 func (e *imageextractor) ExtractMetadata(ctx context.Context, r io.Reader) ([]schema.Meta, []*schema.ArtworkMeta, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, nil, err
@@ -102,6 +112,8 @@ func (e *imageextractor) ExtractMetadata(ctx context.Context, r io.Reader) ([]sc
 	summarizer, err := NewImageSummarizer(ctx)
 	if err != nil {
 		return meta, []*schema.ArtworkMeta{artwork}, err
+	} else if summarizer == nil {
+		return meta, []*schema.ArtworkMeta{artwork}, nil
 	} else if err := summarizer.Summarize(ctx, artwork.Data); err != nil {
 		return meta, []*schema.ArtworkMeta{artwork}, err
 	}
@@ -0,0 +1,197 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	// Packages
+	gofiler "github.com/mutablelogic/go-filer"
+	schema "github.com/mutablelogic/go-filer/filer/schema"
+	xdraw "golang.org/x/image/draw"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// EncoderFunc encodes an image in a format
+type EncoderFunc func(io.Writer, image.Image) error
+
+type encoder struct {
+	contentType string
+	fn          EncoderFunc
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// maxRenditionSize is the maximum size of a source image, in bytes
+	maxRenditionSize = 64 * 1024 * 1024
+
+	// maxRenditionPixels is the maximum number of pixels in a source image,
+	// which limits the memory used to decode it
+	maxRenditionPixels = 64 * 1024 * 1024
+)
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]encoder{
+		schema.RenditionFormatJPEG: {"image/jpeg", func(w io.Writer, img image.Image) error {
+			return jpeg.Encode(w, img, nil)
+		}},
+		schema.RenditionFormatPNG: {"image/png", png.Encode},
+	}
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// RegisterEncoder adds an encoder for a rendition format, such as webp,
+// which is not supported by the standard library
+func RegisterEncoder(format, contentType string, fn EncoderFunc) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[format] = encoder{contentType, fn}
+}
+
+// HasEncoder returns true if renditions can be encoded in a format
+func HasEncoder(format string) bool {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	_, exists := encoders[format]
+	return exists
+}
+
+// CreateRendition resizes an image to the width, height and fit mode of the
+// request, and encodes it in the requested format. Images are never scaled
+// up, and an image which needs no resizing or conversion is returned as is.
+func CreateRendition(r io.Reader, req schema.RenditionRequest) (*schema.ArtworkMeta, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxRenditionSize+1))
+	if err != nil {
+		return nil, err
+	} else if len(data) > maxRenditionSize {
+		return nil, gofiler.ErrBadParameter.Withf("image is larger than %d bytes", maxRenditionSize)
+	}
+
+	// Check the image dimensions before decoding it
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return nil, gofiler.ErrBadParameter.Withf("unable to decode image: %v", err)
+	} else if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > maxRenditionPixels {
+		return nil, gofiler.ErrBadParameter.Withf("image dimensions %dx%d are too large", config.Width, config.Height)
+	}
+
+	// Decode the image
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, gofiler.ErrBadParameter.Withf("unable to decode image: %v", err)
+	}
+
+	// Determine the output format
+	if req.Format == "" {
+		req.Format = defaultFormat(format)
+	}
+
+	// Determine the source rectangle and the size of the rendition
+	src, width, height := renditionBounds(img.Bounds(), req)
+
+	// Fast-path: return the image as is when it needs no resizing or conversion
+	if req.Format == format && src == img.Bounds() && width == src.Dx() && height == src.Dy() {
+		return &schema.ArtworkMeta{
+			Data:   data,
+			Type:   "image/" + format,
+			Width:  uint64(width),
+			Height: uint64(height),
+		}, nil
+	}
+
+	// Scale and encode the image
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.BiLinear.Scale(dst, dst.Rect, img, src, xdraw.Over, nil)
+	result, contentType, err := encodeImage(dst, req.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the rendition
+	return &schema.ArtworkMeta{
+		Data:   result,
+		Type:   contentType,
+		Width:  uint64(width),
+		Height: uint64(height),
+	}, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// defaultFormat returns png for lossless source formats, and jpeg otherwise
+func defaultFormat(format string) string {
+	if slices.Contains(strings.Split(PNGFormats, ","), format) {
+		return schema.RenditionFormatPNG
+	}
+	return schema.RenditionFormatJPEG
+}
+
+// encodeImage encodes an image and returns the data and content type
+func encodeImage(img image.Image, format string) ([]byte, string, error) {
+	encodersMu.RLock()
+	encoder, exists := encoders[format]
+	encodersMu.RUnlock()
+	if !exists {
+		return nil, "", gofiler.ErrNotImplemented.Withf("%s encoding is not available", format)
+	}
+	var buf bytes.Buffer
+	if err := encoder.fn(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), encoder.contentType, nil
+}
+
+// renditionBounds returns the rectangle of the source image to scale, and
+// the width and height of the rendition
+func renditionBounds(bounds image.Rectangle, req schema.RenditionRequest) (image.Rectangle, int, int) {
+	sw, sh := float64(bounds.Dx()), float64(bounds.Dy())
+	w, h := float64(req.Width), float64(req.Height)
+
+	// Scale factors, where an unset dimension does not constrain the size
+	sx, sy := w/sw, h/sh
+	if w == 0 {
+		sx = sy
+	}
+	if h == 0 {
+		sy = sx
+	}
+
+	switch {
+	case req.Fit == schema.RenditionFitFill:
+		return bounds, dimension(sw * min(sx, 1)), dimension(sh * min(sy, 1))
+	case req.Fit == schema.RenditionFitCover && w > 0 && h > 0:
+		scale := min(max(sx, sy), 1)
+		width, height := dimension(min(w, sw*scale)), dimension(min(h, sh*scale))
+
+		// Crop the centre of the source to the aspect ratio of the rendition
+		cw, ch := int(math.Round(float64(width)/scale)), int(math.Round(float64(height)/scale))
+		cw, ch = min(cw, bounds.Dx()), min(ch, bounds.Dy())
+		x0 := bounds.Min.X + (bounds.Dx()-cw)/2
+		y0 := bounds.Min.Y + (bounds.Dy()-ch)/2
+		return image.Rect(x0, y0, x0+cw, y0+ch), width, height
+	default:
+		scale := min(sx, sy, 1)
+		return bounds, dimension(sw * scale), dimension(sh * scale)
+	}
+}
+
+// dimension rounds a size to whole pixels, with a minimum of one pixel
+func dimension(v float64) int {
+	return max(int(math.Round(v)), 1)
+}
@@ -38,6 +38,11 @@ func (e *jsonextractor) MediaType() *regexp.Regexp {
 	return regexp.MustCompile(`^application/json$`)
 }
 
+// Summarizes returns true, since the content is summarised with an LLM
+func (e *jsonextractor) Summarizes() bool {
+	return true
+}
+
 func (e *jsonextractor) ExtractMetadata(ctx context.Context, r io.Reader) ([]schema.Meta, []*schema.ArtworkMeta, error) {
 	// Initialise summarizer first so ollamaMaxInputTokens is set before reading
 	summarizer, err := text.NewTextSummarizer(ctx)
@@ -122,3 +122,29 @@ func (m *Manager) Get(ctx context.Context, mimeType string, r io.Reader) ([]sche
 	// Extract metadata does not produce errors, only warnings
 	return extractor.ExtractMetadata(ctx, r)
 }
+
+// Extractor returns the name of the extractor for a media type, or an empty
+// string if there is no extractor registered
+func (m *Manager) Extractor(mimeType string) string {
+	if extractor, err := metadata.Get(mimeType); err != nil {
+		return ""
+	} else {
+		return metadata.Name(extractor)
+	}
+}
+
+// Capabilities returns the capabilities required to extract metadata for a
+// media type, which are the name of the extractor and the LLM capability
+// when the extractor summarises content. No capabilities are required when
+// there is no extractor registered.
+func (m *Manager) Capabilities(mimeType string) []string {
+	extractor, err := metadata.Get(mimeType)
+	if err != nil {
+		return nil
+	}
+	capabilities := []string{metadata.Name(extractor)}
+	if metadata.Summarizes(extractor) {
+		capabilities = append(capabilities, metadata.CapabilityLLM)
+	}
+	return capabilities
+}
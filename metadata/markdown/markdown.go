@@ -31,6 +31,11 @@ func (e *mdextractor) MediaType() *regexp.Regexp {
 	return regexp.MustCompile(`^text/markdown$`)
 }
 
+// Summarizes returns true, since the content is summarised with an LLM
+func (e *mdextractor) Summarizes() bool {
+	return true
+}
+
 func (e *mdextractor) ExtractMetadata(ctx context.Context, r io.Reader) ([]schema.Meta, []*schema.ArtworkMeta, error) {
 	// Initialise summarizer first so ollamaMaxInputTokens is set before reading
 	summarizer, err := text.NewTextSummarizer(ctx)
@@ -3,7 +3,9 @@ package metadata
 import (
 	"context"
 	"io"
+	"reflect"
 	"regexp"
+	"strings"
 
 	// Packages
 	gofiler "github.com/mutablelogic/go-filer"
@@ -21,11 +23,18 @@ type Extractor interface {
 	ExtractMetadata(ctx context.Context, r io.Reader) ([]schema.Meta, []*schema.ArtworkMeta, error)
 }
 
+// Summarizer is implemented by extractors which summarise content with an LLM
+type Summarizer interface {
+	Summarizes() bool
+}
+
 type FileReader interface {
 	io.Reader
 	Name() string
 }
 
+type contextKey int
+
 ////////////////////////////////////////////////////////////////////////////////
 // GLOBALS
 
@@ -33,6 +42,15 @@ var (
 	extractors = make(map[*regexp.Regexp]Extractor)
 )
 
+const (
+	summarizeKey contextKey = iota
+)
+
+const (
+	// CapabilityLLM is the capability required to summarise content with an LLM
+	CapabilityLLM = "llm"
+)
+
 func RegisterExtractor(e Extractor) {
 	extractors[e.MediaType()] = e
 }
@@ -103,3 +121,36 @@ func Get(mimeType string) (Extractor, error) {
 	}
 	return nil, gofiler.ErrNotImplemented.Withf("no extractor registered for media type %q", mimeType)
 }
+
+// Return a context which enables or disables summarising content with an LLM
+// when extracting metadata
+func WithSummarize(ctx context.Context, summarize bool) context.Context {
+	return context.WithValue(ctx, summarizeKey, summarize)
+}
+
+// Return false when summarising content with an LLM has been disabled in the
+// context
+func Summarize(ctx context.Context) bool {
+	if summarize, ok := ctx.Value(summarizeKey).(bool); ok {
+		return summarize
+	}
+	return true
+}
+
+// Return the name of an extractor, which is the name of the type without the
+// extractor suffix
+func Name(e Extractor) string {
+	t := reflect.TypeOf(e)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return strings.TrimSuffix(t.Name(), "extractor")
+}
+
+// Return true if the extractor summarises content with an LLM
+func Summarizes(e Extractor) bool {
+	if s, ok := e.(Summarizer); ok {
+		return s.Summarizes()
+	}
+	return false
+}
@@ -41,6 +41,11 @@ func (e *codeextractor) MediaType() *regexp.Regexp {
 	return regexp.MustCompile(`^text/(x-)?(go|python|javascript|typescript|java|c|cpp|objective-c|csharp|ruby|php|rust|swift)$`)
 }
 
+// Summarizes returns true, since the content is summarised with an LLM
+func (e *codeextractor) Summarizes() bool {
+	return true
+}
+
 func (e *codeextractor) ExtractMetadata(ctx context.Context, r io.Reader) ([]schema.Meta, []*schema.ArtworkMeta, error) {
 	// Initialise summarizer first so ollamaMaxInputTokens is set before reading
 	summarizer, err := text.NewTextSummarizer(ctx)
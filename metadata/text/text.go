@@ -80,7 +80,12 @@ func NewTextReader(r io.Reader) *textreader {
 	return &textreader{scanner: scanner}
 }
 
+// NewTextSummarizer returns a summarizer, or nil when summarising has been
+// disabled in the context. A nil summarizer returns no metadata.
 func NewTextSummarizer(ctx context.Context) (*textsummarizer, error) {
+	if !metadata.Summarize(ctx) {
+		return nil, nil
+	}
 	ollamaMu.Lock()
 	defer ollamaMu.Unlock()
 	if ollamaClient == nil {
@@ -108,6 +113,11 @@ func (e *textextractor) MediaType() *regexp.Regexp {
 	return regexp.MustCompile(`^text/plain$`)
 }
 
+// Summarizes returns true, since the content is summarised with an LLM
+func (e *textextractor) Summarizes() bool {
+	return true
+}
+
 func (e *textextractor) ExtractMetadata(ctx context.Context, r io.Reader) ([]schema.Meta, []*schema.ArtworkMeta, error) {
 	// Initialise summarizer first so ollamaMaxInputTokens is set before reading
 	summarizer, err := NewTextSummarizer(ctx)
@@ -179,6 +189,9 @@ func (r *textreader) LineCount() int {
 // PUBLIC METHODS - SUMMARIZER
 
 func (r *textsummarizer) Summarize(ctx context.Context, text string, prompts ...string) ([]schema.Meta, error) {
+	if r == nil {
+		return nil, nil
+	}
 	prompts = append([]string{SystemPrompt}, prompts...)
 	opts := []llmopt.Opt{
 		llmopt.AddString(llmopt.SystemPromptKey, strings.Join(prompts, "\n\n")),